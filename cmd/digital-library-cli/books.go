@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/qo/digital-library/pkg/client"
+)
+
+// runBooks runs the books subcommand.
+// Usage: books list|search|add|upload|delete ...
+func (c *cli) runBooks(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("books: subcommand should be one of list, search, add, upload, delete")
+	}
+
+	switch args[0] {
+	case "list":
+		return c.listBooks(ctx, "books list", args[1:], false)
+	case "search":
+		return c.listBooks(ctx, "books search", args[1:], true)
+	case "add":
+		return c.addBook(ctx, args[1:])
+	case "upload":
+		return c.uploadBook(ctx, args[1:])
+	case "delete":
+		return c.deleteBook(ctx, args[1:])
+	default:
+		return fmt.Errorf("books: subcommand %s is unknown", args[0])
+	}
+}
+
+// listBooks prints a page of the books, or of the books matching the query if search is set.
+// Usage: books list [-limit N] [-offset N]
+// Usage: books search [-limit N] [-offset N] QUERY
+func (c *cli) listBooks(ctx context.Context, name string, args []string, search bool) error {
+	const errMsg = "can't list books"
+
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	limitFlag := fs.Int("limit", 0, "page size, the server default if not specified")
+	offsetFlag := fs.Int("offset", 0, "number of the books skipped")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	var query string
+	switch {
+	case search && fs.NArg() == 0:
+		return fmt.Errorf("%s: query should be specified", errMsg)
+	case search:
+		query = strings.Join(fs.Args(), " ")
+	case fs.NArg() > 0:
+		return fmt.Errorf("%s: unexpected arguments %v", errMsg, fs.Args())
+	}
+
+	books, err := c.client.ListBooks(ctx, query, *limitFlag, *offsetFlag)
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	rows := make([][]string, 0, len(books))
+	for _, b := range books {
+		year := ""
+		if b.Year != 0 {
+			year = strconv.Itoa(b.Year)
+		}
+		rows = append(rows, []string{strconv.Itoa(b.Id), b.Title, year, b.Publisher, b.Isbn})
+	}
+
+	return c.out.print(books, []string{"ID", "TITLE", "YEAR", "PUBLISHER", "ISBN"}, rows)
+}
+
+// addBook creates a book with its authors and uploads its file if it's specified.
+// Usage: books add -title TITLE [-isbn ISBN] [-year YEAR] [-publisher PUBLISHER] [-authors "NAME; NAME"] [-file FILE]
+func (c *cli) addBook(ctx context.Context, args []string) error {
+	const errMsg = "can't add book"
+
+	fs := flag.NewFlagSet("books add", flag.ContinueOnError)
+	titleFlag := fs.String("title", "", "title")
+	isbnFlag := fs.String("isbn", "", "isbn")
+	yearFlag := fs.Int("year", 0, "year")
+	publisherFlag := fs.String("publisher", "", "publisher")
+	authorsFlag := fs.String("authors", "", "full names of the authors separated with ;")
+	fileFlag := fs.String("file", "", "PDF of the book")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	if *titleFlag == "" {
+		return fmt.Errorf("%s: title should be specified", errMsg)
+	}
+
+	var authors []string
+	for _, name := range strings.Split(*authorsFlag, ";") {
+		if name = strings.TrimSpace(name); name != "" {
+			authors = append(authors, name)
+		}
+	}
+
+	// the file is opened first so a book isn't created without its file by mistake
+	var file *os.File
+	if *fileFlag != "" {
+		file, err = os.Open(*fileFlag)
+		if err != nil {
+			return fmt.Errorf("%s: %w", errMsg, err)
+		}
+		defer file.Close()
+	}
+
+	b := client.Book{Isbn: *isbnFlag, Title: *titleFlag, Year: *yearFlag, Publisher: *publisherFlag}
+
+	b.Id, err = c.client.PostBook(ctx, b, authors...)
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	if file != nil {
+		_, err = c.client.PutBookFile(ctx, b.Id, file)
+		if err != nil {
+			return fmt.Errorf("%s: book %d created but its file isn't uploaded: %w", errMsg, b.Id, err)
+		}
+	}
+
+	return c.out.done(b, "Book %d created", b.Id)
+}
+
+// uploadBook uploads the PDF of the book.
+// Usage: books upload ID FILE
+func (c *cli) uploadBook(ctx context.Context, args []string) error {
+	const errMsg = "can't upload book"
+
+	if len(args) != 2 {
+		return fmt.Errorf("%s: book id and file should be specified", errMsg)
+	}
+
+	id, err := strconv.Atoi(args[0])
+	if err != nil {
+		return fmt.Errorf("%s: book id is not a number", errMsg)
+	}
+
+	file, err := os.Open(args[1])
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+	defer file.Close()
+
+	size, err := c.client.PutBookFile(ctx, id, file)
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return c.out.done(struct {
+		Id   int   `json:"id"`
+		Size int64 `json:"size"`
+	}{id, size}, "File of book %d uploaded, %d bytes", id, size)
+}
+
+// deleteBook deletes the book, it can be restored until it's purged.
+// Usage: books delete ID
+func (c *cli) deleteBook(ctx context.Context, args []string) error {
+	const errMsg = "can't delete book"
+
+	if len(args) != 1 {
+		return fmt.Errorf("%s: book id should be specified", errMsg)
+	}
+
+	id, err := strconv.Atoi(args[0])
+	if err != nil {
+		return fmt.Errorf("%s: book id is not a number", errMsg)
+	}
+
+	err = c.client.DeleteBook(ctx, id)
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return c.out.done(struct {
+		Id int `json:"id"`
+	}{id}, "Book %d deleted", id)
+}
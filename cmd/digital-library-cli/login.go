@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const defaultServer = "http://localhost:5454"
+
+// login is the stored login of the cli.
+type login struct {
+	Server    string    `json:"server"`
+	UserId    int       `json:"user_id,omitempty"`
+	Token     string    `json:"token,omitempty"`
+	ExpiresAt time.Time `json:"expires_at,omitempty"`
+}
+
+// loginPath returns the path of the stored login,
+// e.g. ~/.config/digital-library/cli.json on Linux.
+func loginPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("can't find config dir: %w", err)
+	}
+	return filepath.Join(dir, "digital-library", "cli.json"), nil
+}
+
+// loadLogin returns the stored login or an empty login if there is none.
+func loadLogin() (*login, error) {
+	const errMsg = "can't load login"
+
+	path, err := loginPath()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &login{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	var l login
+
+	err = json.Unmarshal(data, &l)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s: %w", errMsg, path, err)
+	}
+
+	if !l.ExpiresAt.IsZero() && l.ExpiresAt.Before(time.Now()) {
+		l.Token = ""
+	}
+
+	return &l, nil
+}
+
+// save stores the login, only the user can read it.
+func (l *login) save() error {
+	const errMsg = "can't save login"
+
+	path, err := loginPath()
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0700)
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	err = os.WriteFile(path, data, 0600)
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return nil
+}
+
+// runLogin logs in and stores the token.
+// The password is read from stdin if it's not specified.
+// Usage: login -user ID [-password PASSWORD]
+func (c *cli) runLogin(ctx context.Context, args []string) error {
+	const errMsg = "can't log in"
+
+	fs := flag.NewFlagSet("login", flag.ContinueOnError)
+	userFlag := fs.Int("user", 0, "user id")
+	passwordFlag := fs.String("password", "", "password, read from stdin if not specified")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	if *userFlag == 0 {
+		return fmt.Errorf("%s: user id should be specified", errMsg)
+	}
+
+	password := *passwordFlag
+	if password == "" {
+		fmt.Fprint(os.Stderr, "Password: ")
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return fmt.Errorf("%s: can't read password: %w", errMsg, err)
+		}
+		password = strings.TrimRight(line, "\r\n")
+	}
+
+	s, err := c.client.Login(ctx, *userFlag, password)
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	c.login.UserId = *userFlag
+	c.login.Token = s.Token
+	c.login.ExpiresAt = s.ExpiresAt
+
+	err = c.login.save()
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	fmt.Fprintf(os.Stderr, "Logged in as user %d until %s\n", *userFlag, s.ExpiresAt.Local().Format(time.DateTime))
+
+	return nil
+}
+
+// runLogout ends the session and removes the stored token.
+// Usage: logout
+func (c *cli) runLogout(ctx context.Context, args []string) error {
+	const errMsg = "can't log out"
+
+	if len(args) > 0 {
+		return fmt.Errorf("%s: logout has no arguments", errMsg)
+	}
+
+	if c.login.Token == "" {
+		return fmt.Errorf("%s: not logged in", errMsg)
+	}
+
+	err := c.client.Logout(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	c.login.UserId = 0
+	c.login.Token = ""
+	c.login.ExpiresAt = time.Time{}
+
+	err = c.login.save()
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	fmt.Fprintln(os.Stderr, "Logged out")
+
+	return nil
+}
@@ -0,0 +1,110 @@
+// digital-library-cli is the admin tool of the digital library.
+// It talks to the server over the REST API.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/qo/digital-library/pkg/client"
+)
+
+const usage = `Usage: digital-library-cli [-server URL] [-json] COMMAND
+
+Commands:
+  login -user ID [-password PASSWORD]
+  logout
+  users list [-limit N] [-offset N]
+  users create -first-name NAME -second-name NAME [-role user|mod] [-password PASSWORD]
+  users delete ID
+  users role ID user|mod
+  books list [-limit N] [-offset N]
+  books search [-limit N] [-offset N] QUERY
+  books add -title TITLE [-isbn ISBN] [-year YEAR] [-publisher PUBLISHER] [-authors "NAME; NAME"] [-file FILE]
+  books upload ID FILE
+  books delete ID
+
+The token of the login is stored in the user config dir and sent with the other commands.
+DIGITAL_LIBRARY_TOKEN overrides it, e.g. with the admin token.
+
+Flags:
+`
+
+// cli is the state shared by the commands.
+type cli struct {
+	client *client.Client
+	out    printer
+	login  *login
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+
+	serverFlag := flag.String("server", "", "url of the server, the url of the login or http://localhost:5454 by default")
+	jsonFlag := flag.Bool("json", false, "print json instead of tables")
+
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	err := run(ctx, *serverFlag, *jsonFlag, flag.Args())
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, server string, jsonOutput bool, args []string) error {
+	l, err := loadLogin()
+	if err != nil {
+		return err
+	}
+
+	if server == "" {
+		server = l.Server
+	}
+	if server == "" {
+		server = defaultServer
+	}
+
+	token := l.Token
+	if env := os.Getenv("DIGITAL_LIBRARY_TOKEN"); env != "" {
+		token = env
+	}
+
+	c := cli{
+		client: client.New(server, client.Options{Token: token, Retries: 2}),
+		out:    printer{json: jsonOutput},
+		login:  l,
+	}
+	c.login.Server = server
+
+	switch args[0] {
+	case "login":
+		return c.runLogin(ctx, args[1:])
+	case "logout":
+		return c.runLogout(ctx, args[1:])
+	case "users":
+		return c.runUsers(ctx, args[1:])
+	case "books":
+		return c.runBooks(ctx, args[1:])
+	default:
+		return fmt.Errorf("command %s is unknown, run with -h to see the commands", args[0])
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+// printer prints the results either as tables or as json.
+type printer struct {
+	json bool
+}
+
+// print prints v as json or the rows as a table with the header.
+func (p printer) print(v any, header []string, rows [][]string) error {
+	if p.json {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+
+	return w.Flush()
+}
+
+// done prints the result of a command changing something.
+func (p printer) done(v any, message string, args ...any) error {
+	if p.json {
+		return p.print(v, nil, nil)
+	}
+	_, err := fmt.Printf(message+"\n", args...)
+	return err
+}
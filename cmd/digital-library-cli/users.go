@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strconv"
+
+	"github.com/qo/digital-library/pkg/client"
+)
+
+var roles = map[string]int{
+	"user":  client.RoleUser,
+	"mod":   client.RoleMod,
+	"admin": client.RoleAdmin,
+}
+
+func roleName(role int) string {
+	for name, r := range roles {
+		if r == role {
+			return name
+		}
+	}
+	return strconv.Itoa(role)
+}
+
+func parseRole(s string) (int, error) {
+	role, ok := roles[s]
+	if !ok || role == client.RoleAdmin {
+		return 0, fmt.Errorf("role %s can't be set, it should be user or mod", s)
+	}
+	return role, nil
+}
+
+// runUsers runs the users subcommand.
+// Usage: users list|create|delete|role ...
+func (c *cli) runUsers(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("users: subcommand should be one of list, create, delete, role")
+	}
+
+	switch args[0] {
+	case "list":
+		return c.listUsers(ctx, args[1:])
+	case "create":
+		return c.createUser(ctx, args[1:])
+	case "delete":
+		return c.deleteUser(ctx, args[1:])
+	case "role":
+		return c.changeRole(ctx, args[1:])
+	default:
+		return fmt.Errorf("users: subcommand %s is unknown", args[0])
+	}
+}
+
+// listUsers prints a page of the users.
+// Usage: users list [-limit N] [-offset N]
+func (c *cli) listUsers(ctx context.Context, args []string) error {
+	const errMsg = "can't list users"
+
+	fs := flag.NewFlagSet("users list", flag.ContinueOnError)
+	limitFlag := fs.Int("limit", 0, "page size, the server default if not specified")
+	offsetFlag := fs.Int("offset", 0, "number of the users skipped")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	users, err := c.client.ListUsers(ctx, *limitFlag, *offsetFlag)
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	rows := make([][]string, 0, len(users))
+	for _, u := range users {
+		rows = append(rows, []string{strconv.Itoa(u.Id), u.FirstName, u.SecondName, roleName(u.Role)})
+	}
+
+	return c.out.print(users, []string{"ID", "FIRST NAME", "SECOND NAME", "ROLE"}, rows)
+}
+
+// createUser creates a user or a mod.
+// Usage: users create -first-name NAME -second-name NAME [-role user|mod] [-password PASSWORD]
+func (c *cli) createUser(ctx context.Context, args []string) error {
+	const errMsg = "can't create user"
+
+	fs := flag.NewFlagSet("users create", flag.ContinueOnError)
+	firstNameFlag := fs.String("first-name", "", "first name")
+	secondNameFlag := fs.String("second-name", "", "second name")
+	roleFlag := fs.String("role", "user", "role (user, mod)")
+	passwordFlag := fs.String("password", "", "password, at least 8 characters; the user can't log in without it")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	if *firstNameFlag == "" || *secondNameFlag == "" {
+		return fmt.Errorf("%s: first and second names should be specified", errMsg)
+	}
+
+	role, err := parseRole(*roleFlag)
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	u := client.User{FirstName: *firstNameFlag, SecondName: *secondNameFlag, Role: role}
+
+	u.Id, err = c.client.PostUser(ctx, u, *passwordFlag)
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return c.out.done(u, "User %d created", u.Id)
+}
+
+// deleteUser deletes the user.
+// Usage: users delete ID
+func (c *cli) deleteUser(ctx context.Context, args []string) error {
+	const errMsg = "can't delete user"
+
+	if len(args) != 1 {
+		return fmt.Errorf("%s: user id should be specified", errMsg)
+	}
+
+	id, err := strconv.Atoi(args[0])
+	if err != nil {
+		return fmt.Errorf("%s: user id is not a number", errMsg)
+	}
+
+	err = c.client.DeleteUser(ctx, id)
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return c.out.done(struct {
+		Id int `json:"id"`
+	}{id}, "User %d deleted", id)
+}
+
+// changeRole makes a user a mod or a mod a user.
+// Usage: users role ID user|mod
+func (c *cli) changeRole(ctx context.Context, args []string) error {
+	const errMsg = "can't change role"
+
+	if len(args) != 2 {
+		return fmt.Errorf("%s: user id and role should be specified", errMsg)
+	}
+
+	id, err := strconv.Atoi(args[0])
+	if err != nil {
+		return fmt.Errorf("%s: user id is not a number", errMsg)
+	}
+
+	role, err := parseRole(args[1])
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	err = c.client.PutRole(ctx, id, role)
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return c.out.done(struct {
+		Id   int `json:"id"`
+		Role int `json:"role"`
+	}{id, role}, "User %d is %s now", id, args[1])
+}
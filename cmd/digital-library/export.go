@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/qo/digital-library/internal/catalog"
+	"github.com/qo/digital-library/internal/logger"
+	"github.com/qo/digital-library/internal/storage"
+)
+
+// runExport exports the catalog to the file specified in args.
+// Usage: export [-format csv|jsonl|marcxml] FILE
+func runExport(log logger.Logger, st storage.Storage, args []string) error {
+	const errMsg = "can't export catalog"
+
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	formatFlag := fs.String("format", "", "file format (csv, jsonl, marcxml), guessed by extension if not specified")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	if fs.NArg() != 1 {
+		return fmt.Errorf("%s: exactly one file should be specified", errMsg)
+	}
+
+	path := fs.Arg(0)
+
+	var format catalog.Format
+	if *formatFlag != "" {
+		format, err = catalog.ParseFormat(*formatFlag)
+	} else {
+		format, err = catalog.FormatFromPath(path)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+	defer f.Close()
+
+	err = catalog.Export(context.Background(), st, f, format)
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	log.Info("catalog exported", "path", path, "format", format)
+
+	return nil
+}
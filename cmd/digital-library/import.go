@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/qo/digital-library/internal/catalog"
+	"github.com/qo/digital-library/internal/logger"
+	"github.com/qo/digital-library/internal/storage"
+)
+
+// runImport imports the catalog from the file specified in args.
+// Usage: import [-format csv|jsonl] [-dry-run] FILE
+func runImport(log logger.Logger, st storage.Storage, args []string) error {
+	const errMsg = "can't import catalog"
+
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	formatFlag := fs.String("format", "", "file format (csv, jsonl), guessed by extension if not specified")
+	dryRun := fs.Bool("dry-run", false, "validate the file without saving anything")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	if fs.NArg() != 1 {
+		return fmt.Errorf("%s: exactly one file should be specified", errMsg)
+	}
+
+	path := fs.Arg(0)
+
+	var format catalog.Format
+	if *formatFlag != "" {
+		format, err = catalog.ParseFormat(*formatFlag)
+	} else {
+		format, err = catalog.FormatFromPath(path)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+	defer f.Close()
+
+	report, err := catalog.Import(st, f, format, *dryRun)
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	enc.Encode(report)
+
+	if len(report.Errors) > 0 {
+		return fmt.Errorf("%s: %d rows have errors", errMsg, len(report.Errors))
+	}
+
+	log.Info("catalog imported", "path", path, "committed", report.Committed)
+
+	return nil
+}
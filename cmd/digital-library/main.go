@@ -1,14 +1,26 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
 	defaultLog "log"
 	"net/http"
+	"os"
 
+	"github.com/qo/digital-library/internal/blob"
 	"github.com/qo/digital-library/internal/config"
+	"github.com/qo/digital-library/internal/handlers/view/render"
+	"github.com/qo/digital-library/internal/jobs/mail"
+	"github.com/qo/digital-library/internal/jobs/overdue"
+	"github.com/qo/digital-library/internal/jobs/purge"
+	"github.com/qo/digital-library/internal/jobs/recommend"
 	"github.com/qo/digital-library/internal/logger"
+	"github.com/qo/digital-library/internal/metrics"
+	"github.com/qo/digital-library/internal/notify"
 	"github.com/qo/digital-library/internal/router"
 	"github.com/qo/digital-library/internal/storage"
+	"github.com/qo/digital-library/internal/tracing"
 )
 
 func serve(host string, port int, handler http.Handler) {
@@ -33,8 +45,27 @@ func main() {
 
 	log.Info("logger loaded")
 
+	// the spec is generated from the routes only, so it doesn't need the storage
+	if flag.Arg(0) == "openapi" {
+		err = runOpenAPI(*log, flag.Args()[1:])
+		if err != nil {
+			log.Error(err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+
 	log.Info("starting server")
 
+	shutdownTracing, err := tracing.Init(context.Background(), cfg.TracingOptions)
+	if err != nil {
+		log.Error(err.Error())
+		return
+	}
+	defer shutdownTracing(context.Background())
+
+	log.Info("tracing started", "exporter", cfg.TracingOptions.Exporter)
+
 	s, err := storage.Init(cfg.StorageOptions)
 	if err != nil {
 		log.Error(err.Error())
@@ -43,7 +74,77 @@ func main() {
 
 	log.Info("storage loaded")
 
-	router := router.New(*log, *s)
+	switch flag.Arg(0) {
+	case "":
+	case "import":
+		err = runImport(*log, *s, flag.Args()[1:])
+		if err != nil {
+			log.Error(err.Error())
+			os.Exit(1)
+		}
+		return
+	case "export":
+		err = runExport(*log, *s, flag.Args()[1:])
+		if err != nil {
+			log.Error(err.Error())
+			os.Exit(1)
+		}
+		return
+	default:
+		log.Error(fmt.Sprintf("command %s is unknown", flag.Arg(0)))
+		os.Exit(2)
+	}
+
+	var m *metrics.Metrics
+	if cfg.MetricsOptions.Enabled {
+		m = metrics.New(*log, *s)
+		s.SetQueryObserver(m)
+
+		mux := http.NewServeMux()
+		mux.Handle(cfg.MetricsOptions.Path, m.Handler())
+
+		go serve(cfg.MetricsOptions.Host, cfg.MetricsOptions.Port, mux)
+
+		log.Info("metrics served", "host", cfg.MetricsOptions.Host, "port", cfg.MetricsOptions.Port, "path", cfg.MetricsOptions.Path)
+	}
+
+	go purge.Run(context.Background(), *log, *s, cfg.PurgeOptions)
+
+	log.Info("purge job started")
+
+	go recommend.Run(context.Background(), *log, *s, cfg.RecommendationsOptions)
+
+	log.Info("recommendation job started", "interval", cfg.RecommendationsOptions.Interval)
+
+	nt := notify.New(*log, *s, cfg.NotificationsOptions)
+
+	go overdue.Run(context.Background(), *log, *s, nt, cfg.LoansOptions)
+
+	log.Info("overdue job started", "interval", cfg.LoansOptions.Interval)
+
+	if cfg.SMTPOptions.Enabled {
+		go mail.Run(context.Background(), *log, *s, notify.NewSMTPSender(cfg.SMTPOptions), cfg.NotificationsOptions)
+
+		log.Info("mail job started", "interval", cfg.NotificationsOptions.Interval, "smtp host", cfg.SMTPOptions.Host, "smtp port", cfg.SMTPOptions.Port)
+	}
+
+	bs, err := blob.Open(cfg.BlobOptions)
+	if err != nil {
+		log.Error(err.Error())
+		return
+	}
+
+	log.Info("blob store loaded")
+
+	rd, err := render.New(cfg.ViewsOptions)
+	if err != nil {
+		log.Error(err.Error())
+		return
+	}
+
+	log.Info("templates loaded", "hot reload", cfg.ViewsOptions.HotReload)
+
+	router := router.New(*log, *s, *bs, rd, m, *cfg)
 
 	log.Info("router started")
 
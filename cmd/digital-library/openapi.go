@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/qo/digital-library/internal/logger"
+	"github.com/qo/digital-library/internal/router/api"
+)
+
+var errSpecDrift = errors.New("openapi spec is out of date, run `make openapi`")
+
+// runOpenAPI generates the openapi spec of the REST API into the dir specified in args.
+// With -check it doesn't write anything and fails if the spec in the dir differs from the generated one.
+// Usage: openapi [-check] [-dir DIR]
+func runOpenAPI(log logger.Logger, args []string) error {
+	const errMsg = "can't generate openapi spec"
+
+	fs := flag.NewFlagSet("openapi", flag.ContinueOnError)
+	checkFlag := fs.Bool("check", false, "fail if the spec differs from the generated one instead of writing it")
+	dirFlag := fs.String("dir", "./docs/swagger", "directory of openapi.json and openapi.yaml")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	doc, err := api.Spec()
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	js, err := doc.JSON()
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	ys, err := doc.YAML()
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	files := []struct {
+		name string
+		data []byte
+	}{
+		{"openapi.json", js},
+		{"openapi.yaml", ys},
+	}
+
+	for _, f := range files {
+		path := filepath.Join(*dirFlag, f.name)
+
+		if *checkFlag {
+			old, err := os.ReadFile(path)
+			if err != nil {
+				return fmt.Errorf("%s: %w", errMsg, err)
+			}
+			if !bytes.Equal(old, f.data) {
+				return fmt.Errorf("%s: %w", path, errSpecDrift)
+			}
+			log.Info("openapi spec is up to date", "path", path)
+			continue
+		}
+
+		err = os.WriteFile(path, f.data, 0644)
+		if err != nil {
+			return fmt.Errorf("%s: %w", errMsg, err)
+		}
+		log.Info("openapi spec generated", "path", path)
+	}
+
+	return nil
+}
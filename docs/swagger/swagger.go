@@ -0,0 +1,7 @@
+// Package swagger holds the OpenAPI spec embedded into the binary.
+package swagger
+
+import _ "embed"
+
+//go:embed openapi.json
+var Spec []byte
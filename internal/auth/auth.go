@@ -0,0 +1,101 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/qo/digital-library/internal/config"
+	"github.com/qo/digital-library/internal/storage/user"
+)
+
+// Principal is the one who makes the request.
+type Principal struct {
+	UserId int
+	Role   int
+	// TokenHash is the hash of the session token,
+	// it's empty if the request carries the admin token
+	TokenHash string
+}
+
+type ctxKey struct{}
+
+func NewContext(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, ctxKey{}, p)
+}
+
+func FromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(ctxKey{}).(Principal)
+	return p, ok
+}
+
+func IsAdmin(ctx context.Context) bool {
+	p, ok := FromContext(ctx)
+	return ok && p.Role == user.RoleAdmin
+}
+
+// CanActAs reports whether the one who makes the request can act as the user:
+// users act as themselves, admins act as anyone.
+func CanActAs(ctx context.Context, userId int) bool {
+	p, ok := FromContext(ctx)
+	return ok && (p.UserId == userId || p.Role == user.RoleAdmin)
+}
+
+// SessionTokenHash returns the hash of the token of the session the request was made in,
+// it's empty if the request wasn't made in a session.
+func SessionTokenHash(ctx context.Context) string {
+	p, _ := FromContext(ctx)
+	return p.TokenHash
+}
+
+// Authenticate attaches the principal to the request context
+// if the request carries the admin token or a session token as a bearer token.
+// Requests without a token are passed through anonymously.
+func Authenticate(options config.AuthOptions, st sessionStorage) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, ok := BearerToken(r)
+			if ok && options.AdminToken != "" &&
+				subtle.ConstantTimeCompare([]byte(token), []byte(options.AdminToken)) == 1 {
+				r = r.WithContext(NewContext(r.Context(), Principal{Role: user.RoleAdmin}))
+			} else if ok {
+				if p, ok := principal(r.Context(), st, token); ok {
+					r = r.WithContext(NewContext(r.Context(), p))
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// AuthenticateSession attaches the principal to the request context
+// if the request carries a session cookie.
+// It is only used for the web ui: the api doesn't accept cookies
+// so that other sites can't make requests on behalf of the user.
+func AuthenticateSession(st sessionStorage) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, authenticated := FromContext(r.Context())
+			cookie, err := r.Cookie(SessionCookie)
+			if !authenticated && err == nil {
+				if p, ok := principal(r.Context(), st, cookie.Value); ok {
+					r = r.WithContext(NewContext(r.Context(), p))
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// BearerToken returns the token of the Authorization header.
+func BearerToken(r *http.Request) (string, bool) {
+	const prefix = "Bearer "
+
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, prefix) {
+		return "", false
+	}
+
+	return strings.TrimPrefix(header, prefix), true
+}
@@ -0,0 +1,131 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/qo/digital-library/internal/storage/session"
+	"github.com/qo/digital-library/internal/storage/user"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// SessionCookie is the cookie holding the session token in the web ui.
+const SessionCookie = "session"
+
+const MinPasswordLength = 8
+
+var (
+	ErrInvalidCredentials = errors.New("invalid user id or password")
+	ErrShortPassword      = fmt.Errorf("password must be at least %d characters long", MinPasswordLength)
+)
+
+type sessionStorage interface {
+	GetSession(ctx context.Context, tokenHash string) (*session.Session, error)
+	GetUser(ctx context.Context, id int) (*user.User, error)
+}
+
+type loginStorage interface {
+	sessionStorage
+	GetPasswordHash(ctx context.Context, id int) (string, error)
+	PostSession(context.Context, *session.Session) error
+	DeleteSession(ctx context.Context, tokenHash string) error
+}
+
+func HashPassword(password string) (string, error) {
+	if len(password) < MinPasswordLength {
+		return "", ErrShortPassword
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("can't hash password: %w", err)
+	}
+
+	return string(hash), nil
+}
+
+// Login checks the password of the user and starts a new session.
+// It returns the session token which should be sent back
+// as a bearer token or a session cookie.
+func Login(ctx context.Context, st loginStorage, userId int, password string, ttl time.Duration) (string, time.Time, error) {
+	const errMsg = "can't log in"
+
+	hash, err := st.GetPasswordHash(ctx, userId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", time.Time{}, ErrInvalidCredentials
+	}
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	// users created before passwords were introduced can't log in
+	// until an admin sets their password
+	if hash == "" || bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) != nil {
+		return "", time.Time{}, ErrInvalidCredentials
+	}
+
+	token, err := newToken()
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	s := session.Session{
+		TokenHash: hashToken(token),
+		UserId:    userId,
+		ExpiresAt: time.Now().Add(ttl).UTC(),
+	}
+
+	err = st.PostSession(ctx, &s)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return token, s.ExpiresAt, nil
+}
+
+// Logout ends the session.
+func Logout(ctx context.Context, st loginStorage, token string) error {
+	err := st.DeleteSession(ctx, hashToken(token))
+	if err != nil {
+		return fmt.Errorf("can't log out: %w", err)
+	}
+	return nil
+}
+
+// principal returns the user of the not expired session.
+func principal(ctx context.Context, st sessionStorage, token string) (Principal, bool) {
+	s, err := st.GetSession(ctx, hashToken(token))
+	if err != nil {
+		return Principal{}, false
+	}
+
+	u, err := st.GetUser(ctx, s.UserId)
+	if err != nil {
+		return Principal{}, false
+	}
+
+	return Principal{UserId: u.Id, Role: u.Role, TokenHash: s.TokenHash}, true
+}
+
+func newToken() (string, error) {
+	b := make([]byte, 32)
+
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", fmt.Errorf("can't generate token: %w", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
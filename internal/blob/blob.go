@@ -0,0 +1,140 @@
+package blob
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/qo/digital-library/internal/config"
+)
+
+// Store keeps files like book PDFs on the local disk.
+type Store struct {
+	dir string
+}
+
+func Open(options config.BlobOptions) (*Store, error) {
+	const errMsg = "can't open blob store"
+
+	err := os.MkdirAll(options.Path, 0o755)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return &Store{options.Path}, nil
+}
+
+// MaxBookSize is the max size of the book file in bytes.
+const MaxBookSize = 256 << 20
+
+// pdfMagic is the prefix every pdf file starts with.
+var pdfMagic = []byte("%PDF-")
+
+// IsPDF reports whether the file starts like a pdf file.
+// Nothing is consumed from the reader.
+func IsPDF(r *bufio.Reader) bool {
+	magic, err := r.Peek(len(pdfMagic))
+	return err == nil && bytes.Equal(magic, pdfMagic)
+}
+
+// BookKey returns the key of the book file.
+func BookKey(bookId int) string {
+	return fmt.Sprintf("books/%d.pdf", bookId)
+}
+
+func (s Store) path(key string) string {
+	return filepath.Join(s.dir, filepath.FromSlash(key))
+}
+
+// Put writes the file atomically, so readers never see a partially written file.
+func (s Store) Put(key string, r io.Reader) (int64, error) {
+	const errMsg = "can't put blob"
+
+	p := s.path(key)
+
+	err := os.MkdirAll(filepath.Dir(p), 0o755)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(p), ".tmp-*")
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+	defer os.Remove(f.Name())
+
+	n, err := io.Copy(f, r)
+	if err != nil {
+		f.Close()
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	err = f.Close()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	err = os.Rename(f.Name(), p)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return n, nil
+}
+
+// Get opens the file for reading.
+// It returns an error wrapping os.ErrNotExist if there is no such file.
+func (s Store) Get(key string) (*os.File, error) {
+	const errMsg = "can't get blob"
+
+	f, err := os.Open(s.path(key))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return f, nil
+}
+
+func (s Store) Exists(key string) bool {
+	_, err := os.Stat(s.path(key))
+	return err == nil
+}
+
+func (s Store) Delete(key string) error {
+	const errMsg = "can't delete blob"
+
+	err := os.Remove(s.path(key))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return nil
+}
+
+// CheckWritable checks that files can be written to the store.
+func (s Store) CheckWritable() error {
+	const errMsg = "blob store is not writable"
+
+	f, err := os.CreateTemp(s.dir, ".check-*")
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+	defer os.Remove(f.Name())
+
+	_, err = f.Write([]byte("ok"))
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	err = f.Close()
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,48 @@
+// Package buildinfo describes the build of the server.
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// Commit and BuildTime are set at build time:
+//
+//	go build -ldflags "-X github.com/qo/digital-library/internal/buildinfo.Commit=$(git rev-parse HEAD) -X github.com/qo/digital-library/internal/buildinfo.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+//
+// If they are not, the vcs info embedded by go build is used.
+var (
+	Commit    string
+	BuildTime string
+)
+
+type Info struct {
+	Commit    string `json:"commit"`
+	BuildTime string `json:"build_time"`
+	GoVersion string `json:"go_version"`
+}
+
+// Get returns the info of the build, the unknown fields are empty.
+func Get() Info {
+	info := Info{
+		Commit:    Commit,
+		BuildTime: BuildTime,
+		GoVersion: runtime.Version(),
+	}
+
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+
+	for _, s := range bi.Settings {
+		switch {
+		case s.Key == "vcs.revision" && info.Commit == "":
+			info.Commit = s.Value
+		case s.Key == "vcs.time" && info.BuildTime == "":
+			info.BuildTime = s.Value
+		}
+	}
+
+	return info
+}
@@ -0,0 +1,67 @@
+package catalog
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/qo/digital-library/internal/storage/book"
+)
+
+type Format string
+
+const (
+	FormatCSV     Format = "csv"
+	FormatJSONL   Format = "jsonl"
+	FormatMARCXML Format = "marcxml"
+)
+
+// Record is a book along with the full names of its authors.
+type Record struct {
+	book.Book
+	Authors []string `json:"authors"`
+}
+
+func ParseFormat(s string) (Format, error) {
+	switch strings.ToLower(s) {
+	case "csv":
+		return FormatCSV, nil
+	case "jsonl", "ndjson":
+		return FormatJSONL, nil
+	case "marcxml", "marc", "xml":
+		return FormatMARCXML, nil
+	default:
+		return "", fmt.Errorf("format %s is unknown", s)
+	}
+}
+
+// ContentType returns the media type of the files in the format.
+func (f Format) ContentType() string {
+	switch f {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatJSONL:
+		return "application/x-ndjson"
+	case FormatMARCXML:
+		return "application/marcxml+xml"
+	default:
+		return "application/octet-stream"
+	}
+}
+
+// Extension returns the file extension for the format.
+func (f Format) Extension() string {
+	if f == FormatMARCXML {
+		return "xml"
+	}
+	return string(f)
+}
+
+// FormatFromPath guesses the format by the file extension.
+func FormatFromPath(path string) (Format, error) {
+	ext := strings.TrimPrefix(filepath.Ext(path), ".")
+	if ext == "" {
+		return "", fmt.Errorf("file %s has no extension", path)
+	}
+	return ParseFormat(ext)
+}
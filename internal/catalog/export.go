@@ -0,0 +1,115 @@
+package catalog
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/qo/digital-library/internal/storage/author"
+	"github.com/qo/digital-library/internal/storage/book"
+)
+
+type exportStorage interface {
+	EachBookWithAuthors(ctx context.Context, fn func(book.Book, []author.Author) error) error
+}
+
+type recordWriter interface {
+	begin() error
+	write(Record) error
+	// end finishes the document and flushes everything written.
+	end() error
+}
+
+// Export writes every not deleted book along with its authors in the format.
+// The records are written as soon as they are read from the storage.
+func Export(ctx context.Context, st exportStorage, w io.Writer, format Format) error {
+	const errMsg = "can't export catalog"
+
+	rw, err := newRecordWriter(w, format)
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	err = rw.begin()
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	err = st.EachBookWithAuthors(ctx, func(b book.Book, authors []author.Author) error {
+		names := make([]string, 0, len(authors))
+		for _, a := range authors {
+			names = append(names, a.FullName)
+		}
+		return rw.write(Record{b, names})
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	err = rw.end()
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return nil
+}
+
+func newRecordWriter(w io.Writer, format Format) (recordWriter, error) {
+	switch format {
+	case FormatCSV:
+		return &csvWriter{csv.NewWriter(w)}, nil
+	case FormatJSONL:
+		bw := bufio.NewWriter(w)
+		return &jsonlWriter{bw, json.NewEncoder(bw)}, nil
+	case FormatMARCXML:
+		return newMARCXMLWriter(w), nil
+	default:
+		return nil, fmt.Errorf("format %s is not supported for export", format)
+	}
+}
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func (cw *csvWriter) begin() error {
+	return cw.w.Write(csvColumns)
+}
+
+func (cw *csvWriter) write(rec Record) error {
+	return cw.w.Write([]string{
+		strconv.Itoa(rec.Id),
+		rec.Isbn,
+		rec.Title,
+		strconv.Itoa(rec.Year),
+		rec.Publisher,
+		strings.Join(rec.Authors, csvAuthorsSeparator+" "),
+	})
+}
+
+func (cw *csvWriter) end() error {
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+type jsonlWriter struct {
+	w   *bufio.Writer
+	enc *json.Encoder
+}
+
+func (jw *jsonlWriter) begin() error {
+	return nil
+}
+
+func (jw *jsonlWriter) write(rec Record) error {
+	return jw.enc.Encode(rec)
+}
+
+func (jw *jsonlWriter) end() error {
+	return jw.w.Flush()
+}
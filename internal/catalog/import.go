@@ -0,0 +1,371 @@
+package catalog
+
+import (
+	"bufio"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/qo/digital-library/internal/storage"
+	"github.com/qo/digital-library/internal/storage/author"
+	"github.com/qo/digital-library/internal/storage/authorship"
+)
+
+// csvAuthorsSeparator separates the authors in the authors column of csv.
+const csvAuthorsSeparator = ";"
+
+type importStorage interface {
+	Begin() (*storage.Tx, error)
+}
+
+type RowError struct {
+	Row   int    `json:"row"`
+	Error string `json:"error"`
+}
+
+type Report struct {
+	DryRun    bool `json:"dry_run"`
+	Committed bool `json:"committed"`
+	// Rows is the number of rows read.
+	Rows int `json:"rows"`
+	// Books is the number of books created.
+	Books int `json:"books"`
+	// Authors is the number of authors created.
+	Authors int        `json:"authors"`
+	Errors  []RowError `json:"errors,omitempty"`
+}
+
+// Import reads the records and creates the books,
+// the authors which don't exist yet and the authorships.
+// Everything is done in a single transaction which is committed
+// only if every row is imported and it's not a dry run.
+// Row errors are put into the report, other errors are returned.
+func Import(st importStorage, r io.Reader, format Format, dryRun bool) (*Report, error) {
+	const errMsg = "can't import catalog"
+
+	rd, err := newRecordReader(r, format)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	tx, err := st.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+	defer tx.Rollback()
+
+	report := Report{
+		DryRun: dryRun,
+		Errors: make([]RowError, 0),
+	}
+
+	// author ids by full name
+	authors := make(map[string]int)
+
+	for {
+		rec, row, err := rd.next()
+		if err == io.EOF {
+			break
+		}
+
+		var rowErr rowError
+		if errors.As(err, &rowErr) {
+			report.Rows++
+			report.Errors = append(report.Errors, RowError{row, rowErr.Error()})
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", errMsg, err)
+		}
+
+		report.Rows++
+
+		_, err = importRecord(tx, rec, authors, &report)
+		if err != nil {
+			report.Errors = append(report.Errors, RowError{row, err.Error()})
+		}
+	}
+
+	if dryRun || len(report.Errors) > 0 {
+		return &report, nil
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	report.Committed = true
+
+	return &report, nil
+}
+
+// ErrInvalidRecord is returned by Add if the record can't be added.
+var ErrInvalidRecord = errors.New("invalid record")
+
+// Add creates a single book like Import does
+// and returns the id of the book.
+func Add(st importStorage, rec Record) (int, error) {
+	const errMsg = "can't add book"
+
+	err := validate(&rec)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w: %w", errMsg, ErrInvalidRecord, err)
+	}
+
+	tx, err := st.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+	defer tx.Rollback()
+
+	id, err := importRecord(tx, rec, make(map[string]int), &Report{})
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return id, nil
+}
+
+// importRecord returns the id of the created book.
+func importRecord(tx *storage.Tx, rec Record, authors map[string]int, report *Report) (int, error) {
+	b := rec.Book
+	b.DeletedAt = nil
+
+	err := tx.PostBook(&b)
+	if err != nil {
+		return 0, err
+	}
+
+	report.Books++
+
+	for i, name := range rec.Authors {
+		id, ok := authors[name]
+		if !ok {
+			a, err := tx.GetAuthorByName(name)
+			if errors.Is(err, sql.ErrNoRows) {
+				a = &author.Author{FullName: name}
+				err = tx.PostAuthor(a)
+				if err != nil {
+					return 0, err
+				}
+				report.Authors++
+			} else if err != nil {
+				return 0, err
+			}
+			id = a.Id
+			authors[name] = id
+		}
+
+		err = tx.PostAuthorship(&authorship.Authorship{
+			AuthorId: id,
+			BookId:   b.Id,
+			Position: i,
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return b.Id, nil
+}
+
+// rowError is an error in a single row which doesn't stop the import.
+type rowError struct {
+	error
+}
+
+type recordReader interface {
+	// next returns the next record and its row number.
+	// It returns io.EOF if there are no records left.
+	next() (Record, int, error)
+}
+
+func newRecordReader(r io.Reader, format Format) (recordReader, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVReader(r)
+	case FormatJSONL:
+		return newJSONLReader(r), nil
+	default:
+		return nil, fmt.Errorf("format %s is not supported for import", format)
+	}
+}
+
+func validate(rec *Record) error {
+	rec.Title = strings.TrimSpace(rec.Title)
+	if rec.Title == "" {
+		return errors.New("title is empty")
+	}
+
+	if rec.Year < 0 {
+		return errors.New("year is negative")
+	}
+
+	seen := make(map[string]bool)
+	names := make([]string, 0, len(rec.Authors))
+	for _, name := range rec.Authors {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	rec.Authors = names
+
+	return nil
+}
+
+var csvColumns = []string{"id", "isbn", "title", "year", "publisher", "authors"}
+
+type csvReader struct {
+	r *csv.Reader
+	// column indices by column name
+	columns map[string]int
+}
+
+func newCSVReader(r io.Reader) (*csvReader, error) {
+	const errMsg = "can't read csv header"
+
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	columns := make(map[string]int)
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !isCSVColumn(name) {
+			return nil, fmt.Errorf("%s: column %s is unknown", errMsg, name)
+		}
+		columns[name] = i
+	}
+
+	if _, ok := columns["title"]; !ok {
+		return nil, fmt.Errorf("%s: title column is missing", errMsg)
+	}
+
+	return &csvReader{cr, columns}, nil
+}
+
+func isCSVColumn(name string) bool {
+	for _, c := range csvColumns {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (cr *csvReader) next() (Record, int, error) {
+	fields, err := cr.r.Read()
+	if err == io.EOF {
+		return Record{}, 0, err
+	}
+
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return Record{}, parseErr.Line, rowError{parseErr.Err}
+	}
+	if err != nil {
+		return Record{}, 0, err
+	}
+
+	row, _ := cr.r.FieldPos(0)
+
+	field := func(name string) string {
+		i, ok := cr.columns[name]
+		if !ok {
+			return ""
+		}
+		return strings.TrimSpace(fields[i])
+	}
+
+	var rec Record
+
+	rec.Isbn = field("isbn")
+	rec.Title = field("title")
+	rec.Publisher = field("publisher")
+
+	if id := field("id"); id != "" {
+		rec.Id, err = strconv.Atoi(id)
+		if err != nil {
+			return Record{}, row, rowError{fmt.Errorf("id %s is not a number", id)}
+		}
+	}
+
+	if year := field("year"); year != "" {
+		rec.Year, err = strconv.Atoi(year)
+		if err != nil {
+			return Record{}, row, rowError{fmt.Errorf("year %s is not a number", year)}
+		}
+	}
+
+	if authors := field("authors"); authors != "" {
+		rec.Authors = strings.Split(authors, csvAuthorsSeparator)
+	}
+
+	err = validate(&rec)
+	if err != nil {
+		return Record{}, row, rowError{err}
+	}
+
+	return rec, row, nil
+}
+
+type jsonlReader struct {
+	s   *bufio.Scanner
+	row int
+}
+
+func newJSONLReader(r io.Reader) *jsonlReader {
+	s := bufio.NewScanner(r)
+	s.Buffer(nil, 1024*1024)
+	return &jsonlReader{s: s}
+}
+
+func (jr *jsonlReader) next() (Record, int, error) {
+	for jr.s.Scan() {
+		jr.row++
+
+		line := strings.TrimSpace(jr.s.Text())
+		if line == "" {
+			continue
+		}
+
+		var rec Record
+
+		err := json.Unmarshal([]byte(line), &rec)
+		if err != nil {
+			return Record{}, jr.row, rowError{fmt.Errorf("can't parse json: %w", err)}
+		}
+
+		err = validate(&rec)
+		if err != nil {
+			return Record{}, jr.row, rowError{err}
+		}
+
+		return rec, jr.row, nil
+	}
+
+	err := jr.s.Err()
+	if err != nil {
+		return Record{}, 0, err
+	}
+
+	return Record{}, 0, io.EOF
+}
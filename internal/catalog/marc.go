@@ -0,0 +1,152 @@
+package catalog
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/qo/digital-library/internal/storage/author"
+)
+
+// See https://www.loc.gov/standards/marcxml/
+const marcxmlNamespace = "http://www.loc.gov/MARC21/slim"
+
+// marcLeader describes a new record of a printed monograph
+// with ISBD punctuation omitted.
+const marcLeader = "00000nam a2200000 i 4500"
+
+type marcRecord struct {
+	XMLName       xml.Name           `xml:"record"`
+	Leader        string             `xml:"leader"`
+	ControlFields []marcControlField `xml:"controlfield"`
+	DataFields    []marcDataField    `xml:"datafield"`
+}
+
+type marcControlField struct {
+	Tag   string `xml:"tag,attr"`
+	Value string `xml:",chardata"`
+}
+
+type marcDataField struct {
+	Tag       string         `xml:"tag,attr"`
+	Ind1      string         `xml:"ind1,attr"`
+	Ind2      string         `xml:"ind2,attr"`
+	Subfields []marcSubfield `xml:"subfield"`
+}
+
+type marcSubfield struct {
+	Code  string `xml:"code,attr"`
+	Value string `xml:",chardata"`
+}
+
+type marcxmlWriter struct {
+	enc *xml.Encoder
+	// entered is the date the records are created on in yymmdd form
+	entered string
+}
+
+func newMARCXMLWriter(w io.Writer) *marcxmlWriter {
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	return &marcxmlWriter{
+		enc:     enc,
+		entered: time.Now().Format("060102"),
+	}
+}
+
+var marcCollection = xml.StartElement{
+	Name: xml.Name{Local: "collection"},
+	Attr: []xml.Attr{{Name: xml.Name{Local: "xmlns"}, Value: marcxmlNamespace}},
+}
+
+func (mw *marcxmlWriter) begin() error {
+	err := mw.enc.EncodeToken(xml.ProcInst{
+		Target: "xml",
+		Inst:   []byte(`version="1.0" encoding="UTF-8"`),
+	})
+	if err != nil {
+		return err
+	}
+	return mw.enc.EncodeToken(marcCollection)
+}
+
+func (mw *marcxmlWriter) write(rec Record) error {
+	return mw.enc.Encode(mw.record(rec))
+}
+
+func (mw *marcxmlWriter) end() error {
+	err := mw.enc.EncodeToken(marcCollection.End())
+	if err != nil {
+		return err
+	}
+	return mw.enc.Flush()
+}
+
+func (mw *marcxmlWriter) record(rec Record) marcRecord {
+	year := "uuuu"
+	if rec.Year > 0 {
+		year = fmt.Sprintf("%04d", rec.Year)
+	}
+
+	mr := marcRecord{
+		Leader: marcLeader,
+		ControlFields: []marcControlField{
+			{"001", strconv.Itoa(rec.Id)},
+			// date entered, single known date, date 1, no date 2, unknown place,
+			// undefined positions, undetermined language, not modified, other source
+			{"008", fmt.Sprintf("%ss%s    xx %17sund d", mw.entered, year, "")},
+		},
+		DataFields: make([]marcDataField, 0),
+	}
+
+	if rec.Isbn != "" {
+		mr.DataFields = append(mr.DataFields, marcField("020", " ", " ", "a", rec.Isbn))
+	}
+
+	// main entry is the first author, the rest are added entries
+	titleInd1 := "0"
+	if len(rec.Authors) > 0 {
+		mr.DataFields = append(mr.DataFields, marcName("100", rec.Authors[0]))
+		titleInd1 = "1"
+	}
+
+	mr.DataFields = append(mr.DataFields, marcField("245", titleInd1, "0", "a", rec.Title))
+
+	publication := marcDataField{Tag: "264", Ind1: " ", Ind2: "1"}
+	if rec.Publisher != "" {
+		publication.Subfields = append(publication.Subfields, marcSubfield{"b", rec.Publisher})
+	}
+	if rec.Year > 0 {
+		publication.Subfields = append(publication.Subfields, marcSubfield{"c", strconv.Itoa(rec.Year)})
+	}
+	if len(publication.Subfields) > 0 {
+		mr.DataFields = append(mr.DataFields, publication)
+	}
+
+	for _, name := range rec.Authors[min(1, len(rec.Authors)):] {
+		mr.DataFields = append(mr.DataFields, marcName("700", name))
+	}
+
+	return mr
+}
+
+// marcName returns the personal name field.
+// The name is inverted if it has a family name, otherwise it's kept as is.
+func marcName(tag, fullName string) marcDataField {
+	n := author.ParseName(fullName)
+	if n.Given == "" {
+		return marcField(tag, "0", " ", "a", fullName)
+	}
+	return marcField(tag, "1", " ", "a", n.Inverted())
+}
+
+func marcField(tag, ind1, ind2, code, value string) marcDataField {
+	return marcDataField{
+		Tag:       tag,
+		Ind1:      ind1,
+		Ind2:      ind2,
+		Subfields: []marcSubfield{{code, value}},
+	}
+}
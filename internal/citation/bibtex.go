@@ -0,0 +1,91 @@
+package citation
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/qo/digital-library/internal/storage/author"
+)
+
+var bibtexEscaper = strings.NewReplacer(
+	`\`, `\textbackslash{}`,
+	`{`, `\{`,
+	`}`, `\}`,
+	`&`, `\&`,
+	`%`, `\%`,
+	`$`, `\$`,
+	`#`, `\#`,
+	`_`, `\_`,
+)
+
+func bibtex(it Item) string {
+	var sb strings.Builder
+
+	names := it.names()
+
+	fmt.Fprintf(&sb, "@book{%s,\n", bibtexKey(it))
+
+	field := func(name, value string) {
+		if value == "" {
+			return
+		}
+		fmt.Fprintf(&sb, "  %s = {%s},\n", name, value)
+	}
+
+	authors := make([]string, 0, len(names))
+	for _, n := range names {
+		authors = append(authors, bibtexEscaper.Replace(bibtexName(n)))
+	}
+	field("author", strings.Join(authors, " and "))
+
+	// double braces keep the capitalization of the title
+	if it.Book.Title != "" {
+		field("title", "{"+bibtexEscaper.Replace(it.Book.Title)+"}")
+	}
+	field("publisher", bibtexEscaper.Replace(it.Book.Publisher))
+	if it.Book.Year > 0 {
+		field("year", fmt.Sprint(it.Book.Year))
+	}
+	field("isbn", bibtexEscaper.Replace(it.Book.Isbn))
+
+	sb.WriteString("}\n")
+
+	return sb.String()
+}
+
+// bibtexName returns the name in the "von Last, Jr, First" form of BibTeX,
+// the suffix is left out if there is none.
+func bibtexName(n author.Name) string {
+	if n.Suffix == "" {
+		return n.Inverted()
+	}
+	// the three parts are kept even without the given name,
+	// otherwise the suffix would be read as the given name
+	return strings.TrimSpace(n.Family + ", " + n.Suffix + ", " + n.Given)
+}
+
+// bibtexKey returns the key of the first author's family name and the year,
+// e.g. orwell1961, falling back to the book id.
+func bibtexKey(it Item) string {
+	var sb strings.Builder
+
+	names := it.names()
+	if len(names) > 0 {
+		for _, r := range strings.ToLower(names[0].Family) {
+			if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+				sb.WriteRune(r)
+			}
+		}
+	}
+
+	if sb.Len() == 0 {
+		fmt.Fprintf(&sb, "book%d", it.Book.Id)
+	}
+
+	if it.Book.Year > 0 {
+		fmt.Fprint(&sb, it.Book.Year)
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,49 @@
+package citation
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/qo/digital-library/internal/storage/author"
+	"github.com/qo/digital-library/internal/storage/book"
+)
+
+func TestBibtexName(t *testing.T) {
+	tests := []struct {
+		name     string
+		fullName string
+		want     string
+	}{
+		{"given and family", "George Orwell", "Orwell, George"},
+		{"particle", "Ludwig van Beethoven", "van Beethoven, Ludwig"},
+		{"family only", "Plato", "Plato"},
+		{"suffix", "Martin Luther King Jr.", "King, Jr., Martin Luther"},
+		{"inverted with suffix", "King, Martin Luther, Jr.", "King, Jr., Martin Luther"},
+		{"roman numeral suffix", "John Smith III", "Smith, III, John"},
+		{"suffix without given name", "King, , Jr.", "King, Jr.,"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := bibtexName(author.ParseName(tt.fullName))
+			if got != tt.want {
+				t.Errorf("bibtexName(%q) = %q, want %q", tt.fullName, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBibtexAuthors(t *testing.T) {
+	it := Item{
+		Book: book.Book{Id: 1, Title: "Strength to Love", Year: 1963},
+		Authors: []author.Author{
+			{FullName: "Martin Luther King Jr."},
+			{FullName: "Coretta Scott King"},
+		},
+	}
+
+	want := "  author = {King, Jr., Martin Luther and King, Coretta Scott},\n"
+	if got := bibtex(it); !strings.Contains(got, want) {
+		t.Errorf("bibtex() = %q, want it to contain %q", got, want)
+	}
+}
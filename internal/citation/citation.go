@@ -0,0 +1,102 @@
+package citation
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/qo/digital-library/internal/storage/author"
+	"github.com/qo/digital-library/internal/storage/book"
+)
+
+type Format string
+
+const (
+	FormatBibTeX  Format = "bibtex"
+	FormatRIS     Format = "ris"
+	FormatCSLJSON Format = "csl-json"
+	FormatAPA     Format = "apa"
+	FormatMLA     Format = "mla"
+)
+
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(s)); f {
+	case FormatBibTeX, FormatRIS, FormatCSLJSON, FormatAPA, FormatMLA:
+		return f, nil
+	default:
+		return "", fmt.Errorf("citation format %s is unknown", s)
+	}
+}
+
+// ContentType returns the media type of the citations in the format.
+func (f Format) ContentType() string {
+	switch f {
+	case FormatBibTeX:
+		return "application/x-bibtex; charset=utf-8"
+	case FormatRIS:
+		return "application/x-research-info-systems; charset=utf-8"
+	case FormatCSLJSON:
+		return "application/vnd.citationstyles.csl+json"
+	default:
+		return "text/plain; charset=utf-8"
+	}
+}
+
+// Item is a cited book along with its authors.
+type Item struct {
+	Book    book.Book
+	Authors []author.Author
+}
+
+func (it Item) names() []author.Name {
+	names := make([]author.Name, 0, len(it.Authors))
+	for _, a := range it.Authors {
+		names = append(names, author.ParseName(a.FullName))
+	}
+	return names
+}
+
+// Write writes the citations of the items in the format.
+// Citations are separated with a blank line,
+// CSL-JSON items are written as a single array.
+func Write(w io.Writer, format Format, items []Item) error {
+	const errMsg = "can't write citations"
+
+	if format == FormatCSLJSON {
+		err := writeCSLJSON(w, items)
+		if err != nil {
+			return fmt.Errorf("%s: %w", errMsg, err)
+		}
+		return nil
+	}
+
+	var cite func(Item) string
+
+	switch format {
+	case FormatBibTeX:
+		cite = bibtex
+	case FormatRIS:
+		cite = ris
+	case FormatAPA:
+		cite = apa
+	case FormatMLA:
+		cite = mla
+	default:
+		return fmt.Errorf("%s: citation format %s is unknown", errMsg, format)
+	}
+
+	for i, it := range items {
+		if i > 0 {
+			_, err := io.WriteString(w, "\n")
+			if err != nil {
+				return fmt.Errorf("%s: %w", errMsg, err)
+			}
+		}
+		_, err := io.WriteString(w, cite(it))
+		if err != nil {
+			return fmt.Errorf("%s: %w", errMsg, err)
+		}
+	}
+
+	return nil
+}
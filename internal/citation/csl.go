@@ -0,0 +1,57 @@
+package citation
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// See https://citeproc-js.readthedocs.io/en/latest/csl-json/markup.html
+type cslItem struct {
+	Id        string    `json:"id"`
+	Type      string    `json:"type"`
+	Title     string    `json:"title,omitempty"`
+	Author    []cslName `json:"author,omitempty"`
+	Issued    *cslDate  `json:"issued,omitempty"`
+	Publisher string    `json:"publisher,omitempty"`
+	ISBN      string    `json:"ISBN,omitempty"`
+}
+
+type cslName struct {
+	Family string `json:"family,omitempty"`
+	Given  string `json:"given,omitempty"`
+	Suffix string `json:"suffix,omitempty"`
+}
+
+type cslDate struct {
+	DateParts [][]int `json:"date-parts"`
+}
+
+func writeCSLJSON(w io.Writer, items []Item) error {
+	csl := make([]cslItem, 0, len(items))
+
+	for _, it := range items {
+		ci := cslItem{
+			Id:        fmt.Sprintf("book-%d", it.Book.Id),
+			Type:      "book",
+			Title:     it.Book.Title,
+			Publisher: it.Book.Publisher,
+			ISBN:      it.Book.Isbn,
+		}
+
+		for _, n := range it.names() {
+			ci.Author = append(ci.Author, cslName{n.Family, n.Given, n.Suffix})
+		}
+
+		if it.Book.Year > 0 {
+			ci.Issued = &cslDate{[][]int{{it.Book.Year}}}
+		}
+
+		csl = append(csl, ci)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(csl)
+}
@@ -0,0 +1,32 @@
+package citation
+
+import (
+	"fmt"
+	"strings"
+)
+
+// See https://en.wikipedia.org/wiki/RIS_(file_format)
+func ris(it Item) string {
+	var sb strings.Builder
+
+	tag := func(name, value string) {
+		if value == "" {
+			return
+		}
+		fmt.Fprintf(&sb, "%s  - %s\n", name, value)
+	}
+
+	tag("TY", "BOOK")
+	for _, n := range it.names() {
+		tag("AU", n.Inverted())
+	}
+	tag("TI", it.Book.Title)
+	if it.Book.Year > 0 {
+		tag("PY", fmt.Sprint(it.Book.Year))
+	}
+	tag("PB", it.Book.Publisher)
+	tag("SN", it.Book.Isbn)
+	sb.WriteString("ER  - \n")
+
+	return sb.String()
+}
@@ -0,0 +1,113 @@
+package citation
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/qo/digital-library/internal/storage/author"
+)
+
+// apaMaxAuthors is the max number of authors listed by APA,
+// the rest are replaced with an ellipsis before the last one.
+const apaMaxAuthors = 20
+
+// apa returns the citation in APA 7th edition style, e.g.
+// Pratchett, T., & Gaiman, N. (1990). Good omens. Gollancz.
+func apa(it Item) string {
+	var sb strings.Builder
+
+	names := it.names()
+
+	authors := make([]string, 0, len(names))
+	for _, n := range names {
+		authors = append(authors, apaName(n))
+	}
+
+	switch {
+	case len(authors) == 0:
+	case len(authors) == 1:
+		sb.WriteString(authors[0])
+	case len(authors) > apaMaxAuthors:
+		sb.WriteString(strings.Join(authors[:apaMaxAuthors-1], ", "))
+		sb.WriteString(", . . . ")
+		sb.WriteString(authors[len(authors)-1])
+	default:
+		sb.WriteString(strings.Join(authors[:len(authors)-1], ", "))
+		sb.WriteString(", & ")
+		sb.WriteString(authors[len(authors)-1])
+	}
+
+	year := "n.d."
+	if it.Book.Year > 0 {
+		year = fmt.Sprint(it.Book.Year)
+	}
+
+	if sb.Len() > 0 {
+		sb.WriteString(" ")
+	}
+	fmt.Fprintf(&sb, "(%s). %s.", year, strings.TrimSuffix(it.Book.Title, "."))
+
+	if it.Book.Publisher != "" {
+		fmt.Fprintf(&sb, " %s.", strings.TrimSuffix(it.Book.Publisher, "."))
+	}
+
+	sb.WriteString("\n")
+
+	return sb.String()
+}
+
+func apaName(n author.Name) string {
+	parts := []string{n.Family}
+	if initials := n.Initials(); initials != "" {
+		parts = append(parts, initials)
+	}
+	if n.Suffix != "" {
+		parts = append(parts, n.Suffix)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// mla returns the citation in MLA 9th edition style, e.g.
+// Pratchett, Terry, and Neil Gaiman. Good Omens. Gollancz, 1990.
+func mla(it Item) string {
+	var sb strings.Builder
+
+	names := it.names()
+
+	var authors string
+
+	switch len(names) {
+	case 0:
+	case 1:
+		authors = names[0].Inverted()
+	case 2:
+		authors = fmt.Sprintf("%s, and %s", names[0].Inverted(), mlaDirect(names[1]))
+	default:
+		authors = fmt.Sprintf("%s, et al", names[0].Inverted())
+	}
+
+	if authors != "" {
+		fmt.Fprintf(&sb, "%s. ", strings.TrimSuffix(authors, "."))
+	}
+
+	fmt.Fprintf(&sb, "%s.", strings.TrimSuffix(it.Book.Title, "."))
+
+	publication := make([]string, 0, 2)
+	if it.Book.Publisher != "" {
+		publication = append(publication, it.Book.Publisher)
+	}
+	if it.Book.Year > 0 {
+		publication = append(publication, fmt.Sprint(it.Book.Year))
+	}
+	if len(publication) > 0 {
+		fmt.Fprintf(&sb, " %s.", strings.Join(publication, ", "))
+	}
+
+	sb.WriteString("\n")
+
+	return sb.String()
+}
+
+func mlaDirect(n author.Name) string {
+	return strings.Join(strings.Fields(fmt.Sprintf("%s %s %s", n.Given, n.Family, n.Suffix)), " ")
+}
@@ -2,15 +2,50 @@ package config
 
 import (
 	"fmt"
+	"log/slog"
 	"time"
 
 	"github.com/ilyakaznacheev/cleanenv"
 )
 
 type Config struct {
-	EnvironmentOptions `yaml:"environment"`
-	StorageOptions     `yaml:"storage"`
-	HTTPServerOptions  `yaml:"http_server"`
+	EnvironmentOptions     `yaml:"environment"`
+	StorageOptions         `yaml:"storage"`
+	HTTPServerOptions      `yaml:"http_server"`
+	AuthOptions            `yaml:"auth"`
+	PurgeOptions           `yaml:"purge"`
+	RecommendationsOptions `yaml:"recommendations"`
+	LoansOptions           `yaml:"loans"`
+	NotificationsOptions   `yaml:"notifications"`
+	BlobOptions            `yaml:"blob"`
+	ViewsOptions           `yaml:"views"`
+	ValidationOptions      `yaml:"validation"`
+	MetricsOptions         `yaml:"metrics"`
+	TracingOptions         `yaml:"tracing"`
+	RateLimitOptions       `yaml:"rate_limit"`
+	CORSOptions            `yaml:"cors"`
+	SecurityOptions        `yaml:"security"`
+}
+
+// redacted replaces the secrets set in the logged config
+const redacted = "REDACTED"
+
+// loggedConfig is the config without the LogValue method, so that it's logged as is.
+type loggedConfig Config
+
+// LogValue logs the config with the passwords and the tokens redacted.
+func (c Config) LogValue() slog.Value {
+	secrets := []*string{
+		&c.MySQLOptions.Password,
+		&c.AuthOptions.AdminToken,
+		&c.SMTPOptions.Password,
+	}
+	for _, s := range secrets {
+		if *s != "" {
+			*s = redacted
+		}
+	}
+	return slog.AnyValue(loggedConfig(c))
 }
 
 type EnvironmentOptions struct {
@@ -45,6 +80,137 @@ type HTTPServerOptions struct {
 	IdleTimeout time.Duration `yaml:"idle_timeout" env-default:"10s"`
 }
 
+type AuthOptions struct {
+	AdminToken string `yaml:"admin_token" env:"DIGITAL_LIBRARY_ADMIN_TOKEN"`
+	// SessionTTL is how long a user stays logged in
+	SessionTTL time.Duration `yaml:"session_ttl" env-default:"720h"`
+}
+
+type PurgeOptions struct {
+	Retention time.Duration `yaml:"retention" env-default:"720h"`
+	Interval  time.Duration `yaml:"interval"  env-default:"1h"`
+}
+
+type RecommendationsOptions struct {
+	// Interval is how often the recommendations are recomputed
+	Interval time.Duration `yaml:"interval" env-default:"1h"`
+	// Limit is the number of the recommendations kept per user
+	Limit int `yaml:"limit" env-default:"50"`
+}
+
+type LoansOptions struct {
+	// Period is how long a copy is lent for
+	Period time.Duration `yaml:"period" env-default:"336h"`
+	// Limit is the number of the books a user can borrow at once
+	Limit int `yaml:"limit" env-default:"5"`
+	// Interval is how often the overdue loans are looked for
+	Interval time.Duration `yaml:"interval" env-default:"1h"`
+}
+
+// NotificationsOptions configure the delivery of the notifications.
+// The emails which couldn't be sent are retried after Backoff,
+// the delay doubles after every attempt up to MaxBackoff.
+type NotificationsOptions struct {
+	// Interval is how often the outbox is looked through for the emails to send
+	Interval    time.Duration `yaml:"interval"     env-default:"1m"`
+	MaxAttempts int           `yaml:"max_attempts" env-default:"5"`
+	Backoff     time.Duration `yaml:"backoff"      env-default:"1m"`
+	MaxBackoff  time.Duration `yaml:"max_backoff"  env-default:"1h"`
+	SMTPOptions `              yaml:"smtp"`
+}
+
+// SMTPOptions configure the server the emails are sent through.
+// No emails are queued unless it is enabled.
+type SMTPOptions struct {
+	Enabled  bool          `yaml:"enabled"  env-default:"false"`
+	Host     string        `yaml:"host"     env-default:"localhost"`
+	Port     int           `yaml:"port"     env-default:"1025"`
+	Username string        `yaml:"username"`
+	Password string        `yaml:"password" env:"DIGITAL_LIBRARY_SMTP_PASSWORD"`
+	From     string        `yaml:"from"     env-default:"digital-library@localhost"`
+	Timeout  time.Duration `yaml:"timeout"  env-default:"10s"`
+}
+
+type BlobOptions struct {
+	Path string `yaml:"path" env-default:"./.storage/blobs"`
+}
+
+// ViewsOptions configure the html templates and the OpenAPI spec.
+// They are embedded into the binary and parsed once,
+// unless hot reload is on: then they are read from Dir and SpecPath on every request.
+type ViewsOptions struct {
+	HotReload bool   `yaml:"hot_reload" env-default:"false"`
+	Dir       string `yaml:"dir"        env-default:"./internal/views"`
+	SpecPath  string `yaml:"spec_path"  env-default:"./docs/swagger/openapi.json"`
+}
+
+// ValidationOptions configure the validation of the api requests against the OpenAPI spec.
+// Mode is off, log (the violations are logged) or reject (the invalid requests get 400).
+// In local and dev envs the responses are validated too, their violations are only logged.
+type ValidationOptions struct {
+	Mode string `yaml:"mode" env-default:"off"`
+}
+
+// MetricsOptions configure the Prometheus metrics.
+// They are served on a listener of their own, so they aren't exposed along with the api.
+type MetricsOptions struct {
+	Enabled bool   `yaml:"enabled" env-default:"false"`
+	Host    string `yaml:"host"    env-default:"localhost"`
+	Port    int    `yaml:"port"    env-default:"9454"`
+	Path    string `yaml:"path"    env-default:"/metrics"`
+}
+
+// TracingOptions configure the OpenTelemetry tracing.
+// Exporter is off, stdout (the spans are printed, for local debugging)
+// or otlp (the spans are sent to Endpoint over OTLP/HTTP).
+// SampleRatio is the share of the traces started by the server which are sampled,
+// the traces started by the clients are sampled if the clients sampled them.
+type TracingOptions struct {
+	Exporter    string  `yaml:"exporter"     env-default:"off"`
+	Endpoint    string  `yaml:"endpoint"     env-default:"localhost:4318"`
+	Insecure    bool    `yaml:"insecure"     env-default:"false"`
+	SampleRatio float64 `yaml:"sample_ratio" env-default:"1"`
+	ServiceName string  `yaml:"service_name" env-default:"digital-library"`
+}
+
+// RateLimitOptions configure the rate limiting of the requests of every user or ip.
+// Every limit allows Requests per Per on average with bursts of up to Burst requests.
+// The login and the upload routes have the stricter limits of their own.
+// TrustProxy should only be set if the server is behind a proxy appending the ip of the client
+// to X-Forwarded-For, otherwise the clients can make up their ips.
+type RateLimitOptions struct {
+	Enabled        bool          `yaml:"enabled"         env-default:"true"`
+	TrustProxy     bool          `yaml:"trust_proxy"     env-default:"false"`
+	Requests       int           `yaml:"requests"        env-default:"300"`
+	Per            time.Duration `yaml:"per"             env-default:"1m"`
+	Burst          int           `yaml:"burst"           env-default:"100"`
+	LoginRequests  int           `yaml:"login_requests"  env-default:"10"`
+	LoginPer       time.Duration `yaml:"login_per"       env-default:"1m"`
+	LoginBurst     int           `yaml:"login_burst"     env-default:"5"`
+	UploadRequests int           `yaml:"upload_requests" env-default:"30"`
+	UploadPer      time.Duration `yaml:"upload_per"      env-default:"1h"`
+	UploadBurst    int           `yaml:"upload_burst"    env-default:"10"`
+}
+
+// CORSOptions configure the cross-origin requests to the api.
+// The origins are like https://example.com, * allows any origin.
+// No origins are allowed by default, so only the pages of the server can call the api.
+type CORSOptions struct {
+	AllowedOrigins   []string      `yaml:"allowed_origins"`
+	AllowedMethods   []string      `yaml:"allowed_methods"   env-default:"GET,POST,PUT,DELETE"`
+	AllowCredentials bool          `yaml:"allow_credentials" env-default:"false"`
+	MaxAge           time.Duration `yaml:"max_age"           env-default:"10m"`
+}
+
+// SecurityOptions configure the security headers of the responses.
+// The default content security policy allows the scripts and the styles of the CDNs the views use.
+// Strict-Transport-Security is only sent if the proto of the server is https.
+type SecurityOptions struct {
+	ContentSecurityPolicy string        `yaml:"content_security_policy"`
+	FrameOptions          string        `yaml:"frame_options"           env-default:"DENY"`
+	HSTSMaxAge            time.Duration `yaml:"hsts_max_age"            env-default:"8760h"`
+}
+
 func Load() (*Config, error) {
 	const errMsg = "can't load config"
 
@@ -1,21 +1,27 @@
 package author
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/qo/digital-library/internal/auth"
+	"github.com/qo/digital-library/internal/handlers/api/query"
 	"github.com/qo/digital-library/internal/logger"
 	"github.com/qo/digital-library/internal/storage/author"
 )
 
 type authorStorage interface {
-	GetAuthor(id int) (*author.Author, error)
-	PostAuthor(*author.Author) error
-	PutAuthor(author *author.Author) error
-	DeleteAuthor(id int) error
+	GetAuthor(ctx context.Context, id int, includeDeleted bool) (*author.Author, error)
+	PostAuthor(context.Context, *author.Author) error
+	PutAuthor(ctx context.Context, author *author.Author) error
+	DeleteAuthor(ctx context.Context, id int) error
+	RestoreAuthor(ctx context.Context, id int) error
 }
 
 type authorHandler struct {
@@ -30,10 +36,20 @@ func New(log logger.Logger, as authorStorage) *authorHandler {
 	}
 }
 
-type postRequest = author.Author
+type postRequest struct {
+	// Id is assigned if it is not set
+	Id       int    `json:"id,omitempty"`
+	FullName string `json:"full_name"`
+}
+
+type errorResponse struct {
+	Error string `json:"error,omitempty"`
+}
 
 type postResponse struct {
 	Error string `json:"error,omitempty"`
+	// Id is the id of the created author, it is assigned if it's not specified
+	Id int `json:"id,omitempty"`
 }
 
 func (ah authorHandler) Post() http.HandlerFunc {
@@ -42,6 +58,10 @@ func (ah authorHandler) Post() http.HandlerFunc {
 
 		rd, we := json.NewDecoder(r.Body), json.NewEncoder(w)
 
+		if !ah.admin(w, r, errMsg, "create authors") {
+			return
+		}
+
 		var req postRequest
 
 		err := rd.Decode(&req)
@@ -50,26 +70,28 @@ func (ah authorHandler) Post() http.HandlerFunc {
 			we.Encode(postResponse{
 				Error: "invalid request",
 			})
-			ah.Error(fmt.Sprintf("%s: request not parsed: %s", errMsg, err), "req", req)
+			ah.ErrorContext(r.Context(), fmt.Sprintf("%s: request not parsed: %s", errMsg, err), "req", req)
 			return
 		}
 
-		err = ah.PostAuthor(&req)
+		a := author.Author{Id: req.Id, FullName: req.FullName}
+
+		err = ah.PostAuthor(r.Context(), &a)
 		// TODO: check type of error
 		if err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
 			we.Encode(postResponse{
 				Error: "db error",
 			})
-			ah.Error(fmt.Sprintf("%s: %s", errMsg, err))
+			ah.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
 			return
 		}
 
-		ah.Debug("post author success")
+		ah.DebugContext(r.Context(), "post author success")
 
 		w.WriteHeader(http.StatusCreated)
 
-		we.Encode(postResponse{})
+		we.Encode(postResponse{Id: a.Id})
 	}
 }
 
@@ -91,22 +113,41 @@ func (ah authorHandler) Get() http.HandlerFunc {
 			we.Encode(getResponse{
 				Error: "author id is not a number",
 			})
-			ah.Error(fmt.Sprintf("%s: author id is not a number: %s", errMsg, err))
+			ah.ErrorContext(r.Context(), fmt.Sprintf("%s: author id is not a number: %s", errMsg, err))
+			return
+		}
+
+		includeDeleted, err := query.IncludeDeleted(r)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			we.Encode(getResponse{
+				Error: "include_deleted is not a boolean",
+			})
+			ah.ErrorContext(r.Context(), fmt.Sprintf("%s: include_deleted is not a boolean: %s", errMsg, err))
+			return
+		}
+
+		if includeDeleted && !auth.IsAdmin(r.Context()) {
+			w.WriteHeader(http.StatusForbidden)
+			we.Encode(getResponse{
+				Error: "only admins can include deleted authors",
+			})
+			ah.WarnContext(r.Context(), fmt.Sprintf("%s: only admins can include deleted authors", errMsg))
 			return
 		}
 
-		author, err := ah.GetAuthor(id)
+		author, err := ah.GetAuthor(r.Context(), id, includeDeleted)
 		// TODO: check type of error
 		if err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
 			we.Encode(getResponse{
 				Error: "db error",
 			})
-			ah.Error(fmt.Sprintf("%s: author with %d id doesn't exist: %s", errMsg, id, err))
+			ah.ErrorContext(r.Context(), fmt.Sprintf("%s: author with %d id doesn't exist: %s", errMsg, id, err))
 			return
 		}
 
-		ah.Debug("get author success", "author", author)
+		ah.DebugContext(r.Context(), "get author success", "author", author)
 
 		w.WriteHeader(http.StatusOK)
 
@@ -117,7 +158,10 @@ func (ah authorHandler) Get() http.HandlerFunc {
 	}
 }
 
-type putRequest = author.Author
+type putRequest struct {
+	Id       int    `json:"id"`
+	FullName string `json:"full_name"`
+}
 
 type putResponse struct {
 	Error string `json:"error,omitempty"`
@@ -129,6 +173,10 @@ func (ah authorHandler) Put() http.HandlerFunc {
 
 		rd, we := json.NewDecoder(r.Body), json.NewEncoder(w)
 
+		if !ah.admin(w, r, errMsg, "update authors") {
+			return
+		}
+
 		var req putRequest
 
 		err := rd.Decode(&req)
@@ -137,24 +185,24 @@ func (ah authorHandler) Put() http.HandlerFunc {
 			we.Encode(putResponse{
 				Error: "invalid request",
 			})
-			ah.Error(fmt.Sprintf("%s: request not parsed: %s", errMsg, err), "req", req)
+			ah.ErrorContext(r.Context(), fmt.Sprintf("%s: request not parsed: %s", errMsg, err), "req", req)
 			return
 		}
 
-		ah.Debug("request parsed", "req", req)
+		ah.DebugContext(r.Context(), "request parsed", "req", req)
 
-		err = ah.PutAuthor(&req)
+		err = ah.PutAuthor(r.Context(), &author.Author{Id: req.Id, FullName: req.FullName})
 		// TODO: check type of error
 		if err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
 			we.Encode(putResponse{
 				Error: "db error",
 			})
-			ah.Error(fmt.Sprintf("%s: %s", errMsg, err))
+			ah.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
 			return
 		}
 
-		ah.Debug("put author success")
+		ah.DebugContext(r.Context(), "put author success")
 
 		w.WriteHeader(http.StatusOK)
 
@@ -172,6 +220,10 @@ func (ah authorHandler) Delete() http.HandlerFunc {
 
 		we := json.NewEncoder(w)
 
+		if !ah.admin(w, r, errMsg, "delete authors") {
+			return
+		}
+
 		idParam := chi.URLParam(r, "id")
 		id, err := strconv.Atoi(idParam)
 		if err != nil {
@@ -179,25 +231,96 @@ func (ah authorHandler) Delete() http.HandlerFunc {
 			we.Encode(deleteResponse{
 				Error: "author id is not a number",
 			})
-			ah.Error(fmt.Sprintf("%s: author id is not a number: %s", errMsg, err))
+			ah.ErrorContext(r.Context(), fmt.Sprintf("%s: author id is not a number: %s", errMsg, err))
 			return
 		}
 
-		err = ah.DeleteAuthor(id)
+		err = ah.DeleteAuthor(r.Context(), id)
 		// TODO: check type of error
 		if err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
 			we.Encode(deleteResponse{
 				Error: "db error",
 			})
-			ah.Error(fmt.Sprintf("%s: %s", errMsg, err))
+			ah.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
 			return
 		}
 
-		ah.Debug("delete author success")
+		ah.DebugContext(r.Context(), "delete author success")
 
 		w.WriteHeader(http.StatusOK)
 
 		we.Encode(deleteResponse{})
 	}
 }
+
+type restoreResponse struct {
+	Error string `json:"error,omitempty"`
+}
+
+func (ah authorHandler) Restore() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't restore author"
+
+		we := json.NewEncoder(w)
+
+		if !ah.admin(w, r, errMsg, "restore authors") {
+			return
+		}
+
+		idParam := chi.URLParam(r, "id")
+		id, err := strconv.Atoi(idParam)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			we.Encode(restoreResponse{
+				Error: "author id is not a number",
+			})
+			ah.ErrorContext(r.Context(), fmt.Sprintf("%s: author id is not a number: %s", errMsg, err))
+			return
+		}
+
+		err = ah.RestoreAuthor(r.Context(), id)
+		if errors.Is(err, sql.ErrNoRows) {
+			w.WriteHeader(http.StatusNotFound)
+			we.Encode(restoreResponse{
+				Error: "deleted author not found",
+			})
+			ah.WarnContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			we.Encode(restoreResponse{
+				Error: "db error",
+			})
+			ah.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		ah.DebugContext(r.Context(), "restore author success")
+
+		w.WriteHeader(http.StatusOK)
+
+		we.Encode(restoreResponse{})
+	}
+}
+
+// admin writes the error and returns false unless the request is made by an admin:
+// the anonymous requests are unauthorized, the requests of the users are forbidden.
+func (ah authorHandler) admin(w http.ResponseWriter, r *http.Request, errMsg, action string) bool {
+	if auth.IsAdmin(r.Context()) {
+		return true
+	}
+
+	status, msg := http.StatusForbidden, "only admins can "+action
+	if _, ok := auth.FromContext(r.Context()); !ok {
+		status, msg = http.StatusUnauthorized, "no valid bearer token"
+	}
+
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(errorResponse{
+		Error: msg,
+	})
+	ah.WarnContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, msg))
+	return false
+}
@@ -0,0 +1,94 @@
+package author
+
+import (
+	"net/http"
+
+	"github.com/qo/digital-library/internal/handlers/api/query"
+	"github.com/qo/digital-library/internal/openapi"
+)
+
+// Operations documents the routes of the author api.
+var Operations = []openapi.Operation{
+	{
+		Method:   http.MethodPost,
+		Path:     "/author",
+		Id:       "postAuthor",
+		Tag:      "author",
+		Summary:  "Create an author",
+		Auth:     true,
+		Request:  postRequest{},
+		Response: postResponse{},
+		Statuses: map[int]string{
+			http.StatusCreated:             "Author created",
+			http.StatusBadRequest:          "Invalid request",
+			http.StatusUnauthorized:        "No valid bearer token",
+			http.StatusForbidden:           "Only admins can create authors",
+			http.StatusInternalServerError: "DB error",
+		},
+	},
+	{
+		Method:   http.MethodGet,
+		Path:     "/author/{id}",
+		Id:       "getAuthor",
+		Tag:      "author",
+		Summary:  "Get the author",
+		Query:    []openapi.Param{query.IncludeDeletedParam},
+		Response: getResponse{},
+		Statuses: map[int]string{
+			http.StatusOK:                  "Author",
+			http.StatusBadRequest:          "Invalid id or include_deleted",
+			http.StatusForbidden:           "Only admins can include deleted authors",
+			http.StatusInternalServerError: "DB error",
+		},
+	},
+	{
+		Method:   http.MethodPut,
+		Path:     "/author",
+		Id:       "putAuthor",
+		Tag:      "author",
+		Summary:  "Update the author",
+		Auth:     true,
+		Request:  putRequest{},
+		Response: putResponse{},
+		Statuses: map[int]string{
+			http.StatusOK:                  "Author updated",
+			http.StatusBadRequest:          "Invalid request",
+			http.StatusUnauthorized:        "No valid bearer token",
+			http.StatusForbidden:           "Only admins can update authors",
+			http.StatusInternalServerError: "DB error",
+		},
+	},
+	{
+		Method:   http.MethodDelete,
+		Path:     "/author/{id}",
+		Id:       "deleteAuthor",
+		Tag:      "author",
+		Summary:  "Delete the author, it can be restored until it is purged",
+		Auth:     true,
+		Response: deleteResponse{},
+		Statuses: map[int]string{
+			http.StatusOK:                  "Author deleted",
+			http.StatusBadRequest:          "Invalid id",
+			http.StatusUnauthorized:        "No valid bearer token",
+			http.StatusForbidden:           "Only admins can delete authors",
+			http.StatusInternalServerError: "DB error",
+		},
+	},
+	{
+		Method:   http.MethodPost,
+		Path:     "/author/{id}/restore",
+		Id:       "restoreAuthor",
+		Tag:      "author",
+		Summary:  "Restore the deleted author",
+		Auth:     true,
+		Response: restoreResponse{},
+		Statuses: map[int]string{
+			http.StatusOK:                  "Author restored",
+			http.StatusBadRequest:          "Invalid id",
+			http.StatusUnauthorized:        "No valid bearer token",
+			http.StatusForbidden:           "Only admins can restore authors",
+			http.StatusNotFound:            "Deleted author not found",
+			http.StatusInternalServerError: "DB error",
+		},
+	},
+}
@@ -1,39 +1,84 @@
 package book
 
 import (
+	"bufio"
+	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/qo/digital-library/internal/auth"
+	"github.com/qo/digital-library/internal/blob"
+	"github.com/qo/digital-library/internal/catalog"
+	"github.com/qo/digital-library/internal/citation"
+	"github.com/qo/digital-library/internal/handlers/api/query"
 	"github.com/qo/digital-library/internal/logger"
+	"github.com/qo/digital-library/internal/storage"
+	"github.com/qo/digital-library/internal/storage/author"
 	"github.com/qo/digital-library/internal/storage/book"
 )
 
 type bookStorage interface {
-	PostBook(*book.Book) error
-	GetBook(id int) (*book.Book, error)
-	PutBook(book *book.Book) error
-	DeleteBook(id int) error
+	Begin() (*storage.Tx, error)
+	PostBook(context.Context, *book.Book) error
+	GetBooks(ctx context.Context, limit, offset int) ([]book.Book, error)
+	SearchBooks(ctx context.Context, query string, limit, offset int) ([]book.Book, error)
+	GetTopBooks(ctx context.Context, since *time.Time, limit, offset int) ([]book.TopBook, error)
+	GetBook(ctx context.Context, id int, includeDeleted bool) (*book.Book, error)
+	PutBook(ctx context.Context, book *book.Book) error
+	DeleteBook(ctx context.Context, id int) error
+	RestoreBook(ctx context.Context, id int) error
+	GetBookAuthors(ctx context.Context, bookId int) ([]author.Author, error)
+}
+
+type fileStorage interface {
+	Put(key string, r io.Reader) (int64, error)
+	Get(key string) (*os.File, error)
 }
 
 type bookHandler struct {
 	logger.Logger
 	bookStorage
+	files fileStorage
 }
 
-func New(log logger.Logger, bs bookStorage) *bookHandler {
+func New(log logger.Logger, bs bookStorage, fs fileStorage) *bookHandler {
 	return &bookHandler{
 		log,
 		bs,
+		fs,
 	}
 }
 
-type postRequest = book.Book
+// postRequest is a book along with the full names of its authors,
+// the authors are created if they don't exist.
+// The rating and the copies aren't set on creation.
+type postRequest struct {
+	// Id is assigned if it is not set
+	Id        int      `json:"id,omitempty"`
+	Isbn      string   `json:"isbn,omitempty"`
+	Title     string   `json:"title"`
+	Year      int      `json:"year,omitempty"`
+	Publisher string   `json:"publisher,omitempty"`
+	Authors   []string `json:"authors,omitempty"`
+}
+
+type errorResponse struct {
+	Error string `json:"error,omitempty"`
+}
 
 type postResponse struct {
 	Error string `json:"error,omitempty"`
+	// Id is the id of the created book, it is assigned if it's not specified
+	Id int `json:"id,omitempty"`
 }
 
 func (bh *bookHandler) Post() http.HandlerFunc {
@@ -42,6 +87,10 @@ func (bh *bookHandler) Post() http.HandlerFunc {
 
 		rd, we := json.NewDecoder(r.Body), json.NewEncoder(w)
 
+		if !bh.admin(w, r, errMsg, "create books") {
+			return
+		}
+
 		var req postRequest
 
 		err := rd.Decode(&req)
@@ -50,26 +99,143 @@ func (bh *bookHandler) Post() http.HandlerFunc {
 			we.Encode(postResponse{
 				Error: "invalid request",
 			})
-			bh.Error(fmt.Sprintf("%s: request not parsed: %s", errMsg, err), "req", req)
+			bh.ErrorContext(r.Context(), fmt.Sprintf("%s: request not parsed: %s", errMsg, err), "req", req)
 			return
 		}
 
-		err = bh.PostBook(&req)
+		b := book.Book{Id: req.Id, Isbn: req.Isbn, Title: req.Title, Year: req.Year, Publisher: req.Publisher}
+
+		if len(req.Authors) > 0 {
+			b.Id, err = catalog.Add(bh, catalog.Record{Book: b, Authors: req.Authors})
+		} else {
+			err = bh.PostBook(r.Context(), &b)
+		}
+		if errors.Is(err, catalog.ErrInvalidRecord) {
+			w.WriteHeader(http.StatusBadRequest)
+			we.Encode(postResponse{
+				Error: err.Error(),
+			})
+			bh.WarnContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
 		// TODO: check type of error
 		if err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
 			we.Encode(postResponse{
 				Error: "db error",
 			})
-			bh.Error(fmt.Sprintf("%s: %s", errMsg, err))
+			bh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
 			return
 		}
 
-		bh.Debug("post book success")
+		bh.DebugContext(r.Context(), "post book success")
 
 		w.WriteHeader(http.StatusCreated)
 
-		we.Encode(postResponse{})
+		we.Encode(postResponse{Id: b.Id})
+	}
+}
+
+type listResponse struct {
+	Error string      `json:"error,omitempty"`
+	Books []book.Book `json:"books,omitempty"`
+}
+
+// List returns a page of the books ordered by title.
+// With the q query parameter only the books whose title, isbn, publisher
+// or author contains it are returned.
+func (bh *bookHandler) List() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't list books"
+
+		we := json.NewEncoder(w)
+
+		limit, offset, err := query.Page(r)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			we.Encode(listResponse{
+				Error: err.Error(),
+			})
+			bh.WarnContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		var books []book.Book
+
+		if q := strings.TrimSpace(r.URL.Query().Get("q")); q != "" {
+			books, err = bh.SearchBooks(r.Context(), q, limit, offset)
+		} else {
+			books, err = bh.GetBooks(r.Context(), limit, offset)
+		}
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			we.Encode(listResponse{
+				Error: "db error",
+			})
+			bh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		bh.DebugContext(r.Context(), "list books success", "books", len(books))
+
+		w.WriteHeader(http.StatusOK)
+
+		we.Encode(listResponse{
+			Books: books,
+		})
+	}
+}
+
+type topResponse struct {
+	Error string         `json:"error,omitempty"`
+	Books []book.TopBook `json:"books,omitempty"`
+}
+
+// Top returns a page of the books ranked by the bayesian average of their ratings.
+// With the period query parameter only the reviews updated during the period are counted.
+func (bh *bookHandler) Top() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't list top books"
+
+		we := json.NewEncoder(w)
+
+		limit, offset, err := query.Page(r)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			we.Encode(topResponse{
+				Error: err.Error(),
+			})
+			bh.WarnContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		since, err := query.Since(r, time.Now())
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			we.Encode(topResponse{
+				Error: err.Error(),
+			})
+			bh.WarnContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		books, err := bh.GetTopBooks(r.Context(), since, limit, offset)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			we.Encode(topResponse{
+				Error: "db error",
+			})
+			bh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		bh.DebugContext(r.Context(), "list top books success", "books", len(books))
+
+		w.WriteHeader(http.StatusOK)
+
+		we.Encode(topResponse{
+			Books: books,
+		})
 	}
 }
 
@@ -91,22 +257,41 @@ func (bh *bookHandler) Get() http.HandlerFunc {
 			we.Encode(getResponse{
 				Error: "book id is not a number",
 			})
-			bh.Error(fmt.Sprintf("%s: book id is not a number: %s", errMsg, err))
+			bh.ErrorContext(r.Context(), fmt.Sprintf("%s: book id is not a number: %s", errMsg, err))
+			return
+		}
+
+		includeDeleted, err := query.IncludeDeleted(r)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			we.Encode(getResponse{
+				Error: "include_deleted is not a boolean",
+			})
+			bh.ErrorContext(r.Context(), fmt.Sprintf("%s: include_deleted is not a boolean: %s", errMsg, err))
+			return
+		}
+
+		if includeDeleted && !auth.IsAdmin(r.Context()) {
+			w.WriteHeader(http.StatusForbidden)
+			we.Encode(getResponse{
+				Error: "only admins can include deleted books",
+			})
+			bh.WarnContext(r.Context(), fmt.Sprintf("%s: only admins can include deleted books", errMsg))
 			return
 		}
 
-		book, err := bh.GetBook(id)
+		book, err := bh.GetBook(r.Context(), id, includeDeleted)
 		// TODO: check type of error
 		if err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
 			we.Encode(getResponse{
 				Error: "db error",
 			})
-			bh.Error(fmt.Sprintf("%s: book with %d id doesn't exist: %s", errMsg, id, err))
+			bh.ErrorContext(r.Context(), fmt.Sprintf("%s: book with %d id doesn't exist: %s", errMsg, id, err))
 			return
 		}
 
-		bh.Debug("get book success", "book", book)
+		bh.DebugContext(r.Context(), "get book success", "book", book)
 
 		w.WriteHeader(http.StatusOK)
 
@@ -117,7 +302,14 @@ func (bh *bookHandler) Get() http.HandlerFunc {
 	}
 }
 
-type putRequest = book.Book
+// putRequest replaces the book, the rating and the copies aren't changed by it.
+type putRequest struct {
+	Id        int    `json:"id"`
+	Isbn      string `json:"isbn,omitempty"`
+	Title     string `json:"title"`
+	Year      int    `json:"year,omitempty"`
+	Publisher string `json:"publisher,omitempty"`
+}
 
 type putResponse struct {
 	Error string `json:"error,omitempty"`
@@ -129,6 +321,10 @@ func (bh *bookHandler) Put() http.HandlerFunc {
 
 		rd, we := json.NewDecoder(r.Body), json.NewEncoder(w)
 
+		if !bh.admin(w, r, errMsg, "update books") {
+			return
+		}
+
 		var req putRequest
 
 		err := rd.Decode(&req)
@@ -137,24 +333,24 @@ func (bh *bookHandler) Put() http.HandlerFunc {
 			we.Encode(putResponse{
 				Error: "invalid request",
 			})
-			bh.Error(fmt.Sprintf("%s: request not parsed: %s", errMsg, err), "req", req)
+			bh.ErrorContext(r.Context(), fmt.Sprintf("%s: request not parsed: %s", errMsg, err), "req", req)
 			return
 		}
 
-		bh.Debug("request parsed", "req", req)
+		bh.DebugContext(r.Context(), "request parsed", "req", req)
 
-		err = bh.PutBook(&req)
+		err = bh.PutBook(r.Context(), &book.Book{Id: req.Id, Isbn: req.Isbn, Title: req.Title, Year: req.Year, Publisher: req.Publisher})
 		// TODO: check type of error
 		if err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
 			we.Encode(putResponse{
 				Error: "db error",
 			})
-			bh.Error(fmt.Sprintf("%s: %s", errMsg, err))
+			bh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
 			return
 		}
 
-		bh.Debug("put book success")
+		bh.DebugContext(r.Context(), "put book success")
 
 		w.WriteHeader(http.StatusOK)
 
@@ -172,6 +368,10 @@ func (bh *bookHandler) Delete() http.HandlerFunc {
 
 		we := json.NewEncoder(w)
 
+		if !bh.admin(w, r, errMsg, "delete books") {
+			return
+		}
+
 		idParam := chi.URLParam(r, "id")
 		id, err := strconv.Atoi(idParam)
 		if err != nil {
@@ -179,25 +379,327 @@ func (bh *bookHandler) Delete() http.HandlerFunc {
 			we.Encode(deleteResponse{
 				Error: "book id is not a number",
 			})
-			bh.Error(fmt.Sprintf("%s: book id is not a number: %s", errMsg, err))
+			bh.ErrorContext(r.Context(), fmt.Sprintf("%s: book id is not a number: %s", errMsg, err))
 			return
 		}
 
-		err = bh.DeleteBook(id)
+		err = bh.DeleteBook(r.Context(), id)
 		// TODO: check type of error
 		if err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
 			we.Encode(deleteResponse{
 				Error: "db error",
 			})
-			bh.Error(fmt.Sprintf("%s: %s", errMsg, err))
+			bh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
 			return
 		}
 
-		bh.Debug("delete book success")
+		bh.DebugContext(r.Context(), "delete book success")
 
 		w.WriteHeader(http.StatusOK)
 
 		we.Encode(deleteResponse{})
 	}
 }
+
+type restoreResponse struct {
+	Error string `json:"error,omitempty"`
+}
+
+func (bh *bookHandler) Restore() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't restore book"
+
+		we := json.NewEncoder(w)
+
+		if !bh.admin(w, r, errMsg, "restore books") {
+			return
+		}
+
+		idParam := chi.URLParam(r, "id")
+		id, err := strconv.Atoi(idParam)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			we.Encode(restoreResponse{
+				Error: "book id is not a number",
+			})
+			bh.ErrorContext(r.Context(), fmt.Sprintf("%s: book id is not a number: %s", errMsg, err))
+			return
+		}
+
+		err = bh.RestoreBook(r.Context(), id)
+		if errors.Is(err, sql.ErrNoRows) {
+			w.WriteHeader(http.StatusNotFound)
+			we.Encode(restoreResponse{
+				Error: "deleted book not found",
+			})
+			bh.WarnContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			we.Encode(restoreResponse{
+				Error: "db error",
+			})
+			bh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		bh.DebugContext(r.Context(), "restore book success")
+
+		w.WriteHeader(http.StatusOK)
+
+		we.Encode(restoreResponse{})
+	}
+}
+
+type citeResponse struct {
+	Error string `json:"error,omitempty"`
+}
+
+func (bh *bookHandler) Cite() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't cite book"
+
+		we := json.NewEncoder(w)
+
+		idParam := chi.URLParam(r, "id")
+		id, err := strconv.Atoi(idParam)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			we.Encode(citeResponse{
+				Error: "book id is not a number",
+			})
+			bh.ErrorContext(r.Context(), fmt.Sprintf("%s: book id is not a number: %s", errMsg, err))
+			return
+		}
+
+		formatParam := r.URL.Query().Get("format")
+		if formatParam == "" {
+			formatParam = string(citation.FormatBibTeX)
+		}
+
+		format, err := citation.ParseFormat(formatParam)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			we.Encode(citeResponse{
+				Error: "unknown citation format",
+			})
+			bh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		book, err := bh.GetBook(r.Context(), id, false)
+		if errors.Is(err, sql.ErrNoRows) {
+			w.WriteHeader(http.StatusNotFound)
+			we.Encode(citeResponse{
+				Error: "book not found",
+			})
+			bh.WarnContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			we.Encode(citeResponse{
+				Error: "db error",
+			})
+			bh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		authors, err := bh.GetBookAuthors(r.Context(), id)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			we.Encode(citeResponse{
+				Error: "db error",
+			})
+			bh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		w.Header().Set("Content-Type", format.ContentType())
+		w.WriteHeader(http.StatusOK)
+
+		err = citation.Write(w, format, []citation.Item{{Book: *book, Authors: authors}})
+		if err != nil {
+			bh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		bh.DebugContext(r.Context(), "cite book success", "format", format)
+	}
+}
+
+type putFileResponse struct {
+	Error string `json:"error,omitempty"`
+	Size  int64  `json:"size,omitempty"`
+}
+
+func (bh *bookHandler) PutFile() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't put book file"
+
+		we := json.NewEncoder(w)
+
+		if !bh.admin(w, r, errMsg, "upload books") {
+			return
+		}
+
+		idParam := chi.URLParam(r, "id")
+		id, err := strconv.Atoi(idParam)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			we.Encode(putFileResponse{
+				Error: "book id is not a number",
+			})
+			bh.ErrorContext(r.Context(), fmt.Sprintf("%s: book id is not a number: %s", errMsg, err))
+			return
+		}
+
+		_, err = bh.GetBook(r.Context(), id, false)
+		if errors.Is(err, sql.ErrNoRows) {
+			w.WriteHeader(http.StatusNotFound)
+			we.Encode(putFileResponse{
+				Error: "book not found",
+			})
+			bh.WarnContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			we.Encode(putFileResponse{
+				Error: "db error",
+			})
+			bh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		body := bufio.NewReader(http.MaxBytesReader(w, r.Body, blob.MaxBookSize))
+
+		if !blob.IsPDF(body) {
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			we.Encode(putFileResponse{
+				Error: "book file should be pdf",
+			})
+			bh.WarnContext(r.Context(), fmt.Sprintf("%s: book file is not pdf", errMsg))
+			return
+		}
+
+		size, err := bh.files.Put(blob.BookKey(id), body)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			we.Encode(putFileResponse{
+				Error: "file storage error",
+			})
+			bh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		bh.DebugContext(r.Context(), "put book file success", "id", id, "size", size)
+
+		w.WriteHeader(http.StatusOK)
+
+		we.Encode(putFileResponse{
+			Size: size,
+		})
+	}
+}
+
+type getFileResponse struct {
+	Error string `json:"error,omitempty"`
+}
+
+func (bh *bookHandler) GetFile() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't get book file"
+
+		we := json.NewEncoder(w)
+
+		idParam := chi.URLParam(r, "id")
+		id, err := strconv.Atoi(idParam)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			we.Encode(getFileResponse{
+				Error: "book id is not a number",
+			})
+			bh.ErrorContext(r.Context(), fmt.Sprintf("%s: book id is not a number: %s", errMsg, err))
+			return
+		}
+
+		_, err = bh.GetBook(r.Context(), id, false)
+		if errors.Is(err, sql.ErrNoRows) {
+			w.WriteHeader(http.StatusNotFound)
+			we.Encode(getFileResponse{
+				Error: "book not found",
+			})
+			bh.WarnContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			we.Encode(getFileResponse{
+				Error: "db error",
+			})
+			bh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		f, err := bh.files.Get(blob.BookKey(id))
+		if errors.Is(err, os.ErrNotExist) {
+			w.WriteHeader(http.StatusNotFound)
+			we.Encode(getFileResponse{
+				Error: "book file not found",
+			})
+			bh.WarnContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			we.Encode(getFileResponse{
+				Error: "file storage error",
+			})
+			bh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+		defer f.Close()
+
+		info, err := f.Stat()
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			we.Encode(getFileResponse{
+				Error: "file storage error",
+			})
+			bh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		name := fmt.Sprintf("book-%d.pdf", id)
+
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, name))
+
+		http.ServeContent(w, r, name, info.ModTime(), f)
+
+		bh.DebugContext(r.Context(), "get book file success", "id", id)
+	}
+}
+
+// admin writes the error and returns false unless the request is made by an admin:
+// the anonymous requests are unauthorized, the requests of the users are forbidden.
+func (bh *bookHandler) admin(w http.ResponseWriter, r *http.Request, errMsg, action string) bool {
+	if auth.IsAdmin(r.Context()) {
+		return true
+	}
+
+	status, msg := http.StatusForbidden, "only admins can "+action
+	if _, ok := auth.FromContext(r.Context()); !ok {
+		status, msg = http.StatusUnauthorized, "no valid bearer token"
+	}
+
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(errorResponse{
+		Error: msg,
+	})
+	bh.WarnContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, msg))
+	return false
+}
@@ -0,0 +1,176 @@
+package book
+
+import (
+	"net/http"
+
+	"github.com/qo/digital-library/internal/handlers/api/query"
+	"github.com/qo/digital-library/internal/openapi"
+)
+
+// Operations documents the routes of the book api.
+var Operations = []openapi.Operation{
+	{
+		Method:   http.MethodPost,
+		Path:     "/book",
+		Id:       "postBook",
+		Tag:      "book",
+		Summary:  "Create a book, the authors are looked up by full name and created if they don't exist",
+		Auth:     true,
+		Request:  postRequest{},
+		Response: postResponse{},
+		Statuses: map[int]string{
+			http.StatusCreated:             "Book created",
+			http.StatusBadRequest:          "Invalid request or book",
+			http.StatusUnauthorized:        "No valid bearer token",
+			http.StatusForbidden:           "Only admins can create books",
+			http.StatusInternalServerError: "DB error",
+		},
+	},
+	{
+		Method:  http.MethodGet,
+		Path:    "/books",
+		Id:      "listBooks",
+		Tag:     "book",
+		Summary: "List the books ordered by title",
+		Query: append([]openapi.Param{{
+			Name:        "q",
+			Description: "Only the books whose title, isbn, publisher or author contains it",
+			Type:        "string",
+		}}, query.PageParams...),
+		Response: listResponse{},
+		Statuses: map[int]string{
+			http.StatusOK:                  "Books",
+			http.StatusBadRequest:          "Invalid limit or offset",
+			http.StatusInternalServerError: "DB error",
+		},
+	},
+	{
+		Method:   http.MethodGet,
+		Path:     "/books/top",
+		Id:       "listTopBooks",
+		Tag:      "book",
+		Summary:  "List the books ranked by the bayesian average of their ratings",
+		Query:    append([]openapi.Param{query.PeriodParam}, query.PageParams...),
+		Response: topResponse{},
+		Statuses: map[int]string{
+			http.StatusOK:                  "Top books",
+			http.StatusBadRequest:          "Invalid period, limit or offset",
+			http.StatusInternalServerError: "DB error",
+		},
+	},
+	{
+		Method:   http.MethodGet,
+		Path:     "/book/{id}",
+		Id:       "getBook",
+		Tag:      "book",
+		Summary:  "Get the book",
+		Query:    []openapi.Param{query.IncludeDeletedParam},
+		Response: getResponse{},
+		Statuses: map[int]string{
+			http.StatusOK:                  "Book",
+			http.StatusBadRequest:          "Invalid id or include_deleted",
+			http.StatusForbidden:           "Only admins can include deleted books",
+			http.StatusInternalServerError: "DB error",
+		},
+	},
+	{
+		Method:   http.MethodPut,
+		Path:     "/book",
+		Id:       "putBook",
+		Tag:      "book",
+		Summary:  "Update the book, the copies are set with PUT /book/{id}/copies",
+		Auth:     true,
+		Request:  putRequest{},
+		Response: putResponse{},
+		Statuses: map[int]string{
+			http.StatusOK:                  "Book updated",
+			http.StatusBadRequest:          "Invalid request",
+			http.StatusUnauthorized:        "No valid bearer token",
+			http.StatusForbidden:           "Only admins can update books",
+			http.StatusInternalServerError: "DB error",
+		},
+	},
+	{
+		Method:   http.MethodDelete,
+		Path:     "/book/{id}",
+		Id:       "deleteBook",
+		Tag:      "book",
+		Summary:  "Delete the book, it can be restored until it is purged",
+		Auth:     true,
+		Response: deleteResponse{},
+		Statuses: map[int]string{
+			http.StatusOK:                  "Book deleted",
+			http.StatusBadRequest:          "Invalid id",
+			http.StatusUnauthorized:        "No valid bearer token",
+			http.StatusForbidden:           "Only admins can delete books",
+			http.StatusInternalServerError: "DB error",
+		},
+	},
+	{
+		Method:   http.MethodPost,
+		Path:     "/book/{id}/restore",
+		Id:       "restoreBook",
+		Tag:      "book",
+		Summary:  "Restore the deleted book",
+		Auth:     true,
+		Response: restoreResponse{},
+		Statuses: map[int]string{
+			http.StatusOK:                  "Book restored",
+			http.StatusBadRequest:          "Invalid id",
+			http.StatusUnauthorized:        "No valid bearer token",
+			http.StatusForbidden:           "Only admins can restore books",
+			http.StatusNotFound:            "Deleted book not found",
+			http.StatusInternalServerError: "DB error",
+		},
+	},
+	{
+		Method:        http.MethodGet,
+		Path:          "/book/{id}/cite",
+		Id:            "citeBook",
+		Tag:           "book",
+		Summary:       "Cite the book",
+		Query:         []openapi.Param{query.CitationFormatParam},
+		Response:      citeResponse{},
+		ResponseMedia: query.CitationMedia,
+		Statuses: map[int]string{
+			http.StatusOK:                  "Citation",
+			http.StatusBadRequest:          "Invalid id or unknown format",
+			http.StatusNotFound:            "Book not found",
+			http.StatusInternalServerError: "DB error",
+		},
+	},
+	{
+		Method:       http.MethodPut,
+		Path:         "/book/{id}/file",
+		Id:           "putBookFile",
+		Tag:          "book",
+		Summary:      "Upload the PDF of the book",
+		Auth:         true,
+		RequestMedia: []string{"application/pdf"},
+		Response:     putFileResponse{},
+		Statuses: map[int]string{
+			http.StatusOK:                   "File uploaded",
+			http.StatusBadRequest:           "Invalid id",
+			http.StatusUnauthorized:         "No valid bearer token",
+			http.StatusForbidden:            "Only admins can upload books",
+			http.StatusNotFound:             "Book not found",
+			http.StatusUnsupportedMediaType: "File is not a PDF",
+			http.StatusInternalServerError:  "DB or file storage error",
+		},
+	},
+	{
+		Method:        http.MethodGet,
+		Path:          "/book/{id}/file",
+		Id:            "getBookFile",
+		Tag:           "book",
+		Summary:       "Download the PDF of the book",
+		Response:      getFileResponse{},
+		ResponseMedia: []string{"application/pdf"},
+		Statuses: map[int]string{
+			http.StatusOK:                  "Book file",
+			http.StatusBadRequest:          "Invalid id",
+			http.StatusNotFound:            "Book or book file not found",
+			http.StatusInternalServerError: "DB or file storage error",
+		},
+	},
+}
@@ -0,0 +1,174 @@
+package catalog
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/qo/digital-library/internal/auth"
+	"github.com/qo/digital-library/internal/catalog"
+	"github.com/qo/digital-library/internal/logger"
+	"github.com/qo/digital-library/internal/storage"
+	"github.com/qo/digital-library/internal/storage/author"
+	"github.com/qo/digital-library/internal/storage/book"
+)
+
+// maxImportSize is the max size of the imported file in bytes.
+const maxImportSize = 32 << 20
+
+type catalogStorage interface {
+	Begin() (*storage.Tx, error)
+	EachBookWithAuthors(ctx context.Context, fn func(book.Book, []author.Author) error) error
+}
+
+type catalogHandler struct {
+	logger.Logger
+	catalogStorage
+}
+
+func New(log logger.Logger, cs catalogStorage) *catalogHandler {
+	return &catalogHandler{
+		log,
+		cs,
+	}
+}
+
+type importResponse struct {
+	Error string `json:"error,omitempty"`
+	*catalog.Report
+}
+
+func (ch *catalogHandler) Import() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't import catalog"
+
+		we := json.NewEncoder(w)
+
+		if !auth.IsAdmin(r.Context()) {
+			w.WriteHeader(http.StatusForbidden)
+			we.Encode(importResponse{
+				Error: "only admins can import catalog",
+			})
+			ch.WarnContext(r.Context(), fmt.Sprintf("%s: only admins can import catalog", errMsg))
+			return
+		}
+
+		format, err := importFormat(r)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			we.Encode(importResponse{
+				Error: "unknown format",
+			})
+			ch.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		dryRun := false
+		if param := r.URL.Query().Get("dry_run"); param != "" {
+			dryRun, err = strconv.ParseBool(param)
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				we.Encode(importResponse{
+					Error: "dry_run is not a boolean",
+				})
+				ch.ErrorContext(r.Context(), fmt.Sprintf("%s: dry_run is not a boolean: %s", errMsg, err))
+				return
+			}
+		}
+
+		body := http.MaxBytesReader(w, r.Body, maxImportSize)
+
+		report, err := catalog.Import(ch.catalogStorage, body, format, dryRun)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			we.Encode(importResponse{
+				Error: "invalid file",
+			})
+			ch.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		ch.InfoContext(r.Context(), "catalog imported",
+			"rows", report.Rows,
+			"books", report.Books,
+			"authors", report.Authors,
+			"errors", len(report.Errors),
+			"committed", report.Committed,
+		)
+
+		if len(report.Errors) > 0 {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+		} else if report.Committed {
+			w.WriteHeader(http.StatusCreated)
+		} else {
+			w.WriteHeader(http.StatusOK)
+		}
+
+		we.Encode(importResponse{
+			Report: report,
+		})
+	}
+}
+
+type exportResponse struct {
+	Error string `json:"error,omitempty"`
+}
+
+func (ch *catalogHandler) Export() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't export catalog"
+
+		param := r.URL.Query().Get("format")
+		if param == "" {
+			param = string(catalog.FormatJSONL)
+		}
+
+		format, err := catalog.ParseFormat(param)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(exportResponse{
+				Error: "unknown format",
+			})
+			ch.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		w.Header().Set("Content-Type", format.ContentType())
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="catalog.%s"`, format.Extension()))
+
+		// the status is sent with the first written record,
+		// so the errors after that can only be logged
+		err = catalog.Export(r.Context(), ch.catalogStorage, w, format)
+		if err != nil {
+			ch.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		ch.InfoContext(r.Context(), "catalog exported", "format", format)
+	}
+}
+
+// importFormat takes the format from the format query parameter
+// or from the content type if the parameter is not specified.
+func importFormat(r *http.Request) (catalog.Format, error) {
+	if param := r.URL.Query().Get("format"); param != "" {
+		return catalog.ParseFormat(param)
+	}
+
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return "", fmt.Errorf("format is not specified: %w", err)
+	}
+
+	switch mediaType {
+	case "text/csv":
+		return catalog.FormatCSV, nil
+	case "application/x-ndjson", "application/jsonl":
+		return catalog.FormatJSONL, nil
+	default:
+		return "", fmt.Errorf("content type %s is unknown", mediaType)
+	}
+}
@@ -0,0 +1,61 @@
+package catalog
+
+import (
+	"net/http"
+
+	"github.com/qo/digital-library/internal/openapi"
+)
+
+// Operations documents the routes of the catalog api.
+var Operations = []openapi.Operation{
+	{
+		Method:  http.MethodPost,
+		Path:    "/import",
+		Id:      "importCatalog",
+		Tag:     "catalog",
+		Summary: "Import books from a CSV or JSON Lines file in a single transaction",
+		Auth:    true,
+		Query: []openapi.Param{
+			{
+				Name:        "format",
+				Description: "File format, taken from the content type by default",
+				Type:        "string",
+				Enum:        []string{"csv", "jsonl"},
+			},
+			{
+				Name:        "dry_run",
+				Description: "Validate the file without saving anything",
+				Type:        "boolean",
+			},
+		},
+		RequestMedia:   []string{"text/csv", "application/x-ndjson"},
+		Response:       importResponse{},
+		ResponseErrors: []int{http.StatusUnprocessableEntity},
+		Statuses: map[int]string{
+			http.StatusCreated:             "Catalog imported",
+			http.StatusOK:                  "Dry run succeeded",
+			http.StatusBadRequest:          "Invalid file, format or dry_run",
+			http.StatusForbidden:           "Only admins can import catalog",
+			http.StatusUnprocessableEntity: "Some rows have errors, nothing is saved",
+		},
+	},
+	{
+		Method:  http.MethodGet,
+		Path:    "/export",
+		Id:      "exportCatalog",
+		Tag:     "catalog",
+		Summary: "Export the catalog",
+		Query: []openapi.Param{{
+			Name:        "format",
+			Description: "File format, jsonl by default",
+			Type:        "string",
+			Enum:        []string{"csv", "jsonl", "marcxml"},
+		}},
+		Response:      exportResponse{},
+		ResponseMedia: []string{"text/csv", "application/x-ndjson", "application/marcxml+xml"},
+		Statuses: map[int]string{
+			http.StatusOK:         "Catalog",
+			http.StatusBadRequest: "Unknown format",
+		},
+	},
+}
@@ -0,0 +1,166 @@
+package loan
+
+import (
+	"net/http"
+
+	"github.com/qo/digital-library/internal/handlers/api/query"
+	"github.com/qo/digital-library/internal/openapi"
+	"github.com/qo/digital-library/internal/storage/loan"
+)
+
+var statusParam = openapi.Param{
+	Name:        "status",
+	Description: "Only the loans with the status, the active ones include the overdue ones",
+	Type:        "string",
+	Enum:        []string{loan.StatusActive, loan.StatusOverdue, loan.StatusReturned},
+}
+
+// Operations documents the routes of the loan api.
+var Operations = []openapi.Operation{
+	{
+		Method:   http.MethodGet,
+		Path:     "/loans",
+		Id:       "listAllLoans",
+		Tag:      "loan",
+		Summary:  "List the loans of all users, the most recently checked out first",
+		Auth:     true,
+		Query:    append([]openapi.Param{statusParam}, query.PageParams...),
+		Response: listResponse{},
+		Statuses: map[int]string{
+			http.StatusOK:                  "Loans",
+			http.StatusBadRequest:          "Invalid status, limit or offset",
+			http.StatusForbidden:           "Only admins can list all loans",
+			http.StatusInternalServerError: "DB error",
+		},
+	},
+	{
+		Method:   http.MethodGet,
+		Path:     "/user/{id}/loans",
+		Id:       "listLoans",
+		Tag:      "loan",
+		Summary:  "List the loans of the user, the most recently checked out first",
+		Auth:     true,
+		Query:    append([]openapi.Param{statusParam}, query.PageParams...),
+		Response: listResponse{},
+		Statuses: map[int]string{
+			http.StatusOK:                  "Loans",
+			http.StatusBadRequest:          "Invalid id, status, limit or offset",
+			http.StatusForbidden:           "Only the user and admins can access the loans",
+			http.StatusInternalServerError: "DB error",
+		},
+	},
+	{
+		Method:   http.MethodPost,
+		Path:     "/user/{id}/loans",
+		Id:       "checkoutBook",
+		Tag:      "loan",
+		Summary:  "Check out a copy of the book for the loan period",
+		Auth:     true,
+		Request:  bookRequest{},
+		Response: checkoutResponse{},
+		Statuses: map[int]string{
+			http.StatusCreated:             "Book checked out",
+			http.StatusBadRequest:          "Invalid id",
+			http.StatusForbidden:           "Only the user and admins can access the loans",
+			http.StatusNotFound:            "Book not found",
+			http.StatusConflict:            "No copies available, book already borrowed or loan limit reached",
+			http.StatusInternalServerError: "DB error",
+		},
+	},
+	{
+		Method:   http.MethodPost,
+		Path:     "/user/{id}/loans/{loan_id}/return",
+		Id:       "returnLoan",
+		Tag:      "loan",
+		Summary:  "Return the copy, it is lent to the first user in the queue for the book",
+		Auth:     true,
+		Response: returnResponse{},
+		Statuses: map[int]string{
+			http.StatusOK:                  "Loan returned",
+			http.StatusBadRequest:          "Invalid id",
+			http.StatusForbidden:           "Only the user and admins can access the loans",
+			http.StatusNotFound:            "Loan not found",
+			http.StatusConflict:            "Loan already returned",
+			http.StatusInternalServerError: "DB error",
+		},
+	},
+	{
+		Method:   http.MethodGet,
+		Path:     "/user/{id}/holds",
+		Id:       "listHolds",
+		Tag:      "loan",
+		Summary:  "List the holds of the user along with their places in the queues, the oldest first",
+		Auth:     true,
+		Response: listHoldsResponse{},
+		Statuses: map[int]string{
+			http.StatusOK:                  "Holds",
+			http.StatusBadRequest:          "Invalid id",
+			http.StatusForbidden:           "Only the user and admins can access the loans",
+			http.StatusInternalServerError: "DB error",
+		},
+	},
+	{
+		Method:   http.MethodPost,
+		Path:     "/user/{id}/holds",
+		Id:       "placeHold",
+		Tag:      "loan",
+		Summary:  "Join the queue for a copy of the book, the copy is checked out automatically when it's the user's turn",
+		Auth:     true,
+		Request:  bookRequest{},
+		Response: placeHoldResponse{},
+		Statuses: map[int]string{
+			http.StatusCreated:             "Hold placed",
+			http.StatusBadRequest:          "Invalid id",
+			http.StatusForbidden:           "Only the user and admins can access the loans",
+			http.StatusNotFound:            "Book not found",
+			http.StatusConflict:            "Book already borrowed or held, a copy is available or loan limit reached",
+			http.StatusInternalServerError: "DB error",
+		},
+	},
+	{
+		Method:   http.MethodDelete,
+		Path:     "/user/{id}/holds/{book_id}",
+		Id:       "cancelHold",
+		Tag:      "loan",
+		Summary:  "Leave the queue for the book",
+		Auth:     true,
+		Response: cancelHoldResponse{},
+		Statuses: map[int]string{
+			http.StatusOK:                  "Hold cancelled",
+			http.StatusBadRequest:          "Invalid id",
+			http.StatusForbidden:           "Only the user and admins can access the loans",
+			http.StatusInternalServerError: "DB error",
+		},
+	},
+	{
+		Method:   http.MethodGet,
+		Path:     "/book/{id}/availability",
+		Id:       "getAvailability",
+		Tag:      "loan",
+		Summary:  "Get the number of the copies of the book, the lent ones, the queue length and the copies available right away",
+		Response: availabilityResponse{},
+		Statuses: map[int]string{
+			http.StatusOK:                  "Availability",
+			http.StatusBadRequest:          "Invalid id",
+			http.StatusNotFound:            "Book not found",
+			http.StatusInternalServerError: "DB error",
+		},
+	},
+	{
+		Method:   http.MethodPut,
+		Path:     "/book/{id}/copies",
+		Id:       "putBookCopies",
+		Tag:      "loan",
+		Summary:  "Set the number of the copies of the book, the added copies are lent to the queue",
+		Auth:     true,
+		Request:  putCopiesRequest{},
+		Response: putCopiesResponse{},
+		Statuses: map[int]string{
+			http.StatusOK:                  "Copies set",
+			http.StatusBadRequest:          "Invalid id or copies",
+			http.StatusForbidden:           "Only admins can set the copies",
+			http.StatusNotFound:            "Book not found",
+			http.StatusInternalServerError: "DB error",
+		},
+	},
+}
@@ -0,0 +1,577 @@
+package loan
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/qo/digital-library/internal/auth"
+	"github.com/qo/digital-library/internal/config"
+	"github.com/qo/digital-library/internal/handlers/api/query"
+	"github.com/qo/digital-library/internal/logger"
+	"github.com/qo/digital-library/internal/storage"
+	"github.com/qo/digital-library/internal/storage/hold"
+	"github.com/qo/digital-library/internal/storage/loan"
+)
+
+var statuses = map[string]bool{
+	loan.StatusActive:   true,
+	loan.StatusOverdue:  true,
+	loan.StatusReturned: true,
+}
+
+type loanStorage interface {
+	GetLoan(ctx context.Context, id int) (*loan.Loan, error)
+	GetLoans(ctx context.Context, userId int, status string, limit, offset int) ([]loan.Loan, error)
+	GetUserHolds(ctx context.Context, userId int) ([]hold.Hold, error)
+	GetAvailability(ctx context.Context, bookId int) (*storage.Availability, error)
+	CheckoutBook(ctx context.Context, userId, bookId int, options config.LoansOptions) (*loan.Loan, error)
+	ReturnLoan(ctx context.Context, id int, options config.LoansOptions) ([]loan.Loan, error)
+	PlaceHold(ctx context.Context, userId, bookId int, options config.LoansOptions) (*hold.Hold, error)
+	CancelHold(ctx context.Context, userId, bookId int) error
+	SetBookCopies(ctx context.Context, bookId, copies int, options config.LoansOptions) ([]loan.Loan, error)
+}
+
+// notifier tells the users the loans were made to from the queues
+type notifier interface {
+	HoldAvailable(ctx context.Context, loans []loan.Loan)
+}
+
+type loanHandler struct {
+	logger.Logger
+	loanStorage
+	notifier
+	options config.LoansOptions
+}
+
+func New(log logger.Logger, ls loanStorage, nt notifier, options config.LoansOptions) *loanHandler {
+	return &loanHandler{
+		log,
+		ls,
+		nt,
+		options,
+	}
+}
+
+// errorResponse is written by the helpers shared by the routes,
+// every response of the routes has the error field.
+type errorResponse struct {
+	Error string `json:"error,omitempty"`
+}
+
+type listResponse struct {
+	Error string      `json:"error,omitempty"`
+	Loans []loan.Loan `json:"loans,omitempty"`
+}
+
+// List returns the loans of the user, the most recently checked out first.
+// With the status query parameter only the loans with it are returned.
+func (lh *loanHandler) List() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't list loans"
+
+		userId, ok := lh.userId(w, r, errMsg)
+		if !ok {
+			return
+		}
+
+		lh.list(w, r, userId, errMsg)
+	}
+}
+
+// ListAll returns the loans of all users, the most recently checked out first.
+// Only admins can list them.
+func (lh *loanHandler) ListAll() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't list all loans"
+
+		if !auth.IsAdmin(r.Context()) {
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(listResponse{
+				Error: "only admins can list all loans",
+			})
+			lh.WarnContext(r.Context(), fmt.Sprintf("%s: only admins can list all loans", errMsg))
+			return
+		}
+
+		lh.list(w, r, 0, errMsg)
+	}
+}
+
+func (lh *loanHandler) list(w http.ResponseWriter, r *http.Request, userId int, errMsg string) {
+	we := json.NewEncoder(w)
+
+	status := r.URL.Query().Get("status")
+	if status != "" && !statuses[status] {
+		w.WriteHeader(http.StatusBadRequest)
+		we.Encode(listResponse{
+			Error: "status should be active, overdue or returned",
+		})
+		lh.WarnContext(r.Context(), fmt.Sprintf("%s: status %s is unknown", errMsg, status))
+		return
+	}
+
+	limit, offset, err := query.Page(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		we.Encode(listResponse{
+			Error: err.Error(),
+		})
+		lh.WarnContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+		return
+	}
+
+	loans, err := lh.GetLoans(r.Context(), userId, status, limit, offset)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		we.Encode(listResponse{
+			Error: "db error",
+		})
+		lh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+		return
+	}
+
+	lh.DebugContext(r.Context(), "list loans success", "user id", userId, "status", status, "loans", len(loans))
+
+	w.WriteHeader(http.StatusOK)
+
+	we.Encode(listResponse{
+		Loans: loans,
+	})
+}
+
+type bookRequest struct {
+	BookId int `json:"book_id"`
+}
+
+type checkoutResponse struct {
+	Error string `json:"error,omitempty"`
+	loan.Loan
+}
+
+// Checkout lends a copy of the book to the user for the loan period.
+func (lh *loanHandler) Checkout() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't checkout book"
+
+		we := json.NewEncoder(w)
+
+		userId, ok := lh.userId(w, r, errMsg)
+		if !ok {
+			return
+		}
+
+		bookId, ok := lh.bookRequest(w, r, errMsg)
+		if !ok {
+			return
+		}
+
+		l, err := lh.CheckoutBook(r.Context(), userId, bookId, lh.options)
+		if lh.lendingError(w, r, err, errMsg) {
+			return
+		}
+
+		lh.DebugContext(r.Context(), "checkout book success", "user id", userId, "book id", bookId, "loan id", l.Id)
+
+		w.WriteHeader(http.StatusCreated)
+
+		we.Encode(checkoutResponse{
+			Loan: *l,
+		})
+	}
+}
+
+type returnResponse struct {
+	Error string `json:"error,omitempty"`
+}
+
+// Return returns the copy lent to the user,
+// it is lent to the first user in the queue for the book.
+func (lh *loanHandler) Return() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't return loan"
+
+		we := json.NewEncoder(w)
+
+		userId, ok := lh.userId(w, r, errMsg)
+		if !ok {
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "loan_id"))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			we.Encode(returnResponse{
+				Error: "loan id is not a number",
+			})
+			lh.ErrorContext(r.Context(), fmt.Sprintf("%s: loan id is not a number: %s", errMsg, err))
+			return
+		}
+
+		l, err := lh.GetLoan(r.Context(), id)
+		if err == nil && l.UserId != userId {
+			err = fmt.Errorf("loan %d is not of user %d: %w", id, userId, sql.ErrNoRows)
+		}
+		var loans []loan.Loan
+		if err == nil {
+			loans, err = lh.ReturnLoan(r.Context(), id, lh.options)
+		}
+		if errors.Is(err, sql.ErrNoRows) {
+			w.WriteHeader(http.StatusNotFound)
+			we.Encode(returnResponse{
+				Error: "loan not found",
+			})
+			lh.WarnContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+		if lh.lendingError(w, r, err, errMsg) {
+			return
+		}
+
+		lh.HoldAvailable(r.Context(), loans)
+
+		lh.DebugContext(r.Context(), "return loan success", "user id", userId, "loan id", id, "lent to holds", len(loans))
+
+		w.WriteHeader(http.StatusOK)
+
+		we.Encode(returnResponse{})
+	}
+}
+
+type listHoldsResponse struct {
+	Error string      `json:"error,omitempty"`
+	Holds []hold.Hold `json:"holds,omitempty"`
+}
+
+// ListHolds returns the holds of the user, the oldest first.
+func (lh *loanHandler) ListHolds() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't list holds"
+
+		we := json.NewEncoder(w)
+
+		userId, ok := lh.userId(w, r, errMsg)
+		if !ok {
+			return
+		}
+
+		holds, err := lh.GetUserHolds(r.Context(), userId)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			we.Encode(listHoldsResponse{
+				Error: "db error",
+			})
+			lh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		lh.DebugContext(r.Context(), "list holds success", "user id", userId, "holds", len(holds))
+
+		w.WriteHeader(http.StatusOK)
+
+		we.Encode(listHoldsResponse{
+			Holds: holds,
+		})
+	}
+}
+
+type placeHoldResponse struct {
+	Error string `json:"error,omitempty"`
+	hold.Hold
+}
+
+// PlaceHold puts the user in the queue for a copy of the book,
+// the copy is lent to the user automatically when it's their turn.
+func (lh *loanHandler) PlaceHold() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't place hold"
+
+		we := json.NewEncoder(w)
+
+		userId, ok := lh.userId(w, r, errMsg)
+		if !ok {
+			return
+		}
+
+		bookId, ok := lh.bookRequest(w, r, errMsg)
+		if !ok {
+			return
+		}
+
+		h, err := lh.loanStorage.PlaceHold(r.Context(), userId, bookId, lh.options)
+		if lh.lendingError(w, r, err, errMsg) {
+			return
+		}
+
+		lh.DebugContext(r.Context(), "place hold success", "user id", userId, "book id", bookId, "position", h.Position)
+
+		w.WriteHeader(http.StatusCreated)
+
+		we.Encode(placeHoldResponse{
+			Hold: *h,
+		})
+	}
+}
+
+type cancelHoldResponse struct {
+	Error string `json:"error,omitempty"`
+}
+
+// CancelHold removes the user from the queue for the book.
+func (lh *loanHandler) CancelHold() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't cancel hold"
+
+		we := json.NewEncoder(w)
+
+		userId, ok := lh.userId(w, r, errMsg)
+		if !ok {
+			return
+		}
+
+		bookId, err := strconv.Atoi(chi.URLParam(r, "book_id"))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			we.Encode(cancelHoldResponse{
+				Error: "book id is not a number",
+			})
+			lh.ErrorContext(r.Context(), fmt.Sprintf("%s: book id is not a number: %s", errMsg, err))
+			return
+		}
+
+		err = lh.loanStorage.CancelHold(r.Context(), userId, bookId)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			we.Encode(cancelHoldResponse{
+				Error: "db error",
+			})
+			lh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		lh.DebugContext(r.Context(), "cancel hold success", "user id", userId, "book id", bookId)
+
+		w.WriteHeader(http.StatusOK)
+
+		we.Encode(cancelHoldResponse{})
+	}
+}
+
+type availabilityResponse struct {
+	Error string `json:"error,omitempty"`
+	storage.Availability
+}
+
+// Availability returns how many copies of the book can be checked out.
+func (lh *loanHandler) Availability() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't get availability"
+
+		we := json.NewEncoder(w)
+
+		bookId, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			we.Encode(availabilityResponse{
+				Error: "book id is not a number",
+			})
+			lh.ErrorContext(r.Context(), fmt.Sprintf("%s: book id is not a number: %s", errMsg, err))
+			return
+		}
+
+		a, err := lh.GetAvailability(r.Context(), bookId)
+		if lh.lendingError(w, r, err, errMsg) {
+			return
+		}
+
+		lh.DebugContext(r.Context(), "get availability success", "book id", bookId, "available", a.Available)
+
+		w.WriteHeader(http.StatusOK)
+
+		we.Encode(availabilityResponse{
+			Availability: *a,
+		})
+	}
+}
+
+type putCopiesRequest struct {
+	Copies int `json:"copies"`
+}
+
+type putCopiesResponse struct {
+	Error string `json:"error,omitempty"`
+}
+
+// PutCopies sets the number of the copies of the book, only admins can set it.
+// The added copies are lent to the queue for the book,
+// the lent copies aren't recalled if the number gets lower.
+func (lh *loanHandler) PutCopies() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't put book copies"
+
+		rd, we := json.NewDecoder(r.Body), json.NewEncoder(w)
+
+		if !auth.IsAdmin(r.Context()) {
+			w.WriteHeader(http.StatusForbidden)
+			we.Encode(putCopiesResponse{
+				Error: "only admins can set the copies",
+			})
+			lh.WarnContext(r.Context(), fmt.Sprintf("%s: only admins can set the copies", errMsg))
+			return
+		}
+
+		bookId, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			we.Encode(putCopiesResponse{
+				Error: "book id is not a number",
+			})
+			lh.ErrorContext(r.Context(), fmt.Sprintf("%s: book id is not a number: %s", errMsg, err))
+			return
+		}
+
+		var req putCopiesRequest
+
+		err = rd.Decode(&req)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			we.Encode(putCopiesResponse{
+				Error: "invalid request",
+			})
+			lh.ErrorContext(r.Context(), fmt.Sprintf("%s: request not parsed: %s", errMsg, err), "req", req)
+			return
+		}
+
+		if req.Copies < 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			we.Encode(putCopiesResponse{
+				Error: "copies should be a non-negative number",
+			})
+			lh.WarnContext(r.Context(), fmt.Sprintf("%s: copies %d is negative", errMsg, req.Copies))
+			return
+		}
+
+		loans, err := lh.SetBookCopies(r.Context(), bookId, req.Copies, lh.options)
+		if lh.lendingError(w, r, err, errMsg) {
+			return
+		}
+
+		lh.HoldAvailable(r.Context(), loans)
+
+		lh.DebugContext(r.Context(), "put book copies success", "book id", bookId, "copies", req.Copies, "lent to holds", len(loans))
+
+		w.WriteHeader(http.StatusOK)
+
+		we.Encode(putCopiesResponse{})
+	}
+}
+
+// userId returns the id of the user, it writes the error if it's invalid
+// or the user can't be acted as: only the user and admins can.
+func (lh *loanHandler) userId(w http.ResponseWriter, r *http.Request, errMsg string) (int, bool) {
+	we := json.NewEncoder(w)
+
+	userId, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		we.Encode(errorResponse{
+			Error: "user id is not a number",
+		})
+		lh.ErrorContext(r.Context(), fmt.Sprintf("%s: user id is not a number: %s", errMsg, err))
+		return 0, false
+	}
+
+	if !auth.CanActAs(r.Context(), userId) {
+		w.WriteHeader(http.StatusForbidden)
+		we.Encode(errorResponse{
+			Error: "only the user and admins can access the loans",
+		})
+		lh.WarnContext(r.Context(), fmt.Sprintf("%s: only the user and admins can access the loans", errMsg))
+		return 0, false
+	}
+
+	return userId, true
+}
+
+// bookRequest parses the id of the book, it writes the error if it's invalid.
+func (lh *loanHandler) bookRequest(w http.ResponseWriter, r *http.Request, errMsg string) (int, bool) {
+	we := json.NewEncoder(w)
+
+	var req bookRequest
+
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		we.Encode(errorResponse{
+			Error: "invalid request",
+		})
+		lh.ErrorContext(r.Context(), fmt.Sprintf("%s: request not parsed: %s", errMsg, err), "req", req)
+		return 0, false
+	}
+
+	if req.BookId < 1 {
+		w.WriteHeader(http.StatusBadRequest)
+		we.Encode(errorResponse{
+			Error: "book id should be a positive number",
+		})
+		lh.WarnContext(r.Context(), fmt.Sprintf("%s: book id %d is not positive", errMsg, req.BookId))
+		return 0, false
+	}
+
+	return req.BookId, true
+}
+
+// conflicts are the lending errors reported as conflicts along with their messages.
+var conflicts = []struct {
+	err error
+	msg string
+}{
+	{loan.ErrNoCopies, "all copies of the book are lent, place a hold"},
+	{loan.ErrBorrowed, "book is already borrowed by the user"},
+	{loan.ErrLimit, "user borrowed as many books as allowed"},
+	{loan.ErrReturned, "loan is already returned"},
+	{hold.ErrHeld, "book is already held by the user"},
+	{hold.ErrAvailable, "copy of the book is available, check it out"},
+}
+
+// lendingError writes the error if there is one and reports whether it was written.
+// The missing books are not found and the lending rules are conflicts.
+func (lh *loanHandler) lendingError(w http.ResponseWriter, r *http.Request, err error, errMsg string) bool {
+	if err == nil {
+		return false
+	}
+
+	we := json.NewEncoder(w)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		w.WriteHeader(http.StatusNotFound)
+		we.Encode(errorResponse{
+			Error: "book not found",
+		})
+		lh.WarnContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+		return true
+	}
+
+	for _, c := range conflicts {
+		if errors.Is(err, c.err) {
+			w.WriteHeader(http.StatusConflict)
+			we.Encode(errorResponse{
+				Error: c.msg,
+			})
+			lh.WarnContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return true
+		}
+	}
+
+	w.WriteHeader(http.StatusInternalServerError)
+	we.Encode(errorResponse{
+		Error: "db error",
+	})
+	lh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+	return true
+}
@@ -0,0 +1,113 @@
+package notification
+
+import (
+	"net/http"
+
+	"github.com/qo/digital-library/internal/handlers/api/query"
+	"github.com/qo/digital-library/internal/openapi"
+)
+
+var unreadParam = openapi.Param{
+	Name:        "unread",
+	Description: "Only the unread notifications",
+	Type:        "boolean",
+}
+
+// Operations documents the routes of the notification api.
+var Operations = []openapi.Operation{
+	{
+		Method:   http.MethodGet,
+		Path:     "/user/{id}/notifications",
+		Id:       "listNotifications",
+		Tag:      "notification",
+		Summary:  "List the notifications of the user, the newest first, along with the number of the unread ones",
+		Auth:     true,
+		Query:    append([]openapi.Param{unreadParam}, query.PageParams...),
+		Response: listResponse{},
+		Statuses: map[int]string{
+			http.StatusOK:                  "Notifications",
+			http.StatusBadRequest:          "Invalid id, unread, limit or offset",
+			http.StatusForbidden:           "Only the user and admins can access the notifications",
+			http.StatusInternalServerError: "DB error",
+		},
+	},
+	{
+		Method:   http.MethodPost,
+		Path:     "/user/{id}/notifications/read",
+		Id:       "readAllNotifications",
+		Tag:      "notification",
+		Summary:  "Mark all notifications of the user as read",
+		Auth:     true,
+		Response: readAllResponse{},
+		Statuses: map[int]string{
+			http.StatusOK:                  "Notifications marked as read",
+			http.StatusBadRequest:          "Invalid id",
+			http.StatusForbidden:           "Only the user and admins can access the notifications",
+			http.StatusInternalServerError: "DB error",
+		},
+	},
+	{
+		Method:   http.MethodGet,
+		Path:     "/user/{id}/notifications/preferences",
+		Id:       "getNotificationPreferences",
+		Tag:      "notification",
+		Summary:  "Get the email address of the user and how the user is notified of each type",
+		Auth:     true,
+		Response: getPreferencesResponse{},
+		Statuses: map[int]string{
+			http.StatusOK:                  "Preferences",
+			http.StatusBadRequest:          "Invalid id",
+			http.StatusForbidden:           "Only the user and admins can access the notifications",
+			http.StatusNotFound:            "User not found",
+			http.StatusInternalServerError: "DB error",
+		},
+	},
+	{
+		Method:   http.MethodPut,
+		Path:     "/user/{id}/notifications/preferences",
+		Id:       "putNotificationPreferences",
+		Tag:      "notification",
+		Summary:  "Set the email address of the user and how the user is notified of the listed types",
+		Auth:     true,
+		Request:  putPreferencesRequest{},
+		Response: putPreferencesResponse{},
+		Statuses: map[int]string{
+			http.StatusOK:                  "Preferences set",
+			http.StatusBadRequest:          "Invalid id, email or type",
+			http.StatusForbidden:           "Only the user and admins can access the notifications",
+			http.StatusNotFound:            "User not found",
+			http.StatusInternalServerError: "DB error",
+		},
+	},
+	{
+		Method:   http.MethodPost,
+		Path:     "/user/{id}/notifications/{notification_id}/read",
+		Id:       "readNotification",
+		Tag:      "notification",
+		Summary:  "Mark the notification as read",
+		Auth:     true,
+		Response: readResponse{},
+		Statuses: map[int]string{
+			http.StatusOK:                  "Notification marked as read",
+			http.StatusBadRequest:          "Invalid id",
+			http.StatusForbidden:           "Only the user and admins can access the notifications",
+			http.StatusNotFound:            "Notification not found",
+			http.StatusInternalServerError: "DB error",
+		},
+	},
+	{
+		Method:   http.MethodDelete,
+		Path:     "/user/{id}/notifications/{notification_id}",
+		Id:       "deleteNotification",
+		Tag:      "notification",
+		Summary:  "Delete the notification",
+		Auth:     true,
+		Response: deleteResponse{},
+		Statuses: map[int]string{
+			http.StatusOK:                  "Notification deleted",
+			http.StatusBadRequest:          "Invalid id",
+			http.StatusForbidden:           "Only the user and admins can access the notifications",
+			http.StatusInternalServerError: "DB error",
+		},
+	},
+}
@@ -0,0 +1,440 @@
+package notification
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/qo/digital-library/internal/auth"
+	"github.com/qo/digital-library/internal/handlers/api/query"
+	"github.com/qo/digital-library/internal/logger"
+	"github.com/qo/digital-library/internal/notify"
+	"github.com/qo/digital-library/internal/storage/notification"
+	"github.com/qo/digital-library/internal/storage/notification_preference"
+)
+
+// maxEmailLength is the longest address an email can be sent to
+const maxEmailLength = 254
+
+type notificationStorage interface {
+	GetNotifications(ctx context.Context, userId int, unreadOnly bool, limit, offset int) ([]notification.Notification, error)
+	CountUnreadNotifications(ctx context.Context, userId int) (int, error)
+	MarkNotificationRead(ctx context.Context, userId, id int) error
+	MarkAllNotificationsRead(ctx context.Context, userId int) error
+	DeleteNotification(ctx context.Context, userId, id int) error
+	GetEmail(ctx context.Context, userId int) (string, error)
+	GetNotificationPreferences(ctx context.Context, userId int, types []string) ([]notification_preference.NotificationPreference, error)
+	PutNotificationPreferences(ctx context.Context, userId int, address *string, preferences []notification_preference.NotificationPreference) error
+}
+
+type notificationHandler struct {
+	logger.Logger
+	notificationStorage
+}
+
+func New(log logger.Logger, ns notificationStorage) *notificationHandler {
+	return &notificationHandler{
+		log,
+		ns,
+	}
+}
+
+// errorResponse is written by the helpers shared by the routes,
+// every response of the routes has the error field.
+type errorResponse struct {
+	Error string `json:"error,omitempty"`
+}
+
+type listResponse struct {
+	Error         string                      `json:"error,omitempty"`
+	Notifications []notification.Notification `json:"notifications,omitempty"`
+	// Unread is the number of all unread notifications of the user
+	Unread int `json:"unread"`
+}
+
+// List returns the notifications of the user, the newest first,
+// along with the number of the unread ones.
+// With the unread query parameter only the unread ones are returned.
+func (nh *notificationHandler) List() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't list notifications"
+
+		we := json.NewEncoder(w)
+
+		userId, ok := nh.userId(w, r, errMsg)
+		if !ok {
+			return
+		}
+
+		var unreadOnly bool
+		if param := r.URL.Query().Get("unread"); param != "" {
+			var err error
+			unreadOnly, err = strconv.ParseBool(param)
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				we.Encode(listResponse{
+					Error: "unread should be true or false",
+				})
+				nh.WarnContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+				return
+			}
+		}
+
+		limit, offset, err := query.Page(r)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			we.Encode(listResponse{
+				Error: err.Error(),
+			})
+			nh.WarnContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		notifications, err := nh.GetNotifications(r.Context(), userId, unreadOnly, limit, offset)
+		var unread int
+		if err == nil {
+			unread, err = nh.CountUnreadNotifications(r.Context(), userId)
+		}
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			we.Encode(listResponse{
+				Error: "db error",
+			})
+			nh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		nh.DebugContext(r.Context(), "list notifications success", "user id", userId, "notifications", len(notifications), "unread", unread)
+
+		w.WriteHeader(http.StatusOK)
+
+		we.Encode(listResponse{
+			Notifications: notifications,
+			Unread:        unread,
+		})
+	}
+}
+
+type readResponse struct {
+	Error string `json:"error,omitempty"`
+}
+
+// Read marks the notification as read.
+func (nh *notificationHandler) Read() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't mark notification read"
+
+		we := json.NewEncoder(w)
+
+		userId, ok := nh.userId(w, r, errMsg)
+		if !ok {
+			return
+		}
+
+		id, ok := nh.notificationId(w, r, errMsg)
+		if !ok {
+			return
+		}
+
+		err := nh.MarkNotificationRead(r.Context(), userId, id)
+		if errors.Is(err, sql.ErrNoRows) {
+			w.WriteHeader(http.StatusNotFound)
+			we.Encode(readResponse{
+				Error: "notification not found",
+			})
+			nh.WarnContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			we.Encode(readResponse{
+				Error: "db error",
+			})
+			nh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		nh.DebugContext(r.Context(), "mark notification read success", "user id", userId, "notification id", id)
+
+		w.WriteHeader(http.StatusOK)
+
+		we.Encode(readResponse{})
+	}
+}
+
+type readAllResponse struct {
+	Error string `json:"error,omitempty"`
+}
+
+// ReadAll marks all notifications of the user as read.
+func (nh *notificationHandler) ReadAll() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't mark all notifications read"
+
+		we := json.NewEncoder(w)
+
+		userId, ok := nh.userId(w, r, errMsg)
+		if !ok {
+			return
+		}
+
+		err := nh.MarkAllNotificationsRead(r.Context(), userId)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			we.Encode(readAllResponse{
+				Error: "db error",
+			})
+			nh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		nh.DebugContext(r.Context(), "mark all notifications read success", "user id", userId)
+
+		w.WriteHeader(http.StatusOK)
+
+		we.Encode(readAllResponse{})
+	}
+}
+
+type deleteResponse struct {
+	Error string `json:"error,omitempty"`
+}
+
+func (nh *notificationHandler) Delete() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't delete notification"
+
+		we := json.NewEncoder(w)
+
+		userId, ok := nh.userId(w, r, errMsg)
+		if !ok {
+			return
+		}
+
+		id, ok := nh.notificationId(w, r, errMsg)
+		if !ok {
+			return
+		}
+
+		err := nh.DeleteNotification(r.Context(), userId, id)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			we.Encode(deleteResponse{
+				Error: "db error",
+			})
+			nh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		nh.DebugContext(r.Context(), "delete notification success", "user id", userId, "notification id", id)
+
+		w.WriteHeader(http.StatusOK)
+
+		we.Encode(deleteResponse{})
+	}
+}
+
+type preference struct {
+	Type  string `json:"type"`
+	InApp bool   `json:"in_app"`
+	Email bool   `json:"email"`
+}
+
+type getPreferencesResponse struct {
+	Error string `json:"error,omitempty"`
+	// Email is the address the email notifications are sent to, empty if there is none
+	Email       string       `json:"email"`
+	Preferences []preference `json:"preferences,omitempty"`
+}
+
+// GetPreferences returns the email address of the user
+// and the preferences of all notification types.
+func (nh *notificationHandler) GetPreferences() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't get notification preferences"
+
+		we := json.NewEncoder(w)
+
+		userId, ok := nh.userId(w, r, errMsg)
+		if !ok {
+			return
+		}
+
+		address, err := nh.GetEmail(r.Context(), userId)
+		if errors.Is(err, sql.ErrNoRows) {
+			w.WriteHeader(http.StatusNotFound)
+			we.Encode(getPreferencesResponse{
+				Error: "user not found",
+			})
+			nh.WarnContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		var preferences []notification_preference.NotificationPreference
+		if err == nil {
+			preferences, err = nh.GetNotificationPreferences(r.Context(), userId, notify.Types)
+		}
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			we.Encode(getPreferencesResponse{
+				Error: "db error",
+			})
+			nh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		resp := getPreferencesResponse{
+			Email:       address,
+			Preferences: make([]preference, 0, len(preferences)),
+		}
+		for _, p := range preferences {
+			resp.Preferences = append(resp.Preferences, preference{p.Type, p.InApp, p.Email})
+		}
+
+		nh.DebugContext(r.Context(), "get notification preferences success", "user id", userId)
+
+		w.WriteHeader(http.StatusOK)
+
+		we.Encode(resp)
+	}
+}
+
+type putPreferencesRequest struct {
+	// Email replaces the address of the user if it's present, empty removes it
+	Email *string `json:"email,omitempty"`
+	// Preferences replace the preferences of their types, the other types are kept
+	Preferences []preference `json:"preferences,omitempty"`
+}
+
+type putPreferencesResponse struct {
+	Error string `json:"error,omitempty"`
+}
+
+// PutPreferences sets the email address of the user if it's in the request
+// and the preferences of the listed notification types.
+func (nh *notificationHandler) PutPreferences() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't put notification preferences"
+
+		we := json.NewEncoder(w)
+
+		userId, ok := nh.userId(w, r, errMsg)
+		if !ok {
+			return
+		}
+
+		var req putPreferencesRequest
+
+		err := json.NewDecoder(r.Body).Decode(&req)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			we.Encode(putPreferencesResponse{
+				Error: "invalid request",
+			})
+			nh.ErrorContext(r.Context(), fmt.Sprintf("%s: request not parsed: %s", errMsg, err), "req", req)
+			return
+		}
+
+		if req.Email != nil && *req.Email != "" {
+			a, err := mail.ParseAddress(*req.Email)
+			if err != nil || a.Address != *req.Email || len(*req.Email) > maxEmailLength {
+				w.WriteHeader(http.StatusBadRequest)
+				we.Encode(putPreferencesResponse{
+					Error: "email should be a valid address",
+				})
+				nh.WarnContext(r.Context(), fmt.Sprintf("%s: email %q is invalid", errMsg, *req.Email))
+				return
+			}
+		}
+
+		preferences := make([]notification_preference.NotificationPreference, 0, len(req.Preferences))
+		for _, p := range req.Preferences {
+			if !notify.IsType(p.Type) {
+				w.WriteHeader(http.StatusBadRequest)
+				we.Encode(putPreferencesResponse{
+					Error: fmt.Sprintf("notification type %s is unknown", p.Type),
+				})
+				nh.WarnContext(r.Context(), fmt.Sprintf("%s: notification type %s is unknown", errMsg, p.Type))
+				return
+			}
+			preferences = append(preferences, notification_preference.NotificationPreference{
+				UserId: userId,
+				Type:   p.Type,
+				InApp:  p.InApp,
+				Email:  p.Email,
+			})
+		}
+
+		err = nh.PutNotificationPreferences(r.Context(), userId, req.Email, preferences)
+		if errors.Is(err, sql.ErrNoRows) {
+			w.WriteHeader(http.StatusNotFound)
+			we.Encode(putPreferencesResponse{
+				Error: "user not found",
+			})
+			nh.WarnContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			we.Encode(putPreferencesResponse{
+				Error: "db error",
+			})
+			nh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		nh.DebugContext(r.Context(), "put notification preferences success", "user id", userId, "preferences", len(preferences))
+
+		w.WriteHeader(http.StatusOK)
+
+		we.Encode(putPreferencesResponse{})
+	}
+}
+
+// userId returns the id of the user, it writes the error if it's invalid
+// or the notifications of the user can't be accessed: only the user and admins can.
+func (nh *notificationHandler) userId(w http.ResponseWriter, r *http.Request, errMsg string) (int, bool) {
+	we := json.NewEncoder(w)
+
+	userId, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		we.Encode(errorResponse{
+			Error: "user id is not a number",
+		})
+		nh.ErrorContext(r.Context(), fmt.Sprintf("%s: user id is not a number: %s", errMsg, err))
+		return 0, false
+	}
+
+	if !auth.CanActAs(r.Context(), userId) {
+		w.WriteHeader(http.StatusForbidden)
+		we.Encode(errorResponse{
+			Error: "only the user and admins can access the notifications",
+		})
+		nh.WarnContext(r.Context(), fmt.Sprintf("%s: only the user and admins can access the notifications", errMsg))
+		return 0, false
+	}
+
+	return userId, true
+}
+
+// notificationId parses the id of the notification, it writes the error if it's invalid.
+func (nh *notificationHandler) notificationId(w http.ResponseWriter, r *http.Request, errMsg string) (int, bool) {
+	id, err := strconv.Atoi(chi.URLParam(r, "notification_id"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(errorResponse{
+			Error: "notification id is not a number",
+		})
+		nh.ErrorContext(r.Context(), fmt.Sprintf("%s: notification id is not a number: %s", errMsg, err))
+		return 0, false
+	}
+
+	return id, true
+}
@@ -0,0 +1,78 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/qo/digital-library/internal/citation"
+	"github.com/qo/digital-library/internal/openapi"
+)
+
+// IncludeDeletedParam documents the include_deleted query parameter.
+var IncludeDeletedParam = openapi.Param{
+	Name:        "include_deleted",
+	Description: "Include the deleted rows, admins only",
+	Type:        "boolean",
+}
+
+// PageParams document the limit and offset query parameters.
+var PageParams = []openapi.Param{
+	{
+		Name:        "limit",
+		Description: fmt.Sprintf("Page size from 1 to %d, %d by default", MaxLimit, DefaultLimit),
+		Type:        "integer",
+	},
+	{
+		Name:        "offset",
+		Description: "Number of the rows skipped",
+		Type:        "integer",
+	},
+}
+
+// PeriodParam documents the period query parameter.
+var PeriodParam = openapi.Param{
+	Name:        "period",
+	Description: "Only the reviews updated during the last week, month or year, all by default",
+	Type:        "string",
+	Enum:        []string{"week", "month", "year", "all"},
+}
+
+var citationFormats = []citation.Format{
+	citation.FormatBibTeX,
+	citation.FormatRIS,
+	citation.FormatCSLJSON,
+	citation.FormatAPA,
+	citation.FormatMLA,
+}
+
+// CitationFormatParam documents the format query parameter of the citations.
+var CitationFormatParam = openapi.Param{
+	Name:        "format",
+	Description: "Citation format, bibtex by default",
+	Type:        "string",
+	Enum:        citationEnum(),
+}
+
+// CitationMedia lists the media types of the citations.
+var CitationMedia = citationMedia()
+
+func citationEnum() []string {
+	enum := make([]string, 0, len(citationFormats))
+	for _, f := range citationFormats {
+		enum = append(enum, string(f))
+	}
+	return enum
+}
+
+func citationMedia() []string {
+	var media []string
+	seen := make(map[string]bool)
+	for _, f := range citationFormats {
+		m, _, _ := strings.Cut(f.ContentType(), ";")
+		if !seen[m] {
+			seen[m] = true
+			media = append(media, m)
+		}
+	}
+	return media
+}
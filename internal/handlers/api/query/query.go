@@ -0,0 +1,103 @@
+package query
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	// DefaultLimit is the page size if the limit query parameter is not specified
+	DefaultLimit = 50
+	// MaxLimit is the largest page size
+	MaxLimit = 100
+)
+
+// IncludeDeleted reports whether the request asks to include deleted rows
+// with the include_deleted query parameter.
+func IncludeDeleted(r *http.Request) (bool, error) {
+	param := r.URL.Query().Get("include_deleted")
+	if param == "" {
+		return false, nil
+	}
+	return strconv.ParseBool(param)
+}
+
+// Page returns the limit and the offset of the page
+// from the limit and offset query parameters.
+func Page(r *http.Request) (int, int, error) {
+	limit, offset := DefaultLimit, 0
+
+	if param := r.URL.Query().Get("limit"); param != "" {
+		var err error
+		limit, err = strconv.Atoi(param)
+		if err != nil || limit < 1 || limit > MaxLimit {
+			return 0, 0, fmt.Errorf("limit should be from 1 to %d", MaxLimit)
+		}
+	}
+
+	if param := r.URL.Query().Get("offset"); param != "" {
+		var err error
+		offset, err = strconv.Atoi(param)
+		if err != nil || offset < 0 {
+			return 0, 0, errors.New("offset should be a non-negative number")
+		}
+	}
+
+	return limit, offset, nil
+}
+
+// PageLinks returns the links to the previous and the next pages of limit rows,
+// the page at u skips offset rows and has count rows.
+// The links are empty if there are no such pages,
+// the next page is linked only if the current one is full.
+func PageLinks(u *url.URL, limit, offset, count int) (string, string) {
+	link := func(offset int) string {
+		v := *u
+		q := v.Query()
+		q.Set("offset", strconv.Itoa(offset))
+		v.RawQuery = q.Encode()
+		return v.RequestURI()
+	}
+
+	var prev, next string
+	if offset > 0 {
+		prev = link(max(offset-limit, 0))
+	}
+	if count == limit {
+		next = link(offset + limit)
+	}
+	return prev, next
+}
+
+// periods are the values of the period query parameter
+// along with their durations, all is the whole history.
+var periods = map[string]time.Duration{
+	"week":  7 * 24 * time.Hour,
+	"month": 30 * 24 * time.Hour,
+	"year":  365 * 24 * time.Hour,
+	"all":   0,
+}
+
+// Since returns the start of the period specified by the period query parameter,
+// it is nil if the period is all or not specified.
+func Since(r *http.Request, now time.Time) (*time.Time, error) {
+	param := r.URL.Query().Get("period")
+	if param == "" {
+		return nil, nil
+	}
+
+	d, ok := periods[param]
+	if !ok {
+		return nil, errors.New("period should be week, month, year or all")
+	}
+	if d == 0 {
+		return nil, nil
+	}
+
+	since := now.Add(-d)
+	return &since, nil
+}
@@ -0,0 +1,151 @@
+package reading
+
+import (
+	"net/http"
+
+	"github.com/qo/digital-library/internal/openapi"
+	"github.com/qo/digital-library/internal/storage/reading_state"
+)
+
+var statusEnum = []string{
+	reading_state.StatusWantToRead,
+	reading_state.StatusReading,
+	reading_state.StatusFinished,
+}
+
+// Operations documents the routes of the reading api.
+var Operations = []openapi.Operation{
+	{
+		Method:  http.MethodGet,
+		Path:    "/user/{id}/reading",
+		Id:      "listReadingStates",
+		Tag:     "reading",
+		Summary: "List the reading states of the user, the most recently read first",
+		Auth:    true,
+		Query: []openapi.Param{{
+			Name:        "status",
+			Description: "Only the books with the status",
+			Type:        "string",
+			Enum:        statusEnum,
+		}},
+		Response: listResponse{},
+		Statuses: map[int]string{
+			http.StatusOK:                  "Reading states",
+			http.StatusBadRequest:          "Invalid id or status",
+			http.StatusForbidden:           "Only the user and admins can access the reading states",
+			http.StatusInternalServerError: "DB error",
+		},
+	},
+	{
+		Method:   http.MethodGet,
+		Path:     "/user/{id}/reading/{book_id}",
+		Id:       "getReadingState",
+		Tag:      "reading",
+		Summary:  "Get the reading state of the book",
+		Auth:     true,
+		Response: getResponse{},
+		Statuses: map[int]string{
+			http.StatusOK:                  "Reading state",
+			http.StatusBadRequest:          "Invalid id",
+			http.StatusForbidden:           "Only the user and admins can access the reading states",
+			http.StatusNotFound:            "Reading state not found",
+			http.StatusInternalServerError: "DB error",
+		},
+	},
+	{
+		Method:   http.MethodPut,
+		Path:     "/user/{id}/reading/{book_id}",
+		Id:       "putReadingState",
+		Tag:      "reading",
+		Summary:  "Create or update the reading state of the book, the last read time is set to now",
+		Auth:     true,
+		Request:  putRequest{},
+		Response: putResponse{},
+		Statuses: map[int]string{
+			http.StatusOK:                  "Reading state updated",
+			http.StatusBadRequest:          "Invalid id, status, page or percent",
+			http.StatusForbidden:           "Only the user and admins can access the reading states",
+			http.StatusNotFound:            "Book not found",
+			http.StatusInternalServerError: "DB error",
+		},
+	},
+	{
+		Method:   http.MethodDelete,
+		Path:     "/user/{id}/reading/{book_id}",
+		Id:       "deleteReadingState",
+		Tag:      "reading",
+		Summary:  "Delete the reading state of the book, the bookmarks are kept",
+		Auth:     true,
+		Response: deleteResponse{},
+		Statuses: map[int]string{
+			http.StatusOK:                  "Reading state deleted",
+			http.StatusBadRequest:          "Invalid id",
+			http.StatusForbidden:           "Only the user and admins can access the reading states",
+			http.StatusInternalServerError: "DB error",
+		},
+	},
+	{
+		Method:   http.MethodGet,
+		Path:     "/user/{id}/reading/{book_id}/bookmarks",
+		Id:       "getBookmarks",
+		Tag:      "reading",
+		Summary:  "Get the bookmarks of the book ordered by page",
+		Auth:     true,
+		Response: getBookmarksResponse{},
+		Statuses: map[int]string{
+			http.StatusOK:                  "Bookmarks",
+			http.StatusBadRequest:          "Invalid id",
+			http.StatusForbidden:           "Only the user and admins can access the reading states",
+			http.StatusInternalServerError: "DB error",
+		},
+	},
+	{
+		Method:   http.MethodPost,
+		Path:     "/user/{id}/reading/{book_id}/bookmarks",
+		Id:       "postBookmark",
+		Tag:      "reading",
+		Summary:  "Create a bookmark of the book",
+		Auth:     true,
+		Request:  bookmarkRequest{},
+		Response: postBookmarkResponse{},
+		Statuses: map[int]string{
+			http.StatusCreated:             "Bookmark created",
+			http.StatusBadRequest:          "Invalid id, page or note",
+			http.StatusForbidden:           "Only the user and admins can access the reading states",
+			http.StatusNotFound:            "Book not found",
+			http.StatusInternalServerError: "DB error",
+		},
+	},
+	{
+		Method:   http.MethodPut,
+		Path:     "/user/{id}/reading/{book_id}/bookmarks/{bookmark_id}",
+		Id:       "putBookmark",
+		Tag:      "reading",
+		Summary:  "Update the page and the note of the bookmark",
+		Auth:     true,
+		Request:  bookmarkRequest{},
+		Response: putBookmarkResponse{},
+		Statuses: map[int]string{
+			http.StatusOK:                  "Bookmark updated",
+			http.StatusBadRequest:          "Invalid id, page or note",
+			http.StatusForbidden:           "Only the user and admins can access the reading states",
+			http.StatusNotFound:            "Bookmark not found",
+			http.StatusInternalServerError: "DB error",
+		},
+	},
+	{
+		Method:   http.MethodDelete,
+		Path:     "/user/{id}/reading/{book_id}/bookmarks/{bookmark_id}",
+		Id:       "deleteBookmark",
+		Tag:      "reading",
+		Summary:  "Delete the bookmark",
+		Auth:     true,
+		Response: deleteBookmarkResponse{},
+		Statuses: map[int]string{
+			http.StatusOK:                  "Bookmark deleted",
+			http.StatusBadRequest:          "Invalid id",
+			http.StatusForbidden:           "Only the user and admins can access the reading states",
+			http.StatusInternalServerError: "DB error",
+		},
+	},
+}
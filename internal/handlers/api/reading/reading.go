@@ -0,0 +1,585 @@
+package reading
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/qo/digital-library/internal/auth"
+	"github.com/qo/digital-library/internal/logger"
+	"github.com/qo/digital-library/internal/storage/book"
+	"github.com/qo/digital-library/internal/storage/bookmark"
+	"github.com/qo/digital-library/internal/storage/reading_state"
+)
+
+const maxNoteLength = 10000
+
+var statuses = map[string]bool{
+	reading_state.StatusWantToRead: true,
+	reading_state.StatusReading:    true,
+	reading_state.StatusFinished:   true,
+}
+
+type readingStorage interface {
+	GetBook(ctx context.Context, id int, includeDeleted bool) (*book.Book, error)
+	GetReadingState(ctx context.Context, userId, bookId int) (*reading_state.ReadingState, error)
+	GetReadingStates(ctx context.Context, userId int, status string) ([]reading_state.ReadingState, error)
+	PutReadingState(context.Context, *reading_state.ReadingState) error
+	DeleteReadingState(ctx context.Context, userId, bookId int) error
+	GetBookmarks(ctx context.Context, userId, bookId int) ([]bookmark.Bookmark, error)
+	PostBookmark(context.Context, *bookmark.Bookmark) error
+	PutBookmark(context.Context, *bookmark.Bookmark) error
+	DeleteBookmark(ctx context.Context, userId, bookId, id int) error
+}
+
+type readingHandler struct {
+	logger.Logger
+	readingStorage
+}
+
+func New(log logger.Logger, rs readingStorage) *readingHandler {
+	return &readingHandler{
+		log,
+		rs,
+	}
+}
+
+// errorResponse is written by the helpers shared by the routes,
+// every response of the routes has the error field.
+type errorResponse struct {
+	Error string `json:"error,omitempty"`
+}
+
+type listResponse struct {
+	Error  string                       `json:"error,omitempty"`
+	States []reading_state.ReadingState `json:"states,omitempty"`
+}
+
+// List returns the reading states of the user, the most recently read first.
+// With the status query parameter only the states with it are returned.
+func (rh *readingHandler) List() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't list reading states"
+
+		we := json.NewEncoder(w)
+
+		userId, ok := rh.userId(w, r, errMsg)
+		if !ok {
+			return
+		}
+
+		status := r.URL.Query().Get("status")
+		if status != "" && !statuses[status] {
+			w.WriteHeader(http.StatusBadRequest)
+			we.Encode(listResponse{
+				Error: "status should be want-to-read, reading or finished",
+			})
+			rh.WarnContext(r.Context(), fmt.Sprintf("%s: status %s is unknown", errMsg, status))
+			return
+		}
+
+		states, err := rh.GetReadingStates(r.Context(), userId, status)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			we.Encode(listResponse{
+				Error: "db error",
+			})
+			rh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		rh.DebugContext(r.Context(), "list reading states success", "user id", userId, "states", len(states))
+
+		w.WriteHeader(http.StatusOK)
+
+		we.Encode(listResponse{
+			States: states,
+		})
+	}
+}
+
+type getResponse struct {
+	Error string `json:"error,omitempty"`
+	reading_state.ReadingState
+}
+
+// Get returns the reading state of the book.
+func (rh *readingHandler) Get() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't get reading state"
+
+		we := json.NewEncoder(w)
+
+		userId, bookId, ok := rh.ids(w, r, errMsg)
+		if !ok {
+			return
+		}
+
+		state, err := rh.GetReadingState(r.Context(), userId, bookId)
+		if errors.Is(err, sql.ErrNoRows) {
+			w.WriteHeader(http.StatusNotFound)
+			we.Encode(getResponse{
+				Error: "reading state not found",
+			})
+			rh.WarnContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			we.Encode(getResponse{
+				Error: "db error",
+			})
+			rh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		rh.DebugContext(r.Context(), "get reading state success", "user id", userId, "book id", bookId)
+
+		w.WriteHeader(http.StatusOK)
+
+		we.Encode(getResponse{
+			ReadingState: *state,
+		})
+	}
+}
+
+type putRequest struct {
+	// Status is reading if it's not specified
+	Status  string  `json:"status,omitempty"`
+	Page    int     `json:"page,omitempty"`
+	Percent float64 `json:"percent,omitempty"`
+}
+
+type putResponse struct {
+	Error string `json:"error,omitempty"`
+	reading_state.ReadingState
+}
+
+// Put creates or updates the reading state of the book, the last read time is set to now.
+func (rh *readingHandler) Put() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't put reading state"
+
+		rd, we := json.NewDecoder(r.Body), json.NewEncoder(w)
+
+		userId, bookId, ok := rh.ids(w, r, errMsg)
+		if !ok {
+			return
+		}
+
+		var req putRequest
+
+		err := rd.Decode(&req)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			we.Encode(putResponse{
+				Error: "invalid request",
+			})
+			rh.ErrorContext(r.Context(), fmt.Sprintf("%s: request not parsed: %s", errMsg, err), "req", req)
+			return
+		}
+
+		if req.Status == "" {
+			req.Status = reading_state.StatusReading
+		}
+
+		var msg string
+		switch {
+		case !statuses[req.Status]:
+			msg = "status should be want-to-read, reading or finished"
+		case req.Page < 0:
+			msg = "page should be a non-negative number"
+		case req.Percent < 0 || req.Percent > 100:
+			msg = "percent should be from 0 to 100"
+		}
+		if msg != "" {
+			w.WriteHeader(http.StatusBadRequest)
+			we.Encode(putResponse{
+				Error: msg,
+			})
+			rh.WarnContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, msg))
+			return
+		}
+
+		if !rh.bookExists(w, r, bookId, errMsg) {
+			return
+		}
+
+		state := reading_state.ReadingState{
+			UserId:  userId,
+			BookId:  bookId,
+			Status:  req.Status,
+			Page:    req.Page,
+			Percent: req.Percent,
+		}
+
+		err = rh.PutReadingState(r.Context(), &state)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			we.Encode(putResponse{
+				Error: "db error",
+			})
+			rh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		rh.DebugContext(r.Context(), "put reading state success", "user id", userId, "book id", bookId, "status", state.Status)
+
+		w.WriteHeader(http.StatusOK)
+
+		we.Encode(putResponse{
+			ReadingState: state,
+		})
+	}
+}
+
+type deleteResponse struct {
+	Error string `json:"error,omitempty"`
+}
+
+// Delete removes the reading state of the book, the bookmarks are kept.
+func (rh *readingHandler) Delete() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't delete reading state"
+
+		we := json.NewEncoder(w)
+
+		userId, bookId, ok := rh.ids(w, r, errMsg)
+		if !ok {
+			return
+		}
+
+		err := rh.DeleteReadingState(r.Context(), userId, bookId)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			we.Encode(deleteResponse{
+				Error: "db error",
+			})
+			rh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		rh.DebugContext(r.Context(), "delete reading state success", "user id", userId, "book id", bookId)
+
+		w.WriteHeader(http.StatusOK)
+
+		we.Encode(deleteResponse{})
+	}
+}
+
+type getBookmarksResponse struct {
+	Error     string              `json:"error,omitempty"`
+	Bookmarks []bookmark.Bookmark `json:"bookmarks,omitempty"`
+}
+
+// GetBookmarks returns the bookmarks of the book ordered by page.
+func (rh *readingHandler) GetBookmarks() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't get bookmarks"
+
+		we := json.NewEncoder(w)
+
+		userId, bookId, ok := rh.ids(w, r, errMsg)
+		if !ok {
+			return
+		}
+
+		bookmarks, err := rh.readingStorage.GetBookmarks(r.Context(), userId, bookId)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			we.Encode(getBookmarksResponse{
+				Error: "db error",
+			})
+			rh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		rh.DebugContext(r.Context(), "get bookmarks success", "user id", userId, "book id", bookId, "bookmarks", len(bookmarks))
+
+		w.WriteHeader(http.StatusOK)
+
+		we.Encode(getBookmarksResponse{
+			Bookmarks: bookmarks,
+		})
+	}
+}
+
+type bookmarkRequest struct {
+	// Page is numbered from 1
+	Page int    `json:"page"`
+	Note string `json:"note,omitempty"`
+}
+
+type postBookmarkResponse struct {
+	Error string `json:"error,omitempty"`
+	// Id is the id of the created bookmark
+	Id int `json:"id,omitempty"`
+}
+
+// PostBookmark creates a bookmark of the book.
+func (rh *readingHandler) PostBookmark() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't post bookmark"
+
+		we := json.NewEncoder(w)
+
+		userId, bookId, ok := rh.ids(w, r, errMsg)
+		if !ok {
+			return
+		}
+
+		req, ok := rh.bookmarkRequest(w, r, errMsg)
+		if !ok {
+			return
+		}
+
+		if !rh.bookExists(w, r, bookId, errMsg) {
+			return
+		}
+
+		b := bookmark.Bookmark{
+			UserId: userId,
+			BookId: bookId,
+			Page:   req.Page,
+			Note:   req.Note,
+		}
+
+		err := rh.readingStorage.PostBookmark(r.Context(), &b)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			we.Encode(postBookmarkResponse{
+				Error: "db error",
+			})
+			rh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		rh.DebugContext(r.Context(), "post bookmark success", "user id", userId, "book id", bookId, "id", b.Id)
+
+		w.WriteHeader(http.StatusCreated)
+
+		we.Encode(postBookmarkResponse{Id: b.Id})
+	}
+}
+
+type putBookmarkResponse struct {
+	Error string `json:"error,omitempty"`
+}
+
+// PutBookmark updates the page and the note of the bookmark.
+func (rh *readingHandler) PutBookmark() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't put bookmark"
+
+		we := json.NewEncoder(w)
+
+		userId, bookId, ok := rh.ids(w, r, errMsg)
+		if !ok {
+			return
+		}
+
+		id, ok := rh.bookmarkId(w, r, errMsg)
+		if !ok {
+			return
+		}
+
+		req, ok := rh.bookmarkRequest(w, r, errMsg)
+		if !ok {
+			return
+		}
+
+		err := rh.readingStorage.PutBookmark(r.Context(), &bookmark.Bookmark{
+			Id:     id,
+			UserId: userId,
+			BookId: bookId,
+			Page:   req.Page,
+			Note:   req.Note,
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			w.WriteHeader(http.StatusNotFound)
+			we.Encode(putBookmarkResponse{
+				Error: "bookmark not found",
+			})
+			rh.WarnContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			we.Encode(putBookmarkResponse{
+				Error: "db error",
+			})
+			rh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		rh.DebugContext(r.Context(), "put bookmark success", "user id", userId, "book id", bookId, "id", id)
+
+		w.WriteHeader(http.StatusOK)
+
+		we.Encode(putBookmarkResponse{})
+	}
+}
+
+type deleteBookmarkResponse struct {
+	Error string `json:"error,omitempty"`
+}
+
+// DeleteBookmark removes the bookmark.
+func (rh *readingHandler) DeleteBookmark() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't delete bookmark"
+
+		we := json.NewEncoder(w)
+
+		userId, bookId, ok := rh.ids(w, r, errMsg)
+		if !ok {
+			return
+		}
+
+		id, ok := rh.bookmarkId(w, r, errMsg)
+		if !ok {
+			return
+		}
+
+		err := rh.readingStorage.DeleteBookmark(r.Context(), userId, bookId, id)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			we.Encode(deleteBookmarkResponse{
+				Error: "db error",
+			})
+			rh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		rh.DebugContext(r.Context(), "delete bookmark success", "user id", userId, "book id", bookId, "id", id)
+
+		w.WriteHeader(http.StatusOK)
+
+		we.Encode(deleteBookmarkResponse{})
+	}
+}
+
+// userId returns the id of the user.
+// Only the user and admins can see and change the reading states and the bookmarks.
+// It writes the error if the id is invalid or the user can't be acted as.
+func (rh *readingHandler) userId(w http.ResponseWriter, r *http.Request, errMsg string) (int, bool) {
+	we := json.NewEncoder(w)
+
+	userId, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		we.Encode(errorResponse{
+			Error: "user id is not a number",
+		})
+		rh.ErrorContext(r.Context(), fmt.Sprintf("%s: user id is not a number: %s", errMsg, err))
+		return 0, false
+	}
+
+	if !auth.CanActAs(r.Context(), userId) {
+		w.WriteHeader(http.StatusForbidden)
+		we.Encode(errorResponse{
+			Error: "only the user and admins can access the reading states",
+		})
+		rh.WarnContext(r.Context(), fmt.Sprintf("%s: only the user and admins can access the reading states", errMsg))
+		return 0, false
+	}
+
+	return userId, true
+}
+
+// ids returns the id of the user and the id of the book.
+// It writes the error if the ids are invalid or the user can't be acted as.
+func (rh *readingHandler) ids(w http.ResponseWriter, r *http.Request, errMsg string) (int, int, bool) {
+	bookId, err := strconv.Atoi(chi.URLParam(r, "book_id"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(errorResponse{
+			Error: "book id is not a number",
+		})
+		rh.ErrorContext(r.Context(), fmt.Sprintf("%s: book id is not a number: %s", errMsg, err))
+		return 0, 0, false
+	}
+
+	userId, ok := rh.userId(w, r, errMsg)
+	if !ok {
+		return 0, 0, false
+	}
+
+	return userId, bookId, true
+}
+
+// bookmarkId returns the id of the bookmark, it writes the error if it's invalid.
+func (rh *readingHandler) bookmarkId(w http.ResponseWriter, r *http.Request, errMsg string) (int, bool) {
+	id, err := strconv.Atoi(chi.URLParam(r, "bookmark_id"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(errorResponse{
+			Error: "bookmark id is not a number",
+		})
+		rh.ErrorContext(r.Context(), fmt.Sprintf("%s: bookmark id is not a number: %s", errMsg, err))
+		return 0, false
+	}
+	return id, true
+}
+
+// bookmarkRequest parses and validates the bookmark, it writes the error if it's invalid.
+func (rh *readingHandler) bookmarkRequest(w http.ResponseWriter, r *http.Request, errMsg string) (bookmarkRequest, bool) {
+	we := json.NewEncoder(w)
+
+	var req bookmarkRequest
+
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		we.Encode(errorResponse{
+			Error: "invalid request",
+		})
+		rh.ErrorContext(r.Context(), fmt.Sprintf("%s: request not parsed: %s", errMsg, err), "req", req)
+		return req, false
+	}
+
+	var msg string
+	switch {
+	case req.Page < 1:
+		msg = "page should be a positive number"
+	case len(req.Note) > maxNoteLength:
+		msg = fmt.Sprintf("note should be at most %d characters long", maxNoteLength)
+	}
+	if msg != "" {
+		w.WriteHeader(http.StatusBadRequest)
+		we.Encode(errorResponse{
+			Error: msg,
+		})
+		rh.WarnContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, msg))
+		return req, false
+	}
+
+	return req, true
+}
+
+// bookExists checks that the book isn't deleted, it writes the error if it is.
+func (rh *readingHandler) bookExists(w http.ResponseWriter, r *http.Request, bookId int, errMsg string) bool {
+	_, err := rh.GetBook(r.Context(), bookId, false)
+	if errors.Is(err, sql.ErrNoRows) {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(errorResponse{
+			Error: "book not found",
+		})
+		rh.WarnContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+		return false
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(errorResponse{
+			Error: "db error",
+		})
+		rh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+		return false
+	}
+	return true
+}
@@ -0,0 +1,28 @@
+package recommendation
+
+import (
+	"net/http"
+
+	"github.com/qo/digital-library/internal/handlers/api/query"
+	"github.com/qo/digital-library/internal/openapi"
+)
+
+// Operations documents the routes of the recommendation api.
+var Operations = []openapi.Operation{
+	{
+		Method:   http.MethodGet,
+		Path:     "/user/{id}/recommendations",
+		Id:       "getRecommendations",
+		Tag:      "recommendation",
+		Summary:  "Get the books recommended to the user, they are recomputed periodically",
+		Auth:     true,
+		Query:    query.PageParams,
+		Response: getResponse{},
+		Statuses: map[int]string{
+			http.StatusOK:                  "Recommended books",
+			http.StatusBadRequest:          "Invalid id, limit or offset",
+			http.StatusForbidden:           "Only the user and admins can see the recommendations",
+			http.StatusInternalServerError: "DB error",
+		},
+	},
+}
@@ -0,0 +1,93 @@
+package recommendation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/qo/digital-library/internal/auth"
+	"github.com/qo/digital-library/internal/handlers/api/query"
+	"github.com/qo/digital-library/internal/logger"
+	"github.com/qo/digital-library/internal/storage/recommendation"
+)
+
+type recommendationStorage interface {
+	GetRecommendations(ctx context.Context, userId, limit, offset int) ([]recommendation.RecommendedBook, error)
+}
+
+type recommendationHandler struct {
+	logger.Logger
+	recommendationStorage
+}
+
+func New(log logger.Logger, rs recommendationStorage) *recommendationHandler {
+	return &recommendationHandler{
+		log,
+		rs,
+	}
+}
+
+type getResponse struct {
+	Error string                           `json:"error,omitempty"`
+	Books []recommendation.RecommendedBook `json:"books,omitempty"`
+}
+
+// Get returns a page of the books recommended to the user, the best first.
+// Only the user and admins can see the recommendations.
+func (rh *recommendationHandler) Get() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't get recommendations"
+
+		we := json.NewEncoder(w)
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			we.Encode(getResponse{
+				Error: "user id is not a number",
+			})
+			rh.ErrorContext(r.Context(), fmt.Sprintf("%s: user id is not a number: %s", errMsg, err))
+			return
+		}
+
+		limit, offset, err := query.Page(r)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			we.Encode(getResponse{
+				Error: err.Error(),
+			})
+			rh.WarnContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		if !auth.CanActAs(r.Context(), id) {
+			w.WriteHeader(http.StatusForbidden)
+			we.Encode(getResponse{
+				Error: "only the user and admins can see the recommendations",
+			})
+			rh.WarnContext(r.Context(), fmt.Sprintf("%s: only the user and admins can see the recommendations", errMsg))
+			return
+		}
+
+		books, err := rh.GetRecommendations(r.Context(), id, limit, offset)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			we.Encode(getResponse{
+				Error: "db error",
+			})
+			rh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		rh.DebugContext(r.Context(), "get recommendations success", "user id", id, "books", len(books))
+
+		w.WriteHeader(http.StatusOK)
+
+		we.Encode(getResponse{
+			Books: books,
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package review
+
+import (
+	"net/http"
+
+	"github.com/qo/digital-library/internal/handlers/api/query"
+	"github.com/qo/digital-library/internal/openapi"
+)
+
+// Operations documents the routes of the review api.
+var Operations = []openapi.Operation{
+	{
+		Method:   http.MethodGet,
+		Path:     "/book/{id}/reviews",
+		Id:       "getBookReviews",
+		Tag:      "review",
+		Summary:  "Get the reviews of the book",
+		Response: getBookReviewsResponse{},
+		Statuses: map[int]string{
+			http.StatusOK:                  "Book reviews",
+			http.StatusBadRequest:          "Invalid id",
+			http.StatusInternalServerError: "DB error",
+		},
+	},
+	{
+		Method:   http.MethodGet,
+		Path:     "/user/{id}/reviews",
+		Id:       "getUserReviews",
+		Tag:      "review",
+		Summary:  "Get the reviews written by the user",
+		Query:    []openapi.Param{query.IncludeDeletedParam},
+		Response: getUserReviewsResponse{},
+		Statuses: map[int]string{
+			http.StatusOK:                  "User reviews",
+			http.StatusBadRequest:          "Invalid id or include_deleted",
+			http.StatusForbidden:           "Only admins can include deleted reviews",
+			http.StatusInternalServerError: "DB error",
+		},
+	},
+	{
+		Method:   http.MethodGet,
+		Path:     "/book/{id}/review/{user_id}",
+		Id:       "getBookReview",
+		Tag:      "review",
+		Summary:  "Get the review of the book written by the user",
+		Response: getResponse{},
+		Statuses: map[int]string{
+			http.StatusOK:                  "Book review",
+			http.StatusBadRequest:          "Invalid id",
+			http.StatusNotFound:            "Book review not found",
+			http.StatusInternalServerError: "DB error",
+		},
+	},
+	{
+		Method:   http.MethodPut,
+		Path:     "/book/{id}/review/{user_id}",
+		Id:       "putBookReview",
+		Tag:      "review",
+		Summary:  "Create or update the review of the book written by the user",
+		Auth:     true,
+		Request:  putRequest{},
+		Response: putResponse{},
+		Statuses: map[int]string{
+			http.StatusCreated:             "Book review created",
+			http.StatusOK:                  "Book review updated",
+			http.StatusBadRequest:          "Invalid id, rating or body",
+			http.StatusForbidden:           "Only the user and admins can write the review",
+			http.StatusInternalServerError: "DB error",
+		},
+	},
+	{
+		Method:   http.MethodDelete,
+		Path:     "/book/{id}/review/{user_id}",
+		Id:       "deleteBookReview",
+		Tag:      "review",
+		Summary:  "Delete the review of the book written by the user",
+		Auth:     true,
+		Response: deleteResponse{},
+		Statuses: map[int]string{
+			http.StatusOK:                  "Book review deleted",
+			http.StatusBadRequest:          "Invalid id",
+			http.StatusForbidden:           "Only the user, mods and admins can delete the review",
+			http.StatusInternalServerError: "DB error",
+		},
+	},
+}
@@ -0,0 +1,367 @@
+package review
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/qo/digital-library/internal/auth"
+	"github.com/qo/digital-library/internal/handlers/api/query"
+	"github.com/qo/digital-library/internal/logger"
+	"github.com/qo/digital-library/internal/storage/book_review"
+	"github.com/qo/digital-library/internal/storage/user"
+)
+
+const maxReviewLength = 10000
+
+type reviewStorage interface {
+	GetBookReviews(ctx context.Context, bookId int) ([]book_review.BookReview, error)
+	GetUserBookReviews(ctx context.Context, userId int, includeDeleted bool) ([]book_review.BookReview, error)
+	GetBookReview(ctx context.Context, userId, bookId int, includeDeleted bool) (*book_review.BookReview, error)
+	PostBookReview(context.Context, *book_review.BookReview) error
+	PutBookReview(context.Context, *book_review.BookReview) error
+	DeleteBookReview(ctx context.Context, userId, bookId int) error
+	RestoreBookReview(ctx context.Context, userId, bookId int) error
+}
+
+type reviewHandler struct {
+	logger.Logger
+	reviewStorage
+}
+
+func New(log logger.Logger, rs reviewStorage) *reviewHandler {
+	return &reviewHandler{
+		log,
+		rs,
+	}
+}
+
+type getBookReviewsResponse struct {
+	Error   string                   `json:"error,omitempty"`
+	Reviews []book_review.BookReview `json:"reviews,omitempty"`
+}
+
+// GetBookReviews returns the reviews of the book.
+func (rh *reviewHandler) GetBookReviews() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't get book reviews"
+
+		we := json.NewEncoder(w)
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			we.Encode(getBookReviewsResponse{
+				Error: "book id is not a number",
+			})
+			rh.ErrorContext(r.Context(), fmt.Sprintf("%s: book id is not a number: %s", errMsg, err))
+			return
+		}
+
+		reviews, err := rh.reviewStorage.GetBookReviews(r.Context(), id)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			we.Encode(getBookReviewsResponse{
+				Error: "db error",
+			})
+			rh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		rh.DebugContext(r.Context(), "get book reviews success", "book id", id, "reviews", len(reviews))
+
+		w.WriteHeader(http.StatusOK)
+
+		we.Encode(getBookReviewsResponse{
+			Reviews: reviews,
+		})
+	}
+}
+
+type getUserReviewsResponse struct {
+	Error   string                   `json:"error,omitempty"`
+	Reviews []book_review.BookReview `json:"reviews,omitempty"`
+}
+
+// GetUserReviews returns the reviews written by the user.
+func (rh *reviewHandler) GetUserReviews() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't get user reviews"
+
+		we := json.NewEncoder(w)
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			we.Encode(getUserReviewsResponse{
+				Error: "user id is not a number",
+			})
+			rh.ErrorContext(r.Context(), fmt.Sprintf("%s: user id is not a number: %s", errMsg, err))
+			return
+		}
+
+		includeDeleted, err := query.IncludeDeleted(r)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			we.Encode(getUserReviewsResponse{
+				Error: "include_deleted is not a boolean",
+			})
+			rh.ErrorContext(r.Context(), fmt.Sprintf("%s: include_deleted is not a boolean: %s", errMsg, err))
+			return
+		}
+
+		if includeDeleted && !auth.IsAdmin(r.Context()) {
+			w.WriteHeader(http.StatusForbidden)
+			we.Encode(getUserReviewsResponse{
+				Error: "only admins can include deleted reviews",
+			})
+			rh.WarnContext(r.Context(), fmt.Sprintf("%s: only admins can include deleted reviews", errMsg))
+			return
+		}
+
+		reviews, err := rh.GetUserBookReviews(r.Context(), id, includeDeleted)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			we.Encode(getUserReviewsResponse{
+				Error: "db error",
+			})
+			rh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		rh.DebugContext(r.Context(), "get user reviews success", "user id", id, "reviews", len(reviews))
+
+		w.WriteHeader(http.StatusOK)
+
+		we.Encode(getUserReviewsResponse{
+			Reviews: reviews,
+		})
+	}
+}
+
+type getResponse struct {
+	Error string `json:"error,omitempty"`
+	book_review.BookReview
+}
+
+// Get returns the review of the book written by the user.
+func (rh *reviewHandler) Get() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't get book review"
+
+		we := json.NewEncoder(w)
+
+		bookId, userId, err := ids(r)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			we.Encode(getResponse{
+				Error: err.Error(),
+			})
+			rh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		review, err := rh.GetBookReview(r.Context(), userId, bookId, false)
+		if errors.Is(err, sql.ErrNoRows) {
+			w.WriteHeader(http.StatusNotFound)
+			we.Encode(getResponse{
+				Error: "book review not found",
+			})
+			rh.WarnContext(r.Context(), fmt.Sprintf("%s: book review not found", errMsg), "user id", userId, "book id", bookId)
+			return
+		}
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			we.Encode(getResponse{
+				Error: "db error",
+			})
+			rh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		rh.DebugContext(r.Context(), "get book review success", "review", review)
+
+		w.WriteHeader(http.StatusOK)
+
+		we.Encode(getResponse{
+			"",
+			*review,
+		})
+	}
+}
+
+type putRequest struct {
+	Rating int    `json:"rating"`
+	Body   string `json:"body,omitempty"`
+}
+
+type putResponse struct {
+	Error string `json:"error,omitempty"`
+}
+
+// Put creates or updates the review of the book written by the user.
+// Only the user and admins can write it.
+func (rh *reviewHandler) Put() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't put book review"
+
+		rd, we := json.NewDecoder(r.Body), json.NewEncoder(w)
+
+		bookId, userId, err := ids(r)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			we.Encode(putResponse{
+				Error: err.Error(),
+			})
+			rh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		if !auth.CanActAs(r.Context(), userId) {
+			w.WriteHeader(http.StatusForbidden)
+			we.Encode(putResponse{
+				Error: "only the user and admins can write the review",
+			})
+			rh.WarnContext(r.Context(), fmt.Sprintf("%s: only the user and admins can write the review", errMsg))
+			return
+		}
+
+		var req putRequest
+
+		err = rd.Decode(&req)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			we.Encode(putResponse{
+				Error: "invalid request",
+			})
+			rh.ErrorContext(r.Context(), fmt.Sprintf("%s: request not parsed: %s", errMsg, err))
+			return
+		}
+
+		req.Body = strings.TrimSpace(req.Body)
+
+		if req.Rating < 1 || req.Rating > 5 || len(req.Body) > maxReviewLength {
+			w.WriteHeader(http.StatusBadRequest)
+			we.Encode(putResponse{
+				Error: fmt.Sprintf("rating must be from 1 to 5 and body must be at most %d characters long", maxReviewLength),
+			})
+			rh.WarnContext(r.Context(), fmt.Sprintf("%s: invalid review", errMsg), "rating", req.Rating)
+			return
+		}
+
+		review := book_review.BookReview{
+			UserId: userId,
+			BookId: bookId,
+			Rating: req.Rating,
+			Body:   req.Body,
+		}
+
+		created := false
+
+		// a deleted review keeps its key, so it is restored instead of posted again
+		existing, err := rh.GetBookReview(r.Context(), userId, bookId, true)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			err = rh.PostBookReview(r.Context(), &review)
+			created = true
+		case err != nil:
+		case existing.DeletedAt != nil:
+			err = rh.RestoreBookReview(r.Context(), userId, bookId)
+			if err == nil {
+				err = rh.PutBookReview(r.Context(), &review)
+			}
+			created = true
+		default:
+			err = rh.PutBookReview(r.Context(), &review)
+		}
+		// TODO: check type of error
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			we.Encode(putResponse{
+				Error: "db error",
+			})
+			rh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		rh.InfoContext(r.Context(), "book review saved", "user id", userId, "book id", bookId)
+
+		if created {
+			w.WriteHeader(http.StatusCreated)
+		} else {
+			w.WriteHeader(http.StatusOK)
+		}
+
+		we.Encode(putResponse{})
+	}
+}
+
+type deleteResponse struct {
+	Error string `json:"error,omitempty"`
+}
+
+// Delete deletes the review of the book written by the user.
+// Only the user, mods and admins can delete it.
+func (rh *reviewHandler) Delete() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't delete book review"
+
+		we := json.NewEncoder(w)
+
+		bookId, userId, err := ids(r)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			we.Encode(deleteResponse{
+				Error: err.Error(),
+			})
+			rh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		if p, ok := auth.FromContext(r.Context()); !(ok && (p.UserId == userId || p.Role >= user.RoleMod)) {
+			w.WriteHeader(http.StatusForbidden)
+			we.Encode(deleteResponse{
+				Error: "only the user, mods and admins can delete the review",
+			})
+			rh.WarnContext(r.Context(), fmt.Sprintf("%s: only the user, mods and admins can delete the review", errMsg))
+			return
+		}
+
+		err = rh.DeleteBookReview(r.Context(), userId, bookId)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			we.Encode(deleteResponse{
+				Error: "db error",
+			})
+			rh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		rh.InfoContext(r.Context(), "book review deleted", "user id", userId, "book id", bookId)
+
+		w.WriteHeader(http.StatusOK)
+
+		we.Encode(deleteResponse{})
+	}
+}
+
+// ids returns the book id and the user id of the review.
+func ids(r *http.Request) (int, int, error) {
+	bookId, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		return 0, 0, errors.New("book id is not a number")
+	}
+
+	userId, err := strconv.Atoi(chi.URLParam(r, "user_id"))
+	if err != nil {
+		return 0, 0, errors.New("user id is not a number")
+	}
+
+	return bookId, userId, nil
+}
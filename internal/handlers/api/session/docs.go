@@ -0,0 +1,40 @@
+package session
+
+import (
+	"net/http"
+
+	"github.com/qo/digital-library/internal/openapi"
+)
+
+// Operations documents the routes of the session api.
+var Operations = []openapi.Operation{
+	{
+		Method:   http.MethodPost,
+		Path:     "/login",
+		Id:       "login",
+		Tag:      "session",
+		Summary:  "Start a session, the token is sent as the bearer token",
+		Request:  loginRequest{},
+		Response: loginResponse{},
+		Statuses: map[int]string{
+			http.StatusOK:                  "Session started",
+			http.StatusBadRequest:          "Invalid request",
+			http.StatusUnauthorized:        "Invalid credentials",
+			http.StatusInternalServerError: "DB error",
+		},
+	},
+	{
+		Method:   http.MethodPost,
+		Path:     "/logout",
+		Id:       "logout",
+		Tag:      "session",
+		Summary:  "End the session of the bearer token",
+		Auth:     true,
+		Response: logoutResponse{},
+		Statuses: map[int]string{
+			http.StatusOK:                  "Session ended",
+			http.StatusUnauthorized:        "No bearer token",
+			http.StatusInternalServerError: "DB error",
+		},
+	},
+}
@@ -0,0 +1,138 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/qo/digital-library/internal/auth"
+	"github.com/qo/digital-library/internal/config"
+	"github.com/qo/digital-library/internal/logger"
+	"github.com/qo/digital-library/internal/storage/session"
+	"github.com/qo/digital-library/internal/storage/user"
+)
+
+type SessionStorage interface {
+	GetSession(ctx context.Context, tokenHash string) (*session.Session, error)
+	GetUser(ctx context.Context, id int) (*user.User, error)
+	GetPasswordHash(ctx context.Context, id int) (string, error)
+	PostSession(context.Context, *session.Session) error
+	DeleteSession(ctx context.Context, tokenHash string) error
+}
+
+type sessionHandler struct {
+	logger.Logger
+	SessionStorage
+	options config.AuthOptions
+}
+
+func New(log logger.Logger, ss SessionStorage, options config.AuthOptions) *sessionHandler {
+	return &sessionHandler{
+		log,
+		ss,
+		options,
+	}
+}
+
+type loginRequest struct {
+	UserId   int    `json:"user_id"`
+	Password string `json:"password"`
+}
+
+type loginResponse struct {
+	Error     string     `json:"error,omitempty"`
+	Token     string     `json:"token,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// Login starts a session.
+// The returned token should be sent as a bearer token.
+func (sh *sessionHandler) Login() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't log in"
+
+		rd, we := json.NewDecoder(r.Body), json.NewEncoder(w)
+
+		var req loginRequest
+
+		err := rd.Decode(&req)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			we.Encode(loginResponse{
+				Error: "invalid request",
+			})
+			sh.ErrorContext(r.Context(), fmt.Sprintf("%s: request not parsed: %s", errMsg, err))
+			return
+		}
+
+		token, expiresAt, err := auth.Login(r.Context(), sh, req.UserId, req.Password, sh.options.SessionTTL)
+		if errors.Is(err, auth.ErrInvalidCredentials) {
+			w.WriteHeader(http.StatusUnauthorized)
+			we.Encode(loginResponse{
+				Error: err.Error(),
+			})
+			sh.WarnContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err), "user id", req.UserId)
+			return
+		}
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			we.Encode(loginResponse{
+				Error: "db error",
+			})
+			sh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		sh.DebugContext(r.Context(), "login success", "user id", req.UserId)
+
+		w.WriteHeader(http.StatusOK)
+
+		we.Encode(loginResponse{
+			Token:     token,
+			ExpiresAt: &expiresAt,
+		})
+	}
+}
+
+type logoutResponse struct {
+	Error string `json:"error,omitempty"`
+}
+
+// Logout ends the session of the bearer token.
+func (sh *sessionHandler) Logout() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't log out"
+
+		we := json.NewEncoder(w)
+
+		// the session cookie of the web ui isn't accepted by the api
+		token, ok := auth.BearerToken(r)
+		if !ok {
+			w.WriteHeader(http.StatusUnauthorized)
+			we.Encode(logoutResponse{
+				Error: "no bearer token",
+			})
+			sh.WarnContext(r.Context(), fmt.Sprintf("%s: no bearer token", errMsg))
+			return
+		}
+
+		err := auth.Logout(r.Context(), sh, token)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			we.Encode(logoutResponse{
+				Error: "db error",
+			})
+			sh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		sh.DebugContext(r.Context(), "logout success")
+
+		w.WriteHeader(http.StatusOK)
+
+		we.Encode(logoutResponse{})
+	}
+}
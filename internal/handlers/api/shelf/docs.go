@@ -0,0 +1,136 @@
+package shelf
+
+import (
+	"net/http"
+
+	"github.com/qo/digital-library/internal/openapi"
+)
+
+// Operations documents the routes of the shelf api.
+var Operations = []openapi.Operation{
+	{
+		Method:   http.MethodGet,
+		Path:     "/user/{id}/shelves",
+		Id:       "listShelves",
+		Tag:      "shelf",
+		Summary:  "List the shelves of the user ordered by name, the private ones are listed only to the user and admins",
+		Response: listResponse{},
+		Statuses: map[int]string{
+			http.StatusOK:                  "Shelves",
+			http.StatusBadRequest:          "Invalid id",
+			http.StatusInternalServerError: "DB error",
+		},
+	},
+	{
+		Method:   http.MethodPost,
+		Path:     "/user/{id}/shelves",
+		Id:       "postShelf",
+		Tag:      "shelf",
+		Summary:  "Create a shelf of the user",
+		Auth:     true,
+		Request:  shelfRequest{},
+		Response: postResponse{},
+		Statuses: map[int]string{
+			http.StatusCreated:             "Shelf created",
+			http.StatusBadRequest:          "Invalid id, name or description",
+			http.StatusForbidden:           "Only the user and admins can edit the shelves",
+			http.StatusInternalServerError: "DB error",
+		},
+	},
+	{
+		Method:   http.MethodGet,
+		Path:     "/shelf/{id}",
+		Id:       "getShelf",
+		Tag:      "shelf",
+		Summary:  "Get the shelf along with its books in the order of the shelf",
+		Response: getResponse{},
+		Statuses: map[int]string{
+			http.StatusOK:                  "Shelf",
+			http.StatusBadRequest:          "Invalid id",
+			http.StatusNotFound:            "Shelf not found or private",
+			http.StatusInternalServerError: "DB error",
+		},
+	},
+	{
+		Method:   http.MethodPut,
+		Path:     "/shelf/{id}",
+		Id:       "putShelf",
+		Tag:      "shelf",
+		Summary:  "Update the name, the description and the visibility of the shelf",
+		Auth:     true,
+		Request:  shelfRequest{},
+		Response: putResponse{},
+		Statuses: map[int]string{
+			http.StatusOK:                  "Shelf updated",
+			http.StatusBadRequest:          "Invalid id, name or description",
+			http.StatusForbidden:           "Only the owner and admins can edit the shelf",
+			http.StatusNotFound:            "Shelf not found or private",
+			http.StatusInternalServerError: "DB error",
+		},
+	},
+	{
+		Method:   http.MethodDelete,
+		Path:     "/shelf/{id}",
+		Id:       "deleteShelf",
+		Tag:      "shelf",
+		Summary:  "Delete the shelf, the books on it aren't affected",
+		Auth:     true,
+		Response: deleteResponse{},
+		Statuses: map[int]string{
+			http.StatusOK:                  "Shelf deleted",
+			http.StatusBadRequest:          "Invalid id",
+			http.StatusForbidden:           "Only the owner and admins can edit the shelf",
+			http.StatusNotFound:            "Shelf not found or private",
+			http.StatusInternalServerError: "DB error",
+		},
+	},
+	{
+		Method:   http.MethodPut,
+		Path:     "/shelf/{id}/books",
+		Id:       "putShelfOrder",
+		Tag:      "shelf",
+		Summary:  "Reorder the books of the shelf, every book of the shelf should be listed once",
+		Auth:     true,
+		Request:  putOrderRequest{},
+		Response: putOrderResponse{},
+		Statuses: map[int]string{
+			http.StatusOK:                  "Shelf reordered",
+			http.StatusBadRequest:          "Invalid id or book ids",
+			http.StatusForbidden:           "Only the owner and admins can edit the shelf",
+			http.StatusNotFound:            "Shelf not found or private",
+			http.StatusInternalServerError: "DB error",
+		},
+	},
+	{
+		Method:   http.MethodPut,
+		Path:     "/shelf/{id}/books/{book_id}",
+		Id:       "putShelfBook",
+		Tag:      "shelf",
+		Summary:  "Add the book to the end of the shelf, the book already on the shelf keeps its position",
+		Auth:     true,
+		Response: putBookResponse{},
+		Statuses: map[int]string{
+			http.StatusOK:                  "Book added",
+			http.StatusBadRequest:          "Invalid id",
+			http.StatusForbidden:           "Only the owner and admins can edit the shelf",
+			http.StatusNotFound:            "Shelf or book not found",
+			http.StatusInternalServerError: "DB error",
+		},
+	},
+	{
+		Method:   http.MethodDelete,
+		Path:     "/shelf/{id}/books/{book_id}",
+		Id:       "deleteShelfBook",
+		Tag:      "shelf",
+		Summary:  "Remove the book from the shelf",
+		Auth:     true,
+		Response: deleteBookResponse{},
+		Statuses: map[int]string{
+			http.StatusOK:                  "Book removed",
+			http.StatusBadRequest:          "Invalid id",
+			http.StatusForbidden:           "Only the owner and admins can edit the shelf",
+			http.StatusNotFound:            "Shelf not found or private",
+			http.StatusInternalServerError: "DB error",
+		},
+	},
+}
@@ -0,0 +1,547 @@
+package shelf
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/qo/digital-library/internal/auth"
+	"github.com/qo/digital-library/internal/logger"
+	"github.com/qo/digital-library/internal/storage/book"
+	"github.com/qo/digital-library/internal/storage/shelf"
+	"github.com/qo/digital-library/internal/storage/shelf_book"
+)
+
+const (
+	maxNameLength        = 100
+	maxDescriptionLength = 1000
+)
+
+type shelfStorage interface {
+	GetBook(ctx context.Context, id int, includeDeleted bool) (*book.Book, error)
+	GetShelf(ctx context.Context, id int) (*shelf.Shelf, error)
+	GetUserShelves(ctx context.Context, userId int, includePrivate bool) ([]shelf.Shelf, error)
+	PostShelf(context.Context, *shelf.Shelf) error
+	PutShelf(context.Context, *shelf.Shelf) error
+	DeleteShelf(ctx context.Context, id int) error
+	GetShelfBooks(ctx context.Context, shelfId int) ([]book.Book, error)
+	PostShelfBook(ctx context.Context, shelfId, bookId int) error
+	DeleteShelfBook(ctx context.Context, shelfId, bookId int) error
+	PutShelfOrder(ctx context.Context, shelfId int, bookIds []int) error
+}
+
+type shelfHandler struct {
+	logger.Logger
+	shelfStorage
+}
+
+func New(log logger.Logger, ss shelfStorage) *shelfHandler {
+	return &shelfHandler{
+		log,
+		ss,
+	}
+}
+
+// errorResponse is written by the helpers shared by the routes,
+// every response of the routes has the error field.
+type errorResponse struct {
+	Error string `json:"error,omitempty"`
+}
+
+type listResponse struct {
+	Error   string        `json:"error,omitempty"`
+	Shelves []shelf.Shelf `json:"shelves,omitempty"`
+}
+
+// List returns the shelves of the user ordered by name.
+// The private shelves are returned only to the user and admins.
+func (sh *shelfHandler) List() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't list shelves"
+
+		we := json.NewEncoder(w)
+
+		userId, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			we.Encode(listResponse{
+				Error: "user id is not a number",
+			})
+			sh.ErrorContext(r.Context(), fmt.Sprintf("%s: user id is not a number: %s", errMsg, err))
+			return
+		}
+
+		shelves, err := sh.GetUserShelves(r.Context(), userId, canEdit(r, userId))
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			we.Encode(listResponse{
+				Error: "db error",
+			})
+			sh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		sh.DebugContext(r.Context(), "list shelves success", "user id", userId, "shelves", len(shelves))
+
+		w.WriteHeader(http.StatusOK)
+
+		we.Encode(listResponse{
+			Shelves: shelves,
+		})
+	}
+}
+
+type shelfRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	// Public shelves are seen by everyone, the private ones only by the owner and admins
+	Public bool `json:"public,omitempty"`
+}
+
+type postResponse struct {
+	Error string `json:"error,omitempty"`
+	// Id is the id of the created shelf
+	Id int `json:"id,omitempty"`
+}
+
+// Post creates a shelf of the user.
+func (sh *shelfHandler) Post() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't post shelf"
+
+		we := json.NewEncoder(w)
+
+		userId, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			we.Encode(postResponse{
+				Error: "user id is not a number",
+			})
+			sh.ErrorContext(r.Context(), fmt.Sprintf("%s: user id is not a number: %s", errMsg, err))
+			return
+		}
+
+		if !canEdit(r, userId) {
+			w.WriteHeader(http.StatusForbidden)
+			we.Encode(postResponse{
+				Error: "only the user and admins can edit the shelves",
+			})
+			sh.WarnContext(r.Context(), fmt.Sprintf("%s: only the user and admins can edit the shelves", errMsg))
+			return
+		}
+
+		req, ok := sh.shelfRequest(w, r, errMsg)
+		if !ok {
+			return
+		}
+
+		s := shelf.Shelf{
+			UserId:      userId,
+			Name:        req.Name,
+			Description: req.Description,
+			Public:      req.Public,
+		}
+
+		err = sh.PostShelf(r.Context(), &s)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			we.Encode(postResponse{
+				Error: "db error",
+			})
+			sh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		sh.DebugContext(r.Context(), "post shelf success", "user id", userId, "shelf id", s.Id)
+
+		w.WriteHeader(http.StatusCreated)
+
+		we.Encode(postResponse{
+			Id: s.Id,
+		})
+	}
+}
+
+type getResponse struct {
+	Error string `json:"error,omitempty"`
+	shelf.Shelf
+	// Books are the books of the shelf in its order
+	Books []book.Book `json:"books,omitempty"`
+}
+
+// Get returns the shelf along with its books.
+func (sh *shelfHandler) Get() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't get shelf"
+
+		we := json.NewEncoder(w)
+
+		s, ok := sh.getShelf(w, r, false, errMsg)
+		if !ok {
+			return
+		}
+
+		books, err := sh.GetShelfBooks(r.Context(), s.Id)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			we.Encode(getResponse{
+				Error: "db error",
+			})
+			sh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		sh.DebugContext(r.Context(), "get shelf success", "shelf id", s.Id, "books", len(books))
+
+		w.WriteHeader(http.StatusOK)
+
+		we.Encode(getResponse{
+			Shelf: *s,
+			Books: books,
+		})
+	}
+}
+
+type putResponse struct {
+	Error string `json:"error,omitempty"`
+}
+
+// Put updates the name, the description and the visibility of the shelf.
+func (sh *shelfHandler) Put() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't put shelf"
+
+		we := json.NewEncoder(w)
+
+		s, ok := sh.getShelf(w, r, true, errMsg)
+		if !ok {
+			return
+		}
+
+		req, ok := sh.shelfRequest(w, r, errMsg)
+		if !ok {
+			return
+		}
+
+		s.Name, s.Description, s.Public = req.Name, req.Description, req.Public
+
+		err := sh.PutShelf(r.Context(), s)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			we.Encode(putResponse{
+				Error: "db error",
+			})
+			sh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		sh.DebugContext(r.Context(), "put shelf success", "shelf id", s.Id)
+
+		w.WriteHeader(http.StatusOK)
+
+		we.Encode(putResponse{})
+	}
+}
+
+type deleteResponse struct {
+	Error string `json:"error,omitempty"`
+}
+
+// Delete removes the shelf, the books on it aren't affected.
+func (sh *shelfHandler) Delete() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't delete shelf"
+
+		we := json.NewEncoder(w)
+
+		s, ok := sh.getShelf(w, r, true, errMsg)
+		if !ok {
+			return
+		}
+
+		err := sh.DeleteShelf(r.Context(), s.Id)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			we.Encode(deleteResponse{
+				Error: "db error",
+			})
+			sh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		sh.DebugContext(r.Context(), "delete shelf success", "shelf id", s.Id)
+
+		w.WriteHeader(http.StatusOK)
+
+		we.Encode(deleteResponse{})
+	}
+}
+
+type putBookResponse struct {
+	Error string `json:"error,omitempty"`
+}
+
+// PutBook adds the book to the end of the shelf,
+// the book already on the shelf keeps its position.
+func (sh *shelfHandler) PutBook() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't put shelf book"
+
+		we := json.NewEncoder(w)
+
+		s, bookId, ok := sh.ids(w, r, errMsg)
+		if !ok {
+			return
+		}
+
+		_, err := sh.GetBook(r.Context(), bookId, false)
+		if errors.Is(err, sql.ErrNoRows) {
+			w.WriteHeader(http.StatusNotFound)
+			we.Encode(putBookResponse{
+				Error: "book not found",
+			})
+			sh.WarnContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			we.Encode(putBookResponse{
+				Error: "db error",
+			})
+			sh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		err = sh.PostShelfBook(r.Context(), s.Id, bookId)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			we.Encode(putBookResponse{
+				Error: "db error",
+			})
+			sh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		sh.DebugContext(r.Context(), "put shelf book success", "shelf id", s.Id, "book id", bookId)
+
+		w.WriteHeader(http.StatusOK)
+
+		we.Encode(putBookResponse{})
+	}
+}
+
+type deleteBookResponse struct {
+	Error string `json:"error,omitempty"`
+}
+
+// DeleteBook removes the book from the shelf.
+func (sh *shelfHandler) DeleteBook() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't delete shelf book"
+
+		we := json.NewEncoder(w)
+
+		s, bookId, ok := sh.ids(w, r, errMsg)
+		if !ok {
+			return
+		}
+
+		err := sh.DeleteShelfBook(r.Context(), s.Id, bookId)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			we.Encode(deleteBookResponse{
+				Error: "db error",
+			})
+			sh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		sh.DebugContext(r.Context(), "delete shelf book success", "shelf id", s.Id, "book id", bookId)
+
+		w.WriteHeader(http.StatusOK)
+
+		we.Encode(deleteBookResponse{})
+	}
+}
+
+type putOrderRequest struct {
+	// BookIds are the ids of all books of the shelf in the new order
+	BookIds []int `json:"book_ids"`
+}
+
+type putOrderResponse struct {
+	Error string `json:"error,omitempty"`
+}
+
+// PutOrder reorders the books of the shelf,
+// the request should list every book of the shelf exactly once.
+func (sh *shelfHandler) PutOrder() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't put shelf order"
+
+		rd, we := json.NewDecoder(r.Body), json.NewEncoder(w)
+
+		s, ok := sh.getShelf(w, r, true, errMsg)
+		if !ok {
+			return
+		}
+
+		var req putOrderRequest
+
+		err := rd.Decode(&req)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			we.Encode(putOrderResponse{
+				Error: "invalid request",
+			})
+			sh.ErrorContext(r.Context(), fmt.Sprintf("%s: request not parsed: %s", errMsg, err), "req", req)
+			return
+		}
+
+		err = sh.PutShelfOrder(r.Context(), s.Id, req.BookIds)
+		if errors.Is(err, shelf_book.ErrInvalidOrder) {
+			w.WriteHeader(http.StatusBadRequest)
+			we.Encode(putOrderResponse{
+				Error: "book ids should list every book of the shelf once",
+			})
+			sh.WarnContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			we.Encode(putOrderResponse{
+				Error: "db error",
+			})
+			sh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		sh.DebugContext(r.Context(), "put shelf order success", "shelf id", s.Id, "books", len(req.BookIds))
+
+		w.WriteHeader(http.StatusOK)
+
+		we.Encode(putOrderResponse{})
+	}
+}
+
+// canEdit reports whether the shelves of the user can be edited by the one who makes the request,
+// only the user and admins can edit them and see the private ones.
+func canEdit(r *http.Request, userId int) bool {
+	return auth.CanActAs(r.Context(), userId)
+}
+
+// getShelf returns the shelf with the id of the route.
+// The private shelves are not found for everyone but the owner and admins,
+// with edit the public ones are forbidden for them.
+// It writes the error if the shelf can't be returned.
+func (sh *shelfHandler) getShelf(w http.ResponseWriter, r *http.Request, edit bool, errMsg string) (*shelf.Shelf, bool) {
+	we := json.NewEncoder(w)
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		we.Encode(errorResponse{
+			Error: "shelf id is not a number",
+		})
+		sh.ErrorContext(r.Context(), fmt.Sprintf("%s: shelf id is not a number: %s", errMsg, err))
+		return nil, false
+	}
+
+	s, err := sh.GetShelf(r.Context(), id)
+	if err == nil && !s.Public && !canEdit(r, s.UserId) {
+		err = fmt.Errorf("shelf %d is private: %w", id, sql.ErrNoRows)
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		w.WriteHeader(http.StatusNotFound)
+		we.Encode(errorResponse{
+			Error: "shelf not found",
+		})
+		sh.WarnContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+		return nil, false
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		we.Encode(errorResponse{
+			Error: "db error",
+		})
+		sh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+		return nil, false
+	}
+
+	if edit && !canEdit(r, s.UserId) {
+		w.WriteHeader(http.StatusForbidden)
+		we.Encode(errorResponse{
+			Error: "only the owner and admins can edit the shelf",
+		})
+		sh.WarnContext(r.Context(), fmt.Sprintf("%s: only the owner and admins can edit the shelf", errMsg))
+		return nil, false
+	}
+
+	return s, true
+}
+
+// ids returns the shelf to edit and the id of the book.
+// It writes the error if the ids are invalid or the shelf can't be edited.
+func (sh *shelfHandler) ids(w http.ResponseWriter, r *http.Request, errMsg string) (*shelf.Shelf, int, bool) {
+	bookId, err := strconv.Atoi(chi.URLParam(r, "book_id"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(errorResponse{
+			Error: "book id is not a number",
+		})
+		sh.ErrorContext(r.Context(), fmt.Sprintf("%s: book id is not a number: %s", errMsg, err))
+		return nil, 0, false
+	}
+
+	s, ok := sh.getShelf(w, r, true, errMsg)
+	if !ok {
+		return nil, 0, false
+	}
+
+	return s, bookId, true
+}
+
+// shelfRequest parses and validates the shelf, it writes the error if it's invalid.
+func (sh *shelfHandler) shelfRequest(w http.ResponseWriter, r *http.Request, errMsg string) (shelfRequest, bool) {
+	we := json.NewEncoder(w)
+
+	var req shelfRequest
+
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		we.Encode(errorResponse{
+			Error: "invalid request",
+		})
+		sh.ErrorContext(r.Context(), fmt.Sprintf("%s: request not parsed: %s", errMsg, err), "req", req)
+		return req, false
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+
+	var msg string
+	switch {
+	case req.Name == "":
+		msg = "name should be specified"
+	case len(req.Name) > maxNameLength:
+		msg = fmt.Sprintf("name should be at most %d characters long", maxNameLength)
+	case len(req.Description) > maxDescriptionLength:
+		msg = fmt.Sprintf("description should be at most %d characters long", maxDescriptionLength)
+	}
+	if msg != "" {
+		w.WriteHeader(http.StatusBadRequest)
+		we.Encode(errorResponse{
+			Error: msg,
+		})
+		sh.WarnContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, msg))
+		return req, false
+	}
+
+	return req, true
+}
@@ -0,0 +1,198 @@
+package user
+
+import (
+	"net/http"
+
+	"github.com/qo/digital-library/internal/handlers/api/query"
+	"github.com/qo/digital-library/internal/openapi"
+)
+
+// Operations documents the routes of the user api.
+var Operations = []openapi.Operation{
+	{
+		Method:   http.MethodPost,
+		Path:     "/user",
+		Id:       "postUser",
+		Tag:      "user",
+		Summary:  "Create a user, users without a password can't log in",
+		Request:  postRequest{},
+		Response: postResponse{},
+		Statuses: map[int]string{
+			http.StatusCreated:             "User created",
+			http.StatusBadRequest:          "Invalid request, role or short password",
+			http.StatusForbidden:           "Only admins can create mods",
+			http.StatusInternalServerError: "DB error",
+		},
+	},
+	{
+		Method:   http.MethodGet,
+		Path:     "/users",
+		Id:       "listUsers",
+		Tag:      "user",
+		Summary:  "List the users ordered by id",
+		Query:    query.PageParams,
+		Response: listResponse{},
+		Statuses: map[int]string{
+			http.StatusOK:                  "Users",
+			http.StatusBadRequest:          "Invalid limit or offset",
+			http.StatusInternalServerError: "DB error",
+		},
+	},
+	{
+		Method:   http.MethodGet,
+		Path:     "/user/{id}",
+		Id:       "getUser",
+		Tag:      "user",
+		Summary:  "Get the user",
+		Response: getResponse{},
+		Statuses: map[int]string{
+			http.StatusOK:                  "User",
+			http.StatusBadRequest:          "Invalid id",
+			http.StatusInternalServerError: "DB error",
+		},
+	},
+	{
+		Method:   http.MethodPut,
+		Path:     "/user",
+		Id:       "putUser",
+		Tag:      "user",
+		Summary:  "Update the user, the password and the role are changed only if they are set, changing the password ends the other sessions of the user",
+		Auth:     true,
+		Request:  putRequest{},
+		Response: putResponse{},
+		Statuses: map[int]string{
+			http.StatusOK:                  "User updated",
+			http.StatusBadRequest:          "Invalid request, role transition or short password",
+			http.StatusForbidden:           "Only the user and admins can change the user, only admins can change roles",
+			http.StatusNotFound:            "User not found",
+			http.StatusConflict:            "Role was changed by someone else",
+			http.StatusInternalServerError: "DB error",
+		},
+	},
+	{
+		Method:   http.MethodPut,
+		Path:     "/user/{id}/role",
+		Id:       "putUserRole",
+		Tag:      "user",
+		Summary:  "Change the role of the user, users can become mods and mods can become users",
+		Auth:     true,
+		Request:  putRoleRequest{},
+		Response: putRoleResponse{},
+		Statuses: map[int]string{
+			http.StatusOK:                  "Role changed",
+			http.StatusBadRequest:          "Invalid id or role transition",
+			http.StatusForbidden:           "Only admins can change roles",
+			http.StatusNotFound:            "User not found",
+			http.StatusConflict:            "Role was changed by someone else",
+			http.StatusInternalServerError: "DB error",
+		},
+	},
+	{
+		Method:   http.MethodDelete,
+		Path:     "/user/{id}",
+		Id:       "deleteUser",
+		Tag:      "user",
+		Summary:  "Delete the user along with everything of the user, admins are never deleted",
+		Auth:     true,
+		Response: deleteResponse{},
+		Statuses: map[int]string{
+			http.StatusOK:                  "User deleted",
+			http.StatusBadRequest:          "Invalid id",
+			http.StatusForbidden:           "Only admins can delete users, admins can't be deleted",
+			http.StatusNotFound:            "User not found",
+			http.StatusInternalServerError: "DB error",
+		},
+	},
+	{
+		Method:   http.MethodGet,
+		Path:     "/user/{id}/books",
+		Id:       "getUserFavoriteBooks",
+		Tag:      "user",
+		Summary:  "Get the favorite books of the user",
+		Query:    []openapi.Param{query.IncludeDeletedParam},
+		Response: getFavoriteBooksResponse{},
+		Statuses: map[int]string{
+			http.StatusOK:                  "Favorite books",
+			http.StatusBadRequest:          "Invalid id or include_deleted",
+			http.StatusForbidden:           "Only admins can include deleted books",
+			http.StatusInternalServerError: "DB error",
+		},
+	},
+	{
+		Method:        http.MethodGet,
+		Path:          "/user/{id}/books/cite",
+		Id:            "citeUserFavoriteBooks",
+		Tag:           "user",
+		Summary:       "Cite the favorite books of the user",
+		Query:         []openapi.Param{query.CitationFormatParam},
+		Response:      citeFavoriteBooksResponse{},
+		ResponseMedia: query.CitationMedia,
+		Statuses: map[int]string{
+			http.StatusOK:                  "Citations",
+			http.StatusBadRequest:          "Invalid id or unknown format",
+			http.StatusInternalServerError: "DB error",
+		},
+	},
+	{
+		Method:   http.MethodPut,
+		Path:     "/user/{id}/books/{book_id}",
+		Id:       "putUserFavoriteBook",
+		Tag:      "user",
+		Summary:  "Add the book to the favorites of the user",
+		Auth:     true,
+		Response: favoriteResponse{},
+		Statuses: favoriteStatuses,
+	},
+	{
+		Method:   http.MethodDelete,
+		Path:     "/user/{id}/books/{book_id}",
+		Id:       "deleteUserFavoriteBook",
+		Tag:      "user",
+		Summary:  "Remove the book from the favorites of the user",
+		Auth:     true,
+		Response: favoriteResponse{},
+		Statuses: favoriteStatuses,
+	},
+	{
+		Method:   http.MethodGet,
+		Path:     "/user/{id}/authors",
+		Id:       "getUserFavoriteAuthors",
+		Tag:      "user",
+		Summary:  "Get the favorite authors of the user",
+		Query:    []openapi.Param{query.IncludeDeletedParam},
+		Response: getFavoriteAuthorsResponse{},
+		Statuses: map[int]string{
+			http.StatusOK:                  "Favorite authors",
+			http.StatusBadRequest:          "Invalid id or include_deleted",
+			http.StatusForbidden:           "Only admins can include deleted authors",
+			http.StatusInternalServerError: "DB error",
+		},
+	},
+	{
+		Method:   http.MethodPut,
+		Path:     "/user/{id}/authors/{author_id}",
+		Id:       "putUserFavoriteAuthor",
+		Tag:      "user",
+		Summary:  "Add the author to the favorites of the user",
+		Auth:     true,
+		Response: favoriteResponse{},
+		Statuses: favoriteStatuses,
+	},
+	{
+		Method:   http.MethodDelete,
+		Path:     "/user/{id}/authors/{author_id}",
+		Id:       "deleteUserFavoriteAuthor",
+		Tag:      "user",
+		Summary:  "Remove the author from the favorites of the user",
+		Auth:     true,
+		Response: favoriteResponse{},
+		Statuses: favoriteStatuses,
+	},
+}
+
+var favoriteStatuses = map[int]string{
+	http.StatusOK:                  "Favorites changed",
+	http.StatusBadRequest:          "Invalid id",
+	http.StatusForbidden:           "Only the user and admins can change the favorites",
+	http.StatusInternalServerError: "DB error",
+}
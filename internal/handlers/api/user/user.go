@@ -1,27 +1,45 @@
 package user
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/qo/digital-library/internal/auth"
+	"github.com/qo/digital-library/internal/citation"
+	"github.com/qo/digital-library/internal/handlers/api/query"
 	"github.com/qo/digital-library/internal/logger"
 	"github.com/qo/digital-library/internal/storage/author"
 	"github.com/qo/digital-library/internal/storage/book"
 	"github.com/qo/digital-library/internal/storage/book_review"
+	"github.com/qo/digital-library/internal/storage/favorite_author"
+	"github.com/qo/digital-library/internal/storage/favorite_book"
 	"github.com/qo/digital-library/internal/storage/user"
 )
 
 type UserStorage interface {
-	PostUser(*user.User) error
-	GetUser(id int) (*user.User, error)
-	PutUser(user *user.User) error
-	DeleteUser(id int) error
-	GetUserFavoriteBooks(id int) ([]book.Book, error)
-	GetUserFavoriteAuthors(id int) ([]author.Author, error)
-	GetUserBookReviews(id int) ([]book_review.BookReview, error)
+	PostUser(context.Context, *user.User) error
+	GetUser(ctx context.Context, id int) (*user.User, error)
+	GetUsers(ctx context.Context, limit, offset int) ([]user.User, error)
+	PutRole(ctx context.Context, id, from, to int) error
+	PutUser(ctx context.Context, user *user.User) error
+	PutPasswordHash(ctx context.Context, id int, hash, keepTokenHash string) error
+	DeleteUser(ctx context.Context, id int) error
+	GetUserFavoriteBooks(ctx context.Context, id int, includeDeleted bool) ([]book.Book, error)
+	GetUserFavoriteAuthors(ctx context.Context, id int, includeDeleted bool) ([]author.Author, error)
+	GetUserBookReviews(ctx context.Context, id int, includeDeleted bool) ([]book_review.BookReview, error)
+	GetBookAuthors(ctx context.Context, bookId int) ([]author.Author, error)
+	GetFavoriteBook(ctx context.Context, userId, bookId int) (*favorite_book.FavoriteBook, error)
+	PostFavoriteBook(ctx context.Context, userId, bookId int) error
+	DeleteFavoriteBook(ctx context.Context, userId, bookId int) error
+	GetFavoriteAuthor(ctx context.Context, userId, authorId int) (*favorite_author.FavoriteAuthor, error)
+	PostFavoriteAuthor(ctx context.Context, userId, authorId int) error
+	DeleteFavoriteAuthor(ctx context.Context, userId, authorId int) error
 }
 
 type userHandler struct {
@@ -36,10 +54,21 @@ func New(log logger.Logger, us UserStorage) *userHandler {
 	}
 }
 
-type postRequest = user.User
+type postRequest struct {
+	// Id is assigned if it is not set
+	Id         int    `json:"id,omitempty"`
+	FirstName  string `json:"first_name"`
+	SecondName string `json:"second_name"`
+	// Role is user if it is not set, only admins can create mods
+	Role int `json:"role,omitempty"`
+	// Password is optional, users without a password can't log in
+	Password string `json:"password,omitempty"`
+}
 
 type postResponse struct {
 	Error string `json:"error,omitempty"`
+	// Id is the id of the created user, it is assigned if it's not specified
+	Id int `json:"id,omitempty"`
 }
 
 func (uh *userHandler) Post() http.HandlerFunc {
@@ -56,26 +85,58 @@ func (uh *userHandler) Post() http.HandlerFunc {
 			we.Encode(postResponse{
 				Error: "invalid request",
 			})
-			uh.Error(fmt.Sprintf("%s: request not parsed: %s", errMsg, err), "req", req)
+			uh.ErrorContext(r.Context(), fmt.Sprintf("%s: request not parsed: %s", errMsg, err), "user id", req.Id)
 			return
 		}
 
-		err = uh.PostUser(&req)
+		if req.Role == 0 {
+			req.Role = user.RoleUser
+		}
+
+		if !user.CanCreateRole(req.Role) {
+			w.WriteHeader(http.StatusBadRequest)
+			we.Encode(postResponse{
+				Error: "new users can only be users or mods",
+			})
+			uh.WarnContext(r.Context(), fmt.Sprintf("%s: invalid role", errMsg), "role", req.Role)
+			return
+		}
+
+		if req.Role != user.RoleUser && !auth.IsAdmin(r.Context()) {
+			w.WriteHeader(http.StatusForbidden)
+			we.Encode(postResponse{
+				Error: "only admins can create mods",
+			})
+			uh.WarnContext(r.Context(), fmt.Sprintf("%s: only admins can create mods", errMsg))
+			return
+		}
+
+		hash, ok := uh.hashPassword(w, r, req.Password, errMsg)
+		if !ok {
+			return
+		}
+
+		u := user.User{Id: req.Id, FirstName: req.FirstName, SecondName: req.SecondName, Role: req.Role}
+
+		err = uh.PostUser(r.Context(), &u)
+		if err == nil && hash != "" {
+			err = uh.PutPasswordHash(r.Context(), u.Id, hash, "")
+		}
 		// TODO: check type of error
 		if err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
 			we.Encode(postResponse{
 				Error: "db error",
 			})
-			uh.Error(fmt.Sprintf("%s: %s", errMsg, err))
+			uh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
 			return
 		}
 
-		uh.Debug("post user success")
+		uh.DebugContext(r.Context(), "post user success")
 
 		w.WriteHeader(http.StatusCreated)
 
-		we.Encode(postResponse{})
+		we.Encode(postResponse{Id: u.Id})
 	}
 }
 
@@ -97,22 +158,22 @@ func (uh *userHandler) Get() http.HandlerFunc {
 			we.Encode(getResponse{
 				Error: "user id is not a number",
 			})
-			uh.Error(fmt.Sprintf("%s: user id is not a number: %s", errMsg, err))
+			uh.ErrorContext(r.Context(), fmt.Sprintf("%s: user id is not a number: %s", errMsg, err))
 			return
 		}
 
-		user, err := uh.GetUser(id)
+		user, err := uh.GetUser(r.Context(), id)
 		// TODO: check type of error
 		if err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
 			we.Encode(getResponse{
 				Error: "db error",
 			})
-			uh.Error(fmt.Sprintf("%s: user with %d id doesn't exist: %s", errMsg, id, err))
+			uh.ErrorContext(r.Context(), fmt.Sprintf("%s: user with %d id doesn't exist: %s", errMsg, id, err))
 			return
 		}
 
-		uh.Debug("get user success", "user", user)
+		uh.DebugContext(r.Context(), "get user success", "user", user)
 
 		w.WriteHeader(http.StatusOK)
 
@@ -124,7 +185,58 @@ func (uh *userHandler) Get() http.HandlerFunc {
 	}
 }
 
-type putRequest = user.User
+type listResponse struct {
+	Error string      `json:"error,omitempty"`
+	Users []user.User `json:"users,omitempty"`
+}
+
+// List returns a page of the users ordered by id.
+func (uh *userHandler) List() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't list users"
+
+		we := json.NewEncoder(w)
+
+		limit, offset, err := query.Page(r)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			we.Encode(listResponse{
+				Error: err.Error(),
+			})
+			uh.WarnContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		users, err := uh.GetUsers(r.Context(), limit, offset)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			we.Encode(listResponse{
+				Error: "db error",
+			})
+			uh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		uh.DebugContext(r.Context(), "list users success", "users", len(users))
+
+		w.WriteHeader(http.StatusOK)
+
+		we.Encode(listResponse{
+			Users: users,
+		})
+	}
+}
+
+type putRequest struct {
+	Id         int    `json:"id"`
+	FirstName  string `json:"first_name"`
+	SecondName string `json:"second_name"`
+	// Role is changed only if it is set and differs,
+	// by admins and as PUT /user/{id}/role allows
+	Role int `json:"role,omitempty"`
+	// Password is changed only if it is set
+	Password string `json:"password,omitempty"`
+}
 
 type putResponse struct {
 	Error string `json:"error,omitempty"`
@@ -144,24 +256,90 @@ func (uh *userHandler) Put() http.HandlerFunc {
 			we.Encode(putResponse{
 				Error: "invalid request",
 			})
-			uh.Error(fmt.Sprintf("%s: request not parsed: %s", errMsg, err), "req", req)
+			uh.ErrorContext(r.Context(), fmt.Sprintf("%s: request not parsed: %s", errMsg, err), "user id", req.Id)
 			return
 		}
 
-		uh.Debug("request parsed", "req", req)
+		uh.DebugContext(r.Context(), "request parsed", "user id", req.Id, "role", req.Role)
 
-		err = uh.PutUser(&req)
-		// TODO: check type of error
+		if !auth.CanActAs(r.Context(), req.Id) {
+			w.WriteHeader(http.StatusForbidden)
+			we.Encode(putResponse{
+				Error: "only the user and admins can change the user",
+			})
+			uh.WarnContext(r.Context(), fmt.Sprintf("%s: only the user and admins can change the user", errMsg))
+			return
+		}
+
+		u, err := uh.GetUser(r.Context(), req.Id)
+		if errors.Is(err, sql.ErrNoRows) {
+			w.WriteHeader(http.StatusNotFound)
+			we.Encode(putResponse{
+				Error: "user not found",
+			})
+			uh.WarnContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			we.Encode(putResponse{
+				Error: "db error",
+			})
+			uh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		changeRole := req.Role != 0 && req.Role != u.Role
+
+		if changeRole && !auth.IsAdmin(r.Context()) {
+			w.WriteHeader(http.StatusForbidden)
+			we.Encode(putResponse{
+				Error: "only admins can change roles",
+			})
+			uh.WarnContext(r.Context(), fmt.Sprintf("%s: only admins can change roles", errMsg))
+			return
+		}
+
+		if changeRole && !user.CanChangeRole(u.Role, req.Role) {
+			w.WriteHeader(http.StatusBadRequest)
+			we.Encode(putResponse{
+				Error: fmt.Sprintf("role %d can't be changed to %d", u.Role, req.Role),
+			})
+			uh.WarnContext(r.Context(), fmt.Sprintf("%s: role transition is not allowed", errMsg), "from", u.Role, "to", req.Role)
+			return
+		}
+
+		hash, ok := uh.hashPassword(w, r, req.Password, errMsg)
+		if !ok {
+			return
+		}
+
+		err = uh.PutUser(r.Context(), &user.User{Id: req.Id, FirstName: req.FirstName, SecondName: req.SecondName})
+		if err == nil && hash != "" {
+			err = uh.PutPasswordHash(r.Context(), req.Id, hash, auth.SessionTokenHash(r.Context()))
+		}
+		if err == nil && changeRole {
+			// the role is changed only if nobody has changed it since it was read
+			err = uh.UserStorage.PutRole(r.Context(), req.Id, u.Role, req.Role)
+			if errors.Is(err, sql.ErrNoRows) {
+				w.WriteHeader(http.StatusConflict)
+				we.Encode(putResponse{
+					Error: "role was changed by someone else",
+				})
+				uh.WarnContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+				return
+			}
+		}
 		if err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
 			we.Encode(putResponse{
 				Error: "db error",
 			})
-			uh.Error(fmt.Sprintf("%s: %s", errMsg, err))
+			uh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
 			return
 		}
 
-		uh.Debug("put user success")
+		uh.DebugContext(r.Context(), "put user success")
 
 		w.WriteHeader(http.StatusOK)
 
@@ -169,16 +347,127 @@ func (uh *userHandler) Put() http.HandlerFunc {
 	}
 }
 
+type putRoleRequest struct {
+	Role int `json:"role"`
+}
+
+type putRoleResponse struct {
+	Error string `json:"error,omitempty"`
+}
+
+// PutRole changes the role of the user, only users and mods can swap their roles.
+// Only admins can change roles.
+func (uh *userHandler) PutRole() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't put role"
+
+		rd, we := json.NewDecoder(r.Body), json.NewEncoder(w)
+
+		if !auth.IsAdmin(r.Context()) {
+			w.WriteHeader(http.StatusForbidden)
+			we.Encode(putRoleResponse{
+				Error: "only admins can change roles",
+			})
+			uh.WarnContext(r.Context(), fmt.Sprintf("%s: only admins can change roles", errMsg))
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			we.Encode(putRoleResponse{
+				Error: "user id is not a number",
+			})
+			uh.ErrorContext(r.Context(), fmt.Sprintf("%s: user id is not a number: %s", errMsg, err))
+			return
+		}
+
+		var req putRoleRequest
+
+		err = rd.Decode(&req)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			we.Encode(putRoleResponse{
+				Error: "invalid request",
+			})
+			uh.ErrorContext(r.Context(), fmt.Sprintf("%s: request not parsed: %s", errMsg, err))
+			return
+		}
+
+		u, err := uh.GetUser(r.Context(), id)
+		if errors.Is(err, sql.ErrNoRows) {
+			w.WriteHeader(http.StatusNotFound)
+			we.Encode(putRoleResponse{
+				Error: "user not found",
+			})
+			uh.WarnContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			we.Encode(putRoleResponse{
+				Error: "db error",
+			})
+			uh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		if !user.CanChangeRole(u.Role, req.Role) {
+			w.WriteHeader(http.StatusBadRequest)
+			we.Encode(putRoleResponse{
+				Error: fmt.Sprintf("role %d can't be changed to %d", u.Role, req.Role),
+			})
+			uh.WarnContext(r.Context(), fmt.Sprintf("%s: role transition is not allowed", errMsg), "from", u.Role, "to", req.Role)
+			return
+		}
+
+		// the role is changed only if nobody has changed it since it was read
+		err = uh.UserStorage.PutRole(r.Context(), id, u.Role, req.Role)
+		if errors.Is(err, sql.ErrNoRows) {
+			w.WriteHeader(http.StatusConflict)
+			we.Encode(putRoleResponse{
+				Error: "role was changed by someone else",
+			})
+			uh.WarnContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			we.Encode(putRoleResponse{
+				Error: "db error",
+			})
+			uh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		uh.InfoContext(r.Context(), "role changed", "user id", id, "from", u.Role, "to", req.Role)
+
+		w.WriteHeader(http.StatusOK)
+
+		we.Encode(putRoleResponse{})
+	}
+}
+
 type deleteResponse struct {
 	Error string `json:"error,omitempty"`
 }
 
+// Delete deletes the user, only admins can delete users and admins are never deleted.
 func (uh *userHandler) Delete() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		const errMsg = "can't delete user"
 
 		we := json.NewEncoder(w)
 
+		if !auth.IsAdmin(r.Context()) {
+			w.WriteHeader(http.StatusForbidden)
+			we.Encode(deleteResponse{
+				Error: "only admins can delete users",
+			})
+			uh.WarnContext(r.Context(), fmt.Sprintf("%s: only admins can delete users", errMsg))
+			return
+		}
+
 		idParam := chi.URLParam(r, "id")
 		id, err := strconv.Atoi(idParam)
 		if err != nil {
@@ -186,22 +475,37 @@ func (uh *userHandler) Delete() http.HandlerFunc {
 			we.Encode(deleteResponse{
 				Error: "user id is not a number",
 			})
-			uh.Error(fmt.Sprintf("%s: user id is not a number: %s", errMsg, err))
+			uh.ErrorContext(r.Context(), fmt.Sprintf("%s: user id is not a number: %s", errMsg, err))
 			return
 		}
 
-		err = uh.DeleteUser(id)
-		// TODO: check type of error
+		err = uh.DeleteUser(r.Context(), id)
+		if errors.Is(err, sql.ErrNoRows) {
+			w.WriteHeader(http.StatusNotFound)
+			we.Encode(deleteResponse{
+				Error: "user not found",
+			})
+			uh.WarnContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+		if errors.Is(err, user.ErrAdmin) {
+			w.WriteHeader(http.StatusForbidden)
+			we.Encode(deleteResponse{
+				Error: "admins can't be deleted",
+			})
+			uh.WarnContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err), "user id", id)
+			return
+		}
 		if err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
 			we.Encode(deleteResponse{
 				Error: "db error",
 			})
-			uh.Error(fmt.Sprintf("%s: %s", errMsg, err))
+			uh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
 			return
 		}
 
-		uh.Debug("delete user success")
+		uh.DebugContext(r.Context(), "delete user success")
 
 		w.WriteHeader(http.StatusOK)
 
@@ -227,18 +531,37 @@ func (uh *userHandler) GetFavoriteBooks() http.HandlerFunc {
 			we.Encode(deleteResponse{
 				Error: "user id is not a number",
 			})
-			uh.Error(fmt.Sprintf("%s: user id is not a number: %s", errMsg, err))
+			uh.ErrorContext(r.Context(), fmt.Sprintf("%s: user id is not a number: %s", errMsg, err))
+			return
+		}
+
+		includeDeleted, err := query.IncludeDeleted(r)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			we.Encode(getFavoriteBooksResponse{
+				Error: "include_deleted is not a boolean",
+			})
+			uh.ErrorContext(r.Context(), fmt.Sprintf("%s: include_deleted is not a boolean: %s", errMsg, err))
+			return
+		}
+
+		if includeDeleted && !auth.IsAdmin(r.Context()) {
+			w.WriteHeader(http.StatusForbidden)
+			we.Encode(getFavoriteBooksResponse{
+				Error: "only admins can include deleted books",
+			})
+			uh.WarnContext(r.Context(), fmt.Sprintf("%s: only admins can include deleted books", errMsg))
 			return
 		}
 
-		books, err := uh.GetUserFavoriteBooks(id)
+		books, err := uh.GetUserFavoriteBooks(r.Context(), id, includeDeleted)
 		// TODO: check type of error
 		if err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
 			we.Encode(getFavoriteBooksResponse{
 				Error: "db error",
 			})
-			uh.Error(fmt.Sprintf("%s: %s", errMsg, err))
+			uh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
 			return
 		}
 
@@ -246,6 +569,327 @@ func (uh *userHandler) GetFavoriteBooks() http.HandlerFunc {
 		we.Encode(getFavoriteBooksResponse{
 			Books: books,
 		})
-		uh.Info("favorite books fetched")
+		uh.InfoContext(r.Context(), "favorite books fetched")
+	}
+}
+
+type getFavoriteAuthorsResponse struct {
+	Error   string `json:"error,omitempty"`
+	Authors []author.Author
+}
+
+func (uh *userHandler) GetFavoriteAuthors() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't get favorite authors"
+
+		we := json.NewEncoder(w)
+
+		idParam := chi.URLParam(r, "id")
+		id, err := strconv.Atoi(idParam)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			we.Encode(getFavoriteAuthorsResponse{
+				Error: "user id is not a number",
+			})
+			uh.ErrorContext(r.Context(), fmt.Sprintf("%s: user id is not a number: %s", errMsg, err))
+			return
+		}
+
+		includeDeleted, err := query.IncludeDeleted(r)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			we.Encode(getFavoriteAuthorsResponse{
+				Error: "include_deleted is not a boolean",
+			})
+			uh.ErrorContext(r.Context(), fmt.Sprintf("%s: include_deleted is not a boolean: %s", errMsg, err))
+			return
+		}
+
+		if includeDeleted && !auth.IsAdmin(r.Context()) {
+			w.WriteHeader(http.StatusForbidden)
+			we.Encode(getFavoriteAuthorsResponse{
+				Error: "only admins can include deleted authors",
+			})
+			uh.WarnContext(r.Context(), fmt.Sprintf("%s: only admins can include deleted authors", errMsg))
+			return
+		}
+
+		authors, err := uh.GetUserFavoriteAuthors(r.Context(), id, includeDeleted)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			we.Encode(getFavoriteAuthorsResponse{
+				Error: "db error",
+			})
+			uh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		we.Encode(getFavoriteAuthorsResponse{
+			Authors: authors,
+		})
+		uh.InfoContext(r.Context(), "favorite authors fetched")
+	}
+}
+
+type favoriteResponse struct {
+	Error string `json:"error,omitempty"`
+}
+
+// favorite is a kind of favorites: books or authors.
+type favorite struct {
+	name   string
+	param  string
+	get    func(ctx context.Context, userId, id int) error
+	post   func(ctx context.Context, userId, id int) error
+	delete func(ctx context.Context, userId, id int) error
+}
+
+func (uh *userHandler) favoriteBooks() favorite {
+	return favorite{
+		name:  "book",
+		param: "book_id",
+		get: func(ctx context.Context, userId, id int) error {
+			_, err := uh.GetFavoriteBook(ctx, userId, id)
+			return err
+		},
+		post:   uh.PostFavoriteBook,
+		delete: uh.UserStorage.DeleteFavoriteBook,
+	}
+}
+
+func (uh *userHandler) favoriteAuthors() favorite {
+	return favorite{
+		name:  "author",
+		param: "author_id",
+		get: func(ctx context.Context, userId, id int) error {
+			_, err := uh.GetFavoriteAuthor(ctx, userId, id)
+			return err
+		},
+		post:   uh.PostFavoriteAuthor,
+		delete: uh.UserStorage.DeleteFavoriteAuthor,
+	}
+}
+
+// PutFavoriteBook adds the book to the favorites of the user.
+func (uh *userHandler) PutFavoriteBook() http.HandlerFunc {
+	return uh.putFavorite(uh.favoriteBooks())
+}
+
+// DeleteFavoriteBook removes the book from the favorites of the user.
+func (uh *userHandler) DeleteFavoriteBook() http.HandlerFunc {
+	return uh.deleteFavorite(uh.favoriteBooks())
+}
+
+// PutFavoriteAuthor adds the author to the favorites of the user.
+func (uh *userHandler) PutFavoriteAuthor() http.HandlerFunc {
+	return uh.putFavorite(uh.favoriteAuthors())
+}
+
+// DeleteFavoriteAuthor removes the author from the favorites of the user.
+func (uh *userHandler) DeleteFavoriteAuthor() http.HandlerFunc {
+	return uh.deleteFavorite(uh.favoriteAuthors())
+}
+
+// putFavorite adds to the favorites, adding a favorite twice is not an error.
+func (uh *userHandler) putFavorite(f favorite) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		errMsg := fmt.Sprintf("can't add favorite %s", f.name)
+
+		userId, id, ok := uh.favoriteIds(w, r, f, errMsg)
+		if !ok {
+			return
+		}
+
+		err := f.get(r.Context(), userId, id)
+		if errors.Is(err, sql.ErrNoRows) {
+			err = f.post(r.Context(), userId, id)
+		}
+		// TODO: check type of error
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(favoriteResponse{
+				Error: "db error",
+			})
+			uh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		uh.InfoContext(r.Context(), fmt.Sprintf("favorite %s added", f.name), "user id", userId, "id", id)
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(favoriteResponse{})
+	}
+}
+
+func (uh *userHandler) deleteFavorite(f favorite) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		errMsg := fmt.Sprintf("can't remove favorite %s", f.name)
+
+		userId, id, ok := uh.favoriteIds(w, r, f, errMsg)
+		if !ok {
+			return
+		}
+
+		err := f.delete(r.Context(), userId, id)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(favoriteResponse{
+				Error: "db error",
+			})
+			uh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		uh.InfoContext(r.Context(), fmt.Sprintf("favorite %s removed", f.name), "user id", userId, "id", id)
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(favoriteResponse{})
+	}
+}
+
+// favoriteIds returns the user id and the id of the favorite.
+// Only the user and admins can change the favorites.
+// It writes the error if the ids are invalid or the favorites can't be changed.
+func (uh *userHandler) favoriteIds(w http.ResponseWriter, r *http.Request, f favorite, errMsg string) (int, int, bool) {
+	we := json.NewEncoder(w)
+
+	userId, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		we.Encode(favoriteResponse{
+			Error: "user id is not a number",
+		})
+		uh.ErrorContext(r.Context(), fmt.Sprintf("%s: user id is not a number: %s", errMsg, err))
+		return 0, 0, false
 	}
+
+	id, err := strconv.Atoi(chi.URLParam(r, f.param))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		we.Encode(favoriteResponse{
+			Error: fmt.Sprintf("%s id is not a number", f.name),
+		})
+		uh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s id is not a number: %s", errMsg, f.name, err))
+		return 0, 0, false
+	}
+
+	if !auth.CanActAs(r.Context(), userId) {
+		w.WriteHeader(http.StatusForbidden)
+		we.Encode(favoriteResponse{
+			Error: "only the user and admins can change the favorites",
+		})
+		uh.WarnContext(r.Context(), fmt.Sprintf("%s: only the user and admins can change the favorites", errMsg))
+		return 0, 0, false
+	}
+
+	return userId, id, true
+}
+
+type citeFavoriteBooksResponse struct {
+	Error string `json:"error,omitempty"`
+}
+
+func (uh *userHandler) CiteFavoriteBooks() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't cite favorite books"
+
+		we := json.NewEncoder(w)
+
+		idParam := chi.URLParam(r, "id")
+		id, err := strconv.Atoi(idParam)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			we.Encode(citeFavoriteBooksResponse{
+				Error: "user id is not a number",
+			})
+			uh.ErrorContext(r.Context(), fmt.Sprintf("%s: user id is not a number: %s", errMsg, err))
+			return
+		}
+
+		formatParam := r.URL.Query().Get("format")
+		if formatParam == "" {
+			formatParam = string(citation.FormatBibTeX)
+		}
+
+		format, err := citation.ParseFormat(formatParam)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			we.Encode(citeFavoriteBooksResponse{
+				Error: "unknown citation format",
+			})
+			uh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		books, err := uh.GetUserFavoriteBooks(r.Context(), id, false)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			we.Encode(citeFavoriteBooksResponse{
+				Error: "db error",
+			})
+			uh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		items := make([]citation.Item, 0, len(books))
+
+		for _, b := range books {
+			authors, err := uh.GetBookAuthors(r.Context(), b.Id)
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				we.Encode(citeFavoriteBooksResponse{
+					Error: "db error",
+				})
+				uh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+				return
+			}
+			items = append(items, citation.Item{Book: b, Authors: authors})
+		}
+
+		w.Header().Set("Content-Type", format.ContentType())
+		w.WriteHeader(http.StatusOK)
+
+		err = citation.Write(w, format, items)
+		if err != nil {
+			uh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		uh.DebugContext(r.Context(), "cite favorite books success", "format", format, "books", len(items))
+	}
+}
+
+type passwordResponse struct {
+	Error string `json:"error,omitempty"`
+}
+
+// hashPassword returns the hash of the password or an empty hash if there is no password.
+// It writes the error if the password can't be used.
+func (uh *userHandler) hashPassword(w http.ResponseWriter, r *http.Request, password, errMsg string) (string, bool) {
+	if password == "" {
+		return "", true
+	}
+
+	we := json.NewEncoder(w)
+
+	hash, err := auth.HashPassword(password)
+	if errors.Is(err, auth.ErrShortPassword) {
+		w.WriteHeader(http.StatusBadRequest)
+		we.Encode(passwordResponse{
+			Error: err.Error(),
+		})
+		uh.WarnContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+		return "", false
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		we.Encode(passwordResponse{
+			Error: "can't hash password",
+		})
+		uh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+		return "", false
+	}
+
+	return hash, true
 }
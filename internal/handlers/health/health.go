@@ -0,0 +1,123 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/qo/digital-library/internal/buildinfo"
+	"github.com/qo/digital-library/internal/logger"
+)
+
+// checkTimeout limits how long the readiness checks take,
+// so a stuck db fails the probe instead of hanging it.
+const checkTimeout = 2 * time.Second
+
+type healthStorage interface {
+	Ping(ctx context.Context) error
+	CheckSchema(ctx context.Context) error
+	Dialect() string
+}
+
+type fileStorage interface {
+	CheckWritable() error
+}
+
+type healthHandler struct {
+	logger.Logger
+	healthStorage
+	files fileStorage
+}
+
+func New(log logger.Logger, hs healthStorage, fs fileStorage) *healthHandler {
+	return &healthHandler{
+		log,
+		hs,
+		fs,
+	}
+}
+
+type healthzResponse struct {
+	Status string `json:"status"`
+}
+
+// Healthz responds with 200 as long as the process serves requests.
+func (hh *healthHandler) Healthz() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(healthzResponse{
+			Status: "ok",
+		})
+	}
+}
+
+type readyzResponse struct {
+	Status string `json:"status"`
+	// Checks are the results of the checks by name, ok or the error
+	Checks map[string]string `json:"checks"`
+}
+
+// Readyz responds with 200 if the server can serve the requests:
+// the db is reachable, its schema is the one the server expects and the blob store is writable.
+// Otherwise it responds with 503 and the failed checks.
+func (hh *healthHandler) Readyz() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "server is not ready"
+
+		ctx, cancel := context.WithTimeout(r.Context(), checkTimeout)
+		defer cancel()
+
+		resp := readyzResponse{
+			Status: "ok",
+			Checks: make(map[string]string),
+		}
+
+		for _, c := range []struct {
+			name  string
+			check func() error
+		}{
+			{"db", func() error { return hh.Ping(ctx) }},
+			{"schema", func() error { return hh.CheckSchema(ctx) }},
+			{"blob", hh.files.CheckWritable},
+		} {
+			err := c.check()
+			if err != nil {
+				resp.Status = "unavailable"
+				resp.Checks[c.name] = err.Error()
+				hh.WarnContext(r.Context(), fmt.Sprintf("%s: %s check failed: %s", errMsg, c.name, err))
+				continue
+			}
+			resp.Checks[c.name] = "ok"
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+
+		if resp.Status != "ok" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		} else {
+			w.WriteHeader(http.StatusOK)
+		}
+
+		json.NewEncoder(w).Encode(resp)
+	}
+}
+
+type versionResponse struct {
+	buildinfo.Info
+	Dialect string `json:"dialect"`
+}
+
+// Version returns the info of the build of the server and the configured db dialect.
+func (hh *healthHandler) Version() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(versionResponse{
+			Info:    buildinfo.Get(),
+			Dialect: hh.Dialect(),
+		})
+	}
+}
@@ -0,0 +1,459 @@
+package opds
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/qo/digital-library/internal/auth"
+	"github.com/qo/digital-library/internal/blob"
+	"github.com/qo/digital-library/internal/handlers/api/query"
+	"github.com/qo/digital-library/internal/logger"
+	"github.com/qo/digital-library/internal/opds"
+	"github.com/qo/digital-library/internal/storage/author"
+	"github.com/qo/digital-library/internal/storage/book"
+	"github.com/qo/digital-library/internal/storage/user"
+)
+
+const (
+	// basePath is the path the feeds are mounted on
+	basePath = "/opds"
+
+	internalServerErrorCode = http.StatusInternalServerError
+)
+
+type opdsStorage interface {
+	GetNewBooks(ctx context.Context, limit, offset int) ([]book.Book, error)
+	GetAuthors(ctx context.Context) ([]author.Author, error)
+	GetAuthor(ctx context.Context, id int, includeDeleted bool) (*author.Author, error)
+	GetAuthorBooks(ctx context.Context, authorId int) ([]book.Book, error)
+	GetPublishers(ctx context.Context) ([]string, error)
+	GetPublisherBooks(ctx context.Context, publisher string, limit, offset int) ([]book.Book, error)
+	SearchBooks(ctx context.Context, query string, limit, offset int) ([]book.Book, error)
+	GetUser(ctx context.Context, id int) (*user.User, error)
+	GetUserFavoriteBooks(ctx context.Context, id int, includeDeleted bool) ([]book.Book, error)
+	GetBookAuthors(ctx context.Context, bookId int) ([]author.Author, error)
+}
+
+type fileStorage interface {
+	Exists(key string) bool
+}
+
+type opdsHandler struct {
+	logger.Logger
+	opdsStorage
+	files fileStorage
+}
+
+func New(log logger.Logger, st opdsStorage, fs fileStorage) *opdsHandler {
+	return &opdsHandler{
+		log,
+		st,
+		fs,
+	}
+}
+
+func authorHref(a author.Author) string {
+	return fmt.Sprintf("%s/authors/%d", basePath, a.Id)
+}
+
+func publisherHref(publisher string) string {
+	return fmt.Sprintf("%s/publishers/%s", basePath, url.PathEscape(publisher))
+}
+
+func (oh *opdsHandler) write(w http.ResponseWriter, r *http.Request, feed *opds.Feed, errMsg string) {
+	w.Header().Set("Content-Type", feed.Type())
+	w.WriteHeader(http.StatusOK)
+
+	err := feed.Write(w)
+	if err != nil {
+		oh.ErrorContext(r.Context(), fmt.Sprintf("%s: can't write feed: %s", errMsg, err))
+		return
+	}
+
+	oh.DebugContext(r.Context(), "opds feed written", "id", feed.Id)
+}
+
+// addBooks adds the entries of the books to the feed.
+func (oh *opdsHandler) addBooks(ctx context.Context, feed *opds.Feed, books []book.Book) error {
+	for _, b := range books {
+		authors, err := oh.GetBookAuthors(ctx, b.Id)
+		if err != nil {
+			return err
+		}
+
+		fileHref := ""
+		if oh.files.Exists(blob.BookKey(b.Id)) {
+			fileHref = fmt.Sprintf("/api/book/%d/file", b.Id)
+		}
+
+		feed.AddBook(b, authors, authorHref, fileHref)
+	}
+	return nil
+}
+
+// addPagination adds the links to the neighbour pages.
+// The next page is linked only if the current one is full.
+func addPagination(feed *opds.Feed, self *url.URL, limit, offset, count int) {
+	prev, next := query.PageLinks(self, limit, offset, count)
+	if prev != "" {
+		feed.AddLink(opds.RelPrevious, prev, opds.AcquisitionType)
+	}
+	if next != "" {
+		feed.AddLink(opds.RelNext, next, opds.AcquisitionType)
+	}
+}
+
+func (oh *opdsHandler) Root() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't get opds root"
+
+		feed := opds.NewNavigationFeed("urn:digital-library:root", "Digital Library", time.Now())
+		feed.AddLink(opds.RelSelf, basePath, opds.NavigationType)
+		feed.AddLink(opds.RelStart, basePath, opds.NavigationType)
+		feed.AddLink(opds.RelSearch, basePath+"/opensearch.xml", opds.OpenSearchType)
+
+		feed.AddNavigation("urn:digital-library:new", "New books", basePath+"/new", opds.AcquisitionType, "Recently added books")
+		feed.AddNavigation("urn:digital-library:authors", "By author", basePath+"/authors", opds.NavigationType, "Books grouped by author")
+		feed.AddNavigation("urn:digital-library:publishers", "By publisher", basePath+"/publishers", opds.NavigationType, "Books grouped by publisher")
+
+		if p, ok := auth.FromContext(r.Context()); ok && p.UserId != 0 {
+			feed.AddNavigation(
+				fmt.Sprintf("urn:digital-library:user:%d:favorites", p.UserId),
+				"My favorites",
+				fmt.Sprintf("%s/user/%d/favorites", basePath, p.UserId),
+				opds.AcquisitionType,
+				"Books marked as favorite",
+			)
+		}
+
+		oh.write(w, r, feed, errMsg)
+	}
+}
+
+func (oh *opdsHandler) NewBooks() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't get new books feed"
+
+		limit, offset, err := query.Page(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			oh.WarnContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		books, err := oh.GetNewBooks(r.Context(), limit, offset)
+		if err != nil {
+			const msg = "db error"
+			http.Error(w, msg, internalServerErrorCode)
+			oh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		feed := opds.NewAcquisitionFeed("urn:digital-library:new", "New books", time.Now())
+		feed.AddLink(opds.RelSelf, r.URL.RequestURI(), opds.AcquisitionType)
+		feed.AddLink(opds.RelStart, basePath, opds.NavigationType)
+		feed.AddLink(opds.RelUp, basePath, opds.NavigationType)
+		addPagination(feed, r.URL, limit, offset, len(books))
+
+		err = oh.addBooks(r.Context(), feed, books)
+		if err != nil {
+			const msg = "db error"
+			http.Error(w, msg, internalServerErrorCode)
+			oh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		oh.write(w, r, feed, errMsg)
+	}
+}
+
+func (oh *opdsHandler) Authors() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't get authors feed"
+
+		authors, err := oh.GetAuthors(r.Context())
+		if err != nil {
+			const msg = "db error"
+			http.Error(w, msg, internalServerErrorCode)
+			oh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		feed := opds.NewNavigationFeed("urn:digital-library:authors", "By author", time.Now())
+		feed.AddLink(opds.RelSelf, basePath+"/authors", opds.NavigationType)
+		feed.AddLink(opds.RelStart, basePath, opds.NavigationType)
+		feed.AddLink(opds.RelUp, basePath, opds.NavigationType)
+
+		for _, a := range authors {
+			feed.AddNavigation(
+				fmt.Sprintf("urn:digital-library:author:%d", a.Id),
+				a.FullName,
+				authorHref(a),
+				opds.AcquisitionType,
+				fmt.Sprintf("Books by %s", a.FullName),
+			)
+		}
+
+		oh.write(w, r, feed, errMsg)
+	}
+}
+
+func (oh *opdsHandler) Author() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't get author feed"
+
+		idParam := chi.URLParam(r, "id")
+		id, err := strconv.Atoi(idParam)
+		if err != nil {
+			const msg = "author id is not a number"
+			http.Error(w, msg, http.StatusBadRequest)
+			oh.WarnContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, msg), "err", err)
+			return
+		}
+
+		a, err := oh.GetAuthor(r.Context(), id, false)
+		if errors.Is(err, sql.ErrNoRows) {
+			const msg = "author not found"
+			http.Error(w, msg, http.StatusNotFound)
+			oh.WarnContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, msg), "err", err)
+			return
+		}
+		if err != nil {
+			const msg = "db error"
+			http.Error(w, msg, internalServerErrorCode)
+			oh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		books, err := oh.GetAuthorBooks(r.Context(), id)
+		if err != nil {
+			const msg = "db error"
+			http.Error(w, msg, internalServerErrorCode)
+			oh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		feed := opds.NewAcquisitionFeed(fmt.Sprintf("urn:digital-library:author:%d", a.Id), a.FullName, time.Now())
+		feed.AddLink(opds.RelSelf, authorHref(*a), opds.AcquisitionType)
+		feed.AddLink(opds.RelStart, basePath, opds.NavigationType)
+		feed.AddLink(opds.RelUp, basePath+"/authors", opds.NavigationType)
+
+		err = oh.addBooks(r.Context(), feed, books)
+		if err != nil {
+			const msg = "db error"
+			http.Error(w, msg, internalServerErrorCode)
+			oh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		oh.write(w, r, feed, errMsg)
+	}
+}
+
+func (oh *opdsHandler) Publishers() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't get publishers feed"
+
+		publishers, err := oh.GetPublishers(r.Context())
+		if err != nil {
+			const msg = "db error"
+			http.Error(w, msg, internalServerErrorCode)
+			oh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		feed := opds.NewNavigationFeed("urn:digital-library:publishers", "By publisher", time.Now())
+		feed.AddLink(opds.RelSelf, basePath+"/publishers", opds.NavigationType)
+		feed.AddLink(opds.RelStart, basePath, opds.NavigationType)
+		feed.AddLink(opds.RelUp, basePath, opds.NavigationType)
+
+		for _, p := range publishers {
+			feed.AddNavigation(
+				"urn:digital-library:publisher:"+url.PathEscape(p),
+				p,
+				publisherHref(p),
+				opds.AcquisitionType,
+				fmt.Sprintf("Books published by %s", p),
+			)
+		}
+
+		oh.write(w, r, feed, errMsg)
+	}
+}
+
+func (oh *opdsHandler) Publisher() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't get publisher feed"
+
+		publisher, err := url.PathUnescape(chi.URLParam(r, "publisher"))
+		if err != nil {
+			const msg = "publisher is malformed"
+			http.Error(w, msg, http.StatusBadRequest)
+			oh.WarnContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, msg), "err", err)
+			return
+		}
+
+		limit, offset, err := query.Page(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			oh.WarnContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		books, err := oh.GetPublisherBooks(r.Context(), publisher, limit, offset)
+		if err != nil {
+			const msg = "db error"
+			http.Error(w, msg, internalServerErrorCode)
+			oh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		feed := opds.NewAcquisitionFeed("urn:digital-library:publisher:"+url.PathEscape(publisher), publisher, time.Now())
+		feed.AddLink(opds.RelSelf, r.URL.RequestURI(), opds.AcquisitionType)
+		feed.AddLink(opds.RelStart, basePath, opds.NavigationType)
+		feed.AddLink(opds.RelUp, basePath+"/publishers", opds.NavigationType)
+		addPagination(feed, r.URL, limit, offset, len(books))
+
+		err = oh.addBooks(r.Context(), feed, books)
+		if err != nil {
+			const msg = "db error"
+			http.Error(w, msg, internalServerErrorCode)
+			oh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		oh.write(w, r, feed, errMsg)
+	}
+}
+
+func (oh *opdsHandler) Favorites() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't get favorites feed"
+
+		idParam := chi.URLParam(r, "id")
+		id, err := strconv.Atoi(idParam)
+		if err != nil {
+			const msg = "user id is not a number"
+			http.Error(w, msg, http.StatusBadRequest)
+			oh.WarnContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, msg), "err", err)
+			return
+		}
+
+		if !auth.CanActAs(r.Context(), id) {
+			const msg = "only the user and admins can see the favorites"
+			http.Error(w, msg, http.StatusForbidden)
+			oh.WarnContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, msg))
+			return
+		}
+
+		u, err := oh.GetUser(r.Context(), id)
+		if errors.Is(err, sql.ErrNoRows) {
+			const msg = "user not found"
+			http.Error(w, msg, http.StatusNotFound)
+			oh.WarnContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, msg), "err", err)
+			return
+		}
+		if err != nil {
+			const msg = "db error"
+			http.Error(w, msg, internalServerErrorCode)
+			oh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		books, err := oh.GetUserFavoriteBooks(r.Context(), id, false)
+		if err != nil {
+			const msg = "db error"
+			http.Error(w, msg, internalServerErrorCode)
+			oh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		feed := opds.NewAcquisitionFeed(
+			fmt.Sprintf("urn:digital-library:user:%d:favorites", u.Id),
+			fmt.Sprintf("Favorites of %s %s", u.FirstName, u.SecondName),
+			time.Now(),
+		)
+		feed.AddLink(opds.RelSelf, r.URL.RequestURI(), opds.AcquisitionType)
+		feed.AddLink(opds.RelStart, basePath, opds.NavigationType)
+		feed.AddLink(opds.RelUp, basePath, opds.NavigationType)
+
+		err = oh.addBooks(r.Context(), feed, books)
+		if err != nil {
+			const msg = "db error"
+			http.Error(w, msg, internalServerErrorCode)
+			oh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		oh.write(w, r, feed, errMsg)
+	}
+}
+
+func (oh *opdsHandler) Search() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't get search feed"
+
+		q := r.URL.Query().Get("q")
+
+		limit, offset, err := query.Page(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			oh.WarnContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		books, err := oh.SearchBooks(r.Context(), q, limit, offset)
+		if err != nil {
+			const msg = "db error"
+			http.Error(w, msg, internalServerErrorCode)
+			oh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		feed := opds.NewAcquisitionFeed("urn:digital-library:search:"+url.QueryEscape(q), fmt.Sprintf("Search: %s", q), time.Now())
+		feed.AddLink(opds.RelSelf, r.URL.RequestURI(), opds.AcquisitionType)
+		feed.AddLink(opds.RelStart, basePath, opds.NavigationType)
+		feed.AddLink(opds.RelUp, basePath, opds.NavigationType)
+		addPagination(feed, r.URL, limit, offset, len(books))
+
+		err = oh.addBooks(r.Context(), feed, books)
+		if err != nil {
+			const msg = "db error"
+			http.Error(w, msg, internalServerErrorCode)
+			oh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		oh.write(w, r, feed, errMsg)
+	}
+}
+
+func (oh *opdsHandler) OpenSearch() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't get opensearch description"
+
+		d := opds.NewOpenSearchDescription(
+			"Digital Library",
+			"Search books by title, ISBN, publisher or author",
+			basePath+"/search?q={searchTerms}",
+		)
+
+		w.Header().Set("Content-Type", opds.OpenSearchType)
+		w.WriteHeader(http.StatusOK)
+
+		err := d.Write(w)
+		if err != nil {
+			oh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		oh.DebugContext(r.Context(), "opensearch description written")
+	}
+}
@@ -0,0 +1,89 @@
+package admin
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/qo/digital-library/internal/handlers/view/render"
+	"github.com/qo/digital-library/internal/logger"
+	"github.com/qo/digital-library/internal/storage"
+	"github.com/qo/digital-library/internal/storage/book"
+	"github.com/qo/digital-library/internal/storage/review_report"
+	"github.com/qo/digital-library/internal/storage/user"
+)
+
+const (
+	internalServerErrorCode = http.StatusInternalServerError
+)
+
+type adminStorage interface {
+	Begin() (*storage.Tx, error)
+	GetUsers(ctx context.Context, limit, offset int) ([]user.User, error)
+	GetUser(ctx context.Context, id int) (*user.User, error)
+	PostUser(context.Context, *user.User) error
+	PutRole(ctx context.Context, id, from, to int) error
+	PutPasswordHash(ctx context.Context, id int, hash, keepTokenHash string) error
+	DeleteUser(ctx context.Context, id int) error
+	GetBooks(ctx context.Context, limit, offset int) ([]book.Book, error)
+	GetBook(ctx context.Context, id int, includeDeleted bool) (*book.Book, error)
+	DeleteBook(ctx context.Context, id int) error
+	GetReviewReport(ctx context.Context, id int) (*review_report.ReviewReport, error)
+	GetReviewReportQueue(ctx context.Context) ([]review_report.QueueEntry, error)
+	ResolveReviewReport(ctx context.Context, id int) error
+	ResolveReviewReports(ctx context.Context, userId, bookId int) error
+	DeleteBookReview(ctx context.Context, userId, bookId int) error
+}
+
+type fileStorage interface {
+	Put(key string, r io.Reader) (int64, error)
+	Exists(key string) bool
+}
+
+type adminHandler struct {
+	logger.Logger
+	*render.Renderer
+	adminStorage
+	files fileStorage
+}
+
+func New(log logger.Logger, rd *render.Renderer, st adminStorage, fs fileStorage) *adminHandler {
+	return &adminHandler{
+		log,
+		rd,
+		st,
+		fs,
+	}
+}
+
+func (ah *adminHandler) Index() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't get admin console"
+
+		err := ah.Render(w, r, "admin/index.tmpl", nil)
+		if err != nil {
+			const msg = "can't render page"
+			http.Error(w, msg, internalServerErrorCode)
+			ah.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, msg), "err", err)
+			return
+		}
+
+		ah.InfoContext(r.Context(), "admin console rendered")
+	}
+}
+
+// id returns the url param as a number
+// or writes the error if it is not a number.
+func (ah *adminHandler) id(w http.ResponseWriter, r *http.Request, param, errMsg string) (int, bool) {
+	id, err := strconv.Atoi(chi.URLParam(r, param))
+	if err != nil {
+		msg := fmt.Sprintf("%s is not a number", param)
+		http.Error(w, msg, http.StatusBadRequest)
+		ah.WarnContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, msg), "err", err)
+		return 0, false
+	}
+	return id, true
+}
@@ -0,0 +1,211 @@
+package admin
+
+import (
+	"bufio"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/qo/digital-library/internal/blob"
+	"github.com/qo/digital-library/internal/catalog"
+	"github.com/qo/digital-library/internal/handlers/api/query"
+	"github.com/qo/digital-library/internal/handlers/view/form"
+	"github.com/qo/digital-library/internal/handlers/view/render"
+	"github.com/qo/digital-library/internal/storage/book"
+)
+
+type bookRow struct {
+	book.Book
+	HasFile bool
+}
+
+type booksPage struct {
+	Books []bookRow
+	render.Pagination
+}
+
+func (ah *adminHandler) Books() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't get books"
+
+		limit, offset, err := query.Page(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			ah.WarnContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		books, err := ah.GetBooks(r.Context(), limit, offset)
+		if err != nil {
+			const msg = "db error"
+			http.Error(w, msg, internalServerErrorCode)
+			ah.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, msg), "err", err)
+			return
+		}
+
+		rows := make([]bookRow, 0, len(books))
+		for _, b := range books {
+			rows = append(rows, bookRow{b, ah.files.Exists(blob.BookKey(b.Id))})
+		}
+
+		err = ah.Render(w, r, "admin/books.tmpl", booksPage{
+			rows,
+			render.Paginate(r.URL, limit, offset, len(books)),
+		})
+		if err != nil {
+			const msg = "can't render page"
+			http.Error(w, msg, internalServerErrorCode)
+			ah.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, msg), "err", err)
+			return
+		}
+
+		ah.InfoContext(r.Context(), "admin books view rendered")
+	}
+}
+
+// PostBook creates the book with its authors and uploads its file if one is attached.
+func (ah *adminHandler) PostBook() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't post book"
+
+		const target = "/admin/books"
+
+		rec := catalog.Record{
+			Book: book.Book{
+				Isbn:      strings.TrimSpace(r.PostFormValue("isbn")),
+				Title:     r.PostFormValue("title"),
+				Publisher: strings.TrimSpace(r.PostFormValue("publisher")),
+			},
+			Authors: strings.Split(r.PostFormValue("authors"), ";"),
+		}
+
+		if year := strings.TrimSpace(r.PostFormValue("year")); year != "" {
+			var err error
+			rec.Year, err = strconv.Atoi(year)
+			if err != nil {
+				form.RedirectError(w, r, target, "Year is not a number")
+				ah.WarnContext(r.Context(), fmt.Sprintf("%s: year is not a number", errMsg), "err", err)
+				return
+			}
+		}
+
+		file, _, err := r.FormFile("file")
+		hasFile := err == nil
+		if err != nil && !errors.Is(err, http.ErrMissingFile) {
+			form.RedirectError(w, r, target, "Book file couldn't be read")
+			ah.WarnContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		var body *bufio.Reader
+		if hasFile {
+			defer file.Close()
+			body = bufio.NewReader(file)
+			if !blob.IsPDF(body) {
+				form.RedirectError(w, r, target, "Book file should be pdf")
+				ah.WarnContext(r.Context(), fmt.Sprintf("%s: book file is not pdf", errMsg))
+				return
+			}
+		}
+
+		id, err := catalog.Add(ah, rec)
+		if err != nil {
+			form.RedirectError(w, r, target, fmt.Sprintf("Book couldn't be created: %s", err))
+			ah.WarnContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		if hasFile {
+			_, err = ah.files.Put(blob.BookKey(id), body)
+			if err != nil {
+				form.RedirectError(w, r, target, fmt.Sprintf("Book %d created but its file couldn't be saved, upload it again", id))
+				ah.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+				return
+			}
+		}
+
+		form.Redirect(w, r, target, fmt.Sprintf("Book %d created", id))
+
+		ah.InfoContext(r.Context(), "book created", "book id", id, "file", hasFile)
+	}
+}
+
+// PutBookFile uploads the file of the book replacing the old one.
+func (ah *adminHandler) PutBookFile() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't put book file"
+
+		const target = "/admin/books"
+
+		id, ok := ah.id(w, r, "id", errMsg)
+		if !ok {
+			return
+		}
+
+		_, err := ah.GetBook(r.Context(), id, false)
+		if errors.Is(err, sql.ErrNoRows) {
+			form.RedirectError(w, r, target, "Book not found")
+			ah.WarnContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+		if err != nil {
+			form.RedirectError(w, r, target, "Book file couldn't be saved, try again later")
+			ah.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			form.RedirectError(w, r, target, "Choose a book file")
+			ah.WarnContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+		defer file.Close()
+
+		body := bufio.NewReader(file)
+		if !blob.IsPDF(body) {
+			form.RedirectError(w, r, target, "Book file should be pdf")
+			ah.WarnContext(r.Context(), fmt.Sprintf("%s: book file is not pdf", errMsg))
+			return
+		}
+
+		size, err := ah.files.Put(blob.BookKey(id), body)
+		if err != nil {
+			form.RedirectError(w, r, target, "Book file couldn't be saved, try again later")
+			ah.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		form.Redirect(w, r, target, fmt.Sprintf("File of book %d uploaded", id))
+
+		ah.InfoContext(r.Context(), "book file uploaded", "book id", id, "size", size)
+	}
+}
+
+// DeleteBook soft deletes the book.
+func (ah *adminHandler) DeleteBook() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't delete book"
+
+		const target = "/admin/books"
+
+		id, ok := ah.id(w, r, "id", errMsg)
+		if !ok {
+			return
+		}
+
+		err := ah.adminStorage.DeleteBook(r.Context(), id)
+		if err != nil {
+			form.RedirectError(w, r, target, "Book couldn't be deleted, try again later")
+			ah.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		form.Redirect(w, r, target, fmt.Sprintf("Book %d deleted, it can be restored until it is purged", id))
+
+		ah.InfoContext(r.Context(), "book deleted", "book id", id)
+	}
+}
@@ -0,0 +1,106 @@
+package admin
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/qo/digital-library/internal/handlers/view/form"
+	"github.com/qo/digital-library/internal/storage/review_report"
+)
+
+type reportsPage struct {
+	Reports []review_report.QueueEntry
+}
+
+// Reports renders the moderation queue of the reported reviews.
+func (ah *adminHandler) Reports() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't get review reports"
+
+		queue, err := ah.GetReviewReportQueue(r.Context())
+		if err != nil {
+			const msg = "db error"
+			http.Error(w, msg, internalServerErrorCode)
+			ah.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, msg), "err", err)
+			return
+		}
+
+		err = ah.Render(w, r, "admin/reports.tmpl", reportsPage{queue})
+		if err != nil {
+			const msg = "can't render page"
+			http.Error(w, msg, internalServerErrorCode)
+			ah.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, msg), "err", err)
+			return
+		}
+
+		ah.InfoContext(r.Context(), "admin reports view rendered")
+	}
+}
+
+// DismissReport resolves the report keeping the review.
+func (ah *adminHandler) DismissReport() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't dismiss review report"
+
+		const target = "/admin/reports"
+
+		id, ok := ah.id(w, r, "id", errMsg)
+		if !ok {
+			return
+		}
+
+		err := ah.ResolveReviewReport(r.Context(), id)
+		if err != nil {
+			form.RedirectError(w, r, target, "Report couldn't be dismissed, try again later")
+			ah.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		form.Redirect(w, r, target, "Report dismissed")
+
+		ah.InfoContext(r.Context(), "review report dismissed", "report id", id)
+	}
+}
+
+// DeleteReportedReview deletes the reported review
+// and resolves all of its reports.
+func (ah *adminHandler) DeleteReportedReview() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't delete reported review"
+
+		const target = "/admin/reports"
+
+		id, ok := ah.id(w, r, "id", errMsg)
+		if !ok {
+			return
+		}
+
+		report, err := ah.GetReviewReport(r.Context(), id)
+		if errors.Is(err, sql.ErrNoRows) {
+			form.RedirectError(w, r, target, "Report not found")
+			ah.WarnContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+		if err != nil {
+			form.RedirectError(w, r, target, "Review couldn't be deleted, try again later")
+			ah.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		err = ah.DeleteBookReview(r.Context(), report.UserId, report.BookId)
+		if err == nil {
+			err = ah.ResolveReviewReports(r.Context(), report.UserId, report.BookId)
+		}
+		if err != nil {
+			form.RedirectError(w, r, target, "Review couldn't be deleted, try again later")
+			ah.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		form.Redirect(w, r, target, "Review deleted")
+
+		ah.InfoContext(r.Context(), "reported review deleted", "report id", id, "user id", report.UserId, "book id", report.BookId)
+	}
+}
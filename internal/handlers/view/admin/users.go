@@ -0,0 +1,229 @@
+package admin
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/qo/digital-library/internal/auth"
+	"github.com/qo/digital-library/internal/handlers/api/query"
+	"github.com/qo/digital-library/internal/handlers/view/form"
+	"github.com/qo/digital-library/internal/handlers/view/render"
+	"github.com/qo/digital-library/internal/storage/user"
+)
+
+var roleNames = map[int]string{
+	user.RoleUser:  "User",
+	user.RoleMod:   "Mod",
+	user.RoleAdmin: "Admin",
+}
+
+type userRow struct {
+	user.User
+	RoleName string
+	// NewRole is the role the user can be switched to, 0 if there is none
+	NewRole     int
+	NewRoleName string
+}
+
+type usersPage struct {
+	Users []userRow
+	render.Pagination
+}
+
+func (ah *adminHandler) Users() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't get users"
+
+		limit, offset, err := query.Page(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			ah.WarnContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		users, err := ah.GetUsers(r.Context(), limit, offset)
+		if err != nil {
+			const msg = "db error"
+			http.Error(w, msg, internalServerErrorCode)
+			ah.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, msg), "err", err)
+			return
+		}
+
+		rows := make([]userRow, 0, len(users))
+		for _, u := range users {
+			row := userRow{User: u, RoleName: roleNames[u.Role]}
+			for _, to := range []int{user.RoleUser, user.RoleMod} {
+				if user.CanChangeRole(u.Role, to) {
+					row.NewRole, row.NewRoleName = to, roleNames[to]
+				}
+			}
+			rows = append(rows, row)
+		}
+
+		err = ah.Render(w, r, "admin/users.tmpl", usersPage{
+			rows,
+			render.Paginate(r.URL, limit, offset, len(users)),
+		})
+		if err != nil {
+			const msg = "can't render page"
+			http.Error(w, msg, internalServerErrorCode)
+			ah.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, msg), "err", err)
+			return
+		}
+
+		ah.InfoContext(r.Context(), "admin users view rendered")
+	}
+}
+
+// PostUser creates a user or a mod.
+func (ah *adminHandler) PostUser() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't post user"
+
+		const target = "/admin/users"
+
+		u := user.User{
+			FirstName:  strings.TrimSpace(r.PostFormValue("first_name")),
+			SecondName: strings.TrimSpace(r.PostFormValue("second_name")),
+		}
+		if u.FirstName == "" || u.SecondName == "" {
+			form.RedirectError(w, r, target, "First and second names are required")
+			ah.WarnContext(r.Context(), fmt.Sprintf("%s: names are empty", errMsg))
+			return
+		}
+
+		role, err := strconv.Atoi(r.PostFormValue("role"))
+		if err != nil || !user.CanCreateRole(role) {
+			form.RedirectError(w, r, target, "New users can only be users or mods")
+			ah.WarnContext(r.Context(), fmt.Sprintf("%s: invalid role", errMsg), "role", r.PostFormValue("role"))
+			return
+		}
+		u.Role = role
+
+		var hash string
+
+		if password := r.PostFormValue("password"); password != "" {
+			hash, err = auth.HashPassword(password)
+			if errors.Is(err, auth.ErrShortPassword) {
+				form.RedirectError(w, r, target, "Password must be at least 8 characters long")
+				ah.WarnContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+				return
+			}
+			if err != nil {
+				form.RedirectError(w, r, target, "User couldn't be created, try again later")
+				ah.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+				return
+			}
+		}
+
+		err = ah.adminStorage.PostUser(r.Context(), &u)
+		if err == nil && hash != "" {
+			err = ah.PutPasswordHash(r.Context(), u.Id, hash, "")
+		}
+		if err != nil {
+			form.RedirectError(w, r, target, "User couldn't be created, try again later")
+			ah.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		form.Redirect(w, r, target, fmt.Sprintf("User %d created", u.Id))
+
+		ah.InfoContext(r.Context(), "user created", "user id", u.Id, "role", u.Role)
+	}
+}
+
+// PutRole promotes the user to mod or demotes the mod to user.
+func (ah *adminHandler) PutRole() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't put role"
+
+		const target = "/admin/users"
+
+		id, ok := ah.id(w, r, "id", errMsg)
+		if !ok {
+			return
+		}
+
+		to, err := strconv.Atoi(r.PostFormValue("role"))
+		if err != nil {
+			form.RedirectError(w, r, target, "Role is not a number")
+			ah.WarnContext(r.Context(), fmt.Sprintf("%s: role is not a number", errMsg), "err", err)
+			return
+		}
+
+		u, err := ah.GetUser(r.Context(), id)
+		if errors.Is(err, sql.ErrNoRows) {
+			form.RedirectError(w, r, target, "User not found")
+			ah.WarnContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+		if err != nil {
+			form.RedirectError(w, r, target, "Role couldn't be changed, try again later")
+			ah.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		if !user.CanChangeRole(u.Role, to) {
+			form.RedirectError(w, r, target, fmt.Sprintf("%s can't become %s", roleNames[u.Role], roleNames[to]))
+			ah.WarnContext(r.Context(), fmt.Sprintf("%s: role transition is not allowed", errMsg), "from", u.Role, "to", to)
+			return
+		}
+
+		// the role is changed only if nobody has changed it since it was read
+		err = ah.adminStorage.PutRole(r.Context(), id, u.Role, to)
+		if errors.Is(err, sql.ErrNoRows) {
+			form.RedirectError(w, r, target, "Role was changed by someone else, try again")
+			ah.WarnContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+		if err != nil {
+			form.RedirectError(w, r, target, "Role couldn't be changed, try again later")
+			ah.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		form.Redirect(w, r, target, fmt.Sprintf("User %d is %s now", id, roleNames[to]))
+
+		ah.InfoContext(r.Context(), "role changed", "user id", id, "from", u.Role, "to", to)
+	}
+}
+
+// DeleteUser deletes the user or the mod.
+// Admins can't be deleted.
+func (ah *adminHandler) DeleteUser() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't delete user"
+
+		const target = "/admin/users"
+
+		id, ok := ah.id(w, r, "id", errMsg)
+		if !ok {
+			return
+		}
+
+		err := ah.adminStorage.DeleteUser(r.Context(), id)
+		if errors.Is(err, sql.ErrNoRows) {
+			form.RedirectError(w, r, target, "User not found")
+			ah.WarnContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+		if errors.Is(err, user.ErrAdmin) {
+			form.RedirectError(w, r, target, "Admins can't be deleted")
+			ah.WarnContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err), "user id", id)
+			return
+		}
+		if err != nil {
+			form.RedirectError(w, r, target, "User couldn't be deleted, try again later")
+			ah.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		form.Redirect(w, r, target, fmt.Sprintf("User %d deleted", id))
+
+		ah.InfoContext(r.Context(), "user deleted", "user id", id)
+	}
+}
@@ -0,0 +1,198 @@
+package author
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/qo/digital-library/internal/auth"
+	"github.com/qo/digital-library/internal/handlers/view/form"
+	"github.com/qo/digital-library/internal/handlers/view/render"
+	"github.com/qo/digital-library/internal/logger"
+	"github.com/qo/digital-library/internal/storage/author"
+	"github.com/qo/digital-library/internal/storage/book"
+	"github.com/qo/digital-library/internal/storage/favorite_author"
+)
+
+const (
+	internalServerErrorCode = http.StatusInternalServerError
+)
+
+type authorStorage interface {
+	GetAuthor(ctx context.Context, id int, includeDeleted bool) (*author.Author, error)
+	GetAuthorBooks(ctx context.Context, authorId int) ([]book.Book, error)
+	GetFavoriteAuthor(ctx context.Context, userId, authorId int) (*favorite_author.FavoriteAuthor, error)
+	PostFavoriteAuthor(ctx context.Context, userId, authorId int) error
+	DeleteFavoriteAuthor(ctx context.Context, userId, authorId int) error
+}
+
+type authorHandler struct {
+	logger.Logger
+	*render.Renderer
+	authorStorage
+}
+
+func New(log logger.Logger, rd *render.Renderer, st authorStorage) *authorHandler {
+	return &authorHandler{
+		log,
+		rd,
+		st,
+	}
+}
+
+type authorPage struct {
+	Author author.Author
+	Books  []book.Book
+	// Favorite tells whether the logged in user marked the author as favorite
+	Favorite bool
+}
+
+func (ah *authorHandler) Get() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't get author"
+
+		idParam := chi.URLParam(r, "id")
+		id, err := strconv.Atoi(idParam)
+		if err != nil {
+			const msg = "author id is not a number"
+			http.Error(w, msg, http.StatusBadRequest)
+			ah.WarnContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, msg), "err", err)
+			return
+		}
+
+		a, err := ah.GetAuthor(r.Context(), id, false)
+		if errors.Is(err, sql.ErrNoRows) {
+			const msg = "author not found"
+			http.Error(w, msg, http.StatusNotFound)
+			ah.WarnContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, msg), "err", err)
+			return
+		}
+		if err != nil {
+			const msg = "db error"
+			http.Error(w, msg, internalServerErrorCode)
+			ah.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, msg), "err", err)
+			return
+		}
+
+		books, err := ah.GetAuthorBooks(r.Context(), id)
+		if err != nil {
+			const msg = "db error"
+			http.Error(w, msg, internalServerErrorCode)
+			ah.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, msg), "err", err)
+			return
+		}
+
+		page := authorPage{
+			Author: *a,
+			Books:  books,
+		}
+
+		if p, ok := auth.FromContext(r.Context()); ok && p.UserId != 0 {
+			_, err = ah.GetFavoriteAuthor(r.Context(), p.UserId, id)
+			if err != nil && !errors.Is(err, sql.ErrNoRows) {
+				const msg = "db error"
+				http.Error(w, msg, internalServerErrorCode)
+				ah.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, msg), "err", err)
+				return
+			}
+			page.Favorite = err == nil
+		}
+
+		err = ah.Render(w, r, "author/author.tmpl", page)
+		if err != nil {
+			const msg = "can't render page"
+			http.Error(w, msg, internalServerErrorCode)
+			ah.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, msg), "err", err)
+			return
+		}
+
+		ah.InfoContext(r.Context(), "author view rendered")
+	}
+}
+
+// Favorite adds the author to the favorites of the logged in user.
+func (ah *authorHandler) Favorite() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't add favorite author"
+
+		id, ok := ah.author(w, r, errMsg)
+		if !ok {
+			return
+		}
+
+		p, _ := auth.FromContext(r.Context())
+		target := fmt.Sprintf("/author/%d", id)
+
+		_, err := ah.GetFavoriteAuthor(r.Context(), p.UserId, id)
+		if errors.Is(err, sql.ErrNoRows) {
+			err = ah.PostFavoriteAuthor(r.Context(), p.UserId, id)
+		}
+		if err != nil {
+			form.RedirectError(w, r, target, "Author couldn't be added to favorites, try again later")
+			ah.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		form.Redirect(w, r, target, "Author added to favorites")
+
+		ah.InfoContext(r.Context(), "favorite author added", "user id", p.UserId, "author id", id)
+	}
+}
+
+// Unfavorite removes the author from the favorites of the logged in user.
+func (ah *authorHandler) Unfavorite() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't remove favorite author"
+
+		id, ok := ah.author(w, r, errMsg)
+		if !ok {
+			return
+		}
+
+		p, _ := auth.FromContext(r.Context())
+		target := fmt.Sprintf("/author/%d", id)
+
+		err := ah.DeleteFavoriteAuthor(r.Context(), p.UserId, id)
+		if err != nil {
+			form.RedirectError(w, r, target, "Author couldn't be removed from favorites, try again later")
+			ah.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		form.Redirect(w, r, target, "Author removed from favorites")
+
+		ah.InfoContext(r.Context(), "favorite author removed", "user id", p.UserId, "author id", id)
+	}
+}
+
+// author returns the id of the requested author
+// or writes the error if there is no such author.
+func (ah *authorHandler) author(w http.ResponseWriter, r *http.Request, errMsg string) (int, bool) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		const msg = "author id is not a number"
+		http.Error(w, msg, http.StatusBadRequest)
+		ah.WarnContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, msg), "err", err)
+		return 0, false
+	}
+
+	_, err = ah.GetAuthor(r.Context(), id, false)
+	if errors.Is(err, sql.ErrNoRows) {
+		const msg = "author not found"
+		http.Error(w, msg, http.StatusNotFound)
+		ah.WarnContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, msg), "err", err)
+		return 0, false
+	}
+	if err != nil {
+		const msg = "db error"
+		http.Error(w, msg, internalServerErrorCode)
+		ah.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, msg), "err", err)
+		return 0, false
+	}
+
+	return id, true
+}
@@ -0,0 +1,431 @@
+package book
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/qo/digital-library/internal/auth"
+	"github.com/qo/digital-library/internal/blob"
+	"github.com/qo/digital-library/internal/handlers/api/query"
+	"github.com/qo/digital-library/internal/handlers/view/form"
+	"github.com/qo/digital-library/internal/handlers/view/render"
+	"github.com/qo/digital-library/internal/logger"
+	"github.com/qo/digital-library/internal/storage/author"
+	"github.com/qo/digital-library/internal/storage/book"
+	"github.com/qo/digital-library/internal/storage/book_review"
+	"github.com/qo/digital-library/internal/storage/favorite_book"
+	"github.com/qo/digital-library/internal/storage/review_report"
+)
+
+const (
+	internalServerErrorCode = http.StatusInternalServerError
+)
+
+type bookStorage interface {
+	GetBooks(ctx context.Context, limit, offset int) ([]book.Book, error)
+	GetBook(ctx context.Context, id int, includeDeleted bool) (*book.Book, error)
+	GetBookAuthors(ctx context.Context, bookId int) ([]author.Author, error)
+	GetBookReviews(ctx context.Context, bookId int) ([]book_review.BookReview, error)
+	GetBookReview(ctx context.Context, userId, bookId int, includeDeleted bool) (*book_review.BookReview, error)
+	PostBookReview(context.Context, *book_review.BookReview) error
+	PutBookReview(context.Context, *book_review.BookReview) error
+	DeleteBookReview(ctx context.Context, userId, bookId int) error
+	RestoreBookReview(ctx context.Context, userId, bookId int) error
+	GetFavoriteBook(ctx context.Context, userId, bookId int) (*favorite_book.FavoriteBook, error)
+	PostFavoriteBook(ctx context.Context, userId, bookId int) error
+	DeleteFavoriteBook(ctx context.Context, userId, bookId int) error
+	PostReviewReport(context.Context, *review_report.ReviewReport) error
+}
+
+type fileStorage interface {
+	Exists(key string) bool
+}
+
+type bookHandler struct {
+	logger.Logger
+	*render.Renderer
+	bookStorage
+	files fileStorage
+}
+
+func New(log logger.Logger, rd *render.Renderer, st bookStorage, fs fileStorage) *bookHandler {
+	return &bookHandler{
+		log,
+		rd,
+		st,
+		fs,
+	}
+}
+
+type listPage struct {
+	Books []book.Book
+	render.Pagination
+}
+
+func (bh *bookHandler) List() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't get books"
+
+		limit, offset, err := query.Page(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			bh.WarnContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		books, err := bh.GetBooks(r.Context(), limit, offset)
+		if err != nil {
+			const msg = "db error"
+			http.Error(w, msg, internalServerErrorCode)
+			bh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, msg), "err", err)
+			return
+		}
+
+		err = bh.Render(w, r, "book/list.tmpl", listPage{
+			books,
+			render.Paginate(r.URL, limit, offset, len(books)),
+		})
+		if err != nil {
+			const msg = "can't render page"
+			http.Error(w, msg, internalServerErrorCode)
+			bh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, msg), "err", err)
+			return
+		}
+
+		bh.InfoContext(r.Context(), "books view rendered")
+	}
+}
+
+type bookPage struct {
+	Book    book.Book
+	Authors []author.Author
+	Reviews []book_review.BookReview
+	HasFile bool
+	// Review is the review of the logged in user
+	Review *book_review.BookReview
+	// Favorite tells whether the logged in user marked the book as favorite
+	Favorite bool
+}
+
+func (bh *bookHandler) Get() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't get book"
+
+		idParam := chi.URLParam(r, "id")
+		id, err := strconv.Atoi(idParam)
+		if err != nil {
+			const msg = "book id is not a number"
+			http.Error(w, msg, http.StatusBadRequest)
+			bh.WarnContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, msg), "err", err)
+			return
+		}
+
+		b, err := bh.GetBook(r.Context(), id, false)
+		if errors.Is(err, sql.ErrNoRows) {
+			const msg = "book not found"
+			http.Error(w, msg, http.StatusNotFound)
+			bh.WarnContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, msg), "err", err)
+			return
+		}
+		if err != nil {
+			const msg = "db error"
+			http.Error(w, msg, internalServerErrorCode)
+			bh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, msg), "err", err)
+			return
+		}
+
+		authors, err := bh.GetBookAuthors(r.Context(), id)
+		if err != nil {
+			const msg = "db error"
+			http.Error(w, msg, internalServerErrorCode)
+			bh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, msg), "err", err)
+			return
+		}
+
+		reviews, err := bh.GetBookReviews(r.Context(), id)
+		if err != nil {
+			const msg = "db error"
+			http.Error(w, msg, internalServerErrorCode)
+			bh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, msg), "err", err)
+			return
+		}
+
+		page := bookPage{
+			Book:    *b,
+			Authors: authors,
+			Reviews: reviews,
+			HasFile: bh.files.Exists(blob.BookKey(id)),
+		}
+
+		if p, ok := auth.FromContext(r.Context()); ok && p.UserId != 0 {
+			review, err := bh.GetBookReview(r.Context(), p.UserId, id, false)
+			if err != nil && !errors.Is(err, sql.ErrNoRows) {
+				const msg = "db error"
+				http.Error(w, msg, internalServerErrorCode)
+				bh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, msg), "err", err)
+				return
+			}
+			page.Review = review
+
+			_, err = bh.GetFavoriteBook(r.Context(), p.UserId, id)
+			if err != nil && !errors.Is(err, sql.ErrNoRows) {
+				const msg = "db error"
+				http.Error(w, msg, internalServerErrorCode)
+				bh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, msg), "err", err)
+				return
+			}
+			page.Favorite = err == nil
+		}
+
+		err = bh.Render(w, r, "book/book.tmpl", page)
+		if err != nil {
+			const msg = "can't render page"
+			http.Error(w, msg, internalServerErrorCode)
+			bh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, msg), "err", err)
+			return
+		}
+
+		bh.InfoContext(r.Context(), "book view rendered")
+	}
+}
+
+const maxReviewLength = 10000
+
+// PostReview creates the review of the logged in user or updates it.
+func (bh *bookHandler) PostReview() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't post book review"
+
+		id, ok := bh.book(w, r, errMsg)
+		if !ok {
+			return
+		}
+
+		p, _ := auth.FromContext(r.Context())
+		target := fmt.Sprintf("/book/%d#reviews", id)
+
+		rating, err := strconv.Atoi(r.PostFormValue("rating"))
+		if err != nil || rating < 1 || rating > 5 {
+			form.RedirectError(w, r, target, "Rating must be from 1 to 5")
+			bh.WarnContext(r.Context(), fmt.Sprintf("%s: invalid rating", errMsg), "rating", r.PostFormValue("rating"))
+			return
+		}
+
+		body := strings.TrimSpace(r.PostFormValue("body"))
+		if len(body) > maxReviewLength {
+			form.RedirectError(w, r, target, fmt.Sprintf("Review must be at most %d characters long", maxReviewLength))
+			bh.WarnContext(r.Context(), fmt.Sprintf("%s: review is too long", errMsg))
+			return
+		}
+
+		review := book_review.BookReview{
+			UserId: p.UserId,
+			BookId: id,
+			Rating: rating,
+			Body:   body,
+		}
+
+		// a deleted review keeps its key, so it is restored instead of posted again
+		existing, err := bh.GetBookReview(r.Context(), p.UserId, id, true)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			err = bh.PostBookReview(r.Context(), &review)
+		case err != nil:
+		case existing.DeletedAt != nil:
+			err = bh.RestoreBookReview(r.Context(), p.UserId, id)
+			if err == nil {
+				err = bh.PutBookReview(r.Context(), &review)
+			}
+		default:
+			err = bh.PutBookReview(r.Context(), &review)
+		}
+		if err != nil {
+			form.RedirectError(w, r, target, "Review couldn't be saved, try again later")
+			bh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		form.Redirect(w, r, target, "Review saved")
+
+		bh.InfoContext(r.Context(), "book review posted", "user id", p.UserId, "book id", id)
+	}
+}
+
+// DeleteReview deletes the review of the logged in user.
+func (bh *bookHandler) DeleteReview() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't delete book review"
+
+		id, ok := bh.book(w, r, errMsg)
+		if !ok {
+			return
+		}
+
+		p, _ := auth.FromContext(r.Context())
+		target := fmt.Sprintf("/book/%d#reviews", id)
+
+		err := bh.DeleteBookReview(r.Context(), p.UserId, id)
+		if err != nil {
+			form.RedirectError(w, r, target, "Review couldn't be deleted, try again later")
+			bh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		form.Redirect(w, r, target, "Review deleted")
+
+		bh.InfoContext(r.Context(), "book review deleted", "user id", p.UserId, "book id", id)
+	}
+}
+
+const maxReportReasonLength = 1000
+
+// ReportReview reports the review of another user to the moderators.
+func (bh *bookHandler) ReportReview() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't report book review"
+
+		id, ok := bh.book(w, r, errMsg)
+		if !ok {
+			return
+		}
+
+		p, _ := auth.FromContext(r.Context())
+		target := fmt.Sprintf("/book/%d#reviews", id)
+
+		userId, err := strconv.Atoi(chi.URLParam(r, "user_id"))
+		if err != nil {
+			const msg = "user id is not a number"
+			http.Error(w, msg, http.StatusBadRequest)
+			bh.WarnContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, msg), "err", err)
+			return
+		}
+
+		_, err = bh.GetBookReview(r.Context(), userId, id, false)
+		if errors.Is(err, sql.ErrNoRows) {
+			const msg = "book review not found"
+			http.Error(w, msg, http.StatusNotFound)
+			bh.WarnContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, msg), "err", err)
+			return
+		}
+		if err != nil {
+			const msg = "db error"
+			http.Error(w, msg, internalServerErrorCode)
+			bh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, msg), "err", err)
+			return
+		}
+
+		reason := strings.TrimSpace(r.PostFormValue("reason"))
+		if reason == "" || len(reason) > maxReportReasonLength {
+			form.RedirectError(w, r, target, fmt.Sprintf("Reason must be from 1 to %d characters long", maxReportReasonLength))
+			bh.WarnContext(r.Context(), fmt.Sprintf("%s: invalid reason", errMsg))
+			return
+		}
+
+		err = bh.PostReviewReport(r.Context(), &review_report.ReviewReport{
+			ReporterId: p.UserId,
+			UserId:     userId,
+			BookId:     id,
+			Reason:     reason,
+			CreatedAt:  time.Now(),
+		})
+		if err != nil {
+			form.RedirectError(w, r, target, "Review couldn't be reported, try again later")
+			bh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		form.Redirect(w, r, target, "Review reported to the moderators")
+
+		bh.InfoContext(r.Context(), "book review reported", "reporter id", p.UserId, "user id", userId, "book id", id)
+	}
+}
+
+// Favorite adds the book to the favorites of the logged in user.
+func (bh *bookHandler) Favorite() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't add favorite book"
+
+		id, ok := bh.book(w, r, errMsg)
+		if !ok {
+			return
+		}
+
+		p, _ := auth.FromContext(r.Context())
+		target := fmt.Sprintf("/book/%d", id)
+
+		_, err := bh.GetFavoriteBook(r.Context(), p.UserId, id)
+		if errors.Is(err, sql.ErrNoRows) {
+			err = bh.PostFavoriteBook(r.Context(), p.UserId, id)
+		}
+		if err != nil {
+			form.RedirectError(w, r, target, "Book couldn't be added to favorites, try again later")
+			bh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		form.Redirect(w, r, target, "Book added to favorites")
+
+		bh.InfoContext(r.Context(), "favorite book added", "user id", p.UserId, "book id", id)
+	}
+}
+
+// Unfavorite removes the book from the favorites of the logged in user.
+func (bh *bookHandler) Unfavorite() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't remove favorite book"
+
+		id, ok := bh.book(w, r, errMsg)
+		if !ok {
+			return
+		}
+
+		p, _ := auth.FromContext(r.Context())
+		target := fmt.Sprintf("/book/%d", id)
+
+		err := bh.DeleteFavoriteBook(r.Context(), p.UserId, id)
+		if err != nil {
+			form.RedirectError(w, r, target, "Book couldn't be removed from favorites, try again later")
+			bh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		form.Redirect(w, r, target, "Book removed from favorites")
+
+		bh.InfoContext(r.Context(), "favorite book removed", "user id", p.UserId, "book id", id)
+	}
+}
+
+// book returns the id of the requested book
+// or writes the error if there is no such book.
+func (bh *bookHandler) book(w http.ResponseWriter, r *http.Request, errMsg string) (int, bool) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		const msg = "book id is not a number"
+		http.Error(w, msg, http.StatusBadRequest)
+		bh.WarnContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, msg), "err", err)
+		return 0, false
+	}
+
+	_, err = bh.GetBook(r.Context(), id, false)
+	if errors.Is(err, sql.ErrNoRows) {
+		const msg = "book not found"
+		http.Error(w, msg, http.StatusNotFound)
+		bh.WarnContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, msg), "err", err)
+		return 0, false
+	}
+	if err != nil {
+		const msg = "db error"
+		http.Error(w, msg, internalServerErrorCode)
+		bh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, msg), "err", err)
+		return 0, false
+	}
+
+	return id, true
+}
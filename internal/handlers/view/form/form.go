@@ -0,0 +1,176 @@
+// Package form helps handling html forms:
+// it protects them against cross-site request forgery
+// and passes flash messages between post/redirect/get requests.
+package form
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/qo/digital-library/internal/auth"
+	"github.com/qo/digital-library/internal/blob"
+)
+
+// maxBodySize is the max size of the posted form in bytes,
+// it is big enough for uploading book files.
+const maxBodySize = blob.MaxBookSize + 1<<20
+
+const (
+	CSRFField   = "csrf_token"
+	csrfCookie  = "csrf_token"
+	flashCookie = "flash"
+)
+
+// CSRFToken returns the csrf token of the client,
+// the token is generated and set as a cookie on the first call.
+// Forms should send it back in the CSRFField.
+func CSRFToken(w http.ResponseWriter, r *http.Request) string {
+	cookie, err := r.Cookie(csrfCookie)
+	if err == nil && cookie.Value != "" {
+		return cookie.Value
+	}
+
+	b := make([]byte, 32)
+	rand.Read(b)
+	token := base64.RawURLEncoding.EncodeToString(b)
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     csrfCookie,
+		Value:    token,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	// the same token must be returned by the next calls within the request
+	r.AddCookie(&http.Cookie{Name: csrfCookie, Value: token})
+
+	return token
+}
+
+// Protect rejects the unsafe requests
+// whose form doesn't carry the csrf token of the client.
+func Protect(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, maxBodySize)
+
+		cookie, err := r.Cookie(csrfCookie)
+		token := r.PostFormValue(CSRFField)
+		if err != nil || token == "" ||
+			subtle.ConstantTimeCompare([]byte(token), []byte(cookie.Value)) != 1 {
+			http.Error(w, "invalid csrf token, reload the page and try again", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Flash is a message shown once on the page the user is redirected to.
+type Flash struct {
+	// Kind is either "success" or "error"
+	Kind    string
+	Message string
+}
+
+func setFlash(w http.ResponseWriter, f Flash) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     flashCookie,
+		Value:    f.Kind + ":" + url.QueryEscape(f.Message),
+		Path:     "/",
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// PopFlash returns the flash message and removes it.
+func PopFlash(w http.ResponseWriter, r *http.Request) *Flash {
+	cookie, err := r.Cookie(flashCookie)
+	if err != nil {
+		return nil
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:   flashCookie,
+		Path:   "/",
+		MaxAge: -1,
+	})
+
+	kind, value, ok := strings.Cut(cookie.Value, ":")
+	if !ok {
+		return nil
+	}
+
+	message, err := url.QueryUnescape(value)
+	if err != nil {
+		return nil
+	}
+
+	return &Flash{kind, message}
+}
+
+// Redirect redirects the client after a successful post
+// showing the message on the target page.
+func Redirect(w http.ResponseWriter, r *http.Request, target, message string) {
+	setFlash(w, Flash{"success", message})
+	http.Redirect(w, r, target, http.StatusSeeOther)
+}
+
+// RedirectError redirects the client after a failed post
+// showing the error on the target page.
+func RedirectError(w http.ResponseWriter, r *http.Request, target, message string) {
+	setFlash(w, Flash{"error", message})
+	http.Redirect(w, r, target, http.StatusSeeOther)
+}
+
+// RequireLogin redirects anonymous users to the login page.
+// The users return to the requested page after logging in.
+func RequireLogin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		p, ok := auth.FromContext(r.Context())
+		if ok && p.UserId != 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		target := "/login"
+		if r.Method == http.MethodGet {
+			target += "?next=" + url.QueryEscape(r.URL.RequestURI())
+		} else if ref, err := url.Parse(r.Referer()); err == nil && IsLocal(ref.RequestURI()) {
+			target += "?next=" + url.QueryEscape(ref.RequestURI())
+		}
+
+		RedirectError(w, r, target, "Log in first")
+	}
+}
+
+// RequireRole lets in the users having at least the role
+// and redirects anonymous users to the login page.
+func RequireRole(role int, next http.HandlerFunc) http.HandlerFunc {
+	return RequireLogin(func(w http.ResponseWriter, r *http.Request) {
+		p, _ := auth.FromContext(r.Context())
+		if p.Role < role {
+			http.Error(w, "you don't have access to this page", http.StatusForbidden)
+			return
+		}
+		next(w, r)
+	})
+}
+
+// IsLocal reports whether the redirect target stays on this site.
+func IsLocal(target string) bool {
+	return strings.HasPrefix(target, "/") &&
+		!strings.HasPrefix(target, "//") &&
+		!strings.HasPrefix(target, "/\\")
+}
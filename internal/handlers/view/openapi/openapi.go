@@ -2,11 +2,13 @@ package openapi
 
 import (
 	"fmt"
-	"github.com/qo/digital-library/internal/logger"
-	"html/template"
 	"net/http"
 	"os"
-	"path"
+
+	"github.com/qo/digital-library/docs/swagger"
+	"github.com/qo/digital-library/internal/config"
+	"github.com/qo/digital-library/internal/handlers/view/render"
+	"github.com/qo/digital-library/internal/logger"
 )
 
 const (
@@ -15,45 +17,47 @@ const (
 
 type openapiHandler struct {
 	logger.Logger
+	*render.Renderer
+	options config.ViewsOptions
 }
 
-func New(log logger.Logger) *openapiHandler {
+func New(log logger.Logger, rd *render.Renderer, options config.ViewsOptions) *openapiHandler {
 	return &openapiHandler{
 		log,
+		rd,
+		options,
 	}
 }
 
+// spec returns the embedded spec,
+// or reads it from disk if hot reload is on.
+func (oh openapiHandler) spec() ([]byte, error) {
+	if !oh.options.HotReload {
+		return swagger.Spec, nil
+	}
+	return os.ReadFile(oh.options.SpecPath)
+}
+
 func (oh openapiHandler) Get() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		const errMsg = "can't get swagger"
 
-		tp := path.Join("internal", "views", "swagger", "swagger.tmpl")
-
-		tmpl, err := template.ParseFiles(tp)
-		if err != nil {
-			msg := fmt.Sprintf("%s: can't parse html template: %s", errMsg, err)
-			http.Error(w, msg, internalServerErrorCode)
-			oh.Error(msg, "template path", tp)
-			return
-		}
-
-		jp := path.Join("docs", "swagger", "openapi.json")
-
-		json, err := os.ReadFile(jp)
+		json, err := oh.spec()
 		if err != nil {
 			msg := fmt.Sprintf("%s: can't read json file containing swagger spec: %s", errMsg, err)
 			http.Error(w, msg, internalServerErrorCode)
-			oh.Error(msg, "json path", jp)
+			oh.ErrorContext(r.Context(), msg, "json path", oh.options.SpecPath)
 			return
 		}
 
-		err = tmpl.Execute(w, string(json))
+		err = oh.Render(w, r, "swagger/swagger.tmpl", string(json))
 		if err != nil {
-			msg := fmt.Sprintf("%s: can't execute html template: %s", errMsg, err)
+			msg := fmt.Sprintf("%s: %s", errMsg, err)
 			http.Error(w, msg, internalServerErrorCode)
-			oh.Error(msg, "template path", tp, "template", tmpl, "json path", jp, "json", json)
+			oh.ErrorContext(r.Context(), msg)
+			return
 		}
 
-		oh.Info("swagger view rendered")
+		oh.InfoContext(r.Context(), "swagger view rendered")
 	}
 }
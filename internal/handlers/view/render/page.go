@@ -0,0 +1,22 @@
+package render
+
+import (
+	"net/url"
+
+	"github.com/qo/digital-library/internal/handlers/api/query"
+)
+
+// Pagination holds the links to the neighbour pages of a list view.
+// The links are empty if there are no such pages.
+type Pagination struct {
+	PrevPage string
+	NextPage string
+}
+
+// Paginate returns the links to the neighbour pages of the page of limit items
+// skipping offset items and having count of them.
+func Paginate(u *url.URL, limit, offset, count int) Pagination {
+	var p Pagination
+	p.PrevPage, p.NextPage = query.PageLinks(u, limit, offset, count)
+	return p
+}
@@ -0,0 +1,149 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+
+	"github.com/qo/digital-library/internal/auth"
+	"github.com/qo/digital-library/internal/config"
+	"github.com/qo/digital-library/internal/handlers/view/form"
+	"github.com/qo/digital-library/internal/views"
+)
+
+const layoutPath = "layout/layout.tmpl"
+
+// Renderer executes the page templates.
+// The templates are parsed once on creation,
+// unless hot reload is on: then they are parsed on every render.
+type Renderer struct {
+	fsys      fs.FS
+	hotReload bool
+	pages     map[string]*template.Template
+}
+
+func New(options config.ViewsOptions) (*Renderer, error) {
+	const errMsg = "can't init renderer"
+
+	rd := Renderer{
+		fsys:      views.FS,
+		hotReload: options.HotReload,
+		pages:     make(map[string]*template.Template),
+	}
+
+	if options.HotReload {
+		rd.fsys = os.DirFS(options.Dir)
+		return &rd, nil
+	}
+
+	pages, err := fs.Glob(rd.fsys, "*/*.tmpl")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	for _, page := range pages {
+		if page == layoutPath {
+			continue
+		}
+
+		tmpl, err := rd.parse(page)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", errMsg, err)
+		}
+
+		rd.pages[page] = tmpl
+	}
+
+	return &rd, nil
+}
+
+// funcs returns the functions the templates can call
+// to get the state of the request the page is rendered for.
+// The functions are stubs at parse time and are replaced on every render.
+func funcs(w http.ResponseWriter, r *http.Request) template.FuncMap {
+	return template.FuncMap{
+		// csrfToken returns the token the forms must send back
+		"csrfToken": func() string {
+			if r == nil {
+				return ""
+			}
+			return form.CSRFToken(w, r)
+		},
+		// flash returns the message left by the previous request or nil
+		"flash": func() *form.Flash {
+			if r == nil {
+				return nil
+			}
+			return form.PopFlash(w, r)
+		},
+		// principal returns the logged in user or nil
+		"principal": func() *auth.Principal {
+			if r == nil {
+				return nil
+			}
+			p, ok := auth.FromContext(r.Context())
+			if !ok {
+				return nil
+			}
+			return &p
+		},
+	}
+}
+
+func (rd *Renderer) parse(page string) (*template.Template, error) {
+	tmpl, err := template.New(path.Base(page)).Funcs(funcs(nil, nil)).ParseFS(rd.fsys, page, layoutPath)
+	if err != nil {
+		return nil, fmt.Errorf("can't parse html template %s: %w", page, err)
+	}
+	return tmpl, nil
+}
+
+// Render executes the page template.
+// The page template path is relative to internal/views, e.g. "book/book.tmpl".
+// Pages defining "content" are rendered within the shared layout,
+// the others are rendered on their own.
+// Nothing is written if the template can't be executed.
+func (rd *Renderer) Render(w http.ResponseWriter, r *http.Request, page string, data any) error {
+	const errMsg = "can't render page"
+
+	tmpl, ok := rd.pages[page]
+	if rd.hotReload {
+		var err error
+		tmpl, err = rd.parse(page)
+		if err != nil {
+			return fmt.Errorf("%s: %w", errMsg, err)
+		}
+	} else if !ok {
+		return fmt.Errorf("%s: html template %s doesn't exist", errMsg, page)
+	}
+
+	// the parsed templates are never executed so that they can be cloned
+	tmpl, err := tmpl.Clone()
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+	tmpl.Funcs(funcs(w, r))
+
+	name := path.Base(page)
+	if tmpl.Lookup("content") != nil {
+		name = "layout"
+	}
+
+	var buf bytes.Buffer
+
+	err = tmpl.ExecuteTemplate(&buf, name, data)
+	if err != nil {
+		return fmt.Errorf("%s: can't execute html template %s: %w", errMsg, page, err)
+	}
+
+	_, err = buf.WriteTo(w)
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,77 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/qo/digital-library/internal/handlers/api/query"
+	"github.com/qo/digital-library/internal/handlers/view/render"
+	"github.com/qo/digital-library/internal/logger"
+	"github.com/qo/digital-library/internal/storage/book"
+)
+
+const (
+	internalServerErrorCode = http.StatusInternalServerError
+)
+
+type searchStorage interface {
+	SearchBooks(ctx context.Context, query string, limit, offset int) ([]book.Book, error)
+}
+
+type searchHandler struct {
+	logger.Logger
+	*render.Renderer
+	searchStorage
+}
+
+func New(log logger.Logger, rd *render.Renderer, st searchStorage) *searchHandler {
+	return &searchHandler{
+		log,
+		rd,
+		st,
+	}
+}
+
+type searchPage struct {
+	Query string
+	Books []book.Book
+	render.Pagination
+}
+
+func (sh *searchHandler) Get() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't search books"
+
+		q := r.URL.Query().Get("q")
+
+		limit, offset, err := query.Page(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			sh.WarnContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		books, err := sh.SearchBooks(r.Context(), q, limit, offset)
+		if err != nil {
+			const msg = "db error"
+			http.Error(w, msg, internalServerErrorCode)
+			sh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, msg), "err", err)
+			return
+		}
+
+		err = sh.Render(w, r, "search/search.tmpl", searchPage{
+			q,
+			books,
+			render.Paginate(r.URL, limit, offset, len(books)),
+		})
+		if err != nil {
+			const msg = "can't render page"
+			http.Error(w, msg, internalServerErrorCode)
+			sh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, msg), "err", err)
+			return
+		}
+
+		sh.InfoContext(r.Context(), "search view rendered", "query", q)
+	}
+}
@@ -0,0 +1,150 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/qo/digital-library/internal/auth"
+	"github.com/qo/digital-library/internal/config"
+	"github.com/qo/digital-library/internal/handlers/view/form"
+	"github.com/qo/digital-library/internal/handlers/view/render"
+	"github.com/qo/digital-library/internal/logger"
+	"github.com/qo/digital-library/internal/storage/session"
+	"github.com/qo/digital-library/internal/storage/user"
+)
+
+const (
+	internalServerErrorCode = http.StatusInternalServerError
+)
+
+type sessionStorage interface {
+	GetSession(ctx context.Context, tokenHash string) (*session.Session, error)
+	GetUser(ctx context.Context, id int) (*user.User, error)
+	GetPasswordHash(ctx context.Context, id int) (string, error)
+	PostSession(context.Context, *session.Session) error
+	DeleteSession(ctx context.Context, tokenHash string) error
+}
+
+type sessionHandler struct {
+	logger.Logger
+	*render.Renderer
+	sessionStorage
+	options config.AuthOptions
+}
+
+func New(log logger.Logger, rd *render.Renderer, st sessionStorage, options config.AuthOptions) *sessionHandler {
+	return &sessionHandler{
+		log,
+		rd,
+		st,
+		options,
+	}
+}
+
+type loginPage struct {
+	Next string
+}
+
+func (sh *sessionHandler) Get() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't get login page"
+
+		next := r.URL.Query().Get("next")
+		if !form.IsLocal(next) {
+			next = ""
+		}
+
+		err := sh.Render(w, r, "session/login.tmpl", loginPage{next})
+		if err != nil {
+			const msg = "can't render page"
+			http.Error(w, msg, internalServerErrorCode)
+			sh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, msg), "err", err)
+			return
+		}
+
+		sh.InfoContext(r.Context(), "login view rendered")
+	}
+}
+
+func (sh *sessionHandler) Login() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't log in"
+
+		next := r.PostFormValue("next")
+		if !form.IsLocal(next) {
+			next = ""
+		}
+
+		retry := "/login"
+		if next != "" {
+			retry += "?next=" + url.QueryEscape(next)
+		}
+
+		id, err := strconv.Atoi(r.PostFormValue("user_id"))
+		if err != nil {
+			form.RedirectError(w, r, retry, "User id is not a number")
+			sh.WarnContext(r.Context(), fmt.Sprintf("%s: user id is not a number", errMsg), "err", err)
+			return
+		}
+
+		token, expiresAt, err := auth.Login(r.Context(), sh, id, r.PostFormValue("password"), sh.options.SessionTTL)
+		if errors.Is(err, auth.ErrInvalidCredentials) {
+			form.RedirectError(w, r, retry, "Invalid user id or password")
+			sh.WarnContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err), "user id", id)
+			return
+		}
+		if err != nil {
+			form.RedirectError(w, r, retry, "Something went wrong, try again later")
+			sh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err), "user id", id)
+			return
+		}
+
+		http.SetCookie(w, &http.Cookie{
+			Name:     auth.SessionCookie,
+			Value:    token,
+			Path:     "/",
+			Expires:  expiresAt,
+			HttpOnly: true,
+			Secure:   r.TLS != nil,
+			SameSite: http.SameSiteLaxMode,
+		})
+
+		if next == "" {
+			next = fmt.Sprintf("/user/%d", id)
+		}
+
+		form.Redirect(w, r, next, "Logged in")
+
+		sh.InfoContext(r.Context(), "user logged in", "user id", id)
+	}
+}
+
+func (sh *sessionHandler) Logout() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't log out"
+
+		cookie, err := r.Cookie(auth.SessionCookie)
+		if err == nil {
+			err = auth.Logout(r.Context(), sh, cookie.Value)
+			if err != nil {
+				form.RedirectError(w, r, "/books", "Something went wrong, try again later")
+				sh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+				return
+			}
+		}
+
+		http.SetCookie(w, &http.Cookie{
+			Name:   auth.SessionCookie,
+			Path:   "/",
+			MaxAge: -1,
+		})
+
+		form.Redirect(w, r, "/books", "Logged out")
+
+		sh.InfoContext(r.Context(), "user logged out")
+	}
+}
@@ -0,0 +1,109 @@
+package shelf
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/qo/digital-library/internal/auth"
+	"github.com/qo/digital-library/internal/handlers/view/render"
+	"github.com/qo/digital-library/internal/logger"
+	"github.com/qo/digital-library/internal/storage/book"
+	"github.com/qo/digital-library/internal/storage/shelf"
+	"github.com/qo/digital-library/internal/storage/user"
+)
+
+const (
+	internalServerErrorCode = http.StatusInternalServerError
+)
+
+type shelfStorage interface {
+	GetShelf(ctx context.Context, id int) (*shelf.Shelf, error)
+	GetShelfBooks(ctx context.Context, shelfId int) ([]book.Book, error)
+	GetUser(ctx context.Context, id int) (*user.User, error)
+}
+
+type shelfHandler struct {
+	logger.Logger
+	*render.Renderer
+	shelfStorage
+}
+
+func New(log logger.Logger, rd *render.Renderer, st shelfStorage) *shelfHandler {
+	return &shelfHandler{
+		log,
+		rd,
+		st,
+	}
+}
+
+type shelfPage struct {
+	Shelf shelf.Shelf
+	Owner user.User
+	Books []book.Book
+}
+
+func (sh *shelfHandler) Get() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't get shelf"
+
+		idParam := chi.URLParam(r, "id")
+		id, err := strconv.Atoi(idParam)
+		if err != nil {
+			const msg = "shelf id is not a number"
+			http.Error(w, msg, http.StatusBadRequest)
+			sh.WarnContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, msg), "err", err)
+			return
+		}
+
+		s, err := sh.GetShelf(r.Context(), id)
+		// the private shelves are not found for everyone but the owner and admins
+		if err == nil && !s.Public {
+			if !auth.CanActAs(r.Context(), s.UserId) {
+				err = fmt.Errorf("shelf %d is private: %w", id, sql.ErrNoRows)
+			}
+		}
+		if errors.Is(err, sql.ErrNoRows) {
+			const msg = "shelf not found"
+			http.Error(w, msg, http.StatusNotFound)
+			sh.WarnContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, msg), "err", err)
+			return
+		}
+		if err != nil {
+			const msg = "db error"
+			http.Error(w, msg, internalServerErrorCode)
+			sh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, msg), "err", err)
+			return
+		}
+
+		owner, err := sh.GetUser(r.Context(), s.UserId)
+		if err != nil {
+			const msg = "db error"
+			http.Error(w, msg, internalServerErrorCode)
+			sh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, msg), "err", err)
+			return
+		}
+
+		books, err := sh.GetShelfBooks(r.Context(), id)
+		if err != nil {
+			const msg = "db error"
+			http.Error(w, msg, internalServerErrorCode)
+			sh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, msg), "err", err)
+			return
+		}
+
+		err = sh.Render(w, r, "shelf/shelf.tmpl", shelfPage{*s, *owner, books})
+		if err != nil {
+			const msg = "can't render page"
+			http.Error(w, msg, internalServerErrorCode)
+			sh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, msg), "err", err)
+			return
+		}
+
+		sh.InfoContext(r.Context(), "shelf view rendered")
+	}
+}
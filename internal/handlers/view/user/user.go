@@ -1,33 +1,61 @@
 package user
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"fmt"
-	"html/template"
 	"net/http"
-	"path"
 	"strconv"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/qo/digital-library/internal/auth"
 	"github.com/qo/digital-library/internal/handlers/api/user"
+	"github.com/qo/digital-library/internal/handlers/view/form"
+	"github.com/qo/digital-library/internal/handlers/view/render"
 	"github.com/qo/digital-library/internal/logger"
+	"github.com/qo/digital-library/internal/storage/shelf"
+	storage_user "github.com/qo/digital-library/internal/storage/user"
 )
 
 const (
 	internalServerErrorCode = http.StatusInternalServerError
 )
 
+type userStorage interface {
+	user.UserStorage
+	GetUserShelves(ctx context.Context, userId int, includePrivate bool) ([]shelf.Shelf, error)
+}
+
 type userHandler struct {
 	logger.Logger
-	user.UserStorage
+	*render.Renderer
+	userStorage
 }
 
-func New(log logger.Logger, st user.UserStorage) *userHandler {
+func New(log logger.Logger, rd *render.Renderer, st userStorage) *userHandler {
 	return &userHandler{
 		log,
+		rd,
 		st,
 	}
 }
 
+type userPage struct {
+	storage_user.User
+	// CanEdit tells whether the logged in user can edit the profile
+	CanEdit bool
+	// Shelves are the shelves the logged in user can see
+	Shelves []shelf.Shelf
+}
+
+// canEdit reports whether the logged in user can edit the profile:
+// users edit their own profiles, admins edit any.
+func canEdit(r *http.Request, id int) bool {
+	return auth.CanActAs(r.Context(), id)
+}
+
 func (uh *userHandler) Get() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		const errMsg = "can't get user"
@@ -37,36 +65,160 @@ func (uh *userHandler) Get() http.HandlerFunc {
 		if err != nil {
 			const msg = "user id is not a number"
 			http.Error(w, msg, internalServerErrorCode)
-			uh.Warn(fmt.Sprintf("%s: %s", errMsg, msg), "err", err)
+			uh.WarnContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, msg), "err", err)
 			return
 		}
 
-		handler := user.New(uh.Logger, uh.UserStorage)
-		user, err := handler.GetUser(id)
+		user, err := uh.GetUser(r.Context(), id)
+		if errors.Is(err, sql.ErrNoRows) {
+			const msg = "user not found"
+			http.Error(w, msg, http.StatusNotFound)
+			uh.WarnContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, msg), "err", err)
+			return
+		}
 		if err != nil {
 			const msg = "api request couldn't be done"
 			http.Error(w, msg, internalServerErrorCode)
-			uh.Error(fmt.Sprintf("%s: %s", errMsg, msg), "err", err)
+			uh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, msg), "err", err)
 			return
 		}
 
-		tp := path.Join("internal", "views", "user", "user.tmpl")
+		edit := canEdit(r, id)
 
-		tmpl, err := template.ParseFiles(tp)
+		shelves, err := uh.GetUserShelves(r.Context(), id, edit)
 		if err != nil {
-			const msg = "can't parse html template"
+			const msg = "api request couldn't be done"
 			http.Error(w, msg, internalServerErrorCode)
-			uh.Error(fmt.Sprintf("%s: %s", errMsg, msg), "template path", tp)
+			uh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, msg), "err", err)
 			return
 		}
 
-		err = tmpl.Execute(w, user)
+		err = uh.Render(w, r, "user/user.tmpl", userPage{*user, edit, shelves})
 		if err != nil {
-			const msg = "can't execute html template"
+			const msg = "can't render page"
 			http.Error(w, msg, internalServerErrorCode)
-			uh.Error(fmt.Sprintf("%s: %s", errMsg, msg), "template path", tp, "template", tmpl)
+			uh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, msg), "err", err)
+			return
+		}
+
+		uh.InfoContext(r.Context(), "user view rendered")
+	}
+}
+
+// Edit renders the profile form.
+func (uh *userHandler) Edit() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't get user profile form"
+
+		id, ok := uh.editable(w, r, errMsg)
+		if !ok {
+			return
+		}
+
+		user, err := uh.GetUser(r.Context(), id)
+		if err != nil {
+			const msg = "db error"
+			http.Error(w, msg, internalServerErrorCode)
+			uh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, msg), "err", err)
+			return
+		}
+
+		err = uh.Render(w, r, "user/edit.tmpl", user)
+		if err != nil {
+			const msg = "can't render page"
+			http.Error(w, msg, internalServerErrorCode)
+			uh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, msg), "err", err)
+			return
+		}
+
+		uh.InfoContext(r.Context(), "user profile form rendered")
+	}
+}
+
+// Update saves the profile form.
+// The password is changed only if a new one is entered.
+func (uh *userHandler) Update() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't update user profile"
+
+		id, ok := uh.editable(w, r, errMsg)
+		if !ok {
+			return
+		}
+
+		retry := fmt.Sprintf("/user/%d/edit", id)
+
+		user, err := uh.GetUser(r.Context(), id)
+		if err != nil {
+			form.RedirectError(w, r, retry, "Profile couldn't be saved, try again later")
+			uh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
 		}
 
-		uh.Info("user view rendered")
+		user.FirstName = strings.TrimSpace(r.PostFormValue("first_name"))
+		user.SecondName = strings.TrimSpace(r.PostFormValue("second_name"))
+		if user.FirstName == "" || user.SecondName == "" {
+			form.RedirectError(w, r, retry, "First and second names are required")
+			uh.WarnContext(r.Context(), fmt.Sprintf("%s: names are empty", errMsg))
+			return
+		}
+
+		var hash string
+
+		password := r.PostFormValue("password")
+		if password != "" {
+			if password != r.PostFormValue("password_confirmation") {
+				form.RedirectError(w, r, retry, "Passwords don't match")
+				uh.WarnContext(r.Context(), fmt.Sprintf("%s: passwords don't match", errMsg))
+				return
+			}
+
+			hash, err = auth.HashPassword(password)
+			if errors.Is(err, auth.ErrShortPassword) {
+				form.RedirectError(w, r, retry, "Password must be at least 8 characters long")
+				uh.WarnContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+				return
+			}
+			if err != nil {
+				form.RedirectError(w, r, retry, "Profile couldn't be saved, try again later")
+				uh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+				return
+			}
+		}
+
+		err = uh.PutUser(r.Context(), user)
+		if err == nil && hash != "" {
+			err = uh.PutPasswordHash(r.Context(), id, hash, auth.SessionTokenHash(r.Context()))
+		}
+		if err != nil {
+			form.RedirectError(w, r, retry, "Profile couldn't be saved, try again later")
+			uh.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			return
+		}
+
+		form.Redirect(w, r, fmt.Sprintf("/user/%d", id), "Profile saved")
+
+		uh.InfoContext(r.Context(), "user profile updated", "user id", id)
+	}
+}
+
+// editable returns the id of the requested user
+// or writes the error if the logged in user can't edit the profile.
+func (uh *userHandler) editable(w http.ResponseWriter, r *http.Request, errMsg string) (int, bool) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		const msg = "user id is not a number"
+		http.Error(w, msg, http.StatusBadRequest)
+		uh.WarnContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, msg), "err", err)
+		return 0, false
 	}
+
+	if !canEdit(r, id) {
+		const msg = "only the user and admins can edit the profile"
+		http.Error(w, msg, http.StatusForbidden)
+		uh.WarnContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, msg), "user id", id)
+		return 0, false
+	}
+
+	return id, true
 }
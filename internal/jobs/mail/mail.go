@@ -0,0 +1,106 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/qo/digital-library/internal/config"
+	"github.com/qo/digital-library/internal/logger"
+	"github.com/qo/digital-library/internal/storage/email"
+)
+
+// batch is the number of the emails sent per tick
+const batch = 100
+
+type emailStorage interface {
+	GetDueEmails(ctx context.Context, now time.Time, limit int) ([]email.Email, error)
+	PutEmailSent(ctx context.Context, id int, sentAt time.Time) error
+	PutFailedEmailAttempt(ctx context.Context, id int, lastError string, now time.Time, nextAttemptAt *time.Time) error
+}
+
+type sender interface {
+	Send(ctx context.Context, to, subject, body string) error
+}
+
+// Run periodically sends the due emails of the outbox.
+// The failed ones are retried with the exponential backoff
+// until they run out of attempts.
+// It blocks until the context is done.
+func Run(ctx context.Context, log logger.Logger, st emailStorage, s sender, options config.NotificationsOptions) {
+	const errMsg = "can't send emails"
+
+	ticker := time.NewTicker(options.Interval)
+	defer ticker.Stop()
+
+	for {
+		sent, failed, err := send(ctx, log, st, s, options)
+		if err != nil {
+			log.Error(fmt.Sprintf("%s: %s", errMsg, err))
+		} else {
+			log.Debug("emails sent", "sent", sent, "failed", failed)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func send(ctx context.Context, log logger.Logger, st emailStorage, s sender, options config.NotificationsOptions) (int, int, error) {
+	emails, err := st.GetDueEmails(ctx, time.Now(), batch)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var sent, failed int
+
+	for _, e := range emails {
+		err := s.Send(ctx, e.To, e.Subject, e.Body)
+		now := time.Now()
+
+		if err == nil {
+			err = st.PutEmailSent(ctx, e.Id, now)
+			if err != nil {
+				return sent, failed, err
+			}
+			sent++
+			continue
+		}
+
+		failed++
+
+		attempts := e.Attempts + 1
+
+		var next *time.Time
+		if attempts < options.MaxAttempts {
+			t := now.Add(Backoff(options.Backoff, options.MaxBackoff, attempts))
+			next = &t
+			log.Warn("email not sent, it will be retried", "email id", e.Id, "attempts", attempts, "next attempt at", t, "err", err)
+		} else {
+			log.Error("email not sent, it ran out of attempts", "email id", e.Id, "attempts", attempts, "err", err)
+		}
+
+		err = st.PutFailedEmailAttempt(ctx, e.Id, err.Error(), now, next)
+		if err != nil {
+			return sent, failed, err
+		}
+	}
+
+	return sent, failed, nil
+}
+
+// Backoff returns the delay before the next attempt after the failed ones:
+// base after the first one, doubled after each next one, at most max.
+func Backoff(base, max time.Duration, attempts int) time.Duration {
+	d := base
+	for i := 1; i < attempts; i++ {
+		d *= 2
+		if d >= max {
+			return max
+		}
+	}
+	return min(d, max)
+}
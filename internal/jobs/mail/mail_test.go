@@ -0,0 +1,33 @@
+package mail
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		name     string
+		base     time.Duration
+		max      time.Duration
+		attempts int
+		want     time.Duration
+	}{
+		{"first attempt", time.Minute, time.Hour, 1, time.Minute},
+		{"second attempt", time.Minute, time.Hour, 2, 2 * time.Minute},
+		{"fifth attempt", time.Minute, time.Hour, 5, 16 * time.Minute},
+		{"reaches max", time.Minute, time.Hour, 7, time.Hour},
+		{"capped at max", time.Minute, time.Hour, 100, time.Hour},
+		{"base over max", 2 * time.Hour, time.Hour, 1, time.Hour},
+		{"no attempts", time.Minute, time.Hour, 0, time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Backoff(tt.base, tt.max, tt.attempts)
+			if got != tt.want {
+				t.Errorf("Backoff(%s, %s, %d) = %s, want %s", tt.base, tt.max, tt.attempts, got, tt.want)
+			}
+		})
+	}
+}
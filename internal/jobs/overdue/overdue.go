@@ -0,0 +1,59 @@
+package overdue
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/qo/digital-library/internal/config"
+	"github.com/qo/digital-library/internal/logger"
+	"github.com/qo/digital-library/internal/storage/loan"
+)
+
+type loanStorage interface {
+	MarkOverdueLoans(ctx context.Context, now time.Time) ([]loan.Loan, error)
+	AssignHolds(ctx context.Context, options config.LoansOptions) ([]loan.Loan, error)
+}
+
+type notifier interface {
+	LoanOverdue(ctx context.Context, loans []loan.Loan)
+	HoldAvailable(ctx context.Context, loans []loan.Loan)
+}
+
+// Run periodically marks the loans which became overdue
+// and lends the copies freed without being returned, e.g. by the deleted users,
+// to the queues for them. The users of both are notified.
+// It blocks until the context is done.
+func Run(ctx context.Context, log logger.Logger, st loanStorage, nt notifier, options config.LoansOptions) {
+	const errMsg = "can't check overdue loans"
+
+	ticker := time.NewTicker(options.Interval)
+	defer ticker.Stop()
+
+	for {
+		overdue, err := st.MarkOverdueLoans(ctx, time.Now())
+		if err != nil {
+			log.Error(fmt.Sprintf("%s: %s", errMsg, err))
+		} else {
+			for _, l := range overdue {
+				log.Info("loan is overdue", "loan id", l.Id, "user id", l.UserId, "book id", l.BookId, "due at", l.DueAt)
+			}
+			nt.LoanOverdue(ctx, overdue)
+			log.Debug("overdue loans checked", "overdue", len(overdue))
+		}
+
+		assigned, err := st.AssignHolds(ctx, options)
+		if err != nil {
+			log.Error(fmt.Sprintf("%s: %s", errMsg, err))
+		} else {
+			nt.HoldAvailable(ctx, assigned)
+			log.Debug("holds assigned", "loans", len(assigned))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package purge
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/qo/digital-library/internal/config"
+	"github.com/qo/digital-library/internal/logger"
+)
+
+type purger interface {
+	Purge(ctx context.Context, before time.Time) (int64, error)
+}
+
+// Run periodically removes the rows which were soft deleted
+// longer than the retention ago.
+// It blocks until the context is done.
+func Run(ctx context.Context, log logger.Logger, p purger, options config.PurgeOptions) {
+	const errMsg = "can't purge deleted rows"
+
+	ticker := time.NewTicker(options.Interval)
+	defer ticker.Stop()
+
+	for {
+		before := time.Now().Add(-options.Retention)
+
+		n, err := p.Purge(ctx, before)
+		if err != nil {
+			log.Error(fmt.Sprintf("%s: %s", errMsg, err))
+		} else {
+			log.Debug("deleted rows purged", "rows", n, "before", before)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package recommend
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/qo/digital-library/internal/config"
+	"github.com/qo/digital-library/internal/logger"
+	"github.com/qo/digital-library/internal/recommender"
+	"github.com/qo/digital-library/internal/storage/recommendation"
+)
+
+type recommendationStorage interface {
+	GetRecommendationSignals(ctx context.Context) (*recommendation.Signals, error)
+	PutRecommendations(ctx context.Context, recommendations []recommendation.Recommendation, computedAt time.Time) error
+}
+
+// Run periodically recomputes the recommendations of all users
+// and caches them in the storage.
+// It blocks until the context is done.
+func Run(ctx context.Context, log logger.Logger, st recommendationStorage, options config.RecommendationsOptions) {
+	const errMsg = "can't compute recommendations"
+
+	ticker := time.NewTicker(options.Interval)
+	defer ticker.Stop()
+
+	for {
+		start := time.Now()
+
+		n, err := compute(ctx, st, options.Limit)
+		if err != nil {
+			log.Error(fmt.Sprintf("%s: %s", errMsg, err))
+		} else {
+			log.Debug("recommendations computed", "recommendations", n, "latency", time.Since(start))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func compute(ctx context.Context, st recommendationStorage, limit int) (int, error) {
+	computedAt := time.Now()
+
+	signals, err := st.GetRecommendationSignals(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	recommendations := recommender.Compute(signals, limit)
+
+	err = st.PutRecommendations(ctx, recommendations, computedAt)
+	if err != nil {
+		return 0, err
+	}
+
+	return len(recommendations), nil
+}
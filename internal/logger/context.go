@@ -0,0 +1,38 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+)
+
+type ctxKey struct{}
+
+// WithAttrs returns the context carrying the attributes,
+// they are added to every record logged with the context, e.g. by InfoContext.
+func WithAttrs(ctx context.Context, args ...any) context.Context {
+	attrs, _ := ctx.Value(ctxKey{}).([]slog.Attr)
+	attrs = append(attrs, slog.Group("", args...).Value.Group()...)
+	// the capacity is cut so that the contexts derived from this one don't share the attributes
+	return context.WithValue(ctx, ctxKey{}, attrs[:len(attrs):len(attrs)])
+}
+
+// contextHandler adds the attributes of the context to the records.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if attrs, ok := ctx.Value(ctxKey{}).([]slog.Attr); ok {
+		r = r.Clone()
+		r.AddAttrs(attrs...)
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
@@ -58,5 +58,7 @@ func Init(env string) (*Logger, error) {
 
 	}
 
+	logger = slog.New(contextHandler{logger.Handler()})
+
 	return &Logger{logger}, nil
 }
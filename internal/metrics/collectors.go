@@ -0,0 +1,96 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/qo/digital-library/internal/logger"
+)
+
+func desc(subsystem, name, help string) *prometheus.Desc {
+	return prometheus.NewDesc(prometheus.BuildFQName(namespace, subsystem, name), help, nil, nil)
+}
+
+var (
+	maxOpenConnections = desc("db", "max_open_connections", "Maximum number of the open connections to the db.")
+	openConnections    = desc("db", "open_connections", "Number of the established connections to the db, both in use and idle.")
+	inUseConnections   = desc("db", "in_use_connections", "Number of the connections to the db currently in use.")
+	idleConnections    = desc("db", "idle_connections", "Number of the idle connections to the db.")
+	waitCount          = desc("db", "wait_count_total", "Number of the connections waited for.")
+	waitDuration       = desc("db", "wait_duration_seconds_total", "Time blocked waiting for a new connection.")
+	maxIdleClosed      = desc("db", "max_idle_closed_total", "Number of the connections closed due to the max idle connections limit.")
+	maxIdleTimeClosed  = desc("db", "max_idle_time_closed_total", "Number of the connections closed due to the max idle time.")
+	maxLifetimeClosed  = desc("db", "max_lifetime_closed_total", "Number of the connections closed due to the max lifetime.")
+)
+
+// dbStatsCollector collects the stats of the db connection pool.
+type dbStatsCollector struct {
+	st storage
+}
+
+func (c dbStatsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- maxOpenConnections
+	ch <- openConnections
+	ch <- inUseConnections
+	ch <- idleConnections
+	ch <- waitCount
+	ch <- waitDuration
+	ch <- maxIdleClosed
+	ch <- maxIdleTimeClosed
+	ch <- maxLifetimeClosed
+}
+
+func (c dbStatsCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.st.Stats()
+
+	ch <- prometheus.MustNewConstMetric(maxOpenConnections, prometheus.GaugeValue, float64(stats.MaxOpenConnections))
+	ch <- prometheus.MustNewConstMetric(openConnections, prometheus.GaugeValue, float64(stats.OpenConnections))
+	ch <- prometheus.MustNewConstMetric(inUseConnections, prometheus.GaugeValue, float64(stats.InUse))
+	ch <- prometheus.MustNewConstMetric(idleConnections, prometheus.GaugeValue, float64(stats.Idle))
+	ch <- prometheus.MustNewConstMetric(waitCount, prometheus.CounterValue, float64(stats.WaitCount))
+	ch <- prometheus.MustNewConstMetric(waitDuration, prometheus.CounterValue, stats.WaitDuration.Seconds())
+	ch <- prometheus.MustNewConstMetric(maxIdleClosed, prometheus.CounterValue, float64(stats.MaxIdleClosed))
+	ch <- prometheus.MustNewConstMetric(maxIdleTimeClosed, prometheus.CounterValue, float64(stats.MaxIdleTimeClosed))
+	ch <- prometheus.MustNewConstMetric(maxLifetimeClosed, prometheus.CounterValue, float64(stats.MaxLifetimeClosed))
+}
+
+var (
+	books   = desc("library", "books", "Number of the books which are not deleted.")
+	authors = desc("library", "authors", "Number of the authors which are not deleted.")
+	users   = desc("library", "users", "Number of the users.")
+	reviews = desc("library", "reviews", "Number of the book reviews which are not deleted.")
+)
+
+// libraryCollector collects the gauges of the library contents.
+// A gauge is skipped if it can't be counted.
+type libraryCollector struct {
+	logger.Logger
+	st storage
+}
+
+func (c libraryCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- books
+	ch <- authors
+	ch <- users
+	ch <- reviews
+}
+
+func (c libraryCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, g := range []struct {
+		desc  *prometheus.Desc
+		count func(context.Context) (int, error)
+	}{
+		{books, c.st.CountBooks},
+		{authors, c.st.CountAuthors},
+		{users, c.st.CountUsers},
+		{reviews, c.st.CountBookReviews},
+	} {
+		n, err := g.count(context.Background())
+		if err != nil {
+			c.Error(fmt.Sprintf("can't collect library metrics: %s", err))
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(g.desc, prometheus.GaugeValue, float64(n))
+	}
+}
@@ -0,0 +1,114 @@
+// Package metrics exposes the metrics of the server in the Prometheus format.
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/qo/digital-library/internal/logger"
+)
+
+const namespace = "digital_library"
+
+type storage interface {
+	Stats() sql.DBStats
+	CountBooks(ctx context.Context) (int, error)
+	CountAuthors(ctx context.Context) (int, error)
+	CountUsers(ctx context.Context) (int, error)
+	CountBookReviews(ctx context.Context) (int, error)
+}
+
+// Metrics collects the metrics of the http requests, the db and the library.
+type Metrics struct {
+	registry *prometheus.Registry
+	requests *prometheus.CounterVec
+	latency  *prometheus.HistogramVec
+	queries  *prometheus.HistogramVec
+}
+
+// New creates the metrics. The db pool stats and the library gauges
+// are read from the storage on every scrape.
+func New(log logger.Logger, st storage) *Metrics {
+	m := Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "requests_total",
+			Help:      "Number of the http requests by route pattern, method and status.",
+		}, []string{"route", "method", "status"}),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "request_duration_seconds",
+			Help:      "Latency of the http requests by route pattern, method and status.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method", "status"}),
+		queries: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "db",
+			Name:      "query_duration_seconds",
+			Help:      "Latency of the storage queries by query.",
+			Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+		}, []string{"query"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests,
+		m.latency,
+		m.queries,
+		dbStatsCollector{st},
+		libraryCollector{log, st},
+	)
+
+	return &m
+}
+
+// Handler serves the metrics.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// ObserveQuery implements storage.QueryObserver.
+func (m *Metrics) ObserveQuery(query string, d time.Duration) {
+	m.queries.WithLabelValues(query).Observe(d.Seconds())
+}
+
+// Middleware counts the requests and observes their latency.
+// The requests are labeled with the route pattern, e.g. /api/book/{id},
+// so the label values are bounded; the requests not matching any route are labeled "other".
+// It has to be used by the root router, as the pattern is only complete once the request is routed.
+func (m *Metrics) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+		next.ServeHTTP(ww, r)
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		route := "other"
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			route = rctx.RoutePattern()
+		}
+
+		labels := prometheus.Labels{"route": route, "method": r.Method, "status": strconv.Itoa(status)}
+
+		m.requests.With(labels).Inc()
+		m.latency.With(labels).Observe(time.Since(start).Seconds())
+	})
+}
@@ -0,0 +1,138 @@
+// Package middleware contains the middleware shared by all the routes of the server.
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"runtime/debug"
+	"slices"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/qo/digital-library/internal/logger"
+)
+
+const RequestIdHeader = "X-Request-ID"
+
+// requestIdPattern is what the request ids sent by the clients should look like,
+// the other ids are replaced so they can't mess up the logs.
+var requestIdPattern = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,64}$`)
+
+type ctxKey struct{}
+
+// RequestIdFromContext returns the id of the request.
+func RequestIdFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(ctxKey{}).(string)
+	return id, ok
+}
+
+// RequestId attaches the id of the request to the request context and to the response.
+// The id is taken from the X-Request-ID header if the client sent it, otherwise it's generated.
+// Everything logged with the request context gets the "request id" attribute.
+func RequestId(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIdHeader)
+		if !requestIdPattern.MatchString(id) {
+			id = newRequestId()
+		}
+
+		ctx := context.WithValue(r.Context(), ctxKey{}, id)
+		ctx = logger.WithAttrs(ctx, "request id", id)
+
+		w.Header().Set(RequestIdHeader, id)
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func newRequestId() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// AccessLog logs every request once it's served:
+// its method, path, response status, number of bytes written and latency.
+// The 5xx responses are logged as errors.
+// The requests to the skipped paths, e.g. the probes, are not logged.
+func AccessLog(log logger.Logger, skip ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if slices.Contains(skip, r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			start := time.Now()
+
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+			next.ServeHTTP(ww, r)
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			level := slog.LevelInfo
+			if status >= 500 {
+				level = slog.LevelError
+			}
+
+			log.Log(r.Context(), level, "request served",
+				"method", r.Method,
+				"path", r.URL.Path,
+				"status", status,
+				"bytes", ww.BytesWritten(),
+				"latency", time.Since(start),
+			)
+		})
+	}
+}
+
+// Recover recovers the panics of the handlers: it logs the panic along with the stack
+// and responds with 500 if the response isn't started yet.
+func Recover(log logger.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+			defer func() {
+				rec := recover()
+				if rec == nil {
+					return
+				}
+				// the server aborts the response silently on this panic, it's not an error
+				if rec == http.ErrAbortHandler {
+					panic(rec)
+				}
+
+				log.ErrorContext(r.Context(), "handler panicked",
+					"method", r.Method,
+					"path", r.URL.Path,
+					"panic", rec,
+					"stack", string(debug.Stack()),
+				)
+
+				if ww.Status() != 0 {
+					return
+				}
+
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusInternalServerError)
+				json.NewEncoder(w).Encode(struct {
+					Error string `json:"error"`
+				}{
+					Error: "internal server error",
+				})
+			}()
+
+			next.ServeHTTP(ww, r)
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/cors"
+	"github.com/qo/digital-library/internal/config"
+)
+
+// defaultContentSecurityPolicy allows the scripts and the styles the views load from the CDNs.
+// The inline scripts and styles are allowed as the swagger page and tailwind use them.
+const defaultContentSecurityPolicy = "default-src 'self'; " +
+	"script-src 'self' 'unsafe-inline' https://cdn.tailwindcss.com https://unpkg.com; " +
+	"style-src 'self' 'unsafe-inline' https://cdnjs.cloudflare.com https://cdn.jsdelivr.net https://unpkg.com; " +
+	"font-src 'self' data: https://cdnjs.cloudflare.com; " +
+	"img-src 'self' data:; " +
+	"frame-ancestors 'none'; " +
+	"form-action 'self'; " +
+	"base-uri 'self'"
+
+// CORS allows the cross-origin requests to the paths under the prefix from the configured origins.
+// The other paths, i.e. the views relying on the session cookie, are never shared with other origins.
+// If no origins are configured, it does nothing.
+func CORS(prefix string, options config.CORSOptions) func(http.Handler) http.Handler {
+	if len(options.AllowedOrigins) == 0 {
+		return func(next http.Handler) http.Handler {
+			return next
+		}
+	}
+
+	handler := cors.Handler(cors.Options{
+		AllowedOrigins: options.AllowedOrigins,
+		AllowedMethods: options.AllowedMethods,
+		AllowedHeaders: []string{"Accept", "Authorization", "Content-Type", RequestIdHeader, "traceparent", "tracestate"},
+		ExposedHeaders: []string{
+			RequestIdHeader, "Content-Disposition", "Retry-After",
+			"RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset",
+		},
+		AllowCredentials: options.AllowCredentials,
+		MaxAge:           int(options.MaxAge.Seconds()),
+	})
+
+	return func(next http.Handler) http.Handler {
+		withCORS := handler(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if strings.HasPrefix(r.URL.Path, prefix+"/") {
+				withCORS.ServeHTTP(w, r)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// SecurityHeaders sets the Content-Security-Policy, X-Frame-Options, X-Content-Type-Options
+// and Referrer-Policy headers of the responses, and Strict-Transport-Security if https is set.
+func SecurityHeaders(options config.SecurityOptions, https bool) func(http.Handler) http.Handler {
+	csp := options.ContentSecurityPolicy
+	if csp == "" {
+		csp = defaultContentSecurityPolicy
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			h := w.Header()
+			h.Set("Content-Security-Policy", csp)
+			h.Set("X-Content-Type-Options", "nosniff")
+			h.Set("Referrer-Policy", "strict-origin-when-cross-origin")
+			if options.FrameOptions != "" {
+				h.Set("X-Frame-Options", options.FrameOptions)
+			}
+			if https && options.HSTSMaxAge > 0 {
+				h.Set("Strict-Transport-Security", fmt.Sprintf("max-age=%d; includeSubDomains", int(options.HSTSMaxAge.Seconds())))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,149 @@
+// Package notify tells the users about the events of their loans
+// through the in-app inbox and email.
+// The replies to the reviews aren't notified of, as the reviews can't be replied to yet.
+package notify
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/qo/digital-library/internal/config"
+	"github.com/qo/digital-library/internal/logger"
+	"github.com/qo/digital-library/internal/storage/book"
+	"github.com/qo/digital-library/internal/storage/email"
+	"github.com/qo/digital-library/internal/storage/loan"
+	"github.com/qo/digital-library/internal/storage/notification"
+	"github.com/qo/digital-library/internal/storage/notification_preference"
+	"github.com/qo/digital-library/internal/storage/user"
+)
+
+const (
+	// TypeHoldAvailable is sent when a held book is checked out to the user
+	TypeHoldAvailable = "hold_available"
+	// TypeLoanOverdue is sent when a loan becomes overdue
+	TypeLoanOverdue = "loan_overdue"
+)
+
+// Types are the types of the notifications the users can set preferences of.
+// A type for the replies to the reviews belongs here once the reviews can be replied to.
+var Types = []string{TypeHoldAvailable, TypeLoanOverdue}
+
+func IsType(typ string) bool {
+	for _, t := range Types {
+		if t == typ {
+			return true
+		}
+	}
+	return false
+}
+
+type notifyStorage interface {
+	GetUser(ctx context.Context, id int) (*user.User, error)
+	GetBook(ctx context.Context, id int, includeDeleted bool) (*book.Book, error)
+	GetEmail(ctx context.Context, userId int) (string, error)
+	GetNotificationPreference(ctx context.Context, userId int, typ string) (*notification_preference.NotificationPreference, error)
+	PostNotification(ctx context.Context, n *notification.Notification, e *email.Email) error
+}
+
+// Notifier puts the notifications in the inbox
+// and the emails in the outbox, which are sent by the mail job.
+// The failures are logged and don't fail the event they are about.
+type Notifier struct {
+	logger.Logger
+	notifyStorage
+	options config.NotificationsOptions
+}
+
+func New(log logger.Logger, st notifyStorage, options config.NotificationsOptions) *Notifier {
+	return &Notifier{
+		log,
+		st,
+		options,
+	}
+}
+
+// HoldAvailable notifies the users the loans were made to from the queues.
+func (n *Notifier) HoldAvailable(ctx context.Context, loans []loan.Loan) {
+	for _, l := range loans {
+		n.notify(ctx, TypeHoldAvailable, l)
+	}
+}
+
+// LoanOverdue notifies the users of their overdue loans.
+func (n *Notifier) LoanOverdue(ctx context.Context, loans []loan.Loan) {
+	for _, l := range loans {
+		n.notify(ctx, TypeLoanOverdue, l)
+	}
+}
+
+func (n *Notifier) notify(ctx context.Context, typ string, l loan.Loan) {
+	const errMsg = "can't notify user"
+
+	err := n.post(ctx, typ, l)
+	if err != nil {
+		n.ErrorContext(ctx, fmt.Sprintf("%s: %s", errMsg, err), "type", typ, "user id", l.UserId, "loan id", l.Id)
+		return
+	}
+
+	n.DebugContext(ctx, "user notified", "type", typ, "user id", l.UserId, "loan id", l.Id)
+}
+
+func (n *Notifier) post(ctx context.Context, typ string, l loan.Loan) error {
+	p, err := n.GetNotificationPreference(ctx, l.UserId, typ)
+	if err != nil {
+		return err
+	}
+
+	var address string
+	if p.Email && n.options.Enabled {
+		address, err = n.GetEmail(ctx, l.UserId)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !p.InApp && address == "" {
+		return nil
+	}
+
+	u, err := n.GetUser(ctx, l.UserId)
+	if err != nil {
+		return err
+	}
+
+	// the book may be deleted while it's lent
+	b, err := n.GetBook(ctx, l.BookId, true)
+	if err != nil {
+		return err
+	}
+
+	subject, body, err := render(typ, data{*u, *b, l})
+	if err != nil {
+		return err
+	}
+
+	var (
+		nt *notification.Notification
+		e  *email.Email
+	)
+
+	if p.InApp {
+		nt = &notification.Notification{
+			UserId:  l.UserId,
+			Type:    typ,
+			Subject: subject,
+			Body:    body,
+		}
+	}
+
+	if address != "" {
+		e = &email.Email{
+			UserId:  l.UserId,
+			To:      address,
+			Subject: subject,
+			Body:    body,
+		}
+	}
+
+	return n.PostNotification(ctx, nt, e)
+}
@@ -0,0 +1,123 @@
+package notify
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/qo/digital-library/internal/config"
+)
+
+// SMTPSender sends the emails through the configured SMTP server.
+// The connection is upgraded with STARTTLS if the server supports it,
+// the credentials are sent only if the username is set.
+type SMTPSender struct {
+	options config.SMTPOptions
+}
+
+func NewSMTPSender(options config.SMTPOptions) *SMTPSender {
+	return &SMTPSender{options}
+}
+
+// headerEscaper keeps the header values on one line
+var headerEscaper = strings.NewReplacer("\r", "", "\n", "")
+
+func (s *SMTPSender) Send(ctx context.Context, to, subject, body string) error {
+	const errMsg = "can't send email"
+
+	addr := net.JoinHostPort(s.options.Host, strconv.Itoa(s.options.Port))
+
+	d := net.Dialer{Timeout: s.options.Timeout}
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+	defer conn.Close()
+
+	err = conn.SetDeadline(time.Now().Add(s.options.Timeout))
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	c, err := smtp.NewClient(conn, s.options.Host)
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		err = c.StartTLS(&tls.Config{ServerName: s.options.Host})
+		if err != nil {
+			return fmt.Errorf("%s: %w", errMsg, err)
+		}
+	}
+
+	if s.options.Username != "" {
+		err = c.Auth(smtp.PlainAuth("", s.options.Username, s.options.Password, s.options.Host))
+		if err != nil {
+			return fmt.Errorf("%s: %w", errMsg, err)
+		}
+	}
+
+	err = c.Mail(s.options.From)
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	err = c.Rcpt(to)
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	_, err = w.Write(message(s.options.From, to, subject, body))
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	err = w.Close()
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	err = c.Quit()
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return nil
+}
+
+// message returns the plain text email with the lines ending in CRLF.
+func message(from, to, subject, body string) []byte {
+	var sb strings.Builder
+
+	header := func(name, value string) {
+		fmt.Fprintf(&sb, "%s: %s\r\n", name, headerEscaper.Replace(value))
+	}
+
+	header("From", from)
+	header("To", to)
+	header("Subject", mime.QEncoding.Encode("utf-8", subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("MIME-Version", "1.0")
+	header("Content-Type", `text/plain; charset="utf-8"`)
+	header("Content-Transfer-Encoding", "8bit")
+	sb.WriteString("\r\n")
+
+	body = strings.ReplaceAll(body, "\r\n", "\n")
+	sb.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	sb.WriteString("\r\n")
+
+	return []byte(sb.String())
+}
@@ -0,0 +1,253 @@
+package notify
+
+import (
+	"context"
+	"io"
+	"mime"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/qo/digital-library/internal/config"
+)
+
+// received is what the fake SMTP server got from the client.
+type received struct {
+	commands []string
+	data     []byte
+}
+
+// serveSMTP accepts one connection on the listener and talks SMTP on it without STARTTLS,
+// what it got is sent to the channel when the client quits.
+func serveSMTP(t *testing.T, l net.Listener, got chan<- received) {
+	conn, err := l.Accept()
+	if err != nil {
+		t.Errorf("can't accept: %s", err)
+		close(got)
+		return
+	}
+	defer conn.Close()
+
+	tc := textproto.NewConn(conn)
+	var r received
+
+	reply := func(format string, args ...any) bool {
+		err := tc.PrintfLine(format, args...)
+		if err != nil {
+			t.Errorf("can't reply: %s", err)
+			return false
+		}
+		return true
+	}
+
+	if !reply("220 localhost ESMTP fake") {
+		close(got)
+		return
+	}
+
+	for {
+		line, err := tc.ReadLine()
+		if err != nil {
+			t.Errorf("can't read command: %s", err)
+			close(got)
+			return
+		}
+		r.commands = append(r.commands, line)
+
+		verb, _, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO":
+			// no STARTTLS and no AUTH are advertised
+			reply("250-localhost")
+			reply("250 8BITMIME")
+		case "DATA":
+			reply("354 go ahead")
+			r.data, err = tc.ReadDotBytes()
+			if err != nil {
+				t.Errorf("can't read data: %s", err)
+				close(got)
+				return
+			}
+			reply("250 queued")
+		case "QUIT":
+			reply("221 bye")
+			got <- r
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+}
+
+func TestSMTPSenderSend(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("can't listen: %s", err)
+	}
+	defer l.Close()
+
+	got := make(chan received, 1)
+	go serveSMTP(t, l, got)
+
+	host, port, err := net.SplitHostPort(l.Addr().String())
+	if err != nil {
+		t.Fatalf("can't split address: %s", err)
+	}
+	p, err := strconv.Atoi(port)
+	if err != nil {
+		t.Fatalf("can't parse port: %s", err)
+	}
+
+	s := NewSMTPSender(config.SMTPOptions{
+		Enabled: true,
+		Host:    host,
+		Port:    p,
+		From:    "library@example.com",
+		Timeout: 5 * time.Second,
+	})
+
+	body := "Your hold is available.\n.\nBye"
+	err = s.Send(context.Background(), "reader@example.com", "Книга доступна", body)
+	if err != nil {
+		t.Fatalf("Send() error: %s", err)
+	}
+
+	r, ok := <-got
+	if !ok {
+		t.Fatal("server got nothing")
+	}
+
+	for _, c := range r.commands {
+		if strings.HasPrefix(strings.ToUpper(c), "STARTTLS") || strings.HasPrefix(strings.ToUpper(c), "AUTH") {
+			t.Errorf("command %q sent to a server which doesn't support it", c)
+		}
+	}
+
+	wantCommands := []string{"MAIL FROM:<library@example.com>", "RCPT TO:<reader@example.com>", "DATA", "QUIT"}
+	for _, want := range wantCommands {
+		found := false
+		for _, c := range r.commands {
+			if strings.HasPrefix(c, want) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("command %q not sent, commands: %q", want, r.commands)
+		}
+	}
+
+	m, err := mail.ReadMessage(strings.NewReader(string(r.data)))
+	if err != nil {
+		t.Fatalf("can't parse message: %s", err)
+	}
+
+	headers := []struct {
+		name string
+		want string
+	}{
+		{"From", "library@example.com"},
+		{"To", "reader@example.com"},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", `text/plain; charset="utf-8"`},
+		{"Content-Transfer-Encoding", "8bit"},
+	}
+	for _, h := range headers {
+		if got := m.Header.Get(h.name); got != h.want {
+			t.Errorf("header %s = %q, want %q", h.name, got, h.want)
+		}
+	}
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(m.Header.Get("Subject"))
+	if err != nil {
+		t.Fatalf("can't decode subject: %s", err)
+	}
+	if subject != "Книга доступна" {
+		t.Errorf("subject = %q, want %q", subject, "Книга доступна")
+	}
+
+	_, err = m.Header.Date()
+	if err != nil {
+		t.Errorf("can't parse date: %s", err)
+	}
+
+	b, err := io.ReadAll(m.Body)
+	if err != nil {
+		t.Fatalf("can't read body: %s", err)
+	}
+	// the dots are unstuffed and the line endings are normalized by the reader
+	if want := body + "\n"; string(b) != want {
+		t.Errorf("body = %q, want %q", b, want)
+	}
+}
+
+func TestMessage(t *testing.T) {
+	tests := []struct {
+		name    string
+		to      string
+		subject string
+		body    string
+		want    []string
+	}{
+		{
+			name:    "plain",
+			to:      "reader@example.com",
+			subject: "Hold available",
+			body:    "Hello",
+			want:    []string{"To: reader@example.com\r\n", "Subject: Hold available\r\n", "\r\n\r\nHello\r\n"},
+		},
+		{
+			name:    "header injection",
+			to:      "reader@example.com\r\nBcc: x@example.com",
+			subject: "Hold\r\nBcc: x@example.com",
+			body:    "Hello",
+			want:    []string{"To: reader@example.comBcc: x@example.com\r\n", "Subject: =?utf-8?q?Hold=0D=0ABcc:_x@example.com?=\r\n"},
+		},
+		{
+			name:    "line endings",
+			to:      "reader@example.com",
+			subject: "Hold available",
+			body:    "one\ntwo\r\nthree",
+			want:    []string{"\r\n\r\none\r\ntwo\r\nthree\r\n"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := string(message("library@example.com", tt.to, tt.subject, tt.body))
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("message() = %q, want it to contain %q", got, want)
+				}
+			}
+			if strings.Contains(got, "\nBcc:") {
+				t.Errorf("message() = %q, want no Bcc header", got)
+			}
+		})
+	}
+}
+
+func TestSMTPSenderSendUnreachable(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("can't listen: %s", err)
+	}
+	addr := l.Addr().(*net.TCPAddr)
+	l.Close()
+
+	s := NewSMTPSender(config.SMTPOptions{
+		Host:    "127.0.0.1",
+		Port:    addr.Port,
+		From:    "library@example.com",
+		Timeout: time.Second,
+	})
+
+	err = s.Send(context.Background(), "reader@example.com", "subject", "body")
+	if err == nil {
+		t.Error("Send() to a closed port succeeded")
+	}
+}
@@ -0,0 +1,58 @@
+package notify
+
+import (
+	"embed"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/qo/digital-library/internal/storage/book"
+	"github.com/qo/digital-library/internal/storage/loan"
+	"github.com/qo/digital-library/internal/storage/user"
+)
+
+//go:embed templates/*.tmpl
+var templatesFS embed.FS
+
+// templates are the templates of the types by the type,
+// each of them defines the subject and the body.
+var templates = parseTemplates()
+
+func parseTemplates() map[string]*template.Template {
+	templates := make(map[string]*template.Template, len(Types))
+	for _, typ := range Types {
+		templates[typ] = template.Must(template.ParseFS(templatesFS, "templates/"+typ+".tmpl"))
+	}
+	return templates
+}
+
+// data is what the templates are executed with.
+type data struct {
+	User user.User
+	Book book.Book
+	Loan loan.Loan
+}
+
+// render returns the subject and the body of the notification of the type.
+func render(typ string, d data) (string, string, error) {
+	const errMsg = "can't render notification"
+
+	t, ok := templates[typ]
+	if !ok {
+		return "", "", fmt.Errorf("%s: type %s is unknown", errMsg, typ)
+	}
+
+	var subject, body strings.Builder
+
+	err := t.ExecuteTemplate(&subject, "subject", d)
+	if err != nil {
+		return "", "", fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	err = t.ExecuteTemplate(&body, "body", d)
+	if err != nil {
+		return "", "", fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return strings.TrimSpace(subject.String()), body.String(), nil
+}
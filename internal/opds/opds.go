@@ -0,0 +1,204 @@
+package opds
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/qo/digital-library/internal/storage/author"
+	"github.com/qo/digital-library/internal/storage/book"
+)
+
+// See https://specs.opds.io/opds-1.2
+const (
+	NavigationType  = "application/atom+xml;profile=opds-catalog;kind=navigation"
+	AcquisitionType = "application/atom+xml;profile=opds-catalog;kind=acquisition"
+	OpenSearchType  = "application/opensearchdescription+xml"
+
+	RelSelf        = "self"
+	RelStart       = "start"
+	RelUp          = "up"
+	RelNext        = "next"
+	RelPrevious    = "previous"
+	RelSearch      = "search"
+	RelSubsection  = "subsection"
+	RelAcquisition = "http://opds-spec.org/acquisition"
+)
+
+const (
+	atomNamespace       = "http://www.w3.org/2005/Atom"
+	dcNamespace         = "http://purl.org/dc/terms/"
+	openSearchNamespace = "http://a9.com/-/spec/opensearch/1.1/"
+)
+
+type Feed struct {
+	XMLName  xml.Name `xml:"feed"`
+	Xmlns    string   `xml:"xmlns,attr"`
+	XmlnsDC  string   `xml:"xmlns:dc,attr"`
+	XmlnsOS  string   `xml:"xmlns:opensearch,attr"`
+	Id       string   `xml:"id"`
+	Title    string   `xml:"title"`
+	Updated  string   `xml:"updated"`
+	Links    []Link   `xml:"link"`
+	Entries  []Entry  `xml:"entry"`
+	feedType string
+}
+
+type Entry struct {
+	Title      string   `xml:"title"`
+	Id         string   `xml:"id"`
+	Updated    string   `xml:"updated"`
+	Authors    []Person `xml:"author"`
+	Publisher  string   `xml:"dc:publisher,omitempty"`
+	Issued     string   `xml:"dc:issued,omitempty"`
+	Identifier string   `xml:"dc:identifier,omitempty"`
+	Content    *Content `xml:"content,omitempty"`
+	Links      []Link   `xml:"link"`
+}
+
+type Person struct {
+	Name string `xml:"name"`
+	URI  string `xml:"uri,omitempty"`
+}
+
+type Content struct {
+	Type string `xml:"type,attr"`
+	Text string `xml:",chardata"`
+}
+
+type Link struct {
+	Rel   string `xml:"rel,attr,omitempty"`
+	Href  string `xml:"href,attr"`
+	Type  string `xml:"type,attr,omitempty"`
+	Title string `xml:"title,attr,omitempty"`
+}
+
+func newFeed(feedType, id, title string, updated time.Time) *Feed {
+	return &Feed{
+		Xmlns:    atomNamespace,
+		XmlnsDC:  dcNamespace,
+		XmlnsOS:  openSearchNamespace,
+		Id:       id,
+		Title:    title,
+		Updated:  updated.UTC().Format(time.RFC3339),
+		Links:    make([]Link, 0),
+		Entries:  make([]Entry, 0),
+		feedType: feedType,
+	}
+}
+
+func NewNavigationFeed(id, title string, updated time.Time) *Feed {
+	return newFeed(NavigationType, id, title, updated)
+}
+
+func NewAcquisitionFeed(id, title string, updated time.Time) *Feed {
+	return newFeed(AcquisitionType, id, title, updated)
+}
+
+// Type returns the media type of the feed.
+func (f *Feed) Type() string {
+	return f.feedType
+}
+
+func (f *Feed) AddLink(rel, href, linkType string) {
+	f.Links = append(f.Links, Link{Rel: rel, Href: href, Type: linkType})
+}
+
+// AddNavigation adds an entry leading to another feed.
+func (f *Feed) AddNavigation(id, title, href, linkType, content string) {
+	f.Entries = append(f.Entries, Entry{
+		Title:   title,
+		Id:      id,
+		Updated: f.Updated,
+		Content: &Content{"text", content},
+		Links: []Link{
+			{Rel: RelSubsection, Href: href, Type: linkType},
+		},
+	})
+}
+
+// AddBook adds a book entry.
+// authorHref returns the link to the author feed,
+// fileHref is the link to the book file or empty if there is no file.
+func (f *Feed) AddBook(b book.Book, authors []author.Author, authorHref func(author.Author) string, fileHref string) {
+	e := Entry{
+		Title:     b.Title,
+		Id:        fmt.Sprintf("urn:digital-library:book:%d", b.Id),
+		Updated:   f.Updated,
+		Authors:   make([]Person, 0, len(authors)),
+		Publisher: b.Publisher,
+		Links:     make([]Link, 0),
+	}
+
+	for _, a := range authors {
+		e.Authors = append(e.Authors, Person{a.FullName, authorHref(a)})
+	}
+
+	if b.Year > 0 {
+		e.Issued = strconv.Itoa(b.Year)
+	}
+
+	if b.Isbn != "" {
+		e.Identifier = "urn:isbn:" + b.Isbn
+	}
+
+	if fileHref != "" {
+		e.Links = append(e.Links, Link{Rel: RelAcquisition, Href: fileHref, Type: "application/pdf"})
+	}
+
+	f.Entries = append(f.Entries, e)
+}
+
+func (f *Feed) Write(w io.Writer) error {
+	_, err := io.WriteString(w, xml.Header)
+	if err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+
+	return enc.Encode(f)
+}
+
+// OpenSearchDescription describes how to search the catalog.
+// See https://github.com/dewitt/opensearch
+type OpenSearchDescription struct {
+	XMLName     xml.Name        `xml:"OpenSearchDescription"`
+	Xmlns       string          `xml:"xmlns,attr"`
+	ShortName   string          `xml:"ShortName"`
+	Description string          `xml:"Description"`
+	URLs        []OpenSearchURL `xml:"Url"`
+}
+
+type OpenSearchURL struct {
+	Type     string `xml:"type,attr"`
+	Template string `xml:"template,attr"`
+}
+
+// NewOpenSearchDescription returns the description of the search
+// where template contains {searchTerms} to be replaced with the query.
+func NewOpenSearchDescription(shortName, description, template string) *OpenSearchDescription {
+	return &OpenSearchDescription{
+		Xmlns:       openSearchNamespace,
+		ShortName:   shortName,
+		Description: description,
+		URLs: []OpenSearchURL{
+			{AcquisitionType, template},
+		},
+	}
+}
+
+func (d *OpenSearchDescription) Write(w io.Writer) error {
+	_, err := io.WriteString(w, xml.Header)
+	if err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+
+	return enc.Encode(d)
+}
@@ -0,0 +1,81 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/qo/digital-library/internal/logger"
+)
+
+// Validate returns the middleware validating the requests against the spec.
+// The violations are logged, and the request is rejected with 400 if reject is set.
+// If responses is set the responses are validated too, their violations are only logged
+// since the response is already sent.
+// The requests not described by the spec are passed as is.
+func Validate(log logger.Logger, v *Validator, reject, responses bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			known, err := v.ValidateRequest(r)
+			if !known {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if err != nil {
+				log.WarnContext(r.Context(), "request doesn't match openapi spec",
+					"method", r.Method,
+					"path", r.URL.Path,
+					"violations", err.Error(),
+				)
+				if reject {
+					w.WriteHeader(http.StatusBadRequest)
+					json.NewEncoder(w).Encode(struct {
+						Error string `json:"error"`
+					}{
+						Error: fmt.Sprintf("invalid request: %s", err),
+					})
+					return
+				}
+			}
+
+			if !responses {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			var body limitedBuffer
+
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			ww.Tee(&body)
+
+			next.ServeHTTP(ww, r)
+
+			_, err = v.ValidateResponse(r, ww.Status(), ww.Header(), body.data, body.truncated)
+			if err != nil {
+				log.ErrorContext(r.Context(), "response doesn't match openapi spec",
+					"method", r.Method,
+					"path", r.URL.Path,
+					"status", ww.Status(),
+					"violations", err.Error(),
+				)
+			}
+		})
+	}
+}
+
+// limitedBuffer keeps the first maxBodySize bytes written to it.
+type limitedBuffer struct {
+	data      []byte
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := maxBodySize - len(b.data); len(p) > room {
+		b.data = append(b.data, p[:room]...)
+		b.truncated = true
+	} else {
+		b.data = append(b.data, p...)
+	}
+	return len(p), nil
+}
@@ -0,0 +1,356 @@
+// Package openapi generates the OpenAPI spec of the REST API
+// from the registered routes and the operations documented next to the handlers.
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const version = "3.0.3"
+
+// Operation documents a route of the REST API.
+type Operation struct {
+	Method string
+	// Path is the route relative to the api root, e.g. "/book/{id}"
+	Path    string
+	Id      string
+	Tag     string
+	Summary string
+	// Auth tells that the operation needs the bearer token
+	Auth bool
+	// Query lists the query params, the path params are taken from the path
+	Query []Param
+	// Request is the json request body, nil if there is none
+	Request any
+	// RequestMedia lists the media types of the body which is not json
+	RequestMedia []string
+	// Response is the json response body of the successful statuses,
+	// the errors are reported as {"error": "..."}
+	Response any
+	// ResponseErrors lists the error statuses responding with the Response body
+	ResponseErrors []int
+	// ResponseMedia lists the media types of the successful response which is not json
+	ResponseMedia []string
+	// Statuses describes the status codes the operation responds with
+	Statuses map[int]string
+}
+
+// Param is a query param.
+type Param struct {
+	Name        string
+	Description string
+	// Type is "string", "integer" or "boolean"
+	Type string
+	Enum []string
+}
+
+// Route is a registered route of the REST API.
+type Route struct {
+	Method string
+	Path   string
+}
+
+// Recorder records the routes registered by the api routers.
+type Recorder struct {
+	Routes []Route
+}
+
+func (rc *Recorder) add(method, route string) {
+	rc.Routes = append(rc.Routes, Route{method, route})
+}
+
+func (rc *Recorder) Get(route string, _ http.HandlerFunc)    { rc.add(http.MethodGet, route) }
+func (rc *Recorder) Post(route string, _ http.HandlerFunc)   { rc.add(http.MethodPost, route) }
+func (rc *Recorder) Put(route string, _ http.HandlerFunc)    { rc.add(http.MethodPut, route) }
+func (rc *Recorder) Delete(route string, _ http.HandlerFunc) { rc.add(http.MethodDelete, route) }
+
+// Info describes the api.
+type Info struct {
+	Title       string `json:"title"                 yaml:"title"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	Version     string `json:"version"               yaml:"version"`
+}
+
+// Document is the generated spec.
+type Document struct {
+	OpenAPI    string              `json:"openapi"    yaml:"openapi"`
+	Info       Info                `json:"info"       yaml:"info"`
+	Servers    []server            `json:"servers"    yaml:"servers"`
+	Tags       []Tag               `json:"tags"       yaml:"tags"`
+	Paths      map[string]pathItem `json:"paths"      yaml:"paths"`
+	Components components          `json:"components" yaml:"components"`
+}
+
+type server struct {
+	Url string `json:"url" yaml:"url"`
+}
+
+// Tag groups the operations.
+type Tag struct {
+	Name        string `json:"name"                  yaml:"name"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+}
+
+// pathItem holds the operations of the path by lowercase method.
+type pathItem map[string]operation
+
+type operation struct {
+	Tags        []string              `json:"tags"                  yaml:"tags"`
+	Summary     string                `json:"summary"               yaml:"summary"`
+	OperationId string                `json:"operationId"           yaml:"operationId"`
+	Security    []map[string][]string `json:"security,omitempty"    yaml:"security,omitempty"`
+	Parameters  []parameter           `json:"parameters,omitempty"  yaml:"parameters,omitempty"`
+	RequestBody *requestBody          `json:"requestBody,omitempty" yaml:"requestBody,omitempty"`
+	Responses   map[string]response   `json:"responses"             yaml:"responses"`
+}
+
+type parameter struct {
+	Name        string `json:"name"                  yaml:"name"`
+	In          string `json:"in"                    yaml:"in"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	Required    bool   `json:"required,omitempty"    yaml:"required,omitempty"`
+	Schema      Schema `json:"schema"                yaml:"schema"`
+}
+
+type requestBody struct {
+	Required bool                 `json:"required"  yaml:"required"`
+	Content  map[string]mediaType `json:"content"   yaml:"content"`
+}
+
+type response struct {
+	Description string               `json:"description"       yaml:"description"`
+	Content     map[string]mediaType `json:"content,omitempty" yaml:"content,omitempty"`
+}
+
+type mediaType struct {
+	Schema Schema `json:"schema" yaml:"schema"`
+}
+
+type components struct {
+	Schemas         map[string]Schema         `json:"schemas"         yaml:"schemas"`
+	SecuritySchemes map[string]securityScheme `json:"securitySchemes" yaml:"securitySchemes"`
+}
+
+type securityScheme struct {
+	Type   string `json:"type"   yaml:"type"`
+	Scheme string `json:"scheme" yaml:"scheme"`
+}
+
+const (
+	bearerAuth = "bearerAuth"
+	errorRef   = "#/components/schemas/Error"
+)
+
+var errorSchema = Schema{
+	Type:       "object",
+	Properties: map[string]Schema{"error": {Type: "string"}},
+}
+
+var pathParam = regexp.MustCompile(`{([^}]+)}`)
+
+// Generate builds the spec of the operations served at the server url.
+// Every route must be documented by exactly one operation and every operation must be routed,
+// so the spec can't drift from the routes.
+func Generate(info Info, serverUrl string, tags []Tag, routes []Route, ops []Operation) (*Document, error) {
+	const errMsg = "can't generate openapi spec"
+
+	tagged := make(map[string]bool)
+	for _, t := range tags {
+		tagged[t.Name] = true
+	}
+
+	routed := make(map[Route]bool)
+	for _, r := range routes {
+		routed[r] = true
+	}
+
+	documented := make(map[Route]bool)
+	ids := make(map[string]bool)
+
+	for _, op := range ops {
+		r := Route{op.Method, op.Path}
+		if !routed[r] {
+			return nil, fmt.Errorf("%s: operation %s %s is not routed", errMsg, op.Method, op.Path)
+		}
+		if documented[r] {
+			return nil, fmt.Errorf("%s: operation %s %s is documented twice", errMsg, op.Method, op.Path)
+		}
+		if !tagged[op.Tag] {
+			return nil, fmt.Errorf("%s: operation %s %s has unknown tag %s", errMsg, op.Method, op.Path, op.Tag)
+		}
+		if ids[op.Id] {
+			return nil, fmt.Errorf("%s: operation id %s is used twice", errMsg, op.Id)
+		}
+		documented[r] = true
+		ids[op.Id] = true
+	}
+
+	for _, r := range routes {
+		if !documented[r] {
+			return nil, fmt.Errorf("%s: route %s %s is not documented", errMsg, r.Method, r.Path)
+		}
+	}
+
+	doc := Document{
+		OpenAPI: version,
+		Info:    info,
+		Servers: []server{{serverUrl}},
+		Tags:    tags,
+		Paths:   make(map[string]pathItem),
+		Components: components{
+			Schemas: map[string]Schema{"Error": errorSchema},
+			SecuritySchemes: map[string]securityScheme{
+				bearerAuth: {Type: "http", Scheme: "bearer"},
+			},
+		},
+	}
+
+	sc := newSchemas(doc.Components.Schemas)
+
+	for _, op := range ops {
+		o, err := buildOperation(op, sc)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s %s: %w", errMsg, op.Method, op.Path, err)
+		}
+
+		item, ok := doc.Paths[op.Path]
+		if !ok {
+			item = make(pathItem)
+			doc.Paths[op.Path] = item
+		}
+		item[strings.ToLower(op.Method)] = o
+	}
+
+	return &doc, nil
+}
+
+func buildOperation(op Operation, sc *schemas) (operation, error) {
+	o := operation{
+		Tags:        []string{op.Tag},
+		Summary:     op.Summary,
+		OperationId: op.Id,
+		Responses:   make(map[string]response),
+	}
+
+	if op.Auth {
+		o.Security = []map[string][]string{{bearerAuth: {}}}
+	}
+
+	for _, m := range pathParam.FindAllStringSubmatch(op.Path, -1) {
+		o.Parameters = append(o.Parameters, parameter{
+			Name:     m[1],
+			In:       "path",
+			Required: true,
+			Schema:   Schema{Type: "integer"},
+		})
+	}
+
+	for _, p := range op.Query {
+		o.Parameters = append(o.Parameters, parameter{
+			Name:        p.Name,
+			In:          "query",
+			Description: p.Description,
+			Schema:      Schema{Type: p.Type, Enum: p.Enum},
+		})
+	}
+
+	switch {
+	case op.Request != nil:
+		s, err := sc.of(op.Request)
+		if err != nil {
+			return o, err
+		}
+		o.RequestBody = &requestBody{
+			Required: true,
+			Content:  map[string]mediaType{"application/json": {s}},
+		}
+	case len(op.RequestMedia) > 0:
+		o.RequestBody = &requestBody{
+			Required: true,
+			Content:  binary(op.RequestMedia),
+		}
+	}
+
+	var body *Schema
+	if op.Response != nil {
+		s, err := sc.of(op.Response)
+		if err != nil {
+			return o, err
+		}
+		body = &s
+	}
+
+	if len(op.Statuses) == 0 {
+		return o, fmt.Errorf("no statuses")
+	}
+
+	for code, description := range op.Statuses {
+		resp := response{Description: description}
+
+		switch {
+		case code >= 400 && !slices.Contains(op.ResponseErrors, code):
+			resp.Content = map[string]mediaType{"application/json": {Schema{Ref: errorRef}}}
+		case len(op.ResponseMedia) > 0:
+			resp.Content = binary(op.ResponseMedia)
+		case body != nil:
+			resp.Content = map[string]mediaType{"application/json": {*body}}
+		}
+
+		o.Responses[strconv.Itoa(code)] = resp
+	}
+
+	return o, nil
+}
+
+func binary(media []string) map[string]mediaType {
+	content := make(map[string]mediaType, len(media))
+	for _, m := range media {
+		content[m] = mediaType{Schema{Type: "string", Format: "binary"}}
+	}
+	return content
+}
+
+// JSON returns the spec as indented json.
+func (doc *Document) JSON() ([]byte, error) {
+	var buf bytes.Buffer
+
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+
+	err := enc.Encode(doc)
+	if err != nil {
+		return nil, fmt.Errorf("can't encode openapi spec to json: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// YAML returns the spec as yaml.
+func (doc *Document) YAML() ([]byte, error) {
+	var buf bytes.Buffer
+
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+
+	err := enc.Encode(doc)
+	if err != nil {
+		return nil, fmt.Errorf("can't encode openapi spec to yaml: %w", err)
+	}
+
+	err = enc.Close()
+	if err != nil {
+		return nil, fmt.Errorf("can't encode openapi spec to yaml: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,191 @@
+package openapi
+
+import (
+	"fmt"
+	"go/token"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Schema is a json schema of the spec.
+type Schema struct {
+	Ref                  string            `json:"$ref,omitempty"                 yaml:"$ref,omitempty"`
+	Type                 string            `json:"type,omitempty"                 yaml:"type,omitempty"`
+	Format               string            `json:"format,omitempty"               yaml:"format,omitempty"`
+	Enum                 []string          `json:"enum,omitempty"                 yaml:"enum,omitempty"`
+	Nullable             bool              `json:"nullable,omitempty"             yaml:"nullable,omitempty"`
+	Items                *Schema           `json:"items,omitempty"                yaml:"items,omitempty"`
+	Properties           map[string]Schema `json:"properties,omitempty"           yaml:"properties,omitempty"`
+	Required             []string          `json:"required,omitempty"             yaml:"required,omitempty"`
+	AdditionalProperties *Schema           `json:"additionalProperties,omitempty" yaml:"additionalProperties,omitempty"`
+}
+
+// schemas builds the schemas of the go types.
+// The exported named structs are put into the components and referenced,
+// the rest are inlined.
+type schemas struct {
+	components map[string]Schema
+	types      map[string]reflect.Type
+}
+
+func newSchemas(components map[string]Schema) *schemas {
+	return &schemas{
+		components: components,
+		types:      make(map[string]reflect.Type),
+	}
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func (sc *schemas) of(v any) (Schema, error) {
+	return sc.schema(reflect.TypeOf(v))
+}
+
+func (sc *schemas) schema(t reflect.Type) (Schema, error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t == timeType {
+		return Schema{Type: "string", Format: "date-time"}, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return Schema{Type: "boolean"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return Schema{Type: "integer"}, nil
+	case reflect.Int64, reflect.Uint64:
+		return Schema{Type: "integer", Format: "int64"}, nil
+	case reflect.Float32, reflect.Float64:
+		return Schema{Type: "number"}, nil
+	case reflect.String:
+		return Schema{Type: "string"}, nil
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return Schema{Type: "string", Format: "byte"}, nil
+		}
+		items, err := sc.schema(t.Elem())
+		if err != nil {
+			return Schema{}, err
+		}
+		return Schema{Type: "array", Items: &items}, nil
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return Schema{}, fmt.Errorf("map key of %s is not a string", t)
+		}
+		values, err := sc.schema(t.Elem())
+		if err != nil {
+			return Schema{}, err
+		}
+		return Schema{Type: "object", AdditionalProperties: &values}, nil
+	case reflect.Struct:
+		return sc.object(t)
+	default:
+		return Schema{}, fmt.Errorf("type %s is not supported", t)
+	}
+}
+
+func (sc *schemas) object(t reflect.Type) (Schema, error) {
+	name := t.Name()
+	if !token.IsExported(name) {
+		return sc.inline(t)
+	}
+
+	ref := Schema{Ref: "#/components/schemas/" + name}
+
+	if known, ok := sc.types[name]; ok {
+		if known != t {
+			return Schema{}, fmt.Errorf("types %s and %s have the same name", known, t)
+		}
+		return ref, nil
+	}
+
+	if _, ok := sc.components[name]; ok {
+		return Schema{}, fmt.Errorf("schema name %s of %s is reserved", name, t)
+	}
+
+	// the type is known before its schema is built so the recursive types end up with a ref
+	sc.types[name] = t
+
+	s, err := sc.inline(t)
+	if err != nil {
+		return Schema{}, err
+	}
+	sc.components[name] = s
+
+	return ref, nil
+}
+
+func (sc *schemas) inline(t reflect.Type) (Schema, error) {
+	s := Schema{Type: "object", Properties: make(map[string]Schema)}
+
+	err := sc.fields(t, &s, true)
+	if err != nil {
+		return Schema{}, err
+	}
+
+	return s, nil
+}
+
+// fields adds the properties of the struct fields the way encoding/json marshals them,
+// the fields of the embedded structs are promoted.
+// The fields without omitempty are always encoded, so they are required
+// unless they are promoted from an embedded pointer, which is skipped if it's nil.
+func (sc *schemas) fields(t reflect.Type, s *Schema, required bool) error {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+
+		ft := f.Type
+		for ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+
+		if f.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			err := sc.fields(ft, s, required && f.Type.Kind() != reflect.Pointer)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		if !f.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+
+		fs, err := sc.schema(f.Type)
+		if err != nil {
+			return fmt.Errorf("field %s of %s: %w", f.Name, t, err)
+		}
+
+		omitempty := strings.Contains(opts, "omitempty")
+
+		// nil slices and maps are encoded as null unless they are omitted
+		if k := f.Type.Kind(); (k == reflect.Slice || k == reflect.Map) && !omitempty {
+			fs.Nullable = true
+		}
+
+		s.Properties[name] = fs
+
+		// the field replaces the one of the same name, as the property does
+		s.Required = slices.DeleteFunc(s.Required, func(r string) bool { return r == name })
+		if required && !omitempty {
+			s.Required = append(s.Required, name)
+		}
+	}
+
+	return nil
+}
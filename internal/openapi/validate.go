@@ -0,0 +1,336 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxBodySize is the size of the largest json body validated,
+// the larger bodies are skipped.
+const maxBodySize = 1 << 20
+
+// Violations are the mismatches between a request or a response and the spec.
+type Violations []string
+
+func (vs Violations) Error() string {
+	return strings.Join(vs, "; ")
+}
+
+func (vs *Violations) add(format string, args ...any) {
+	*vs = append(*vs, fmt.Sprintf(format, args...))
+}
+
+// Validator validates the requests and the responses of the api against the spec.
+type Validator struct {
+	doc *Document
+	// prefix is the path of the server url the api is mounted on
+	prefix string
+	routes []route
+}
+
+type route struct {
+	pattern *regexp.Regexp
+	params  []string
+	item    pathItem
+}
+
+// NewValidator parses the json spec.
+func NewValidator(spec []byte) (*Validator, error) {
+	const errMsg = "can't create openapi validator"
+
+	var doc Document
+
+	err := json.Unmarshal(spec, &doc)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	v := Validator{doc: &doc}
+
+	if len(doc.Servers) > 0 {
+		v.prefix = strings.TrimSuffix(doc.Servers[0].Url, "/")
+	}
+
+	for path, item := range doc.Paths {
+		var params []string
+
+		pattern := "^"
+		last := 0
+		for _, m := range pathParam.FindAllStringSubmatchIndex(path, -1) {
+			pattern += regexp.QuoteMeta(path[last:m[0]]) + "([^/]+)"
+			params = append(params, path[m[2]:m[3]])
+			last = m[1]
+		}
+		pattern += regexp.QuoteMeta(path[last:]) + "$"
+
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%s: path %s: %w", errMsg, path, err)
+		}
+
+		v.routes = append(v.routes, route{re, params, item})
+	}
+
+	return &v, nil
+}
+
+// find returns the operation of the request along with its path params.
+func (v *Validator) find(r *http.Request) (operation, map[string]string, bool) {
+	path, ok := strings.CutPrefix(r.URL.Path, v.prefix)
+	if !ok {
+		return operation{}, nil, false
+	}
+
+	for _, rt := range v.routes {
+		m := rt.pattern.FindStringSubmatch(path)
+		if m == nil {
+			continue
+		}
+
+		op, ok := rt.item[strings.ToLower(r.Method)]
+		if !ok {
+			return operation{}, nil, false
+		}
+
+		params := make(map[string]string, len(rt.params))
+		for i, name := range rt.params {
+			params[name] = m[i+1]
+		}
+
+		return op, params, true
+	}
+
+	return operation{}, nil, false
+}
+
+// ValidateRequest checks the path and query params and the json body of the request.
+// The body is read and replaced, so it can still be read by the handler.
+// It returns false if the request is not described by the spec.
+func (v *Validator) ValidateRequest(r *http.Request) (bool, error) {
+	op, pathParams, ok := v.find(r)
+	if !ok {
+		return false, nil
+	}
+
+	var vs Violations
+
+	query := r.URL.Query()
+
+	for _, p := range op.Parameters {
+		var value string
+		var present bool
+
+		switch p.In {
+		case "path":
+			value, present = pathParams[p.Name]
+		case "query":
+			present = query.Has(p.Name)
+			value = query.Get(p.Name)
+		default:
+			continue
+		}
+
+		if !present {
+			if p.Required {
+				vs.add("%s param %s is required", p.In, p.Name)
+			}
+			continue
+		}
+
+		checkParam(&vs, p, value)
+	}
+
+	if op.RequestBody != nil {
+		if mt, ok := op.RequestBody.Content["application/json"]; ok {
+			body, err := io.ReadAll(io.LimitReader(r.Body, maxBodySize+1))
+			if err != nil {
+				return true, fmt.Errorf("can't read request body: %w", err)
+			}
+			r.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), r.Body))
+
+			if len(body) <= maxBodySize {
+				v.checkBody(&vs, "body", mt.Schema, body, op.RequestBody.Required)
+			}
+		}
+	}
+
+	if len(vs) > 0 {
+		return true, vs
+	}
+
+	return true, nil
+}
+
+// ValidateResponse checks the status and the body of the response to the request.
+// The json body is checked only if it's not truncated.
+// It returns false if the request is not described by the spec.
+func (v *Validator) ValidateResponse(r *http.Request, status int, header http.Header, body []byte, truncated bool) (bool, error) {
+	op, _, ok := v.find(r)
+	if !ok {
+		return false, nil
+	}
+
+	var vs Violations
+
+	resp, ok := op.Responses[strconv.Itoa(status)]
+	if !ok {
+		vs.add("status %d is not documented", status)
+		return true, vs
+	}
+
+	if mt, ok := resp.Content["application/json"]; ok {
+		if !truncated {
+			v.checkBody(&vs, "body", mt.Schema, body, true)
+		}
+	} else if len(resp.Content) > 0 {
+		media, _, err := mime.ParseMediaType(header.Get("Content-Type"))
+		if _, ok := resp.Content[media]; err != nil || !ok {
+			vs.add("response content type %q is not documented", header.Get("Content-Type"))
+		}
+	}
+
+	if len(vs) > 0 {
+		return true, vs
+	}
+
+	return true, nil
+}
+
+func checkParam(vs *Violations, p parameter, value string) {
+	var err error
+
+	switch p.Schema.Type {
+	case "integer":
+		_, err = strconv.Atoi(value)
+	case "boolean":
+		_, err = strconv.ParseBool(value)
+	}
+	if err != nil {
+		vs.add("%s param %s is not %s", p.In, p.Name, article(p.Schema.Type))
+		return
+	}
+
+	if len(p.Schema.Enum) > 0 && !slices.Contains(p.Schema.Enum, value) {
+		vs.add("%s param %s is not one of %s", p.In, p.Name, strings.Join(p.Schema.Enum, ", "))
+	}
+}
+
+func (v *Validator) checkBody(vs *Violations, name string, s Schema, body []byte, required bool) {
+	body = bytes.TrimSpace(body)
+
+	if len(body) == 0 {
+		if required {
+			vs.add("%s is required", name)
+		}
+		return
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+
+	var value any
+
+	err := dec.Decode(&value)
+	if err != nil {
+		vs.add("%s is not json: %s", name, err)
+		return
+	}
+
+	v.check(vs, name, s, value)
+}
+
+// check checks the decoded json value against the schema.
+// The properties missing from the schema are allowed as they are ignored by the decoder,
+// the required ones have to be present.
+func (v *Validator) check(vs *Violations, name string, s Schema, value any) {
+	if s.Ref != "" {
+		ref, ok := v.doc.Components.Schemas[strings.TrimPrefix(s.Ref, "#/components/schemas/")]
+		if !ok {
+			vs.add("%s: schema %s is not found", name, s.Ref)
+			return
+		}
+		s = ref
+	}
+
+	if value == nil {
+		if !s.Nullable {
+			vs.add("%s is null", name)
+		}
+		return
+	}
+
+	switch s.Type {
+	case "object":
+		obj, ok := value.(map[string]any)
+		if !ok {
+			vs.add("%s is not an object", name)
+			return
+		}
+		for _, key := range s.Required {
+			if _, ok := obj[key]; !ok {
+				vs.add("%s.%s is required", name, key)
+			}
+		}
+		for key, val := range obj {
+			if ps, ok := s.Properties[key]; ok {
+				v.check(vs, name+"."+key, ps, val)
+			} else if s.AdditionalProperties != nil {
+				v.check(vs, name+"."+key, *s.AdditionalProperties, val)
+			}
+		}
+	case "array":
+		arr, ok := value.([]any)
+		if !ok {
+			vs.add("%s is not an array", name)
+			return
+		}
+		if s.Items != nil {
+			for i, val := range arr {
+				v.check(vs, fmt.Sprintf("%s[%d]", name, i), *s.Items, val)
+			}
+		}
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			vs.add("%s is not a string", name)
+			return
+		}
+		if s.Format == "date-time" {
+			if _, err := time.Parse(time.RFC3339, str); err != nil {
+				vs.add("%s is not a date-time", name)
+			}
+		}
+		if len(s.Enum) > 0 && !slices.Contains(s.Enum, str) {
+			vs.add("%s is not one of %s", name, strings.Join(s.Enum, ", "))
+		}
+	case "integer":
+		num, ok := value.(json.Number)
+		if _, err := num.Int64(); !ok || err != nil {
+			vs.add("%s is not an integer", name)
+		}
+	case "number":
+		if _, ok := value.(json.Number); !ok {
+			vs.add("%s is not a number", name)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			vs.add("%s is not a boolean", name)
+		}
+	}
+}
+
+func article(typ string) string {
+	if typ == "integer" {
+		return "an integer"
+	}
+	return "a " + typ
+}
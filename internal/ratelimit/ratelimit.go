@@ -0,0 +1,179 @@
+// Package ratelimit limits the rate of the requests of every client with token buckets.
+package ratelimit
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/qo/digital-library/internal/auth"
+	"github.com/qo/digital-library/internal/logger"
+)
+
+// Limit allows Requests per Per on average with bursts of up to Burst requests.
+// The zero limit doesn't limit anything.
+type Limit struct {
+	Requests int
+	Per      time.Duration
+	Burst    int
+}
+
+func (l Limit) unlimited() bool {
+	return l.Requests <= 0 || l.Per <= 0
+}
+
+// rate is the number of the tokens added to the bucket per second.
+func (l Limit) rate() float64 {
+	return float64(l.Requests) / l.Per.Seconds()
+}
+
+// burst is the capacity of the bucket, the number of requests if it's not set.
+func (l Limit) burst() int {
+	if l.Burst <= 0 {
+		return l.Requests
+	}
+	return l.Burst
+}
+
+// Route is a route of a class, the {param} segments of the path match any segment.
+type Route struct {
+	Method string
+	Path   string
+}
+
+func (rt Route) match(r *http.Request) bool {
+	if rt.Method != r.Method {
+		return false
+	}
+
+	want := strings.Split(rt.Path, "/")
+	got := strings.Split(r.URL.Path, "/")
+	if len(want) != len(got) {
+		return false
+	}
+
+	for i := range want {
+		param := strings.HasPrefix(want[i], "{") && strings.HasSuffix(want[i], "}")
+		if !param && want[i] != got[i] || param && got[i] == "" {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Class is a group of the routes sharing the buckets and the limit,
+// e.g. the login routes which are limited stricter than the others.
+type Class struct {
+	Name   string
+	Limit  Limit
+	Routes []Route
+}
+
+// Limiter limits the requests of every client: of every user if the request is authenticated,
+// of every ip otherwise. Every class of the routes has the buckets of its own.
+type Limiter struct {
+	logger.Logger
+	store Store
+	// trustProxy is set if the server is behind a proxy
+	// appending the ip of the client to X-Forwarded-For
+	trustProxy bool
+	classes    []Class
+}
+
+// New creates the limiter. The requests which don't match any of the classes
+// belong to the default class limited by the default limit.
+func New(log logger.Logger, store Store, trustProxy bool, defaultLimit Limit, classes ...Class) *Limiter {
+	return &Limiter{
+		log,
+		store,
+		trustProxy,
+		append(classes, Class{Name: "default", Limit: defaultLimit}),
+	}
+}
+
+func (l *Limiter) class(r *http.Request) Class {
+	for _, c := range l.classes {
+		for _, rt := range c.Routes {
+			if rt.match(r) {
+				return c
+			}
+		}
+	}
+	return l.classes[len(l.classes)-1]
+}
+
+// client returns the key of the client who made the request.
+func (l *Limiter) client(r *http.Request) string {
+	if p, ok := auth.FromContext(r.Context()); ok {
+		return fmt.Sprintf("user:%d", p.UserId)
+	}
+
+	if l.trustProxy {
+		forwarded := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+		if ip := strings.TrimSpace(forwarded[len(forwarded)-1]); ip != "" {
+			return "ip:" + ip
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	return "ip:" + host
+}
+
+// Middleware responds with 429 to the requests over the limit.
+// Every limited response gets the RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset headers,
+// the 429 responses also get Retry-After.
+// It has to be used after auth.Authenticate so the requests of the users are told apart.
+func (l *Limiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		const errMsg = "can't limit rate"
+
+		c := l.class(r)
+		if c.Limit.unlimited() {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		client := l.client(r)
+
+		res, err := l.store.Take(c.Name+":"+client, c.Limit, time.Now())
+		if err != nil {
+			// the store being down shouldn't take the server down with it
+			l.ErrorContext(r.Context(), fmt.Sprintf("%s: %s", errMsg, err))
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("RateLimit-Limit", strconv.Itoa(c.Limit.burst()))
+		w.Header().Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		w.Header().Set("RateLimit-Reset", ceilSeconds(res.Reset))
+
+		if !res.Allowed {
+			w.Header().Set("Retry-After", ceilSeconds(res.RetryAfter))
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusTooManyRequests)
+			json.NewEncoder(w).Encode(struct {
+				Error string `json:"error"`
+			}{
+				Error: "too many requests",
+			})
+			l.WarnContext(r.Context(), "rate limit exceeded", "class", c.Name, "client", client)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
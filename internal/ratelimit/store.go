@@ -0,0 +1,98 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Result is the state of a bucket after a request was taken from it.
+type Result struct {
+	Allowed bool
+	// Remaining is the number of the requests which can be made right away
+	Remaining int
+	// Reset is how long it takes the bucket to refill completely
+	Reset time.Duration
+	// RetryAfter is how long to wait before the next request is allowed,
+	// it's only set if the request is not allowed
+	RetryAfter time.Duration
+}
+
+// Store keeps the token buckets.
+// It can be shared by several servers, e.g. if it's backed by Redis.
+type Store interface {
+	// Take takes a token for the request from the bucket of the key,
+	// the bucket is created full if it doesn't exist.
+	Take(key string, limit Limit, now time.Time) (Result, error)
+}
+
+// sweepInterval is how often the memory store drops the full buckets.
+const sweepInterval = time.Minute
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	// full is when the bucket is refilled completely
+	full time.Time
+}
+
+// MemoryStore keeps the buckets in memory of the server.
+// It is safe for concurrent use.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets: make(map[string]*bucket),
+	}
+}
+
+func (s *MemoryStore) Take(key string, limit Limit, now time.Time) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.lastSweep) > sweepInterval {
+		s.sweep(now)
+	}
+
+	capacity, rate := float64(limit.burst()), limit.rate()
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: capacity, last: now}
+		s.buckets[key] = b
+	}
+
+	b.tokens = math.Min(capacity, b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+
+	res := Result{Allowed: b.tokens >= 1}
+	if res.Allowed {
+		b.tokens--
+	} else {
+		res.RetryAfter = seconds((1 - b.tokens) / rate)
+	}
+
+	res.Remaining = int(b.tokens)
+	res.Reset = seconds((capacity - b.tokens) / rate)
+	b.full = now.Add(res.Reset)
+
+	return res, nil
+}
+
+// sweep drops the buckets which are full by now, as they are the same as the new ones.
+func (s *MemoryStore) sweep(now time.Time) {
+	for key, b := range s.buckets {
+		if !now.Before(b.full) {
+			delete(s.buckets, key)
+		}
+	}
+	s.lastSweep = now
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
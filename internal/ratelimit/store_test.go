@@ -0,0 +1,110 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreTake(t *testing.T) {
+	// a token every 6 seconds with bursts of up to 3 requests
+	limit := Limit{Requests: 10, Per: time.Minute, Burst: 3}
+
+	// take is a request after the time since the first one
+	type take struct {
+		after time.Duration
+		want  Result
+	}
+
+	tests := []struct {
+		name  string
+		limit Limit
+		takes []take
+	}{
+		{
+			name:  "first request",
+			limit: limit,
+			takes: []take{
+				{0, Result{Allowed: true, Remaining: 2, Reset: 6 * time.Second}},
+			},
+		},
+		{
+			name:  "burst",
+			limit: limit,
+			takes: []take{
+				{0, Result{Allowed: true, Remaining: 2, Reset: 6 * time.Second}},
+				{0, Result{Allowed: true, Remaining: 1, Reset: 12 * time.Second}},
+				{0, Result{Allowed: true, Remaining: 0, Reset: 18 * time.Second}},
+				{0, Result{Allowed: false, Remaining: 0, Reset: 18 * time.Second, RetryAfter: 6 * time.Second}},
+			},
+		},
+		{
+			name:  "partial refill",
+			limit: limit,
+			takes: []take{
+				{0, Result{Allowed: true, Remaining: 2, Reset: 6 * time.Second}},
+				{0, Result{Allowed: true, Remaining: 1, Reset: 12 * time.Second}},
+				{0, Result{Allowed: true, Remaining: 0, Reset: 18 * time.Second}},
+				{3 * time.Second, Result{Allowed: false, Remaining: 0, Reset: 15 * time.Second, RetryAfter: 3 * time.Second}},
+				{6 * time.Second, Result{Allowed: true, Remaining: 0, Reset: 18 * time.Second}},
+			},
+		},
+		{
+			name:  "refill is capped at the burst",
+			limit: limit,
+			takes: []take{
+				{0, Result{Allowed: true, Remaining: 2, Reset: 6 * time.Second}},
+				{time.Hour, Result{Allowed: true, Remaining: 2, Reset: 6 * time.Second}},
+			},
+		},
+		{
+			name:  "burst defaults to the requests",
+			limit: Limit{Requests: 2, Per: time.Second},
+			takes: []take{
+				{0, Result{Allowed: true, Remaining: 1, Reset: 500 * time.Millisecond}},
+				{0, Result{Allowed: true, Remaining: 0, Reset: time.Second}},
+				{0, Result{Allowed: false, Remaining: 0, Reset: time.Second, RetryAfter: 500 * time.Millisecond}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewMemoryStore()
+			start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+			for i, tk := range tt.takes {
+				got, err := s.Take("key", tt.limit, start.Add(tk.after))
+				if err != nil {
+					t.Fatalf("Take() error: %s", err)
+				}
+				if got != tk.want {
+					t.Errorf("Take() #%d after %s = %+v, want %+v", i, tk.after, got, tk.want)
+				}
+			}
+		})
+	}
+}
+
+func TestMemoryStoreTakeKeys(t *testing.T) {
+	s := NewMemoryStore()
+	limit := Limit{Requests: 1, Per: time.Minute}
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for _, key := range []string{"a", "b"} {
+		res, err := s.Take(key, limit, now)
+		if err != nil {
+			t.Fatalf("Take(%q) error: %s", key, err)
+		}
+		if !res.Allowed {
+			t.Errorf("Take(%q) isn't allowed, want every key to have a bucket of its own", key)
+		}
+	}
+
+	res, err := s.Take("a", limit, now)
+	if err != nil {
+		t.Fatalf("Take(%q) error: %s", "a", err)
+	}
+	if res.Allowed {
+		t.Errorf("Take(%q) is allowed after the bucket is empty", "a")
+	}
+}
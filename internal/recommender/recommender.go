@@ -0,0 +1,242 @@
+// Package recommender computes the book recommendations of the users.
+//
+// The score of a book combines item-item collaborative filtering,
+// the books liked by the readers of the books the user liked,
+// with content signals: the authors and the publishers of the liked books
+// and the favorite authors of the user.
+package recommender
+
+import (
+	"math"
+	"sort"
+
+	"github.com/qo/digital-library/internal/storage/recommendation"
+)
+
+// The reasons of the recommendations.
+const (
+	ReasonSimilarReaders = "similar_readers"
+	ReasonSameAuthor     = "same_author"
+	ReasonFavoriteAuthor = "favorite_author"
+	ReasonSamePublisher  = "same_publisher"
+)
+
+// The weights of the content signals relative to the collaborative filtering,
+// whose similarities are from 0 to 1.
+const (
+	sameAuthorWeight     = 0.5
+	favoriteAuthorWeight = 0.5
+	samePublisherWeight  = 0.1
+)
+
+// likeWeight returns how much the user liked the book.
+// A favorite book is liked the most, the reviews are liked by their rating
+// and the ratings of 3 and lower aren't likes.
+func likeWeight(favorite bool, rating int) float64 {
+	if favorite {
+		return 1
+	}
+	return math.Max(0, float64(rating-3)/2)
+}
+
+type score struct {
+	signals map[string]float64
+}
+
+func (s *score) add(reason string, v float64) {
+	if s.signals == nil {
+		s.signals = make(map[string]float64)
+	}
+	s.signals[reason] += v
+}
+
+// total returns the sum of the signals and the reason of the largest one.
+func (s score) total() (float64, string) {
+	var (
+		total, max float64
+		reason     string
+	)
+	// the reasons are checked in order so the ties are broken the same way every time
+	for _, r := range []string{ReasonSimilarReaders, ReasonSameAuthor, ReasonFavoriteAuthor, ReasonSamePublisher} {
+		v := s.signals[r]
+		total += v
+		if v > max {
+			max, reason = v, r
+		}
+	}
+	return total, reason
+}
+
+// Compute returns at most limit recommendations for every user with signals.
+// The books the user favorited or reviewed are never recommended to them.
+func Compute(s *recommendation.Signals, limit int) []recommendation.Recommendation {
+	// likes are the weights of the liked books by user id and book id
+	likes := make(map[int]map[int]float64)
+	// seen are the books the user interacted with by user id
+	seen := make(map[int]map[int]bool)
+
+	mark := func(userId, bookId int, w float64) {
+		if seen[userId] == nil {
+			seen[userId] = make(map[int]bool)
+		}
+		seen[userId][bookId] = true
+		if w <= 0 {
+			return
+		}
+		if likes[userId] == nil {
+			likes[userId] = make(map[int]float64)
+		}
+		likes[userId][bookId] = math.Max(likes[userId][bookId], w)
+	}
+
+	for userId, ratings := range s.Ratings {
+		for bookId, rating := range ratings {
+			mark(userId, bookId, likeWeight(false, rating))
+		}
+	}
+	for userId, books := range s.Favorites {
+		for _, bookId := range books {
+			mark(userId, bookId, likeWeight(true, 0))
+		}
+	}
+
+	similar := similarities(likes)
+
+	authorBooks := make(map[int][]int)
+	for bookId, authors := range s.Authors {
+		for _, authorId := range authors {
+			authorBooks[authorId] = append(authorBooks[authorId], bookId)
+		}
+	}
+
+	publisherBooks := make(map[string][]int)
+	for bookId, publisher := range s.Publishers {
+		if publisher != "" {
+			publisherBooks[publisher] = append(publisherBooks[publisher], bookId)
+		}
+	}
+
+	users := make([]int, 0, len(seen)+len(s.FavoriteAuthors))
+	for userId := range seen {
+		users = append(users, userId)
+	}
+	for userId := range s.FavoriteAuthors {
+		if seen[userId] == nil {
+			users = append(users, userId)
+		}
+	}
+	sort.Ints(users)
+
+	var recommendations []recommendation.Recommendation
+
+	for _, userId := range users {
+		scores := make(map[int]*score)
+		add := func(bookId int, reason string, v float64) {
+			if _, ok := s.Publishers[bookId]; !ok || seen[userId][bookId] {
+				return
+			}
+			if scores[bookId] == nil {
+				scores[bookId] = &score{}
+			}
+			scores[bookId].add(reason, v)
+		}
+
+		for liked, w := range likes[userId] {
+			for bookId, sim := range similar[liked] {
+				add(bookId, ReasonSimilarReaders, w*sim)
+			}
+
+			sameAuthor := make(map[int]bool)
+			for _, authorId := range s.Authors[liked] {
+				for _, bookId := range authorBooks[authorId] {
+					sameAuthor[bookId] = true
+				}
+			}
+			for bookId := range sameAuthor {
+				add(bookId, ReasonSameAuthor, w*sameAuthorWeight)
+			}
+
+			if publisher := s.Publishers[liked]; publisher != "" {
+				for _, bookId := range publisherBooks[publisher] {
+					add(bookId, ReasonSamePublisher, w*samePublisherWeight)
+				}
+			}
+		}
+
+		favorite := make(map[int]bool)
+		for _, authorId := range s.FavoriteAuthors[userId] {
+			for _, bookId := range authorBooks[authorId] {
+				favorite[bookId] = true
+			}
+		}
+		for bookId := range favorite {
+			add(bookId, ReasonFavoriteAuthor, favoriteAuthorWeight)
+		}
+
+		recommendations = append(recommendations, top(userId, scores, limit)...)
+	}
+
+	return recommendations
+}
+
+// similarities returns the cosine similarities of the liked books by book id,
+// the vector of a book is the like weights of its readers.
+func similarities(likes map[int]map[int]float64) map[int]map[int]float64 {
+	dots := make(map[int]map[int]float64)
+	norms := make(map[int]float64)
+
+	for _, books := range likes {
+		for i, wi := range books {
+			norms[i] += wi * wi
+			for j, wj := range books {
+				if i == j {
+					continue
+				}
+				if dots[i] == nil {
+					dots[i] = make(map[int]float64)
+				}
+				dots[i][j] += wi * wj
+			}
+		}
+	}
+
+	for i, row := range dots {
+		for j, dot := range row {
+			row[j] = dot / math.Sqrt(norms[i]*norms[j])
+		}
+	}
+
+	return dots
+}
+
+// top returns the recommendations of the books with the highest scores,
+// the ties are broken by the book id.
+func top(userId int, scores map[int]*score, limit int) []recommendation.Recommendation {
+	recommendations := make([]recommendation.Recommendation, 0, len(scores))
+
+	for bookId, s := range scores {
+		total, reason := s.total()
+		if total <= 0 {
+			continue
+		}
+		recommendations = append(recommendations, recommendation.Recommendation{
+			UserId: userId,
+			BookId: bookId,
+			Score:  total,
+			Reason: reason,
+		})
+	}
+
+	sort.Slice(recommendations, func(i, j int) bool {
+		if recommendations[i].Score != recommendations[j].Score {
+			return recommendations[i].Score > recommendations[j].Score
+		}
+		return recommendations[i].BookId < recommendations[j].BookId
+	})
+
+	if len(recommendations) > limit {
+		recommendations = recommendations[:limit]
+	}
+
+	return recommendations
+}
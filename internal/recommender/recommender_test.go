@@ -0,0 +1,170 @@
+package recommender
+
+import (
+	"math"
+	"testing"
+
+	"github.com/qo/digital-library/internal/storage/recommendation"
+)
+
+func TestSimilarities(t *testing.T) {
+	tests := []struct {
+		name  string
+		likes map[int]map[int]float64
+		i, j  int
+		want  float64
+	}{
+		{
+			name:  "same readers",
+			likes: map[int]map[int]float64{1: {1: 1, 2: 1}, 2: {1: 1, 2: 1}},
+			i:     1, j: 2,
+			want: 1,
+		},
+		{
+			name:  "half of the readers",
+			likes: map[int]map[int]float64{1: {1: 1, 2: 1}, 2: {1: 1}},
+			i:     1, j: 2,
+			want: 1 / math.Sqrt2,
+		},
+		{
+			name:  "symmetric",
+			likes: map[int]map[int]float64{1: {1: 1, 2: 1}, 2: {1: 1}},
+			i:     2, j: 1,
+			want: 1 / math.Sqrt2,
+		},
+		{
+			name:  "scaled weights",
+			likes: map[int]map[int]float64{1: {1: 1, 2: 0.5}},
+			i:     1, j: 2,
+			want: 1,
+		},
+		{
+			name:  "different weights",
+			likes: map[int]map[int]float64{1: {1: 1, 2: 0.5}, 2: {1: 0.5, 2: 1}},
+			i:     1, j: 2,
+			// (1*0.5 + 0.5*1) / (sqrt(1.25) * sqrt(1.25))
+			want: 0.8,
+		},
+		{
+			name:  "no common readers",
+			likes: map[int]map[int]float64{1: {1: 1}, 2: {2: 1}},
+			i:     1, j: 2,
+			want: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := similarities(tt.likes)[tt.i][tt.j]
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("similarity of %d and %d = %v, want %v", tt.i, tt.j, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompute(t *testing.T) {
+	tests := []struct {
+		name    string
+		signals recommendation.Signals
+		limit   int
+		want    []recommendation.Recommendation
+	}{
+		{
+			name: "similar readers",
+			signals: recommendation.Signals{
+				Favorites:  map[int][]int{1: {1}, 2: {1, 2}},
+				Publishers: map[int]string{1: "", 2: "", 3: ""},
+			},
+			limit: 10,
+			want:  []recommendation.Recommendation{{UserId: 1, BookId: 2, Score: 1 / math.Sqrt2, Reason: ReasonSimilarReaders}},
+		},
+		{
+			name: "similarity is weighted by the like",
+			signals: recommendation.Signals{
+				Ratings:    map[int]map[int]int{1: {1: 4}, 2: {1: 5, 2: 5}},
+				Publishers: map[int]string{1: "", 2: ""},
+			},
+			limit: 10,
+			// the likes of the book 1 are 0.5 and 1, its norm is sqrt(1.25)
+			want: []recommendation.Recommendation{{UserId: 1, BookId: 2, Score: 0.5 / math.Sqrt(1.25), Reason: ReasonSimilarReaders}},
+		},
+		{
+			name: "ratings of 3 aren't likes",
+			signals: recommendation.Signals{
+				Ratings:    map[int]map[int]int{1: {1: 3}},
+				Authors:    map[int][]int{1: {10}, 2: {10}},
+				Publishers: map[int]string{1: "", 2: ""},
+			},
+			limit: 10,
+		},
+		{
+			name: "same author and publisher",
+			signals: recommendation.Signals{
+				Ratings:    map[int]map[int]int{1: {1: 5}},
+				Authors:    map[int][]int{1: {10}, 2: {10}},
+				Publishers: map[int]string{1: "P", 2: "", 3: "P"},
+			},
+			limit: 10,
+			want: []recommendation.Recommendation{
+				{UserId: 1, BookId: 2, Score: sameAuthorWeight, Reason: ReasonSameAuthor},
+				{UserId: 1, BookId: 3, Score: samePublisherWeight, Reason: ReasonSamePublisher},
+			},
+		},
+		{
+			name: "favorite author",
+			signals: recommendation.Signals{
+				FavoriteAuthors: map[int][]int{1: {10}},
+				Authors:         map[int][]int{2: {10}},
+				Publishers:      map[int]string{2: ""},
+			},
+			limit: 10,
+			want:  []recommendation.Recommendation{{UserId: 1, BookId: 2, Score: favoriteAuthorWeight, Reason: ReasonFavoriteAuthor}},
+		},
+		{
+			name: "signals are summed",
+			signals: recommendation.Signals{
+				Favorites:       map[int][]int{1: {1}},
+				FavoriteAuthors: map[int][]int{1: {10}},
+				Authors:         map[int][]int{1: {10}, 2: {10}},
+				Publishers:      map[int]string{1: "", 2: ""},
+			},
+			limit: 10,
+			// the ties are broken by the order of the reasons
+			want: []recommendation.Recommendation{{UserId: 1, BookId: 2, Score: sameAuthorWeight + favoriteAuthorWeight, Reason: ReasonSameAuthor}},
+		},
+		{
+			name: "deleted books aren't recommended",
+			signals: recommendation.Signals{
+				FavoriteAuthors: map[int][]int{1: {10}},
+				Authors:         map[int][]int{2: {10}},
+			},
+			limit: 10,
+		},
+		{
+			name: "limit",
+			signals: recommendation.Signals{
+				Ratings:    map[int]map[int]int{1: {1: 5}},
+				Authors:    map[int][]int{1: {10}, 2: {10}},
+				Publishers: map[int]string{1: "P", 2: "", 3: "P"},
+			},
+			limit: 1,
+			want:  []recommendation.Recommendation{{UserId: 1, BookId: 2, Score: sameAuthorWeight, Reason: ReasonSameAuthor}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Compute(&tt.signals, tt.limit)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Compute() = %+v, want %+v", got, tt.want)
+			}
+			for i := range got {
+				g, w := got[i], tt.want[i]
+				if g.UserId != w.UserId || g.BookId != w.BookId || g.Reason != w.Reason || math.Abs(g.Score-w.Score) > 1e-9 {
+					t.Errorf("Compute()[%d] = %+v, want %+v", i, g, w)
+				}
+			}
+		})
+	}
+}
@@ -1,13 +1,36 @@
 package api
 
 import (
+	"fmt"
+
 	"github.com/go-chi/chi/v5"
+	"github.com/qo/digital-library/docs/swagger"
+	"github.com/qo/digital-library/internal/blob"
+	"github.com/qo/digital-library/internal/config"
 	author_handler "github.com/qo/digital-library/internal/handlers/api/author"
 	book_handler "github.com/qo/digital-library/internal/handlers/api/book"
+	catalog_handler "github.com/qo/digital-library/internal/handlers/api/catalog"
+	loan_handler "github.com/qo/digital-library/internal/handlers/api/loan"
+	notification_handler "github.com/qo/digital-library/internal/handlers/api/notification"
+	reading_handler "github.com/qo/digital-library/internal/handlers/api/reading"
+	recommendation_handler "github.com/qo/digital-library/internal/handlers/api/recommendation"
+	review_handler "github.com/qo/digital-library/internal/handlers/api/review"
+	session_handler "github.com/qo/digital-library/internal/handlers/api/session"
+	shelf_handler "github.com/qo/digital-library/internal/handlers/api/shelf"
 	user_handler "github.com/qo/digital-library/internal/handlers/api/user"
 	"github.com/qo/digital-library/internal/logger"
+	"github.com/qo/digital-library/internal/notify"
+	"github.com/qo/digital-library/internal/openapi"
 	author_router "github.com/qo/digital-library/internal/router/api/author"
 	book_router "github.com/qo/digital-library/internal/router/api/book"
+	catalog_router "github.com/qo/digital-library/internal/router/api/catalog"
+	loan_router "github.com/qo/digital-library/internal/router/api/loan"
+	notification_router "github.com/qo/digital-library/internal/router/api/notification"
+	reading_router "github.com/qo/digital-library/internal/router/api/reading"
+	recommendation_router "github.com/qo/digital-library/internal/router/api/recommendation"
+	review_router "github.com/qo/digital-library/internal/router/api/review"
+	session_router "github.com/qo/digital-library/internal/router/api/session"
+	shelf_router "github.com/qo/digital-library/internal/router/api/shelf"
 	user_router "github.com/qo/digital-library/internal/router/api/user"
 	"github.com/qo/digital-library/internal/storage"
 )
@@ -16,19 +39,123 @@ type Router struct {
 	chi.Router
 }
 
-func New(log logger.Logger, st storage.Storage) *Router {
+func New(log logger.Logger, st storage.Storage, bs blob.Store, cfg config.Config) *Router {
 	cr := chi.NewRouter()
 	r := Router{cr}
-	r.mountRoutes(log, st)
+	r.useValidation(log, cfg)
+	mountRoutes(&r, log, st, bs, cfg)
 	return &r
 }
 
-func (r *Router) mountRoutes(log logger.Logger, st storage.Storage) {
+// useValidation validates the requests against the embedded spec if it's on,
+// in local and dev envs the responses are validated too.
+func (r *Router) useValidation(log logger.Logger, cfg config.Config) {
+	var reject bool
+
+	switch cfg.ValidationOptions.Mode {
+	case "", "off":
+		return
+	case "log":
+	case "reject":
+		reject = true
+	default:
+		log.Error(fmt.Sprintf("openapi validation mode %s is unknown, validation is off", cfg.ValidationOptions.Mode))
+		return
+	}
+
+	v, err := openapi.NewValidator(swagger.Spec)
+	if err != nil {
+		log.Error(fmt.Sprintf("openapi validation is off: %s", err))
+		return
+	}
+
+	responses := cfg.Env == "local" || cfg.Env == "dev"
+
+	r.Use(openapi.Validate(log, v, reject, responses))
+
+	log.Info("openapi validation on", "mode", cfg.ValidationOptions.Mode, "responses", responses)
+}
+
+type routes interface {
+	author_router.Router
+	book_router.Router
+	catalog_router.Router
+	loan_router.Router
+	notification_router.Router
+	reading_router.Router
+	recommendation_router.Router
+	review_router.Router
+	session_router.Router
+	shelf_router.Router
+	user_router.Router
+}
+
+func mountRoutes(r routes, log logger.Logger, st storage.Storage, bs blob.Store, cfg config.Config) {
+	nt := notify.New(log, st, cfg.NotificationsOptions)
+
 	ah := author_handler.New(log, st)
-	bh := book_handler.New(log, st)
+	bh := book_handler.New(log, st, bs)
+	ch := catalog_handler.New(log, st)
+	lh := loan_handler.New(log, st, nt, cfg.LoansOptions)
+	nh := notification_handler.New(log, st)
+	rh := review_handler.New(log, st)
+	rch := recommendation_handler.New(log, st)
+	rdh := reading_handler.New(log, st)
+	sh := session_handler.New(log, st, cfg.AuthOptions)
+	shh := shelf_handler.New(log, st)
 	uh := user_handler.New(log, st)
 
 	author_router.Init(r, ah)
 	book_router.Init(r, bh)
+	catalog_router.Init(r, ch)
+	loan_router.Init(r, lh)
+	notification_router.Init(r, nh)
+	review_router.Init(r, rh)
+	recommendation_router.Init(r, rch)
+	reading_router.Init(r, rdh)
+	session_router.Init(r, sh)
+	shelf_router.Init(r, shh)
 	user_router.Init(r, uh)
 }
+
+var info = openapi.Info{
+	Title:       "Digital Library",
+	Description: "REST API of the Digital Library. The spec is generated by `digital-library openapi`, don't edit it by hand.",
+	Version:     "1.0.0",
+}
+
+var tags = []openapi.Tag{
+	{Name: "user", Description: "Users and their favorite books and authors"},
+	{Name: "book", Description: "Books, their citations and files"},
+	{Name: "author", Description: "Authors"},
+	{Name: "review", Description: "Book reviews"},
+	{Name: "recommendation", Description: "Personalized book recommendations"},
+	{Name: "reading", Description: "Reading progress and bookmarks"},
+	{Name: "shelf", Description: "Named shelves of books created by the users"},
+	{Name: "loan", Description: "Lending the copies of the books and the queues for them"},
+	{Name: "notification", Description: "In-app inbox and notification preferences"},
+	{Name: "catalog", Description: "Bulk import and export of the catalog"},
+	{Name: "session", Description: "Logging in and out"},
+}
+
+// Spec generates the openapi spec from the routes and the operations documented by the handlers.
+func Spec() (*openapi.Document, error) {
+	// the handlers are only created to record the routes, they are never called
+	var rc openapi.Recorder
+	mountRoutes(&rc, logger.Logger{}, storage.Storage{}, blob.Store{}, config.Config{})
+
+	var ops []openapi.Operation
+	ops = append(ops, author_handler.Operations...)
+	ops = append(ops, book_handler.Operations...)
+	ops = append(ops, catalog_handler.Operations...)
+	ops = append(ops, loan_handler.Operations...)
+	ops = append(ops, notification_handler.Operations...)
+	ops = append(ops, review_handler.Operations...)
+	ops = append(ops, recommendation_handler.Operations...)
+	ops = append(ops, reading_handler.Operations...)
+	ops = append(ops, session_handler.Operations...)
+	ops = append(ops, shelf_handler.Operations...)
+	ops = append(ops, user_handler.Operations...)
+
+	return openapi.Generate(info, "/api", tags, rc.Routes, ops)
+}
@@ -7,6 +7,7 @@ type AuthorApi interface {
 	Post() http.HandlerFunc
 	Put() http.HandlerFunc
 	Delete() http.HandlerFunc
+	Restore() http.HandlerFunc
 }
 
 type Router interface {
@@ -21,4 +22,5 @@ func Init(r Router, a AuthorApi) {
 	r.Post("/author", a.Post())
 	r.Put("/author", a.Put())
 	r.Delete("/author/{id}", a.Delete())
+	r.Post("/author/{id}/restore", a.Restore())
 }
@@ -4,9 +4,15 @@ import "net/http"
 
 type BookApi interface {
 	Get() http.HandlerFunc
+	List() http.HandlerFunc
+	Top() http.HandlerFunc
 	Post() http.HandlerFunc
 	Put() http.HandlerFunc
 	Delete() http.HandlerFunc
+	Restore() http.HandlerFunc
+	Cite() http.HandlerFunc
+	PutFile() http.HandlerFunc
+	GetFile() http.HandlerFunc
 }
 
 type Router interface {
@@ -17,8 +23,14 @@ type Router interface {
 }
 
 func Init(r Router, a BookApi) {
+	r.Get("/books", a.List())
+	r.Get("/books/top", a.Top())
 	r.Get("/book/{id}", a.Get())
 	r.Post("/book", a.Post())
 	r.Put("/book", a.Put())
 	r.Delete("/book/{id}", a.Delete())
+	r.Post("/book/{id}/restore", a.Restore())
+	r.Get("/book/{id}/cite", a.Cite())
+	r.Put("/book/{id}/file", a.PutFile())
+	r.Get("/book/{id}/file", a.GetFile())
 }
@@ -0,0 +1,20 @@
+package catalog
+
+import "net/http"
+
+type CatalogApi interface {
+	Import() http.HandlerFunc
+	Export() http.HandlerFunc
+}
+
+type Router interface {
+	Get(route string, handler http.HandlerFunc)
+	Post(route string, handler http.HandlerFunc)
+	Put(route string, handler http.HandlerFunc)
+	Delete(route string, handler http.HandlerFunc)
+}
+
+func Init(r Router, a CatalogApi) {
+	r.Post("/import", a.Import())
+	r.Get("/export", a.Export())
+}
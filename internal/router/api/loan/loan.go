@@ -0,0 +1,34 @@
+package loan
+
+import "net/http"
+
+type LoanApi interface {
+	List() http.HandlerFunc
+	ListAll() http.HandlerFunc
+	Checkout() http.HandlerFunc
+	Return() http.HandlerFunc
+	ListHolds() http.HandlerFunc
+	PlaceHold() http.HandlerFunc
+	CancelHold() http.HandlerFunc
+	Availability() http.HandlerFunc
+	PutCopies() http.HandlerFunc
+}
+
+type Router interface {
+	Get(route string, handler http.HandlerFunc)
+	Post(route string, handler http.HandlerFunc)
+	Put(route string, handler http.HandlerFunc)
+	Delete(route string, handler http.HandlerFunc)
+}
+
+func Init(r Router, a LoanApi) {
+	r.Get("/loans", a.ListAll())
+	r.Get("/user/{id}/loans", a.List())
+	r.Post("/user/{id}/loans", a.Checkout())
+	r.Post("/user/{id}/loans/{loan_id}/return", a.Return())
+	r.Get("/user/{id}/holds", a.ListHolds())
+	r.Post("/user/{id}/holds", a.PlaceHold())
+	r.Delete("/user/{id}/holds/{book_id}", a.CancelHold())
+	r.Get("/book/{id}/availability", a.Availability())
+	r.Put("/book/{id}/copies", a.PutCopies())
+}
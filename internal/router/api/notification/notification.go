@@ -0,0 +1,28 @@
+package notification
+
+import "net/http"
+
+type NotificationApi interface {
+	List() http.HandlerFunc
+	Read() http.HandlerFunc
+	ReadAll() http.HandlerFunc
+	Delete() http.HandlerFunc
+	GetPreferences() http.HandlerFunc
+	PutPreferences() http.HandlerFunc
+}
+
+type Router interface {
+	Get(route string, handler http.HandlerFunc)
+	Post(route string, handler http.HandlerFunc)
+	Put(route string, handler http.HandlerFunc)
+	Delete(route string, handler http.HandlerFunc)
+}
+
+func Init(r Router, a NotificationApi) {
+	r.Get("/user/{id}/notifications", a.List())
+	r.Post("/user/{id}/notifications/read", a.ReadAll())
+	r.Get("/user/{id}/notifications/preferences", a.GetPreferences())
+	r.Put("/user/{id}/notifications/preferences", a.PutPreferences())
+	r.Post("/user/{id}/notifications/{notification_id}/read", a.Read())
+	r.Delete("/user/{id}/notifications/{notification_id}", a.Delete())
+}
@@ -0,0 +1,32 @@
+package reading
+
+import "net/http"
+
+type ReadingApi interface {
+	List() http.HandlerFunc
+	Get() http.HandlerFunc
+	Put() http.HandlerFunc
+	Delete() http.HandlerFunc
+	GetBookmarks() http.HandlerFunc
+	PostBookmark() http.HandlerFunc
+	PutBookmark() http.HandlerFunc
+	DeleteBookmark() http.HandlerFunc
+}
+
+type Router interface {
+	Get(route string, handler http.HandlerFunc)
+	Post(route string, handler http.HandlerFunc)
+	Put(route string, handler http.HandlerFunc)
+	Delete(route string, handler http.HandlerFunc)
+}
+
+func Init(r Router, a ReadingApi) {
+	r.Get("/user/{id}/reading", a.List())
+	r.Get("/user/{id}/reading/{book_id}", a.Get())
+	r.Put("/user/{id}/reading/{book_id}", a.Put())
+	r.Delete("/user/{id}/reading/{book_id}", a.Delete())
+	r.Get("/user/{id}/reading/{book_id}/bookmarks", a.GetBookmarks())
+	r.Post("/user/{id}/reading/{book_id}/bookmarks", a.PostBookmark())
+	r.Put("/user/{id}/reading/{book_id}/bookmarks/{bookmark_id}", a.PutBookmark())
+	r.Delete("/user/{id}/reading/{book_id}/bookmarks/{bookmark_id}", a.DeleteBookmark())
+}
@@ -0,0 +1,15 @@
+package recommendation
+
+import "net/http"
+
+type RecommendationApi interface {
+	Get() http.HandlerFunc
+}
+
+type Router interface {
+	Get(route string, handler http.HandlerFunc)
+}
+
+func Init(r Router, a RecommendationApi) {
+	r.Get("/user/{id}/recommendations", a.Get())
+}
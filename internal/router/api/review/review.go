@@ -0,0 +1,26 @@
+package review
+
+import "net/http"
+
+type ReviewApi interface {
+	GetBookReviews() http.HandlerFunc
+	GetUserReviews() http.HandlerFunc
+	Get() http.HandlerFunc
+	Put() http.HandlerFunc
+	Delete() http.HandlerFunc
+}
+
+type Router interface {
+	Get(route string, handler http.HandlerFunc)
+	Post(route string, handler http.HandlerFunc)
+	Put(route string, handler http.HandlerFunc)
+	Delete(route string, handler http.HandlerFunc)
+}
+
+func Init(r Router, a ReviewApi) {
+	r.Get("/book/{id}/reviews", a.GetBookReviews())
+	r.Get("/user/{id}/reviews", a.GetUserReviews())
+	r.Get("/book/{id}/review/{user_id}", a.Get())
+	r.Put("/book/{id}/review/{user_id}", a.Put())
+	r.Delete("/book/{id}/review/{user_id}", a.Delete())
+}
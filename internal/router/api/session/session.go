@@ -0,0 +1,20 @@
+package session
+
+import "net/http"
+
+type SessionApi interface {
+	Login() http.HandlerFunc
+	Logout() http.HandlerFunc
+}
+
+type Router interface {
+	Get(route string, handler http.HandlerFunc)
+	Post(route string, handler http.HandlerFunc)
+	Put(route string, handler http.HandlerFunc)
+	Delete(route string, handler http.HandlerFunc)
+}
+
+func Init(r Router, a SessionApi) {
+	r.Post("/login", a.Login())
+	r.Post("/logout", a.Logout())
+}
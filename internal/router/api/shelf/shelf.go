@@ -0,0 +1,32 @@
+package shelf
+
+import "net/http"
+
+type ShelfApi interface {
+	List() http.HandlerFunc
+	Post() http.HandlerFunc
+	Get() http.HandlerFunc
+	Put() http.HandlerFunc
+	Delete() http.HandlerFunc
+	PutOrder() http.HandlerFunc
+	PutBook() http.HandlerFunc
+	DeleteBook() http.HandlerFunc
+}
+
+type Router interface {
+	Get(route string, handler http.HandlerFunc)
+	Post(route string, handler http.HandlerFunc)
+	Put(route string, handler http.HandlerFunc)
+	Delete(route string, handler http.HandlerFunc)
+}
+
+func Init(r Router, a ShelfApi) {
+	r.Get("/user/{id}/shelves", a.List())
+	r.Post("/user/{id}/shelves", a.Post())
+	r.Get("/shelf/{id}", a.Get())
+	r.Put("/shelf/{id}", a.Put())
+	r.Delete("/shelf/{id}", a.Delete())
+	r.Put("/shelf/{id}/books", a.PutOrder())
+	r.Put("/shelf/{id}/books/{book_id}", a.PutBook())
+	r.Delete("/shelf/{id}/books/{book_id}", a.DeleteBook())
+}
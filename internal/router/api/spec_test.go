@@ -0,0 +1,105 @@
+package api
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/qo/digital-library/docs/swagger"
+	"github.com/qo/digital-library/internal/logger"
+	"github.com/qo/digital-library/internal/openapi"
+)
+
+// TestSpec fails if the committed openapi spec differs from the generated one,
+// run `make openapi` to update it.
+func TestSpec(t *testing.T) {
+	doc, err := Spec()
+	if err != nil {
+		t.Fatalf("Spec() error: %s", err)
+	}
+
+	js, err := doc.JSON()
+	if err != nil {
+		t.Fatalf("JSON() error: %s", err)
+	}
+
+	ys, err := doc.YAML()
+	if err != nil {
+		t.Fatalf("YAML() error: %s", err)
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"openapi.json", js},
+		{"openapi.yaml", ys},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join("..", "..", "..", "docs", "swagger", tt.name)
+
+			old, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("can't read %s: %s", path, err)
+			}
+
+			if !bytes.Equal(old, tt.data) {
+				t.Errorf("%s is out of date, run `make openapi`", path)
+			}
+		})
+	}
+}
+
+// TestValidateRequests checks that the valid writes pass the validation in reject mode,
+// so the server assigned and read only fields aren't required in the request bodies.
+func TestValidateRequests(t *testing.T) {
+	v, err := openapi.NewValidator(swagger.Spec)
+	if err != nil {
+		t.Fatalf("NewValidator() error: %s", err)
+	}
+
+	log := logger.Logger{Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	h := openapi.Validate(log, v, true, false)(ok)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		want   int
+	}{
+		{"minimal book", http.MethodPost, "/api/book", `{"title":"Dune","authors":["Frank Herbert"]}`, http.StatusNoContent},
+		{"book without authors", http.MethodPost, "/api/book", `{"title":"Dune"}`, http.StatusNoContent},
+		{"book without title", http.MethodPost, "/api/book", `{"authors":["Frank Herbert"]}`, http.StatusBadRequest},
+		{"book update", http.MethodPut, "/api/book", `{"id":1,"title":"Dune"}`, http.StatusNoContent},
+		{"book update without id", http.MethodPut, "/api/book", `{"title":"Dune"}`, http.StatusBadRequest},
+		{"minimal author", http.MethodPost, "/api/author", `{"full_name":"Frank Herbert"}`, http.StatusNoContent},
+		{"author update", http.MethodPut, "/api/author", `{"id":1,"full_name":"Frank Herbert"}`, http.StatusNoContent},
+		{"minimal user", http.MethodPost, "/api/user", `{"first_name":"Paul","second_name":"Atreides"}`, http.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			r.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			h.ServeHTTP(w, r)
+
+			if w.Code != tt.want {
+				t.Errorf("%s %s %s = %d %s, want %d", tt.method, tt.path, tt.body, w.Code, w.Body, tt.want)
+			}
+		})
+	}
+}
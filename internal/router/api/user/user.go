@@ -4,11 +4,18 @@ import "net/http"
 
 type UserApi interface {
 	Get() http.HandlerFunc
+	List() http.HandlerFunc
+	PutRole() http.HandlerFunc
 	Post() http.HandlerFunc
 	Put() http.HandlerFunc
 	Delete() http.HandlerFunc
 	GetFavoriteBooks() http.HandlerFunc
-	// GetFavoriteAuthors() http.HandlerFunc
+	CiteFavoriteBooks() http.HandlerFunc
+	PutFavoriteBook() http.HandlerFunc
+	DeleteFavoriteBook() http.HandlerFunc
+	GetFavoriteAuthors() http.HandlerFunc
+	PutFavoriteAuthor() http.HandlerFunc
+	DeleteFavoriteAuthor() http.HandlerFunc
 }
 
 type Router interface {
@@ -19,10 +26,17 @@ type Router interface {
 }
 
 func Init(r Router, a UserApi) {
+	r.Get("/users", a.List())
 	r.Get("/user/{id}", a.Get())
+	r.Put("/user/{id}/role", a.PutRole())
 	r.Post("/user", a.Post())
 	r.Put("/user", a.Put())
 	r.Delete("/user/{id}", a.Delete())
 	r.Get("/user/{id}/books", a.GetFavoriteBooks())
-	// r.Get("/user/{id}/authors", a.GetUserFavoriteAuthors)
+	r.Get("/user/{id}/books/cite", a.CiteFavoriteBooks())
+	r.Put("/user/{id}/books/{book_id}", a.PutFavoriteBook())
+	r.Delete("/user/{id}/books/{book_id}", a.DeleteFavoriteBook())
+	r.Get("/user/{id}/authors", a.GetFavoriteAuthors())
+	r.Put("/user/{id}/authors/{author_id}", a.PutFavoriteAuthor())
+	r.Delete("/user/{id}/authors/{author_id}", a.DeleteFavoriteAuthor())
 }
@@ -0,0 +1,22 @@
+package health
+
+import "net/http"
+
+// Paths are the paths of the probes, they are left out of the access log.
+var Paths = []string{"/healthz", "/readyz", "/version"}
+
+type HealthApi interface {
+	Healthz() http.HandlerFunc
+	Readyz() http.HandlerFunc
+	Version() http.HandlerFunc
+}
+
+type Router interface {
+	Get(route string, handler http.HandlerFunc)
+}
+
+func Init(r Router, a HealthApi) {
+	r.Get("/healthz", a.Healthz())
+	r.Get("/readyz", a.Readyz())
+	r.Get("/version", a.Version())
+}
@@ -0,0 +1,34 @@
+package opds
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/qo/digital-library/internal/blob"
+	opds_handler "github.com/qo/digital-library/internal/handlers/opds"
+	"github.com/qo/digital-library/internal/logger"
+	"github.com/qo/digital-library/internal/storage"
+)
+
+type Router struct {
+	chi.Router
+}
+
+func New(log logger.Logger, st storage.Storage, bs blob.Store) *Router {
+	cr := chi.NewRouter()
+	r := Router{cr}
+	r.mountRoutes(log, st, bs)
+	return &r
+}
+
+func (r *Router) mountRoutes(log logger.Logger, st storage.Storage, bs blob.Store) {
+	oh := opds_handler.New(log, st, bs)
+
+	r.Get("/", oh.Root())
+	r.Get("/new", oh.NewBooks())
+	r.Get("/authors", oh.Authors())
+	r.Get("/authors/{id}", oh.Author())
+	r.Get("/publishers", oh.Publishers())
+	r.Get("/publishers/{publisher}", oh.Publisher())
+	r.Get("/user/{id}/favorites", oh.Favorites())
+	r.Get("/search", oh.Search())
+	r.Get("/opensearch.xml", oh.OpenSearch())
+}
@@ -1,25 +1,90 @@
 package router
 
 import (
+	"net/http"
+
 	"github.com/go-chi/chi/v5"
+	"github.com/qo/digital-library/internal/auth"
+	"github.com/qo/digital-library/internal/blob"
+	"github.com/qo/digital-library/internal/config"
+	health_handler "github.com/qo/digital-library/internal/handlers/health"
+	"github.com/qo/digital-library/internal/handlers/view/render"
 	"github.com/qo/digital-library/internal/logger"
+	"github.com/qo/digital-library/internal/metrics"
+	"github.com/qo/digital-library/internal/middleware"
+	"github.com/qo/digital-library/internal/ratelimit"
 	"github.com/qo/digital-library/internal/router/api"
+	health_router "github.com/qo/digital-library/internal/router/health"
+	"github.com/qo/digital-library/internal/router/opds"
 	"github.com/qo/digital-library/internal/router/views"
 	"github.com/qo/digital-library/internal/storage"
+	"github.com/qo/digital-library/internal/tracing"
 )
 
 type Router struct {
 	chi.Router
 }
 
-func New(log logger.Logger, st storage.Storage) *Router {
+// New creates the router of the server.
+// The requests are measured if the metrics are not nil.
+func New(log logger.Logger, st storage.Storage, bs blob.Store, rd *render.Renderer, m *metrics.Metrics, cfg config.Config) *Router {
 	cr := chi.NewRouter()
 	r := Router{cr}
-	r.mountRoutes(log, st)
+	r.Use(middleware.RequestId)
+	r.Use(tracing.Middleware)
+	r.Use(middleware.AccessLog(log, health_router.Paths...))
+	if m != nil {
+		r.Use(m.Middleware)
+	}
+	r.Use(middleware.Recover(log))
+	r.Use(middleware.SecurityHeaders(cfg.SecurityOptions, cfg.HTTPServerOptions.Proto == "https"))
+	r.Use(middleware.CORS("/api", cfg.CORSOptions))
+	r.Use(auth.Authenticate(cfg.AuthOptions, st))
+	if cfg.RateLimitOptions.Enabled {
+		r.Use(limiter(log, cfg.RateLimitOptions).Middleware)
+	}
+	r.mountRoutes(log, st, bs, rd, cfg)
 	return &r
 }
 
-func (r Router) mountRoutes(log logger.Logger, st storage.Storage) {
-	r.Mount("/api", api.New(log, st))
-	r.Mount("/", views.New(log, st))
+func (r Router) mountRoutes(log logger.Logger, st storage.Storage, bs blob.Store, rd *render.Renderer, cfg config.Config) {
+	health_router.Init(r, health_handler.New(log, st, bs))
+	r.Mount("/api", api.New(log, st, bs, cfg))
+	r.Mount("/opds", opds.New(log, st, bs))
+	r.Mount("/", views.New(log, st, bs, rd, cfg))
+}
+
+// limiter creates the rate limiter of the routes.
+// The probes are not limited, the login and the upload routes are limited stricter.
+func limiter(log logger.Logger, options config.RateLimitOptions) *ratelimit.Limiter {
+	probes := make([]ratelimit.Route, 0, len(health_router.Paths))
+	for _, path := range health_router.Paths {
+		probes = append(probes, ratelimit.Route{Method: http.MethodGet, Path: path})
+	}
+
+	return ratelimit.New(log, ratelimit.NewMemoryStore(), options.TrustProxy,
+		ratelimit.Limit{Requests: options.Requests, Per: options.Per, Burst: options.Burst},
+		ratelimit.Class{
+			Name:   "probe",
+			Routes: probes,
+		},
+		ratelimit.Class{
+			Name:  "login",
+			Limit: ratelimit.Limit{Requests: options.LoginRequests, Per: options.LoginPer, Burst: options.LoginBurst},
+			Routes: []ratelimit.Route{
+				{Method: http.MethodPost, Path: "/api/login"},
+				{Method: http.MethodPost, Path: "/login"},
+			},
+		},
+		ratelimit.Class{
+			Name:  "upload",
+			Limit: ratelimit.Limit{Requests: options.UploadRequests, Per: options.UploadPer, Burst: options.UploadBurst},
+			Routes: []ratelimit.Route{
+				{Method: http.MethodPut, Path: "/api/book/{id}/file"},
+				{Method: http.MethodPost, Path: "/api/import"},
+				{Method: http.MethodPost, Path: "/admin/books"},
+				{Method: http.MethodPost, Path: "/admin/book/{id}/file"},
+			},
+		},
+	)
 }
@@ -0,0 +1,53 @@
+package admin
+
+import (
+	"net/http"
+
+	"github.com/qo/digital-library/internal/handlers/view/form"
+	"github.com/qo/digital-library/internal/storage/user"
+)
+
+type adminView interface {
+	Index() http.HandlerFunc
+	Users() http.HandlerFunc
+	PostUser() http.HandlerFunc
+	PutRole() http.HandlerFunc
+	DeleteUser() http.HandlerFunc
+	Books() http.HandlerFunc
+	PostBook() http.HandlerFunc
+	PutBookFile() http.HandlerFunc
+	DeleteBook() http.HandlerFunc
+	Reports() http.HandlerFunc
+	DismissReport() http.HandlerFunc
+	DeleteReportedReview() http.HandlerFunc
+}
+
+type router interface {
+	Get(route string, handler http.HandlerFunc)
+	Post(route string, handler http.HandlerFunc)
+	Put(route string, handler http.HandlerFunc)
+	Delete(route string, handler http.HandlerFunc)
+}
+
+// Init mounts the admin console.
+// Mods only have access to the moderation queue.
+func Init(r router, a adminView) {
+	mod := func(h http.HandlerFunc) http.HandlerFunc { return form.RequireRole(user.RoleMod, h) }
+	admin := func(h http.HandlerFunc) http.HandlerFunc { return form.RequireRole(user.RoleAdmin, h) }
+
+	r.Get("/admin", mod(a.Index()))
+
+	r.Get("/admin/users", admin(a.Users()))
+	r.Post("/admin/users", admin(a.PostUser()))
+	r.Post("/admin/user/{id}/role", admin(a.PutRole()))
+	r.Post("/admin/user/{id}/delete", admin(a.DeleteUser()))
+
+	r.Get("/admin/books", admin(a.Books()))
+	r.Post("/admin/books", admin(a.PostBook()))
+	r.Post("/admin/book/{id}/file", admin(a.PutBookFile()))
+	r.Post("/admin/book/{id}/delete", admin(a.DeleteBook()))
+
+	r.Get("/admin/reports", mod(a.Reports()))
+	r.Post("/admin/report/{id}/dismiss", mod(a.DismissReport()))
+	r.Post("/admin/report/{id}/delete-review", mod(a.DeleteReportedReview()))
+}
@@ -0,0 +1,26 @@
+package author
+
+import (
+	"net/http"
+
+	"github.com/qo/digital-library/internal/handlers/view/form"
+)
+
+type authorView interface {
+	Get() http.HandlerFunc
+	Favorite() http.HandlerFunc
+	Unfavorite() http.HandlerFunc
+}
+
+type router interface {
+	Get(route string, handler http.HandlerFunc)
+	Post(route string, handler http.HandlerFunc)
+	Put(route string, handler http.HandlerFunc)
+	Delete(route string, handler http.HandlerFunc)
+}
+
+func Init(r router, a authorView) {
+	r.Get("/author/{id}", a.Get())
+	r.Post("/author/{id}/favorite", form.RequireLogin(a.Favorite()))
+	r.Post("/author/{id}/unfavorite", form.RequireLogin(a.Unfavorite()))
+}
@@ -0,0 +1,34 @@
+package book
+
+import (
+	"net/http"
+
+	"github.com/qo/digital-library/internal/handlers/view/form"
+)
+
+type bookView interface {
+	List() http.HandlerFunc
+	Get() http.HandlerFunc
+	PostReview() http.HandlerFunc
+	DeleteReview() http.HandlerFunc
+	ReportReview() http.HandlerFunc
+	Favorite() http.HandlerFunc
+	Unfavorite() http.HandlerFunc
+}
+
+type router interface {
+	Get(route string, handler http.HandlerFunc)
+	Post(route string, handler http.HandlerFunc)
+	Put(route string, handler http.HandlerFunc)
+	Delete(route string, handler http.HandlerFunc)
+}
+
+func Init(r router, a bookView) {
+	r.Get("/books", a.List())
+	r.Get("/book/{id}", a.Get())
+	r.Post("/book/{id}/review", form.RequireLogin(a.PostReview()))
+	r.Post("/book/{id}/review/delete", form.RequireLogin(a.DeleteReview()))
+	r.Post("/book/{id}/review/{user_id}/report", form.RequireLogin(a.ReportReview()))
+	r.Post("/book/{id}/favorite", form.RequireLogin(a.Favorite()))
+	r.Post("/book/{id}/unfavorite", form.RequireLogin(a.Unfavorite()))
+}
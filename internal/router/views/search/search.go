@@ -0,0 +1,18 @@
+package search
+
+import "net/http"
+
+type searchView interface {
+	Get() http.HandlerFunc
+}
+
+type router interface {
+	Get(route string, handler http.HandlerFunc)
+	Post(route string, handler http.HandlerFunc)
+	Put(route string, handler http.HandlerFunc)
+	Delete(route string, handler http.HandlerFunc)
+}
+
+func Init(r router, a searchView) {
+	r.Get("/search", a.Get())
+}
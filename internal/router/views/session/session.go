@@ -0,0 +1,22 @@
+package session
+
+import "net/http"
+
+type sessionView interface {
+	Get() http.HandlerFunc
+	Login() http.HandlerFunc
+	Logout() http.HandlerFunc
+}
+
+type router interface {
+	Get(route string, handler http.HandlerFunc)
+	Post(route string, handler http.HandlerFunc)
+	Put(route string, handler http.HandlerFunc)
+	Delete(route string, handler http.HandlerFunc)
+}
+
+func Init(r router, a sessionView) {
+	r.Get("/login", a.Get())
+	r.Post("/login", a.Login())
+	r.Post("/logout", a.Logout())
+}
@@ -0,0 +1,18 @@
+package shelf
+
+import "net/http"
+
+type shelfView interface {
+	Get() http.HandlerFunc
+}
+
+type router interface {
+	Get(route string, handler http.HandlerFunc)
+	Post(route string, handler http.HandlerFunc)
+	Put(route string, handler http.HandlerFunc)
+	Delete(route string, handler http.HandlerFunc)
+}
+
+func Init(r router, a shelfView) {
+	r.Get("/shelf/{id}", a.Get())
+}
@@ -1,9 +1,15 @@
 package user
 
-import "net/http"
+import (
+	"net/http"
+
+	"github.com/qo/digital-library/internal/handlers/view/form"
+)
 
 type userView interface {
 	Get() http.HandlerFunc
+	Edit() http.HandlerFunc
+	Update() http.HandlerFunc
 }
 
 type router interface {
@@ -15,4 +21,6 @@ type router interface {
 
 func Init(r router, a userView) {
 	r.Get("/user/{id}", a.Get())
+	r.Get("/user/{id}/edit", form.RequireLogin(a.Edit()))
+	r.Post("/user/{id}/edit", form.RequireLogin(a.Update()))
 }
@@ -1,11 +1,30 @@
 package views
 
 import (
+	"net/http"
+
 	"github.com/go-chi/chi/v5"
+	"github.com/qo/digital-library/internal/auth"
+	"github.com/qo/digital-library/internal/blob"
+	"github.com/qo/digital-library/internal/config"
+	admin_handler "github.com/qo/digital-library/internal/handlers/view/admin"
+	author_handler "github.com/qo/digital-library/internal/handlers/view/author"
+	book_handler "github.com/qo/digital-library/internal/handlers/view/book"
+	"github.com/qo/digital-library/internal/handlers/view/form"
 	openapi_handler "github.com/qo/digital-library/internal/handlers/view/openapi"
+	"github.com/qo/digital-library/internal/handlers/view/render"
+	search_handler "github.com/qo/digital-library/internal/handlers/view/search"
+	session_handler "github.com/qo/digital-library/internal/handlers/view/session"
+	shelf_handler "github.com/qo/digital-library/internal/handlers/view/shelf"
 	user_handler "github.com/qo/digital-library/internal/handlers/view/user"
 	"github.com/qo/digital-library/internal/logger"
+	admin_router "github.com/qo/digital-library/internal/router/views/admin"
+	author_router "github.com/qo/digital-library/internal/router/views/author"
+	book_router "github.com/qo/digital-library/internal/router/views/book"
 	openapi_router "github.com/qo/digital-library/internal/router/views/openapi"
+	search_router "github.com/qo/digital-library/internal/router/views/search"
+	session_router "github.com/qo/digital-library/internal/router/views/session"
+	shelf_router "github.com/qo/digital-library/internal/router/views/shelf"
 	user_router "github.com/qo/digital-library/internal/router/views/user"
 	"github.com/qo/digital-library/internal/storage"
 )
@@ -14,17 +33,33 @@ type Router struct {
 	chi.Router
 }
 
-func New(log logger.Logger, st storage.Storage) *Router {
+func New(log logger.Logger, st storage.Storage, bs blob.Store, rd *render.Renderer, cfg config.Config) *Router {
 	cr := chi.NewRouter()
 	r := Router{cr}
-	r.mountRoutes(log, st)
+	r.Use(auth.AuthenticateSession(st))
+	r.Use(form.Protect)
+	r.mountRoutes(log, st, bs, rd, cfg)
 	return &r
 }
 
-func (r *Router) mountRoutes(log logger.Logger, st storage.Storage) {
-	oh := openapi_handler.New(log)
-	uh := user_handler.New(log, st)
+func (r *Router) mountRoutes(log logger.Logger, st storage.Storage, bs blob.Store, rd *render.Renderer, cfg config.Config) {
+	adh := admin_handler.New(log, rd, st, bs)
+	ah := author_handler.New(log, rd, st)
+	bh := book_handler.New(log, rd, st, bs)
+	oh := openapi_handler.New(log, rd, cfg.ViewsOptions)
+	sh := search_handler.New(log, rd, st)
+	ssh := session_handler.New(log, rd, st, cfg.AuthOptions)
+	shh := shelf_handler.New(log, rd, st)
+	uh := user_handler.New(log, rd, st)
 
+	admin_router.Init(r, adh)
+	author_router.Init(r, ah)
+	book_router.Init(r, bh)
 	openapi_router.Init(r, oh)
+	search_router.Init(r, sh)
+	session_router.Init(r, ssh)
+	shelf_router.Init(r, shh)
 	user_router.Init(r, uh)
+
+	r.Get("/", http.RedirectHandler("/books", http.StatusFound).ServeHTTP)
 }
@@ -3,11 +3,16 @@ package author
 import (
 	"database/sql"
 	"fmt"
+	"time"
+
+	"github.com/qo/digital-library/internal/storage/querier"
+	"github.com/qo/digital-library/internal/storage/softdelete"
 )
 
 type Author struct {
-	Id       int    `json:"id"`
-	FullName string `json:"full_name"`
+	Id        int        `json:"id"`
+	FullName  string     `json:"full_name"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
 }
 
 func InitTable(db *sql.DB) error {
@@ -16,7 +21,8 @@ func InitTable(db *sql.DB) error {
 	stmt, err := db.Prepare(`
     CREATE TABLE IF NOT EXISTS authors(
       id INTEGER PRIMARY KEY,
-      full_name TEXT
+      full_name TEXT,
+      deleted_at INTEGER
     );
   `)
 	if err != nil {
@@ -28,88 +34,290 @@ func InitTable(db *sql.DB) error {
 		return fmt.Errorf("%s: %w", errMsg, err)
 	}
 
+	err = softdelete.InitColumn(db, "authors")
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
 	return nil
 }
 
-func GetAuthor(db *sql.DB, id int) (*Author, error) {
+func GetAuthor(db *sql.DB, id int, includeDeleted bool) (*Author, error) {
 	const errMsg = "can't get author"
 
+	stmt, err := db.Prepare(fmt.Sprintf(`
+    SELECT id, full_name, deleted_at FROM authors
+    WHERE id = ?
+    %s;
+  `, softdelete.Filter("deleted_at", includeDeleted)))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	row := stmt.QueryRow(id)
+
+	var (
+		author    Author
+		deletedAt sql.NullInt64
+	)
+
+	err = row.Scan(&author.Id, &author.FullName, &deletedAt)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	author.DeletedAt = softdelete.Time(deletedAt)
+
+	return &author, nil
+}
+
+// GetAuthorByName returns the first not deleted author with the specified full name.
+func GetAuthorByName(db querier.Querier, fullName string) (*Author, error) {
+	const errMsg = "can't get author by name"
+
 	stmt, err := db.Prepare(`
-    SELECT * FROM authors
-    WHERE id = ?;
+    SELECT id, full_name, deleted_at FROM authors
+    WHERE full_name = ?
+    AND deleted_at IS NULL
+    ORDER BY id
+    LIMIT 1;
   `)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", errMsg, err)
 	}
 
-	row := stmt.QueryRow(id)
+	row := stmt.QueryRow(fullName)
 
-	var author Author
+	var (
+		author    Author
+		deletedAt sql.NullInt64
+	)
 
-	err = row.Scan(&author.Id, &author.FullName)
+	err = row.Scan(&author.Id, &author.FullName, &deletedAt)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", errMsg, err)
 	}
 
+	author.DeletedAt = softdelete.Time(deletedAt)
+
 	return &author, nil
 }
 
-func PostAuthor(db *sql.DB, author *Author) error {
+// PostAuthor inserts the author.
+// If the author id is 0, the id is assigned by the db and set on the author.
+func PostAuthor(db querier.Querier, author *Author) (int64, error) {
 	const errMsg = "can't post author"
 
 	stmt, err := db.Prepare(`
-    INSERT INTO books
+    INSERT INTO authors
     (id, full_name)
     VALUES 
     (?, ?);
   `)
 	if err != nil {
-		return fmt.Errorf("%s: %w", errMsg, err)
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
 	}
 
-	_, err = stmt.Exec(author.Id, author.FullName)
+	var id any
+	if author.Id != 0 {
+		id = author.Id
+	}
+
+	res, err := stmt.Exec(id, author.FullName)
 	if err != nil {
-		return fmt.Errorf("%s: %w", errMsg, err)
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
 	}
 
-	return nil
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	if author.Id == 0 {
+		lastId, err := res.LastInsertId()
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", errMsg, err)
+		}
+		author.Id = int(lastId)
+	}
+
+	return n, nil
 }
 
-func PutAuthor(db *sql.DB, author *Author) error {
+func PutAuthor(db *sql.DB, author *Author) (int64, error) {
 	const errMsg = "can't put author"
 
 	stmt, err := db.Prepare(`
     UPDATE authors
     SET full_name = ?
-    WHERE id = ?;
+    WHERE id = ?
+    AND deleted_at IS NULL;
   `)
 	if err != nil {
-		return fmt.Errorf("%s: %w", errMsg, err)
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
 	}
 
-	_, err = stmt.Exec(author.FullName, author.Id)
+	res, err := stmt.Exec(author.FullName, author.Id)
 	if err != nil {
-		return fmt.Errorf("%s: %w", errMsg, err)
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
 	}
 
-	return nil
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return n, nil
 }
 
-func DeleteAuthor(db *sql.DB, id int) error {
+// DeleteAuthor marks the author as deleted.
+// The row is kept until it is purged.
+func DeleteAuthor(db *sql.DB, id int) (int64, error) {
 	const errMsg = "can't delete author"
 
 	stmt, err := db.Prepare(`
+    UPDATE authors
+    SET deleted_at = ?
+    WHERE id = ?
+    AND deleted_at IS NULL;
+  `)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	res, err := stmt.Exec(time.Now().Unix(), id)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return n, nil
+}
+
+func RestoreAuthor(db *sql.DB, id int) (int64, error) {
+	const errMsg = "can't restore author"
+
+	stmt, err := db.Prepare(`
+    UPDATE authors
+    SET deleted_at = NULL
+    WHERE id = ?
+    AND deleted_at IS NOT NULL;
+  `)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	res, err := stmt.Exec(id)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	if n == 0 {
+		return 0, fmt.Errorf("%s: deleted author with %d id doesn't exist: %w", errMsg, id, sql.ErrNoRows)
+	}
+
+	return n, nil
+}
+
+// PurgeAuthors permanently removes the authors deleted before the specified time
+// along with the rows referencing them.
+func PurgeAuthors(tx *sql.Tx, before time.Time) (int64, error) {
+	const errMsg = "can't purge authors"
+
+	for _, table := range []string{"authorships", "favorite_authors"} {
+		_, err := tx.Exec(fmt.Sprintf(`
+    DELETE FROM %s
+    WHERE author_id IN (
+      SELECT id FROM authors
+      WHERE deleted_at < ?
+    );
+  `, table), before.Unix())
+		if err != nil {
+			return 0, fmt.Errorf("%s: can't delete from %s: %w", errMsg, table, err)
+		}
+	}
+
+	res, err := tx.Exec(`
     DELETE FROM authors
-    WHERE id = ?;
+    WHERE deleted_at < ?;
+  `, before.Unix())
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return n, nil
+}
+
+// GetAuthors returns the not deleted authors ordered by full name.
+func GetAuthors(db *sql.DB) ([]Author, error) {
+	const errMsg = "can't get authors"
+
+	stmt, err := db.Prepare(`
+    SELECT id, full_name FROM authors
+    WHERE deleted_at IS NULL
+    ORDER BY full_name, id;
   `)
 	if err != nil {
-		return fmt.Errorf("%s: %w", errMsg, err)
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
 	}
 
-	_, err = stmt.Exec(id)
+	rows, err := stmt.Query()
 	if err != nil {
-		return fmt.Errorf("%s: %w", errMsg, err)
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
 	}
+	defer rows.Close()
 
-	return nil
+	authors := make([]Author, 0)
+
+	for rows.Next() {
+		var author Author
+		err := rows.Scan(&author.Id, &author.FullName)
+		if err != nil {
+			return nil, fmt.Errorf("%s: can't scan author: %s", errMsg, err)
+		}
+		authors = append(authors, author)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("%s: error occured while iterating over authors: %s", errMsg, err)
+	}
+
+	return authors, nil
+}
+
+// CountAuthors returns the number of the authors which are not deleted.
+func CountAuthors(db *sql.DB) (int, error) {
+	const errMsg = "can't count authors"
+
+	stmt, err := db.Prepare(`
+    SELECT COUNT(*) FROM authors
+    WHERE deleted_at IS NULL;
+  `)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	var n int
+
+	err = stmt.QueryRow().Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return n, nil
 }
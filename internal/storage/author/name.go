@@ -0,0 +1,101 @@
+package author
+
+import "strings"
+
+// Name is the full name of an author split into parts.
+type Name struct {
+	Given  string
+	Family string
+	Suffix string
+}
+
+// particles are lowercase prefixes which belong to the family name,
+// e.g. van in Ludwig van Beethoven.
+var particles = map[string]bool{
+	"da": true, "de": true, "del": true, "della": true, "der": true, "di": true,
+	"du": true, "la": true, "le": true, "van": true, "von": true, "y": true,
+}
+
+var suffixes = map[string]bool{
+	"jr": true, "jr.": true, "sr": true, "sr.": true,
+	"ii": true, "iii": true, "iv": true,
+}
+
+// ParseName splits the full name into given and family names.
+// Both "Given Family" and "Family, Given" forms are supported.
+// A single word is treated as the family name.
+func ParseName(fullName string) Name {
+	var n Name
+
+	if family, given, ok := strings.Cut(fullName, ","); ok {
+		n.Family = strings.Join(strings.Fields(family), " ")
+		rest := strings.Split(given, ",")
+		n.Given = strings.Join(strings.Fields(rest[0]), " ")
+		if len(rest) > 1 {
+			n.Suffix = strings.Join(strings.Fields(strings.Join(rest[1:], " ")), " ")
+		}
+		return n
+	}
+
+	words := strings.Fields(fullName)
+
+	if len(words) > 1 && suffixes[strings.ToLower(words[len(words)-1])] {
+		n.Suffix = words[len(words)-1]
+		words = words[:len(words)-1]
+	}
+
+	if len(words) == 0 {
+		return n
+	}
+
+	// the family name starts at the first particle or at the last word
+	i := len(words) - 1
+	for j := 1; j < len(words)-1; j++ {
+		if particles[words[j]] {
+			i = j
+			break
+		}
+	}
+
+	n.Given = strings.Join(words[:i], " ")
+	n.Family = strings.Join(words[i:], " ")
+
+	return n
+}
+
+// Inverted returns the name in "Family, Given, Suffix" form.
+func (n Name) Inverted() string {
+	parts := []string{n.Family}
+	if n.Given != "" {
+		parts = append(parts, n.Given)
+	}
+	if n.Suffix != "" {
+		parts = append(parts, n.Suffix)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Initials returns the initials of the given name, e.g. "J. R. R.".
+func (n Name) Initials() string {
+	words := strings.Fields(strings.ReplaceAll(n.Given, ".", ". "))
+	initials := make([]string, 0, len(words))
+	for _, w := range words {
+		// hyphenated parts are joined to the initial of the same word,
+		// e.g. "J.-P." for Jean-Paul, but not to the previous word
+		joined := false
+		for _, part := range strings.Split(w, "-") {
+			r := []rune(part)
+			if len(r) == 0 {
+				continue
+			}
+			initial := string(r[0]) + "."
+			if joined {
+				initials[len(initials)-1] += "-" + initial
+				continue
+			}
+			initials = append(initials, initial)
+			joined = true
+		}
+	}
+	return strings.Join(initials, " ")
+}
@@ -0,0 +1,33 @@
+package author
+
+import "testing"
+
+func TestInitials(t *testing.T) {
+	tests := []struct {
+		name     string
+		fullName string
+		want     string
+	}{
+		{"single given name", "George Orwell", "G."},
+		{"several given names", "John Ronald Reuel Tolkien", "J. R. R."},
+		{"given names with dots", "J.R.R. Tolkien", "J. R. R."},
+		{"hyphenated given name", "Jean-Paul Sartre", "J.-P."},
+		{"inverted hyphenated given name", "Sartre, Jean-Paul", "J.-P."},
+		{"leading hyphen", "-Paul Smith", "P."},
+		{"leading hyphen after a given name", "Anne -Marie Smith", "A. M."},
+		{"trailing hyphen", "Jean- Smith", "J."},
+		{"double hyphen", "Jean--Paul Sartre", "J.-P."},
+		{"only hyphens", "- Smith", ""},
+		{"family name only", "Plato", ""},
+		{"empty", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseName(tt.fullName).Initials()
+			if got != tt.want {
+				t.Errorf("ParseName(%q).Initials() = %q, want %q", tt.fullName, got, tt.want)
+			}
+		})
+	}
+}
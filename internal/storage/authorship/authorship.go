@@ -3,11 +3,19 @@ package authorship
 import (
 	"database/sql"
 	"fmt"
+
+	"github.com/qo/digital-library/internal/storage/author"
+	"github.com/qo/digital-library/internal/storage/book"
+	"github.com/qo/digital-library/internal/storage/column"
+	"github.com/qo/digital-library/internal/storage/querier"
 )
 
 type Authorship struct {
 	AuthorId int `json:"author_id"`
 	BookId   int `json:"book_id"`
+	// Position is the order of the author among the authors of the book,
+	// the main author is 0
+	Position int `json:"position"`
 }
 
 func InitTable(db *sql.DB) error {
@@ -17,6 +25,7 @@ func InitTable(db *sql.DB) error {
     CREATE TABLE IF NOT EXISTS authorships(
       author_id INTEGER,
       book_id INTEGER,
+      position INTEGER NOT NULL DEFAULT 0,
       FOREIGN KEY (author_id) REFERENCES authors (id),
       FOREIGN KEY (book_id) REFERENCES books (id),
       PRIMARY KEY (author_id, book_id)
@@ -31,6 +40,13 @@ func InitTable(db *sql.DB) error {
 		return fmt.Errorf("%s: %w", errMsg, err)
 	}
 
+	// the authorships created before the position was introduced
+	// are ordered by the author id as they used to be
+	err = column.Init(db, "authorships", "position", "INTEGER NOT NULL DEFAULT 0")
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
 	return nil
 }
 
@@ -38,7 +54,7 @@ func GetAuthorship(db *sql.DB, authorId, bookId int) (*Authorship, error) {
 	const errMsg = "can't get authorship"
 
 	stmt, err := db.Prepare(`
-    SELECT * FROM authorships
+    SELECT author_id, book_id, position FROM authorships
     WHERE author_id = ?
     AND book_id = ?
   `)
@@ -50,7 +66,7 @@ func GetAuthorship(db *sql.DB, authorId, bookId int) (*Authorship, error) {
 
 	var authorship Authorship
 
-	err = row.Scan(&authorship.AuthorId, &authorship.BookId)
+	err = row.Scan(&authorship.AuthorId, &authorship.BookId, &authorship.Position)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", errMsg, err)
 	}
@@ -58,20 +74,23 @@ func GetAuthorship(db *sql.DB, authorId, bookId int) (*Authorship, error) {
 	return &authorship, nil
 }
 
-func PostAuthorship(db *sql.DB, authorship *Authorship) (int, int, error) {
+func PostAuthorship(db querier.Querier, authorship *Authorship) (int, int, error) {
 	const errMsg = "can't put authorship"
 
 	stmt, err := db.Prepare(`
     INSERT INTO authorships
-    (author_id, book_id)
+    (author_id, book_id, position)
     VALUES
-    (?, ?);
+    (?, ?, ?);
   `)
 	if err != nil {
 		return 0, 0, fmt.Errorf("%s: %w", errMsg, err)
 	}
 
-	stmt.QueryRow(authorship.AuthorId, authorship.BookId)
+	_, err = stmt.Exec(authorship.AuthorId, authorship.BookId, authorship.Position)
+	if err != nil {
+		return 0, 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
 
 	return authorship.AuthorId, authorship.BookId, nil
 }
@@ -92,3 +111,163 @@ func DeleteAuthorship(db *sql.DB, authorId, bookId int) (int, int, error) {
 
 	return authorId, bookId, nil
 }
+
+// EachBookWithAuthors calls fn for every not deleted book
+// along with its not deleted authors ordered by position, the main author first.
+// The books are read one by one, so the whole catalog is never kept in memory.
+func EachBookWithAuthors(db *sql.DB, fn func(book.Book, []author.Author) error) error {
+	const errMsg = "can't iterate over books with authors"
+
+	stmt, err := db.Prepare(`
+    SELECT b.id, b.isbn, b.title, b.year, b.publisher, a.id, a.full_name
+    FROM books AS b
+    LEFT JOIN authorships AS ash
+    ON ash.book_id = b.id
+    LEFT JOIN authors AS a
+    ON a.id = ash.author_id
+    AND a.deleted_at IS NULL
+    WHERE b.deleted_at IS NULL
+    ORDER BY b.id, ash.position, a.id;
+  `)
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	rows, err := stmt.Query()
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+	defer rows.Close()
+
+	var (
+		current *book.Book
+		authors []author.Author
+	)
+
+	for rows.Next() {
+		var (
+			b        book.Book
+			authorId sql.NullInt64
+			fullName sql.NullString
+		)
+		err := rows.Scan(&b.Id, &b.Isbn, &b.Title, &b.Year, &b.Publisher, &authorId, &fullName)
+		if err != nil {
+			return fmt.Errorf("%s: can't scan book with author: %s", errMsg, err)
+		}
+
+		if current == nil || current.Id != b.Id {
+			if current != nil {
+				err = fn(*current, authors)
+				if err != nil {
+					return err
+				}
+			}
+			current = &b
+			authors = make([]author.Author, 0)
+		}
+
+		if authorId.Valid {
+			authors = append(authors, author.Author{
+				Id:       int(authorId.Int64),
+				FullName: fullName.String,
+			})
+		}
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return fmt.Errorf("%s: error occured while iterating over books with authors: %s", errMsg, err)
+	}
+
+	if current != nil {
+		return fn(*current, authors)
+	}
+
+	return nil
+}
+
+// GetBookAuthors returns the not deleted authors of the book ordered by position, the main author first.
+func GetBookAuthors(db *sql.DB, bookId int) ([]author.Author, error) {
+	const errMsg = "can't get book authors"
+
+	stmt, err := db.Prepare(`
+    SELECT a.id, a.full_name FROM authorships AS ash
+    JOIN authors AS a
+    ON ash.author_id = a.id
+    WHERE ash.book_id = ?
+    AND a.deleted_at IS NULL
+    ORDER BY ash.position, a.id;
+  `)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	rows, err := stmt.Query(bookId)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+	defer rows.Close()
+
+	authors := make([]author.Author, 0)
+
+	for rows.Next() {
+		var author author.Author
+		err := rows.Scan(&author.Id, &author.FullName)
+		if err != nil {
+			return nil, fmt.Errorf("%s: can't scan author: %s", errMsg, err)
+		}
+		authors = append(authors, author)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("%s: error occured while iterating over authors: %s", errMsg, err)
+	}
+
+	return authors, nil
+}
+
+// GetAuthorBooks returns the not deleted books of the author ordered by year and title.
+func GetAuthorBooks(db *sql.DB, authorId int) ([]book.Book, error) {
+	const errMsg = "can't get author books"
+
+	stmt, err := db.Prepare(`
+    SELECT b.id, b.isbn, b.title, b.year, b.publisher, b.rating_sum, b.rating_count, b.copies FROM authorships AS ash
+    JOIN books AS b
+    ON ash.book_id = b.id
+    WHERE ash.author_id = ?
+    AND b.deleted_at IS NULL
+    ORDER BY b.year, b.title;
+  `)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	rows, err := stmt.Query(authorId)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+	defer rows.Close()
+
+	books := make([]book.Book, 0)
+
+	for rows.Next() {
+		var (
+			book                   book.Book
+			ratingSum, ratingCount int
+		)
+		err := rows.Scan(&book.Id, &book.Isbn, &book.Title, &book.Year, &book.Publisher, &ratingSum, &ratingCount, &book.Copies)
+		if err != nil {
+			return nil, fmt.Errorf("%s: can't scan book: %s", errMsg, err)
+		}
+		book.SetRating(ratingSum, ratingCount)
+		books = append(books, book)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("%s: error occured while iterating over books: %s", errMsg, err)
+	}
+
+	return books, nil
+}
@@ -3,14 +3,38 @@ package book
 import (
 	"database/sql"
 	"fmt"
+	"strings"
+	"time"
+
+	"github.com/qo/digital-library/internal/storage/column"
+	"github.com/qo/digital-library/internal/storage/querier"
+	"github.com/qo/digital-library/internal/storage/softdelete"
 )
 
 type Book struct {
-	Id        int    `json:"id"`
-	Isbn      string `json:"isbn"`
-	Title     string `json:"title"`
-	Year      int    `json:"year"`
-	Publisher string `json:"publisher"`
+	Id        int        `json:"id"`
+	Isbn      string     `json:"isbn"`
+	Title     string     `json:"title"`
+	Year      int        `json:"year"`
+	Publisher string     `json:"publisher"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	// AverageRating is the average rating of the not deleted reviews,
+	// it is 0 if the book has no reviews
+	AverageRating float64 `json:"average_rating"`
+	ReviewCount   int     `json:"review_count"`
+	// Copies is the number of the licensed copies which can be lent at the same time,
+	// it is changed with SetCopies only
+	Copies int `json:"copies"`
+}
+
+// SetRating sets the average rating and the review count
+// from the aggregates stored with the book.
+func (b *Book) SetRating(ratingSum, ratingCount int) {
+	b.ReviewCount = ratingCount
+	b.AverageRating = 0
+	if ratingCount > 0 {
+		b.AverageRating = float64(ratingSum) / float64(ratingCount)
+	}
 }
 
 func InitTable(db *sql.DB) error {
@@ -22,7 +46,11 @@ func InitTable(db *sql.DB) error {
       isbn TEXT,
       title TEXT,
       year INTEGER,
-      publisher TEXT
+      publisher TEXT,
+      deleted_at INTEGER,
+      rating_sum INTEGER NOT NULL DEFAULT 0,
+      rating_count INTEGER NOT NULL DEFAULT 0,
+      copies INTEGER NOT NULL DEFAULT 1
     );
   `)
 	if err != nil {
@@ -34,33 +62,60 @@ func InitTable(db *sql.DB) error {
 		return fmt.Errorf("%s: %w", errMsg, err)
 	}
 
+	err = softdelete.InitColumn(db, "books")
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	for _, name := range []string{"rating_sum", "rating_count"} {
+		err = column.Init(db, "books", name, "INTEGER NOT NULL DEFAULT 0")
+		if err != nil {
+			return fmt.Errorf("%s: %w", errMsg, err)
+		}
+	}
+
+	err = column.Init(db, "books", "copies", "INTEGER NOT NULL DEFAULT 1")
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
 	return nil
 }
 
-func GetBook(db *sql.DB, id int) (*Book, error) {
+func GetBook(db *sql.DB, id int, includeDeleted bool) (*Book, error) {
 	const errMsg = "can't get book"
 
-	stmt, err := db.Prepare(`
-    SELECT * FROM books
-    WHERE id = ?;
-  `)
+	stmt, err := db.Prepare(fmt.Sprintf(`
+    SELECT id, isbn, title, year, publisher, deleted_at, rating_sum, rating_count, copies FROM books
+    WHERE id = ?
+    %s;
+  `, softdelete.Filter("deleted_at", includeDeleted)))
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", errMsg, err)
 	}
 
 	row := stmt.QueryRow(id)
 
-	var book Book
+	var (
+		book                   Book
+		deletedAt              sql.NullInt64
+		ratingSum, ratingCount int
+	)
 
-	err = row.Scan(&book.Id, &book.Isbn, &book.Title, &book.Year, &book.Publisher)
+	err = row.Scan(&book.Id, &book.Isbn, &book.Title, &book.Year, &book.Publisher, &deletedAt, &ratingSum, &ratingCount, &book.Copies)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", errMsg, err)
 	}
 
+	book.DeletedAt = softdelete.Time(deletedAt)
+	book.SetRating(ratingSum, ratingCount)
+
 	return &book, nil
 }
 
-func PostBook(db *sql.DB, book *Book) error {
+// PostBook inserts the book.
+// If the book id is 0, the id is assigned by the db and set on the book.
+func PostBook(db querier.Querier, book *Book) (int64, error) {
 	const errMsg = "can't post book"
 
 	stmt, err := db.Prepare(`
@@ -70,52 +125,371 @@ func PostBook(db *sql.DB, book *Book) error {
     (?, ?, ?, ?, ?);
   `)
 	if err != nil {
-		return fmt.Errorf("%s: %w", errMsg, err)
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
 	}
 
-	_, err = stmt.Exec(book.Id, book.Isbn, book.Title, book.Year, book.Publisher)
+	var id any
+	if book.Id != 0 {
+		id = book.Id
+	}
+
+	res, err := stmt.Exec(id, book.Isbn, book.Title, book.Year, book.Publisher)
 	if err != nil {
-		return fmt.Errorf("%s: %w", errMsg, err)
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
 	}
 
-	return nil
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	if book.Id == 0 {
+		lastId, err := res.LastInsertId()
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", errMsg, err)
+		}
+		book.Id = int(lastId)
+	}
+
+	return n, nil
 }
 
-func PutBook(db *sql.DB, book *Book) error {
+func PutBook(db *sql.DB, book *Book) (int64, error) {
 	const errMsg = "can't put book"
 
 	stmt, err := db.Prepare(`
     UPDATE books
     SET isbn = ?, title = ?, year = ?, publisher = ?
-    WHERE id = ?;
+    WHERE id = ?
+    AND deleted_at IS NULL;
   `)
 	if err != nil {
-		return fmt.Errorf("%s: %w", errMsg, err)
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
 	}
 
-	_, err = stmt.Exec(book.Isbn, book.Title, book.Year, book.Publisher, book.Id)
+	res, err := stmt.Exec(book.Isbn, book.Title, book.Year, book.Publisher, book.Id)
 	if err != nil {
-		return fmt.Errorf("%s: %w", errMsg, err)
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
 	}
 
-	return nil
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return n, nil
 }
 
-func DeleteBook(db *sql.DB, id int) error {
+// DeleteBook marks the book as deleted.
+// The row is kept until it is purged.
+func DeleteBook(db *sql.DB, id int) (int64, error) {
 	const errMsg = "can't delete book"
 
 	stmt, err := db.Prepare(`
+    UPDATE books
+    SET deleted_at = ?
+    WHERE id = ?
+    AND deleted_at IS NULL;
+  `)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	res, err := stmt.Exec(time.Now().Unix(), id)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return n, nil
+}
+
+func RestoreBook(db *sql.DB, id int) (int64, error) {
+	const errMsg = "can't restore book"
+
+	stmt, err := db.Prepare(`
+    UPDATE books
+    SET deleted_at = NULL
+    WHERE id = ?
+    AND deleted_at IS NOT NULL;
+  `)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	res, err := stmt.Exec(id)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	if n == 0 {
+		return 0, fmt.Errorf("%s: deleted book with %d id doesn't exist: %w", errMsg, id, sql.ErrNoRows)
+	}
+
+	return n, nil
+}
+
+// PurgeBooks permanently removes the books deleted before the specified time
+// along with the rows referencing them.
+func PurgeBooks(tx *sql.Tx, before time.Time) (int64, error) {
+	const errMsg = "can't purge books"
+
+	for _, table := range []string{"authorships", "favorite_books", "book_reviews", "recommendations", "reading_states", "bookmarks", "shelf_books", "loans", "holds"} {
+		_, err := tx.Exec(fmt.Sprintf(`
+    DELETE FROM %s
+    WHERE book_id IN (
+      SELECT id FROM books
+      WHERE deleted_at < ?
+    );
+  `, table), before.Unix())
+		if err != nil {
+			return 0, fmt.Errorf("%s: can't delete from %s: %w", errMsg, table, err)
+		}
+	}
+
+	res, err := tx.Exec(`
     DELETE FROM books
-    WHERE id = ?;
+    WHERE deleted_at < ?;
+  `, before.Unix())
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return n, nil
+}
+
+// GetBooks returns the not deleted books ordered by title.
+func GetBooks(db *sql.DB, limit, offset int) ([]Book, error) {
+	const errMsg = "can't get books"
+
+	stmt, err := db.Prepare(`
+    SELECT id, isbn, title, year, publisher, deleted_at, rating_sum, rating_count, copies FROM books
+    WHERE deleted_at IS NULL
+    ORDER BY title, id
+    LIMIT ? OFFSET ?;
   `)
 	if err != nil {
-		return fmt.Errorf("%s: %w", errMsg, err)
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
 	}
 
-	_, err = stmt.Exec(id)
+	rows, err := stmt.Query(limit, offset)
 	if err != nil {
-		return fmt.Errorf("%s: %w", errMsg, err)
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
 	}
 
-	return nil
+	books, err := scanBooks(rows)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return books, nil
+}
+
+// GetNewBooks returns the not deleted books, the most recently added first.
+func GetNewBooks(db *sql.DB, limit, offset int) ([]Book, error) {
+	const errMsg = "can't get new books"
+
+	stmt, err := db.Prepare(`
+    SELECT id, isbn, title, year, publisher, deleted_at, rating_sum, rating_count, copies FROM books
+    WHERE deleted_at IS NULL
+    ORDER BY id DESC
+    LIMIT ? OFFSET ?;
+  `)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	rows, err := stmt.Query(limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	books, err := scanBooks(rows)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return books, nil
+}
+
+// GetPublisherBooks returns the not deleted books of the publisher ordered by title.
+func GetPublisherBooks(db *sql.DB, publisher string, limit, offset int) ([]Book, error) {
+	const errMsg = "can't get publisher books"
+
+	stmt, err := db.Prepare(`
+    SELECT id, isbn, title, year, publisher, deleted_at, rating_sum, rating_count, copies FROM books
+    WHERE publisher = ?
+    AND deleted_at IS NULL
+    ORDER BY title, id
+    LIMIT ? OFFSET ?;
+  `)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	rows, err := stmt.Query(publisher, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	books, err := scanBooks(rows)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return books, nil
+}
+
+// SearchBooks returns the not deleted books whose title, isbn, publisher
+// or author full name contains the query, ordered by title.
+func SearchBooks(db *sql.DB, query string, limit, offset int) ([]Book, error) {
+	const errMsg = "can't search books"
+
+	stmt, err := db.Prepare(`
+    SELECT b.id, b.isbn, b.title, b.year, b.publisher, b.deleted_at, b.rating_sum, b.rating_count, b.copies FROM books AS b
+    WHERE b.deleted_at IS NULL
+    AND (
+      b.title LIKE ? ESCAPE '!'
+      OR b.isbn LIKE ? ESCAPE '!'
+      OR b.publisher LIKE ? ESCAPE '!'
+      OR b.id IN (
+        SELECT ash.book_id FROM authorships AS ash
+        JOIN authors AS a
+        ON ash.author_id = a.id
+        WHERE a.full_name LIKE ? ESCAPE '!'
+        AND a.deleted_at IS NULL
+      )
+    )
+    ORDER BY b.title, b.id
+    LIMIT ? OFFSET ?;
+  `)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	pattern := "%" + escapeLike(query) + "%"
+
+	rows, err := stmt.Query(pattern, pattern, pattern, pattern, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	books, err := scanBooks(rows)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return books, nil
+}
+
+// escapeLike escapes the wildcards of LIKE in the query with '!',
+// which is escaped the same way in SQLite and MySQL unlike the backslash.
+func escapeLike(query string) string {
+	return likeEscaper.Replace(query)
+}
+
+var likeEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+
+// GetPublishers returns the distinct publishers of the not deleted books.
+func GetPublishers(db *sql.DB) ([]string, error) {
+	const errMsg = "can't get publishers"
+
+	stmt, err := db.Prepare(`
+    SELECT DISTINCT publisher FROM books
+    WHERE deleted_at IS NULL
+    AND publisher IS NOT NULL
+    AND publisher <> ''
+    ORDER BY publisher;
+  `)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	rows, err := stmt.Query()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+	defer rows.Close()
+
+	publishers := make([]string, 0)
+
+	for rows.Next() {
+		var publisher string
+		err := rows.Scan(&publisher)
+		if err != nil {
+			return nil, fmt.Errorf("%s: can't scan publisher: %s", errMsg, err)
+		}
+		publishers = append(publishers, publisher)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("%s: error occured while iterating over publishers: %s", errMsg, err)
+	}
+
+	return publishers, nil
+}
+
+func scanBooks(rows *sql.Rows) ([]Book, error) {
+	defer rows.Close()
+
+	books := make([]Book, 0)
+
+	for rows.Next() {
+		var (
+			book                   Book
+			deletedAt              sql.NullInt64
+			ratingSum, ratingCount int
+		)
+		err := rows.Scan(&book.Id, &book.Isbn, &book.Title, &book.Year, &book.Publisher, &deletedAt, &ratingSum, &ratingCount, &book.Copies)
+		if err != nil {
+			return nil, fmt.Errorf("can't scan book: %s", err)
+		}
+		book.DeletedAt = softdelete.Time(deletedAt)
+		book.SetRating(ratingSum, ratingCount)
+		books = append(books, book)
+	}
+
+	err := rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("error occured while iterating over books: %s", err)
+	}
+
+	return books, nil
+}
+
+// CountBooks returns the number of the books which are not deleted.
+func CountBooks(db *sql.DB) (int, error) {
+	const errMsg = "can't count books"
+
+	stmt, err := db.Prepare(`
+    SELECT COUNT(*) FROM books
+    WHERE deleted_at IS NULL;
+  `)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	var n int
+
+	err = stmt.QueryRow().Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return n, nil
 }
@@ -0,0 +1,77 @@
+package book
+
+import (
+	"fmt"
+
+	"github.com/qo/digital-library/internal/storage/querier"
+)
+
+// GetCopies returns the number of the copies of the not deleted book.
+func GetCopies(db querier.Querier, id int) (int, error) {
+	const errMsg = "can't get book copies"
+
+	stmt, err := db.Prepare(`
+    SELECT copies FROM books
+    WHERE id = ?
+    AND deleted_at IS NULL;
+  `)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	var copies int
+
+	err = stmt.QueryRow(id).Scan(&copies)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return copies, nil
+}
+
+// SetCopies sets the number of the copies of the not deleted book.
+// The copies already lent aren't recalled if the number gets lower.
+func SetCopies(db querier.Querier, id, copies int) (int64, error) {
+	const errMsg = "can't set book copies"
+
+	stmt, err := db.Prepare(`
+    UPDATE books
+    SET copies = ?
+    WHERE id = ?
+    AND deleted_at IS NULL;
+  `)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	res, err := stmt.Exec(copies, id)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return n, nil
+}
+
+// Lock locks the row of the book until the end of the transaction,
+// so that the copies are counted and lent by one transaction at a time.
+// The no-op update locks the row on MySQL, SQLite has one writer anyway.
+// The books locked along with the users are locked first.
+func Lock(tx querier.Querier, id int) error {
+	const errMsg = "can't lock book"
+
+	_, err := tx.Exec(`
+    UPDATE books
+    SET copies = copies
+    WHERE id = ?;
+  `, id)
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,196 @@
+package book
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/qo/digital-library/internal/storage/querier"
+	"github.com/qo/digital-library/internal/storage/softdelete"
+)
+
+// priorWeight is the number of the mean ratings every book is assumed to have
+// when the books are ranked, so a single 5 star review doesn't outrank
+// a book with many good reviews.
+const priorWeight = 5
+
+// TopBook is the book along with its score in the top rated books.
+// AverageRating and ReviewCount of the book are of all reviews,
+// while the score is of the reviews of the period.
+type TopBook struct {
+	Book
+	// Score is the bayesian average of the ratings of the period
+	Score float64 `json:"score"`
+	// PeriodReviewCount is the number of the reviews of the period the score is computed from,
+	// it's ReviewCount if the period is all time
+	PeriodReviewCount int `json:"period_review_count"`
+}
+
+// AddRating adds the rating sum and the rating count to the aggregates of the book.
+// The deltas are negative when a review is removed.
+func AddRating(db querier.Querier, id, ratingSum, ratingCount int) error {
+	const errMsg = "can't add book rating"
+
+	stmt, err := db.Prepare(`
+    UPDATE books
+    SET rating_sum = rating_sum + ?, rating_count = rating_count + ?
+    WHERE id = ?;
+  `)
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	_, err = stmt.Exec(ratingSum, ratingCount, id)
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return nil
+}
+
+// RecountRatings recomputes the rating aggregates of all books
+// from the not deleted reviews.
+func RecountRatings(db querier.Querier) error {
+	const errMsg = "can't recount book ratings"
+
+	_, err := db.Exec(`
+    UPDATE books
+    SET rating_sum = COALESCE((
+      SELECT SUM(rating) FROM book_reviews
+      WHERE book_id = books.id
+      AND deleted_at IS NULL
+    ), 0),
+    rating_count = (
+      SELECT COUNT(*) FROM book_reviews
+      WHERE book_id = books.id
+      AND deleted_at IS NULL
+    );
+  `)
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return nil
+}
+
+// GetTopBooks returns the not deleted books ranked by the bayesian average
+// of the ratings of the reviews updated since the specified time.
+// If since is nil, the aggregates of all reviews are used.
+// The average rating and the review count of the books are of all reviews either way.
+// The books without reviews in the period are not returned.
+func GetTopBooks(db *sql.DB, since *time.Time, limit, offset int) ([]TopBook, error) {
+	const errMsg = "can't get top books"
+
+	mean, err := meanRating(db, since)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	var (
+		query string
+		args  = []any{priorWeight, mean, priorWeight}
+	)
+
+	if since == nil {
+		query = `
+    SELECT id, isbn, title, year, publisher, deleted_at, rating_sum, rating_count, copies,
+    (? * ? + rating_sum) / (? + rating_count) AS score, rating_count
+    FROM books
+    WHERE deleted_at IS NULL
+    AND rating_count > 0
+    ORDER BY score DESC, rating_count DESC, id
+    LIMIT ? OFFSET ?;
+  `
+	} else {
+		query = `
+    SELECT b.id, b.isbn, b.title, b.year, b.publisher, b.deleted_at, b.rating_sum, b.rating_count, b.copies,
+    (? * ? + r.rating_sum) / (? + r.rating_count) AS score, r.rating_count
+    FROM books AS b
+    JOIN (
+      SELECT book_id, SUM(rating) AS rating_sum, COUNT(*) AS rating_count FROM book_reviews
+      WHERE deleted_at IS NULL
+      AND updated_at >= ?
+      GROUP BY book_id
+    ) AS r
+    ON r.book_id = b.id
+    WHERE b.deleted_at IS NULL
+    ORDER BY score DESC, r.rating_count DESC, b.id
+    LIMIT ? OFFSET ?;
+  `
+		args = append(args, since.Unix())
+	}
+
+	args = append(args, limit, offset)
+
+	stmt, err := db.Prepare(query)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	rows, err := stmt.Query(args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+	defer rows.Close()
+
+	books := make([]TopBook, 0)
+
+	for rows.Next() {
+		var (
+			book                   TopBook
+			deletedAt              sql.NullInt64
+			ratingSum, ratingCount int
+		)
+		err := rows.Scan(&book.Id, &book.Isbn, &book.Title, &book.Year, &book.Publisher, &deletedAt, &ratingSum, &ratingCount, &book.Copies, &book.Score, &book.PeriodReviewCount)
+		if err != nil {
+			return nil, fmt.Errorf("%s: can't scan book: %s", errMsg, err)
+		}
+		book.DeletedAt = softdelete.Time(deletedAt)
+		book.SetRating(ratingSum, ratingCount)
+		books = append(books, book)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("%s: error occured while iterating over books: %s", errMsg, err)
+	}
+
+	return books, nil
+}
+
+// meanRating returns the mean rating of the reviews of the not deleted books
+// updated since the specified time, or of all reviews if since is nil.
+func meanRating(db *sql.DB, since *time.Time) (float64, error) {
+	const errMsg = "can't get mean rating"
+
+	var row *sql.Row
+
+	if since == nil {
+		row = db.QueryRow(`
+    SELECT COALESCE(SUM(rating_sum), 0), COALESCE(SUM(rating_count), 0) FROM books
+    WHERE deleted_at IS NULL;
+  `)
+	} else {
+		row = db.QueryRow(`
+    SELECT COALESCE(SUM(r.rating), 0), COUNT(*) FROM book_reviews AS r
+    JOIN books AS b
+    ON r.book_id = b.id
+    WHERE r.deleted_at IS NULL
+    AND r.updated_at >= ?
+    AND b.deleted_at IS NULL;
+  `, since.Unix())
+	}
+
+	var sum, count int
+
+	err := row.Scan(&sum, &count)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	if count == 0 {
+		return 0, nil
+	}
+
+	return float64(sum) / float64(count), nil
+}
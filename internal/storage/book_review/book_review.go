@@ -3,12 +3,19 @@ package book_review
 import (
 	"database/sql"
 	"fmt"
+	"time"
+
+	"github.com/qo/digital-library/internal/storage/column"
+	"github.com/qo/digital-library/internal/storage/querier"
+	"github.com/qo/digital-library/internal/storage/softdelete"
 )
 
 type BookReview struct {
-	UserId int `json:"user_id"`
-	BookId int `json:"book_id"`
-	Rating int `json:"rating"`
+	UserId    int        `json:"user_id"`
+	BookId    int        `json:"book_id"`
+	Rating    int        `json:"rating"`
+	Body      string     `json:"body"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
 }
 
 func InitTable(db *sql.DB) error {
@@ -19,6 +26,9 @@ func InitTable(db *sql.DB) error {
       user_id INTEGER,
       book_id INTEGER,
       rating INTEGER,
+      body TEXT,
+      deleted_at INTEGER,
+      updated_at INTEGER,
       FOREIGN KEY (user_id) REFERENCES users (id),
       FOREIGN KEY (book_id) REFERENCES books (id),
       PRIMARY KEY (user_id, book_id)
@@ -33,64 +43,251 @@ func InitTable(db *sql.DB) error {
 		return fmt.Errorf("%s: %w", errMsg, err)
 	}
 
+	err = softdelete.InitColumn(db, "book_reviews")
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	err = column.Init(db, "book_reviews", "body", "TEXT")
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	// updated_at is used to rank the books by the recent ratings,
+	// the reviews written before it was introduced don't have it
+	err = column.Init(db, "book_reviews", "updated_at", "INTEGER")
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
 	return nil
 }
 
-func GetBookReview(db *sql.DB, userId, bookId int) (*BookReview, error) {
+func GetBookReview(db querier.Querier, userId, bookId int, includeDeleted bool) (*BookReview, error) {
 	const errMsg = "can't get book review"
 
-	stmt, err := db.Prepare(`
-    SELECT * FROM book_reviews
+	stmt, err := db.Prepare(fmt.Sprintf(`
+    SELECT user_id, book_id, rating, COALESCE(body, ''), deleted_at FROM book_reviews
     WHERE user_id = ?
     AND book_id = ?
-  `)
+    %s
+  `, softdelete.Filter("deleted_at", includeDeleted)))
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", errMsg, err)
 	}
 
 	row := stmt.QueryRow(userId, bookId)
 
-	var bookReview BookReview
+	var (
+		bookReview BookReview
+		deletedAt  sql.NullInt64
+	)
 
-	err = row.Scan(&bookReview.UserId, &bookReview.BookId)
+	err = row.Scan(&bookReview.UserId, &bookReview.BookId, &bookReview.Rating, &bookReview.Body, &deletedAt)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", errMsg, err)
 	}
 
+	bookReview.DeletedAt = softdelete.Time(deletedAt)
+
 	return &bookReview, nil
 }
 
-func PostBookReview(db *sql.DB, bookReview *BookReview) (int, int, error) {
+// GetBookReviews returns the not deleted reviews of the book.
+func GetBookReviews(db *sql.DB, bookId int) ([]BookReview, error) {
+	const errMsg = "can't get book reviews"
+
+	stmt, err := db.Prepare(`
+    SELECT user_id, book_id, rating, COALESCE(body, '') FROM book_reviews
+    WHERE book_id = ?
+    AND deleted_at IS NULL
+    ORDER BY user_id;
+  `)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	rows, err := stmt.Query(bookId)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+	defer rows.Close()
+
+	reviews := make([]BookReview, 0)
+
+	for rows.Next() {
+		var review BookReview
+		err := rows.Scan(&review.UserId, &review.BookId, &review.Rating, &review.Body)
+		if err != nil {
+			return nil, fmt.Errorf("%s: can't scan book review: %s", errMsg, err)
+		}
+		reviews = append(reviews, review)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("%s: error occured while iterating over book reviews: %s", errMsg, err)
+	}
+
+	return reviews, nil
+}
+
+func PostBookReview(db querier.Querier, bookReview *BookReview) (int64, error) {
 	const errMsg = "can't put book review"
 
 	stmt, err := db.Prepare(`
     INSERT INTO book_reviews
-    (user_id, book_id)
+    (user_id, book_id, rating, body, updated_at)
     VALUES
-    (?, ?);
+    (?, ?, ?, ?, ?);
+  `)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	res, err := stmt.Exec(bookReview.UserId, bookReview.BookId, bookReview.Rating, bookReview.Body, time.Now().Unix())
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return n, nil
+}
+
+// PutBookReview updates the rating and the body of the not deleted review.
+// It returns sql.ErrNoRows if there is no such review.
+func PutBookReview(db querier.Querier, bookReview *BookReview) (int64, error) {
+	const errMsg = "can't put book review"
+
+	stmt, err := db.Prepare(`
+    UPDATE book_reviews
+    SET rating = ?, body = ?, updated_at = ?
+    WHERE user_id = ?
+    AND book_id = ?
+    AND deleted_at IS NULL;
   `)
 	if err != nil {
-		return 0, 0, fmt.Errorf("%s: %w", errMsg, err)
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
 	}
 
-	stmt.QueryRow(bookReview.UserId, bookReview.BookId)
+	res, err := stmt.Exec(bookReview.Rating, bookReview.Body, time.Now().Unix(), bookReview.UserId, bookReview.BookId)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
 
-	return bookReview.UserId, bookReview.BookId, nil
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+	if n == 0 {
+		return 0, fmt.Errorf("%s: %w", errMsg, sql.ErrNoRows)
+	}
+
+	return n, nil
 }
 
-func DeleteBookReview(db *sql.DB, userId, bookId int) (int, int, error) {
+// DeleteBookReview marks the book review as deleted.
+// The row is kept until it is purged.
+func DeleteBookReview(db querier.Querier, userId, bookId int) (int64, error) {
 	const errMsg = "can't delete book review"
 
 	stmt, err := db.Prepare(`
-    DELETE FROM book_reviews
+    UPDATE book_reviews
+    SET deleted_at = ?
+    WHERE user_id = ?
+    AND book_id = ?
+    AND deleted_at IS NULL
+  `)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	res, err := stmt.Exec(time.Now().Unix(), userId, bookId)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return n, nil
+}
+
+func RestoreBookReview(db querier.Querier, userId, bookId int) (int64, error) {
+	const errMsg = "can't restore book review"
+
+	stmt, err := db.Prepare(`
+    UPDATE book_reviews
+    SET deleted_at = NULL, updated_at = ?
     WHERE user_id = ?
     AND book_id = ?
+    AND deleted_at IS NOT NULL
+  `)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	res, err := stmt.Exec(time.Now().Unix(), userId, bookId)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	if n == 0 {
+		return 0, fmt.Errorf("%s: deleted book review doesn't exist: %w", errMsg, sql.ErrNoRows)
+	}
+
+	return n, nil
+}
+
+// PurgeBookReviews permanently removes the book reviews deleted before the specified time.
+func PurgeBookReviews(tx *sql.Tx, before time.Time) (int64, error) {
+	const errMsg = "can't purge book reviews"
+
+	res, err := tx.Exec(`
+    DELETE FROM book_reviews
+    WHERE deleted_at < ?;
+  `, before.Unix())
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return n, nil
+}
+
+// CountBookReviews returns the number of the book reviews which are not deleted.
+func CountBookReviews(db *sql.DB) (int, error) {
+	const errMsg = "can't count book reviews"
+
+	stmt, err := db.Prepare(`
+    SELECT COUNT(*) FROM book_reviews
+    WHERE deleted_at IS NULL;
   `)
 	if err != nil {
-		return 0, 0, fmt.Errorf("%s: %w", errMsg, err)
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
 	}
 
-	stmt.QueryRow(userId, bookId)
+	var n int
+
+	err = stmt.QueryRow().Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
 
-	return userId, bookId, nil
+	return n, nil
 }
@@ -0,0 +1,186 @@
+package bookmark
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Bookmark is a page of the book marked by the user, with an optional note.
+type Bookmark struct {
+	Id        int        `json:"id"`
+	UserId    int        `json:"user_id"`
+	BookId    int        `json:"book_id"`
+	Page      int        `json:"page"`
+	Note      string     `json:"note"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+}
+
+func InitTable(db *sql.DB) error {
+	const errMsg = "can't init bookmarks table"
+
+	stmt, err := db.Prepare(`
+    CREATE TABLE IF NOT EXISTS bookmarks(
+      id INTEGER PRIMARY KEY,
+      user_id INTEGER,
+      book_id INTEGER,
+      page INTEGER,
+      note TEXT,
+      created_at INTEGER,
+      FOREIGN KEY (user_id) REFERENCES users (id),
+      FOREIGN KEY (book_id) REFERENCES books (id)
+    );
+  `)
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	_, err = stmt.Exec()
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return nil
+}
+
+// GetBookmarks returns the bookmarks of the book made by the user ordered by page.
+func GetBookmarks(db *sql.DB, userId, bookId int) ([]Bookmark, error) {
+	const errMsg = "can't get bookmarks"
+
+	stmt, err := db.Prepare(`
+    SELECT id, user_id, book_id, page, COALESCE(note, ''), created_at FROM bookmarks
+    WHERE user_id = ?
+    AND book_id = ?
+    ORDER BY page, id;
+  `)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	rows, err := stmt.Query(userId, bookId)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+	defer rows.Close()
+
+	bookmarks := make([]Bookmark, 0)
+
+	for rows.Next() {
+		var (
+			b         Bookmark
+			createdAt sql.NullInt64
+		)
+		err := rows.Scan(&b.Id, &b.UserId, &b.BookId, &b.Page, &b.Note, &createdAt)
+		if err != nil {
+			return nil, fmt.Errorf("%s: can't scan bookmark: %s", errMsg, err)
+		}
+		if createdAt.Valid {
+			t := time.Unix(createdAt.Int64, 0).UTC()
+			b.CreatedAt = &t
+		}
+		bookmarks = append(bookmarks, b)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("%s: error occured while iterating over bookmarks: %s", errMsg, err)
+	}
+
+	return bookmarks, nil
+}
+
+// PostBookmark inserts the bookmark, its id is assigned by the db and set on the bookmark.
+func PostBookmark(db *sql.DB, bookmark *Bookmark) (int64, error) {
+	const errMsg = "can't post bookmark"
+
+	stmt, err := db.Prepare(`
+    INSERT INTO bookmarks
+    (user_id, book_id, page, note, created_at)
+    VALUES
+    (?, ?, ?, ?, ?);
+  `)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+
+	res, err := stmt.Exec(bookmark.UserId, bookmark.BookId, bookmark.Page, bookmark.Note, now.Unix())
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	bookmark.Id = int(id)
+	bookmark.CreatedAt = &now
+
+	return n, nil
+}
+
+// PutBookmark updates the page and the note of the bookmark of the book made by the user.
+// It returns sql.ErrNoRows if there is no such bookmark.
+func PutBookmark(db *sql.DB, bookmark *Bookmark) (int64, error) {
+	const errMsg = "can't put bookmark"
+
+	stmt, err := db.Prepare(`
+    UPDATE bookmarks
+    SET page = ?, note = ?
+    WHERE id = ?
+    AND user_id = ?
+    AND book_id = ?;
+  `)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	res, err := stmt.Exec(bookmark.Page, bookmark.Note, bookmark.Id, bookmark.UserId, bookmark.BookId)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+	if n == 0 {
+		return 0, fmt.Errorf("%s: %w", errMsg, sql.ErrNoRows)
+	}
+
+	return n, nil
+}
+
+// DeleteBookmark removes the bookmark of the book made by the user.
+func DeleteBookmark(db *sql.DB, userId, bookId, id int) (int64, error) {
+	const errMsg = "can't delete bookmark"
+
+	stmt, err := db.Prepare(`
+    DELETE FROM bookmarks
+    WHERE id = ?
+    AND user_id = ?
+    AND book_id = ?;
+  `)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	res, err := stmt.Exec(id, userId, bookId)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return n, nil
+}
@@ -0,0 +1,29 @@
+package column
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// Exists reports whether the table has the column.
+func Exists(db *sql.DB, table, name string) bool {
+	_, err := db.Exec(fmt.Sprintf("SELECT %s FROM %s LIMIT 1;", name, table))
+	return err == nil
+}
+
+// Init adds the column to the table
+// if the table was created before the column was introduced.
+func Init(db *sql.DB, table, name, definition string) error {
+	const errMsg = "can't init column"
+
+	if Exists(db, table, name) {
+		return nil
+	}
+
+	_, err := db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s;", table, name, definition))
+	if err != nil {
+		return fmt.Errorf("%s %s.%s: %w", errMsg, table, name, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,203 @@
+package email
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/qo/digital-library/internal/storage/querier"
+)
+
+// Email is a message in the outbox.
+// It is sent until it succeeds or runs out of attempts,
+// the next attempt is made after a growing delay.
+type Email struct {
+	Id     int
+	UserId int
+	// To is the address the email is sent to
+	To            string
+	Subject       string
+	Body          string
+	Attempts      int
+	NextAttemptAt time.Time
+	// SentAt is set once the email is sent
+	SentAt *time.Time
+	// FailedAt is set once the email runs out of attempts
+	FailedAt *time.Time
+	// LastError is the error of the last failed attempt
+	LastError string
+}
+
+func InitTable(db *sql.DB) error {
+	const errMsg = "can't init emails table"
+
+	stmt, err := db.Prepare(`
+    CREATE TABLE IF NOT EXISTS emails(
+      id INTEGER PRIMARY KEY,
+      user_id INTEGER,
+      to_address TEXT,
+      subject TEXT,
+      body TEXT,
+      attempts INTEGER NOT NULL DEFAULT 0,
+      next_attempt_at INTEGER,
+      sent_at INTEGER,
+      failed_at INTEGER,
+      last_error TEXT,
+      FOREIGN KEY (user_id) REFERENCES users (id)
+    );
+  `)
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	_, err = stmt.Exec()
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return nil
+}
+
+// PostEmail puts the email in the outbox to be sent right away,
+// its id is assigned by the db and set on the email.
+func PostEmail(db querier.Querier, e *Email) (int64, error) {
+	const errMsg = "can't post email"
+
+	stmt, err := db.Prepare(`
+    INSERT INTO emails
+    (user_id, to_address, subject, body, next_attempt_at)
+    VALUES
+    (?, ?, ?, ?, ?);
+  `)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	e.NextAttemptAt = time.Now().UTC().Truncate(time.Second)
+
+	res, err := stmt.Exec(e.UserId, e.To, e.Subject, e.Body, e.NextAttemptAt.Unix())
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	e.Id = int(id)
+
+	return rows, nil
+}
+
+// GetDueEmails returns at most limit emails which are neither sent nor failed
+// and whose next attempt is due, the oldest first.
+func GetDueEmails(db *sql.DB, now time.Time, limit int) ([]Email, error) {
+	const errMsg = "can't get due emails"
+
+	stmt, err := db.Prepare(`
+    SELECT id, user_id, to_address, subject, body, attempts, next_attempt_at, COALESCE(last_error, '') FROM emails
+    WHERE sent_at IS NULL
+    AND failed_at IS NULL
+    AND next_attempt_at <= ?
+    ORDER BY next_attempt_at, id
+    LIMIT ?;
+  `)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	rows, err := stmt.Query(now.Unix(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+	defer rows.Close()
+
+	emails := make([]Email, 0)
+
+	for rows.Next() {
+		var (
+			e             Email
+			nextAttemptAt int64
+		)
+		err := rows.Scan(&e.Id, &e.UserId, &e.To, &e.Subject, &e.Body, &e.Attempts, &nextAttemptAt, &e.LastError)
+		if err != nil {
+			return nil, fmt.Errorf("%s: can't scan email: %s", errMsg, err)
+		}
+		e.NextAttemptAt = time.Unix(nextAttemptAt, 0).UTC()
+		emails = append(emails, e)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("%s: error occured while iterating over emails: %s", errMsg, err)
+	}
+
+	return emails, nil
+}
+
+// PutSent marks the email as sent.
+func PutSent(db *sql.DB, id int, sentAt time.Time) (int64, error) {
+	const errMsg = "can't put email sent"
+
+	stmt, err := db.Prepare(`
+    UPDATE emails
+    SET attempts = attempts + 1, sent_at = ?
+    WHERE id = ?;
+  `)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	res, err := stmt.Exec(sentAt.Unix(), id)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return n, nil
+}
+
+// PutFailedAttempt records the failed attempt to send the email.
+// If the next attempt time is nil the email is marked as failed
+// and it isn't sent anymore.
+func PutFailedAttempt(db *sql.DB, id int, lastError string, now time.Time, nextAttemptAt *time.Time) (int64, error) {
+	const errMsg = "can't put failed email attempt"
+
+	stmt, err := db.Prepare(`
+    UPDATE emails
+    SET attempts = attempts + 1, last_error = ?, next_attempt_at = COALESCE(?, next_attempt_at), failed_at = ?
+    WHERE id = ?;
+  `)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	var next, failedAt any
+	if nextAttemptAt != nil {
+		next = nextAttemptAt.Unix()
+	} else {
+		failedAt = now.Unix()
+	}
+
+	res, err := stmt.Exec(lastError, next, failedAt, id)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return n, nil
+}
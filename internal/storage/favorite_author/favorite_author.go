@@ -58,7 +58,7 @@ func GetFavoriteAuthor(db *sql.DB, userId, authorId int) (*FavoriteAuthor, error
 	return &favoriteAuthor, nil
 }
 
-func PutFavoriteAuthor(db *sql.DB, favoriteAuthor *FavoriteAuthor) (int, int, error) {
+func PutFavoriteAuthor(db *sql.DB, favoriteAuthor *FavoriteAuthor) (int64, error) {
 	const errMsg = "can't put favorite author"
 
 	stmt, err := db.Prepare(`
@@ -68,15 +68,23 @@ func PutFavoriteAuthor(db *sql.DB, favoriteAuthor *FavoriteAuthor) (int, int, er
     (?, ?);
   `)
 	if err != nil {
-		return 0, 0, fmt.Errorf("%s: %w", errMsg, err)
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
 	}
 
-	stmt.QueryRow(favoriteAuthor.UserId, favoriteAuthor.AuthorId)
+	res, err := stmt.Exec(favoriteAuthor.UserId, favoriteAuthor.AuthorId)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
 
-	return favoriteAuthor.UserId, favoriteAuthor.AuthorId, nil
+	return n, nil
 }
 
-func DeleteFavoriteAuthor(db *sql.DB, userId, authorId int) (int, int, error) {
+func DeleteFavoriteAuthor(db *sql.DB, userId, authorId int) (int64, error) {
 	const errMsg = "can't delete favorite author"
 
 	stmt, err := db.Prepare(`
@@ -85,10 +93,18 @@ func DeleteFavoriteAuthor(db *sql.DB, userId, authorId int) (int, int, error) {
     AND author_id = ?
   `)
 	if err != nil {
-		return 0, 0, fmt.Errorf("%s: %w", errMsg, err)
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
 	}
 
-	stmt.QueryRow(userId, authorId)
+	res, err := stmt.Exec(userId, authorId)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
 
-	return userId, authorId, nil
+	return n, nil
 }
@@ -58,7 +58,7 @@ func GetFavoriteBook(db *sql.DB, userId, bookId int) (*FavoriteBook, error) {
 	return &favoriteBook, nil
 }
 
-func PutFavoriteBook(db *sql.DB, favoriteBook *FavoriteBook) (int, int, error) {
+func PutFavoriteBook(db *sql.DB, favoriteBook *FavoriteBook) (int64, error) {
 	const errMsg = "can't put favorite book"
 
 	stmt, err := db.Prepare(`
@@ -68,15 +68,23 @@ func PutFavoriteBook(db *sql.DB, favoriteBook *FavoriteBook) (int, int, error) {
     (?, ?);
   `)
 	if err != nil {
-		return 0, 0, fmt.Errorf("%s: %w", errMsg, err)
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
 	}
 
-	stmt.QueryRow(favoriteBook.UserId, favoriteBook.BookId)
+	res, err := stmt.Exec(favoriteBook.UserId, favoriteBook.BookId)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
 
-	return favoriteBook.UserId, favoriteBook.BookId, nil
+	return n, nil
 }
 
-func DeleteFavoriteBook(db *sql.DB, userId, bookId int) (int, int, error) {
+func DeleteFavoriteBook(db *sql.DB, userId, bookId int) (int64, error) {
 	const errMsg = "can't delete favorite book"
 
 	stmt, err := db.Prepare(`
@@ -85,10 +93,18 @@ func DeleteFavoriteBook(db *sql.DB, userId, bookId int) (int, int, error) {
     AND book_id = ?
   `)
 	if err != nil {
-		return 0, 0, fmt.Errorf("%s: %w", errMsg, err)
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
 	}
 
-	stmt.QueryRow(userId, bookId)
+	res, err := stmt.Exec(userId, bookId)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
 
-	return userId, bookId, nil
+	return n, nil
 }
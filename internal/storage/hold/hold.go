@@ -0,0 +1,282 @@
+package hold
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/qo/digital-library/internal/storage/querier"
+)
+
+var (
+	// ErrHeld is returned if the user already holds the book.
+	ErrHeld = errors.New("book is already held by the user")
+	// ErrAvailable is returned if a copy of the book can be checked out right away.
+	ErrAvailable = errors.New("copy of the book is available")
+)
+
+// Hold is the place of the user in the queue for a copy of the book.
+// When a copy is returned it is lent to the first user in the queue.
+type Hold struct {
+	Id        int       `json:"id"`
+	UserId    int       `json:"user_id"`
+	BookId    int       `json:"book_id"`
+	CreatedAt time.Time `json:"created_at"`
+	// Position is the place in the queue starting from 1
+	Position int `json:"position"`
+}
+
+func InitTable(db *sql.DB) error {
+	const errMsg = "can't init holds table"
+
+	stmt, err := db.Prepare(`
+    CREATE TABLE IF NOT EXISTS holds(
+      id INTEGER PRIMARY KEY,
+      user_id INTEGER,
+      book_id INTEGER,
+      created_at INTEGER,
+      FOREIGN KEY (user_id) REFERENCES users (id),
+      FOREIGN KEY (book_id) REFERENCES books (id)
+    );
+  `)
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	_, err = stmt.Exec()
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return nil
+}
+
+// the queue is ordered by id, the position is the number of the holds of the book up to the hold
+const columns = `
+    h.id, h.user_id, h.book_id, h.created_at, (
+      SELECT COUNT(*) FROM holds AS q
+      WHERE q.book_id = h.book_id
+      AND q.id <= h.id
+    )`
+
+func GetHold(db querier.Querier, userId, bookId int) (*Hold, error) {
+	const errMsg = "can't get hold"
+
+	stmt, err := db.Prepare(`
+    SELECT ` + columns + ` FROM holds AS h
+    WHERE h.user_id = ?
+    AND h.book_id = ?;
+  `)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	hold, err := scanHold(stmt.QueryRow(userId, bookId))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return hold, nil
+}
+
+// GetUserHolds returns the holds of the user, the oldest first.
+func GetUserHolds(db *sql.DB, userId int) ([]Hold, error) {
+	const errMsg = "can't get user holds"
+
+	stmt, err := db.Prepare(`
+    SELECT ` + columns + ` FROM holds AS h
+    WHERE h.user_id = ?
+    ORDER BY h.id;
+  `)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	rows, err := stmt.Query(userId)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return scanHolds(rows, errMsg)
+}
+
+// GetQueue returns the first n holds of the queue of the book.
+func GetQueue(db querier.Querier, bookId, n int) ([]Hold, error) {
+	const errMsg = "can't get hold queue"
+
+	stmt, err := db.Prepare(`
+    SELECT ` + columns + ` FROM holds AS h
+    WHERE h.book_id = ?
+    ORDER BY h.id
+    LIMIT ?;
+  `)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	rows, err := stmt.Query(bookId, n)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return scanHolds(rows, errMsg)
+}
+
+// GetHeldBooks returns the ids of the books with a not empty queue.
+func GetHeldBooks(db querier.Querier) ([]int, error) {
+	const errMsg = "can't get held books"
+
+	rows, err := db.Query(`
+    SELECT DISTINCT book_id FROM holds
+    ORDER BY book_id;
+  `)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+	defer rows.Close()
+
+	ids := make([]int, 0)
+
+	for rows.Next() {
+		var id int
+		err := rows.Scan(&id)
+		if err != nil {
+			return nil, fmt.Errorf("%s: can't scan book id: %s", errMsg, err)
+		}
+		ids = append(ids, id)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("%s: error occured while iterating over book ids: %s", errMsg, err)
+	}
+
+	return ids, nil
+}
+
+// CountHolds returns the length of the queue of the book.
+func CountHolds(db querier.Querier, bookId int) (int, error) {
+	const errMsg = "can't count holds"
+
+	stmt, err := db.Prepare(`
+    SELECT COUNT(*) FROM holds
+    WHERE book_id = ?;
+  `)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	var n int
+
+	err = stmt.QueryRow(bookId).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return n, nil
+}
+
+// PostHold appends the hold to the end of the queue of the book,
+// its id and creation time are set on the hold.
+func PostHold(db querier.Querier, hold *Hold) (int64, error) {
+	const errMsg = "can't post hold"
+
+	stmt, err := db.Prepare(`
+    INSERT INTO holds
+    (user_id, book_id, created_at)
+    VALUES
+    (?, ?, ?);
+  `)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+
+	res, err := stmt.Exec(hold.UserId, hold.BookId, now.Unix())
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	hold.Id = int(id)
+	hold.CreatedAt = now
+
+	return n, nil
+}
+
+func DeleteHold(db querier.Querier, userId, bookId int) (int64, error) {
+	const errMsg = "can't delete hold"
+
+	stmt, err := db.Prepare(`
+    DELETE FROM holds
+    WHERE user_id = ?
+    AND book_id = ?;
+  `)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	res, err := stmt.Exec(userId, bookId)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return n, nil
+}
+
+func scanHolds(rows *sql.Rows, errMsg string) ([]Hold, error) {
+	defer rows.Close()
+
+	holds := make([]Hold, 0)
+
+	for rows.Next() {
+		hold, err := scanHold(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: can't scan hold: %s", errMsg, err)
+		}
+		holds = append(holds, *hold)
+	}
+
+	err := rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("%s: error occured while iterating over holds: %s", errMsg, err)
+	}
+
+	return holds, nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanHold(row scanner) (*Hold, error) {
+	var (
+		hold      Hold
+		createdAt int64
+	)
+
+	err := row.Scan(&hold.Id, &hold.UserId, &hold.BookId, &createdAt, &hold.Position)
+	if err != nil {
+		return nil, err
+	}
+
+	hold.CreatedAt = time.Unix(createdAt, 0).UTC()
+
+	return &hold, nil
+}
@@ -0,0 +1,360 @@
+package loan
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/qo/digital-library/internal/storage/querier"
+)
+
+// The statuses the loans are listed by.
+const (
+	// StatusActive loans are not returned yet, the overdue ones too
+	StatusActive = "active"
+	// StatusOverdue loans are not returned after their due time
+	StatusOverdue = "overdue"
+	// StatusReturned loans are the lending history
+	StatusReturned = "returned"
+)
+
+var (
+	// ErrNoCopies is returned if all copies of the book are lent.
+	ErrNoCopies = errors.New("all copies of the book are lent")
+	// ErrBorrowed is returned if the user already borrowed the book.
+	ErrBorrowed = errors.New("book is already borrowed by the user")
+	// ErrLimit is returned if the user borrowed as many books as allowed.
+	ErrLimit = errors.New("user borrowed as many books as allowed")
+	// ErrReturned is returned if the loan is already returned.
+	ErrReturned = errors.New("loan is already returned")
+)
+
+// Loan is a copy of the book lent to the user.
+type Loan struct {
+	Id           int       `json:"id"`
+	UserId       int       `json:"user_id"`
+	BookId       int       `json:"book_id"`
+	CheckedOutAt time.Time `json:"checked_out_at"`
+	DueAt        time.Time `json:"due_at"`
+	// ReturnedAt is not set until the copy is returned
+	ReturnedAt *time.Time `json:"returned_at,omitempty"`
+	// OverdueAt is the time the loan was found overdue,
+	// it is set once by the overdue job
+	OverdueAt *time.Time `json:"overdue_at,omitempty"`
+}
+
+func InitTable(db *sql.DB) error {
+	const errMsg = "can't init loans table"
+
+	stmt, err := db.Prepare(`
+    CREATE TABLE IF NOT EXISTS loans(
+      id INTEGER PRIMARY KEY,
+      user_id INTEGER,
+      book_id INTEGER,
+      checked_out_at INTEGER,
+      due_at INTEGER,
+      returned_at INTEGER,
+      overdue_at INTEGER,
+      FOREIGN KEY (user_id) REFERENCES users (id),
+      FOREIGN KEY (book_id) REFERENCES books (id)
+    );
+  `)
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	_, err = stmt.Exec()
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return nil
+}
+
+const columns = "id, user_id, book_id, checked_out_at, due_at, returned_at, overdue_at"
+
+func GetLoan(db querier.Querier, id int) (*Loan, error) {
+	const errMsg = "can't get loan"
+
+	stmt, err := db.Prepare(`
+    SELECT ` + columns + ` FROM loans
+    WHERE id = ?;
+  `)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	loan, err := scanLoan(stmt.QueryRow(id))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return loan, nil
+}
+
+// GetActiveLoan returns the not returned loan of the book to the user.
+func GetActiveLoan(db querier.Querier, userId, bookId int) (*Loan, error) {
+	const errMsg = "can't get active loan"
+
+	stmt, err := db.Prepare(`
+    SELECT ` + columns + ` FROM loans
+    WHERE user_id = ?
+    AND book_id = ?
+    AND returned_at IS NULL;
+  `)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	loan, err := scanLoan(stmt.QueryRow(userId, bookId))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return loan, nil
+}
+
+// GetLoans returns the loans with the status, all of them if it's empty,
+// the most recently checked out first.
+// If the user id is 0 the loans of all users are returned.
+func GetLoans(db *sql.DB, userId int, status string, now time.Time, limit, offset int) ([]Loan, error) {
+	const errMsg = "can't get loans"
+
+	var filter string
+	switch status {
+	case StatusActive:
+		filter = "AND returned_at IS NULL"
+	case StatusOverdue:
+		filter = "AND returned_at IS NULL AND due_at < ?"
+	case StatusReturned:
+		filter = "AND returned_at IS NOT NULL"
+	}
+
+	stmt, err := db.Prepare(fmt.Sprintf(`
+    SELECT `+columns+` FROM loans
+    WHERE (? = 0 OR user_id = ?)
+    %s
+    ORDER BY checked_out_at DESC, id DESC
+    LIMIT ? OFFSET ?;
+  `, filter))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	args := []any{userId, userId}
+	if status == StatusOverdue {
+		args = append(args, now.Unix())
+	}
+	args = append(args, limit, offset)
+
+	rows, err := stmt.Query(args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+	defer rows.Close()
+
+	loans := make([]Loan, 0)
+
+	for rows.Next() {
+		loan, err := scanLoan(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: can't scan loan: %s", errMsg, err)
+		}
+		loans = append(loans, *loan)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("%s: error occured while iterating over loans: %s", errMsg, err)
+	}
+
+	return loans, nil
+}
+
+// CountBookLoans returns the number of the lent copies of the book.
+func CountBookLoans(db querier.Querier, bookId int) (int, error) {
+	return count(db, "can't count book loans", `
+    SELECT COUNT(*) FROM loans
+    WHERE book_id = ?
+    AND returned_at IS NULL;
+  `, bookId)
+}
+
+// CountUserLoans returns the number of the books borrowed by the user.
+func CountUserLoans(db querier.Querier, userId int) (int, error) {
+	return count(db, "can't count user loans", `
+    SELECT COUNT(*) FROM loans
+    WHERE user_id = ?
+    AND returned_at IS NULL;
+  `, userId)
+}
+
+func count(db querier.Querier, errMsg, query string, id int) (int, error) {
+	stmt, err := db.Prepare(query)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	var n int
+
+	err = stmt.QueryRow(id).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return n, nil
+}
+
+// PostLoan inserts the loan, its id is assigned by the db and set on the loan.
+func PostLoan(db querier.Querier, loan *Loan) (int64, error) {
+	const errMsg = "can't post loan"
+
+	stmt, err := db.Prepare(`
+    INSERT INTO loans
+    (user_id, book_id, checked_out_at, due_at)
+    VALUES
+    (?, ?, ?, ?);
+  `)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	res, err := stmt.Exec(loan.UserId, loan.BookId, loan.CheckedOutAt.Unix(), loan.DueAt.Unix())
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	loan.Id = int(id)
+
+	return n, nil
+}
+
+// ReturnLoan marks the loan as returned.
+// It returns ErrReturned if the loan is already returned.
+func ReturnLoan(db querier.Querier, id int, returnedAt time.Time) (int64, error) {
+	const errMsg = "can't return loan"
+
+	stmt, err := db.Prepare(`
+    UPDATE loans
+    SET returned_at = ?
+    WHERE id = ?
+    AND returned_at IS NULL;
+  `)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	res, err := stmt.Exec(returnedAt.Unix(), id)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	if n == 0 {
+		return 0, fmt.Errorf("%s: %w", errMsg, ErrReturned)
+	}
+
+	return n, nil
+}
+
+// MarkOverdue sets the overdue time of the not returned loans
+// which became overdue since the last call and returns them.
+func MarkOverdue(tx *sql.Tx, now time.Time) ([]Loan, error) {
+	const errMsg = "can't mark overdue loans"
+
+	rows, err := tx.Query(`
+    SELECT `+columns+` FROM loans
+    WHERE returned_at IS NULL
+    AND overdue_at IS NULL
+    AND due_at < ?
+    ORDER BY id;
+  `, now.Unix())
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+	defer rows.Close()
+
+	loans := make([]Loan, 0)
+
+	for rows.Next() {
+		loan, err := scanLoan(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: can't scan loan: %s", errMsg, err)
+		}
+		loans = append(loans, *loan)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("%s: error occured while iterating over loans: %s", errMsg, err)
+	}
+
+	stmt, err := tx.Prepare(`
+    UPDATE loans
+    SET overdue_at = ?
+    WHERE id = ?;
+  `)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+	defer stmt.Close()
+
+	overdueAt := now.UTC().Truncate(time.Second)
+
+	for i := range loans {
+		_, err = stmt.Exec(overdueAt.Unix(), loans[i].Id)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", errMsg, err)
+		}
+		loans[i].OverdueAt = &overdueAt
+	}
+
+	return loans, nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanLoan(row scanner) (*Loan, error) {
+	var (
+		loan                  Loan
+		checkedOutAt, dueAt   int64
+		returnedAt, overdueAt sql.NullInt64
+	)
+
+	err := row.Scan(&loan.Id, &loan.UserId, &loan.BookId, &checkedOutAt, &dueAt, &returnedAt, &overdueAt)
+	if err != nil {
+		return nil, err
+	}
+
+	loan.CheckedOutAt = time.Unix(checkedOutAt, 0).UTC()
+	loan.DueAt = time.Unix(dueAt, 0).UTC()
+	loan.ReturnedAt = nullTime(returnedAt)
+	loan.OverdueAt = nullTime(overdueAt)
+
+	return &loan, nil
+}
+
+func nullTime(t sql.NullInt64) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	u := time.Unix(t.Int64, 0).UTC()
+	return &u
+}
@@ -0,0 +1,243 @@
+package notification
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/qo/digital-library/internal/storage/querier"
+)
+
+// Notification is a message in the in-app inbox of the user.
+type Notification struct {
+	Id     int    `json:"id"`
+	UserId int    `json:"user_id"`
+	Type   string `json:"type"`
+	// Subject and Body are rendered from the template of the type
+	Subject   string    `json:"subject"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+	// ReadAt is not set until the user reads the notification
+	ReadAt *time.Time `json:"read_at,omitempty"`
+}
+
+func InitTable(db *sql.DB) error {
+	const errMsg = "can't init notifications table"
+
+	stmt, err := db.Prepare(`
+    CREATE TABLE IF NOT EXISTS notifications(
+      id INTEGER PRIMARY KEY,
+      user_id INTEGER,
+      type TEXT,
+      subject TEXT,
+      body TEXT,
+      created_at INTEGER,
+      read_at INTEGER,
+      FOREIGN KEY (user_id) REFERENCES users (id)
+    );
+  `)
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	_, err = stmt.Exec()
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return nil
+}
+
+// GetNotifications returns the notifications of the user, the newest first.
+// With unreadOnly the read ones are skipped.
+func GetNotifications(db *sql.DB, userId int, unreadOnly bool, limit, offset int) ([]Notification, error) {
+	const errMsg = "can't get notifications"
+
+	stmt, err := db.Prepare(`
+    SELECT id, user_id, type, subject, body, created_at, read_at FROM notifications
+    WHERE user_id = ?
+    AND (NOT ? OR read_at IS NULL)
+    ORDER BY created_at DESC, id DESC
+    LIMIT ? OFFSET ?;
+  `)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	rows, err := stmt.Query(userId, unreadOnly, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+	defer rows.Close()
+
+	notifications := make([]Notification, 0)
+
+	for rows.Next() {
+		var (
+			n         Notification
+			createdAt int64
+			readAt    sql.NullInt64
+		)
+		err := rows.Scan(&n.Id, &n.UserId, &n.Type, &n.Subject, &n.Body, &createdAt, &readAt)
+		if err != nil {
+			return nil, fmt.Errorf("%s: can't scan notification: %s", errMsg, err)
+		}
+		n.CreatedAt = time.Unix(createdAt, 0).UTC()
+		if readAt.Valid {
+			t := time.Unix(readAt.Int64, 0).UTC()
+			n.ReadAt = &t
+		}
+		notifications = append(notifications, n)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("%s: error occured while iterating over notifications: %s", errMsg, err)
+	}
+
+	return notifications, nil
+}
+
+// CountUnread returns the number of the unread notifications of the user.
+func CountUnread(db *sql.DB, userId int) (int, error) {
+	const errMsg = "can't count unread notifications"
+
+	stmt, err := db.Prepare(`
+    SELECT COUNT(*) FROM notifications
+    WHERE user_id = ?
+    AND read_at IS NULL;
+  `)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	var n int
+
+	err = stmt.QueryRow(userId).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return n, nil
+}
+
+// PostNotification inserts the notification, its id and creation time are set on it.
+func PostNotification(db querier.Querier, n *Notification) (int64, error) {
+	const errMsg = "can't post notification"
+
+	stmt, err := db.Prepare(`
+    INSERT INTO notifications
+    (user_id, type, subject, body, created_at)
+    VALUES
+    (?, ?, ?, ?, ?);
+  `)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+
+	res, err := stmt.Exec(n.UserId, n.Type, n.Subject, n.Body, now.Unix())
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	n.Id = int(id)
+	n.CreatedAt = now
+
+	return rows, nil
+}
+
+// MarkRead marks the notification of the user as read,
+// the read time of the already read one is kept.
+// It returns sql.ErrNoRows if the user has no such notification.
+func MarkRead(db *sql.DB, userId, id int) (int64, error) {
+	const errMsg = "can't mark notification read"
+
+	stmt, err := db.Prepare(`
+    UPDATE notifications
+    SET read_at = COALESCE(read_at, ?)
+    WHERE user_id = ?
+    AND id = ?;
+  `)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	res, err := stmt.Exec(time.Now().Unix(), userId, id)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+	if n == 0 {
+		return 0, fmt.Errorf("%s: %w", errMsg, sql.ErrNoRows)
+	}
+
+	return n, nil
+}
+
+// MarkAllRead marks all unread notifications of the user as read.
+func MarkAllRead(db *sql.DB, userId int) (int64, error) {
+	const errMsg = "can't mark all notifications read"
+
+	stmt, err := db.Prepare(`
+    UPDATE notifications
+    SET read_at = ?
+    WHERE user_id = ?
+    AND read_at IS NULL;
+  `)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	res, err := stmt.Exec(time.Now().Unix(), userId)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return n, nil
+}
+
+func DeleteNotification(db *sql.DB, userId, id int) (int64, error) {
+	const errMsg = "can't delete notification"
+
+	stmt, err := db.Prepare(`
+    DELETE FROM notifications
+    WHERE user_id = ?
+    AND id = ?;
+  `)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	res, err := stmt.Exec(userId, id)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return n, nil
+}
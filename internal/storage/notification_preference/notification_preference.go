@@ -0,0 +1,160 @@
+package notification_preference
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/qo/digital-library/internal/storage/querier"
+)
+
+// NotificationPreference tells how the user wants to be notified of the type.
+type NotificationPreference struct {
+	UserId int    `json:"user_id"`
+	Type   string `json:"type"`
+	// InApp notifications are put in the inbox
+	InApp bool `json:"in_app"`
+	// Email notifications are sent if the user has an email address
+	Email bool `json:"email"`
+}
+
+// Default is the preference of the type the user didn't set:
+// the user is notified both ways.
+func Default(userId int, typ string) NotificationPreference {
+	return NotificationPreference{
+		UserId: userId,
+		Type:   typ,
+		InApp:  true,
+		Email:  true,
+	}
+}
+
+func InitTable(db *sql.DB) error {
+	const errMsg = "can't init notification preferences table"
+
+	stmt, err := db.Prepare(`
+    CREATE TABLE IF NOT EXISTS notification_preferences(
+      user_id INTEGER,
+      type TEXT,
+      in_app INTEGER,
+      email INTEGER,
+      FOREIGN KEY (user_id) REFERENCES users (id),
+      PRIMARY KEY (user_id, type)
+    );
+  `)
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	_, err = stmt.Exec()
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return nil
+}
+
+// GetNotificationPreference returns the preference of the type,
+// the default one if the user didn't set it.
+func GetNotificationPreference(db *sql.DB, userId int, typ string) (*NotificationPreference, error) {
+	const errMsg = "can't get notification preference"
+
+	stmt, err := db.Prepare(`
+    SELECT user_id, type, in_app, email FROM notification_preferences
+    WHERE user_id = ?
+    AND type = ?;
+  `)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	var p NotificationPreference
+
+	err = stmt.QueryRow(userId, typ).Scan(&p.UserId, &p.Type, &p.InApp, &p.Email)
+	if errors.Is(err, sql.ErrNoRows) {
+		p = Default(userId, typ)
+	} else if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return &p, nil
+}
+
+// GetNotificationPreferences returns the preferences of the types,
+// the default ones for the types the user didn't set.
+func GetNotificationPreferences(db *sql.DB, userId int, types []string) ([]NotificationPreference, error) {
+	const errMsg = "can't get notification preferences"
+
+	stmt, err := db.Prepare(`
+    SELECT user_id, type, in_app, email FROM notification_preferences
+    WHERE user_id = ?;
+  `)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	rows, err := stmt.Query(userId)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+	defer rows.Close()
+
+	set := make(map[string]NotificationPreference)
+
+	for rows.Next() {
+		var p NotificationPreference
+		err := rows.Scan(&p.UserId, &p.Type, &p.InApp, &p.Email)
+		if err != nil {
+			return nil, fmt.Errorf("%s: can't scan notification preference: %s", errMsg, err)
+		}
+		set[p.Type] = p
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("%s: error occured while iterating over notification preferences: %s", errMsg, err)
+	}
+
+	preferences := make([]NotificationPreference, 0, len(types))
+
+	for _, typ := range types {
+		p, ok := set[typ]
+		if !ok {
+			p = Default(userId, typ)
+		}
+		preferences = append(preferences, p)
+	}
+
+	return preferences, nil
+}
+
+// PutNotificationPreference creates or replaces the preference of the type.
+func PutNotificationPreference(db querier.Querier, p NotificationPreference) (int64, error) {
+	const errMsg = "can't put notification preference"
+
+	_, err := db.Exec(`
+    DELETE FROM notification_preferences
+    WHERE user_id = ?
+    AND type = ?;
+  `, p.UserId, p.Type)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	res, err := db.Exec(`
+    INSERT INTO notification_preferences
+    (user_id, type, in_app, email)
+    VALUES
+    (?, ?, ?, ?);
+  `, p.UserId, p.Type, p.InApp, p.Email)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return n, nil
+}
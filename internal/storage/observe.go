@@ -0,0 +1,44 @@
+package storage
+
+import (
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/qo/digital-library/internal/storage")
+
+// observation is a query being observed.
+type observation struct {
+	observer QueryObserver
+	query    string
+	span     trace.Span
+	start    time.Time
+}
+
+// rowsAffected records the number of the rows affected by the query.
+func (o *observation) rowsAffected(n int64) {
+	o.span.SetAttributes(attribute.Int64("db.rows_affected", n))
+}
+
+// written records the number of the rows affected by the write if it succeeded,
+// the error otherwise, and returns the error of the write.
+func (o *observation) written(n int64, err error) error {
+	if err != nil {
+		o.span.RecordError(err)
+		o.span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	o.rowsAffected(n)
+	return nil
+}
+
+func (o *observation) end() {
+	o.span.End()
+	if o.observer != nil {
+		o.observer.ObserveQuery(o.query, time.Since(o.start))
+	}
+}
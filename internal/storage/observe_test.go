@@ -0,0 +1,86 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/qo/digital-library/internal/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestWriteSpans(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	st := newTestStorage(t, 2)
+	ctx := context.Background()
+	options := config.LoansOptions{Period: time.Hour, Limit: 5}
+
+	tests := []struct {
+		name      string
+		write     func() error
+		wantRows  int64
+		wantError bool
+	}{
+		{"set copies", func() error {
+			_, err := st.SetBookCopies(ctx, 1, 1, options)
+			return err
+		}, 1, false},
+		{"checkout", func() error {
+			_, err := st.CheckoutBook(ctx, 1, 1, options)
+			return err
+		}, 1, false},
+		{"checkout without copies", func() error {
+			_, err := st.CheckoutBook(ctx, 2, 1, options)
+			return err
+		}, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exporter.Reset()
+
+			err := tt.write()
+			if (err != nil) != tt.wantError {
+				t.Fatalf("write error = %v, want error %v", err, tt.wantError)
+			}
+
+			spans := exporter.GetSpans()
+			if len(spans) != 1 {
+				t.Fatalf("got %d spans, want 1", len(spans))
+			}
+			span := spans[0]
+
+			if tt.wantError {
+				if span.Status.Code != codes.Error {
+					t.Errorf("status = %v, want %v", span.Status.Code, codes.Error)
+				}
+				if len(span.Events) == 0 {
+					t.Errorf("error isn't recorded")
+				}
+				return
+			}
+
+			var rows *attribute.Value
+			for _, a := range span.Attributes {
+				if a.Key == "db.rows_affected" {
+					rows = &a.Value
+				}
+			}
+			if rows == nil {
+				t.Fatalf("db.rows_affected isn't set")
+			}
+			if rows.AsInt64() != tt.wantRows {
+				t.Errorf("db.rows_affected = %d, want %d", rows.AsInt64(), tt.wantRows)
+			}
+		})
+	}
+}
@@ -0,0 +1,12 @@
+package querier
+
+import "database/sql"
+
+// Querier is implemented by both *sql.DB and *sql.Tx,
+// so storage functions accepting it can be run inside a transaction.
+type Querier interface {
+	Prepare(query string) (*sql.Stmt, error)
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
@@ -0,0 +1,218 @@
+package storage
+
+import (
+	"context"
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/qo/digital-library/internal/config"
+	"github.com/qo/digital-library/internal/storage/book"
+	"github.com/qo/digital-library/internal/storage/book_review"
+	"github.com/qo/digital-library/internal/storage/user"
+)
+
+// newTestStorage returns the storage of a new sqlite db with the users and the books of the ids from 1 to n.
+func newTestStorage(t *testing.T, n int) *Storage {
+	t.Helper()
+
+	st, err := Init(config.StorageOptions{
+		Db:            sqliteDb,
+		SQLiteOptions: config.SQLiteOptions{Path: filepath.Join(t.TempDir(), "storage.db"), ForeignKeys: true},
+	})
+	if err != nil {
+		t.Fatalf("Init() error: %s", err)
+	}
+	t.Cleanup(func() { st.db.Close() })
+
+	ctx := context.Background()
+
+	for id := 1; id <= n; id++ {
+		err = st.PostUser(ctx, &user.User{Id: id, FirstName: "First", SecondName: "Second", Role: user.RoleUser})
+		if err != nil {
+			t.Fatalf("PostUser() error: %s", err)
+		}
+		err = st.PostBook(ctx, &book.Book{Id: id, Title: "Title"})
+		if err != nil {
+			t.Fatalf("PostBook() error: %s", err)
+		}
+	}
+
+	return st
+}
+
+// reviewOp is a change of the review of the user of the book 1.
+type reviewOp struct {
+	action string
+	userId int
+	rating int
+}
+
+func TestBookRatingAggregates(t *testing.T) {
+	tests := []struct {
+		name        string
+		ops         []reviewOp
+		wantAverage float64
+		wantCount   int
+	}{
+		{"no reviews", nil, 0, 0},
+		{"one review", []reviewOp{{"post", 1, 4}}, 4, 1},
+		{"two reviews", []reviewOp{{"post", 1, 4}, {"post", 2, 1}}, 2.5, 2},
+		{"updated review", []reviewOp{{"post", 1, 4}, {"put", 1, 2}}, 2, 1},
+		{"deleted review", []reviewOp{{"post", 1, 4}, {"post", 2, 2}, {"delete", 1, 0}}, 2, 1},
+		{"deleted twice", []reviewOp{{"post", 1, 4}, {"delete", 1, 0}, {"delete", 1, 0}}, 0, 0},
+		{"restored review", []reviewOp{{"post", 1, 5}, {"post", 2, 3}, {"delete", 1, 0}, {"restore", 1, 0}}, 4, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := newTestStorage(t, 2)
+			ctx := context.Background()
+
+			for _, op := range tt.ops {
+				r := &book_review.BookReview{UserId: op.userId, BookId: 1, Rating: op.rating}
+
+				var err error
+				switch op.action {
+				case "post":
+					err = st.PostBookReview(ctx, r)
+				case "put":
+					err = st.PutBookReview(ctx, r)
+				case "delete":
+					err = st.DeleteBookReview(ctx, op.userId, 1)
+				case "restore":
+					err = st.RestoreBookReview(ctx, op.userId, 1)
+				}
+				if err != nil {
+					t.Fatalf("%s review of user %d: %s", op.action, op.userId, err)
+				}
+			}
+
+			b, err := st.GetBook(ctx, 1, false)
+			if err != nil {
+				t.Fatalf("GetBook() error: %s", err)
+			}
+			if b.AverageRating != tt.wantAverage || b.ReviewCount != tt.wantCount {
+				t.Errorf("rating = %v of %d reviews, want %v of %d", b.AverageRating, b.ReviewCount, tt.wantAverage, tt.wantCount)
+			}
+
+			// the incremental aggregates match the ones computed from scratch
+			err = book.RecountRatings(st.db)
+			if err != nil {
+				t.Fatalf("RecountRatings() error: %s", err)
+			}
+			recounted, err := st.GetBook(ctx, 1, false)
+			if err != nil {
+				t.Fatalf("GetBook() error: %s", err)
+			}
+			if recounted.AverageRating != b.AverageRating || recounted.ReviewCount != b.ReviewCount {
+				t.Errorf("recounted rating = %v of %d reviews, incremental %v of %d",
+					recounted.AverageRating, recounted.ReviewCount, b.AverageRating, b.ReviewCount)
+			}
+		})
+	}
+}
+
+func TestGetTopBooks(t *testing.T) {
+	// ratings[i] are the ratings of the book i+1 by the users 1, 2, ...
+	tests := []struct {
+		name    string
+		ratings [][]int
+		// old are the ratings updated before the period, by book and by user
+		old       map[[2]int]bool
+		period    bool
+		wantIds   []int
+		wantScore []float64
+		wantCount []int
+	}{
+		{
+			name:    "no reviews",
+			ratings: [][]int{nil, nil},
+		},
+		{
+			// the mean is 34/11, a single 5 is pulled down to it more than four 5s and a 4
+			name:      "many good reviews outrank a single best one",
+			ratings:   [][]int{{5}, {5, 5, 5, 5, 4}, {1, 1, 1, 1, 1}},
+			wantIds:   []int{2, 1, 3},
+			wantScore: []float64{(5*34.0/11 + 24) / 10, (5*34.0/11 + 5) / 6, (5*34.0/11 + 5) / 10},
+			wantCount: []int{5, 1, 5},
+		},
+		{
+			name:      "same score is ranked by count",
+			ratings:   [][]int{{3}, {3, 3}, nil},
+			wantIds:   []int{2, 1},
+			wantScore: []float64{3, 3},
+			wantCount: []int{2, 1},
+		},
+		{
+			// in the period only the 1 of the book 1 and the 5 of the book 2 are counted, the mean is 3
+			name:      "period",
+			ratings:   [][]int{{5, 1}, {1, 5}},
+			old:       map[[2]int]bool{{1, 1}: true, {2, 1}: true},
+			period:    true,
+			wantIds:   []int{2, 1},
+			wantScore: []float64{20.0 / 6, 16.0 / 6},
+			wantCount: []int{1, 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := 0
+			for _, rs := range tt.ratings {
+				users = max(users, len(rs))
+			}
+			st := newTestStorage(t, max(users, len(tt.ratings)))
+			ctx := context.Background()
+
+			now := time.Now()
+			since := now.Add(-time.Hour)
+
+			for i, rs := range tt.ratings {
+				for j, rating := range rs {
+					bookId, userId := i+1, j+1
+					err := st.PostBookReview(ctx, &book_review.BookReview{UserId: userId, BookId: bookId, Rating: rating})
+					if err != nil {
+						t.Fatalf("PostBookReview() error: %s", err)
+					}
+					if tt.old[[2]int{bookId, userId}] {
+						_, err = st.db.Exec("UPDATE book_reviews SET updated_at = ? WHERE book_id = ? AND user_id = ?",
+							since.Add(-time.Hour).Unix(), bookId, userId)
+						if err != nil {
+							t.Fatalf("can't age review: %s", err)
+						}
+					}
+				}
+			}
+
+			var sincePtr *time.Time
+			if tt.period {
+				sincePtr = &since
+			}
+
+			books, err := st.GetTopBooks(ctx, sincePtr, 10, 0)
+			if err != nil {
+				t.Fatalf("GetTopBooks() error: %s", err)
+			}
+
+			if len(books) != len(tt.wantIds) {
+				t.Fatalf("GetTopBooks() returned %d books, want %d", len(books), len(tt.wantIds))
+			}
+			for i, b := range books {
+				if b.Id != tt.wantIds[i] {
+					t.Errorf("book %d is %d, want %d", i, b.Id, tt.wantIds[i])
+				}
+				if math.Abs(b.Score-tt.wantScore[i]) > 1e-9 {
+					t.Errorf("score of book %d = %v, want %v", b.Id, b.Score, tt.wantScore[i])
+				}
+				if b.PeriodReviewCount != tt.wantCount[i] {
+					t.Errorf("period review count of book %d = %d, want %d", b.Id, b.PeriodReviewCount, tt.wantCount[i])
+				}
+				if b.ReviewCount != len(tt.ratings[b.Id-1]) {
+					t.Errorf("review count of book %d = %d, want all %d reviews", b.Id, b.ReviewCount, len(tt.ratings[b.Id-1]))
+				}
+			}
+		})
+	}
+}
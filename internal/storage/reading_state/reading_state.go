@@ -0,0 +1,227 @@
+package reading_state
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/qo/digital-library/internal/storage/querier"
+)
+
+// The statuses of the reading states.
+const (
+	StatusWantToRead = "want-to-read"
+	StatusReading    = "reading"
+	StatusFinished   = "finished"
+)
+
+// ReadingState is where the user stopped reading the book.
+type ReadingState struct {
+	UserId int    `json:"user_id"`
+	BookId int    `json:"book_id"`
+	Status string `json:"status"`
+	// Page is the current page, pages are numbered from 1, 0 means the book wasn't opened
+	Page int `json:"page"`
+	// Percent is the read part of the book from 0 to 100
+	Percent    float64    `json:"percent"`
+	LastReadAt *time.Time `json:"last_read_at,omitempty"`
+}
+
+func InitTable(db *sql.DB) error {
+	const errMsg = "can't init reading states table"
+
+	stmt, err := db.Prepare(`
+    CREATE TABLE IF NOT EXISTS reading_states(
+      user_id INTEGER,
+      book_id INTEGER,
+      status TEXT,
+      page INTEGER,
+      percent REAL,
+      last_read_at INTEGER,
+      FOREIGN KEY (user_id) REFERENCES users (id),
+      FOREIGN KEY (book_id) REFERENCES books (id),
+      PRIMARY KEY (user_id, book_id)
+    );
+  `)
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	_, err = stmt.Exec()
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return nil
+}
+
+func GetReadingState(db querier.Querier, userId, bookId int) (*ReadingState, error) {
+	const errMsg = "can't get reading state"
+
+	stmt, err := db.Prepare(`
+    SELECT user_id, book_id, status, page, percent, last_read_at FROM reading_states
+    WHERE user_id = ?
+    AND book_id = ?
+  `)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	row := stmt.QueryRow(userId, bookId)
+
+	var (
+		state      ReadingState
+		lastReadAt sql.NullInt64
+	)
+
+	err = row.Scan(&state.UserId, &state.BookId, &state.Status, &state.Page, &state.Percent, &lastReadAt)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	state.LastReadAt = timeOf(lastReadAt)
+
+	return &state, nil
+}
+
+// GetReadingStates returns the reading states of the not deleted books of the user,
+// the most recently read first. If the status is not empty only the states with it are returned.
+func GetReadingStates(db *sql.DB, userId int, status string) ([]ReadingState, error) {
+	const errMsg = "can't get reading states"
+
+	stmt, err := db.Prepare(`
+    SELECT rs.user_id, rs.book_id, rs.status, rs.page, rs.percent, rs.last_read_at FROM reading_states AS rs
+    JOIN books AS b
+    ON rs.book_id = b.id
+    WHERE rs.user_id = ?
+    AND (? = '' OR rs.status = ?)
+    AND b.deleted_at IS NULL
+    ORDER BY rs.last_read_at DESC, rs.book_id;
+  `)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	rows, err := stmt.Query(userId, status, status)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+	defer rows.Close()
+
+	states := make([]ReadingState, 0)
+
+	for rows.Next() {
+		var (
+			state      ReadingState
+			lastReadAt sql.NullInt64
+		)
+		err := rows.Scan(&state.UserId, &state.BookId, &state.Status, &state.Page, &state.Percent, &lastReadAt)
+		if err != nil {
+			return nil, fmt.Errorf("%s: can't scan reading state: %s", errMsg, err)
+		}
+		state.LastReadAt = timeOf(lastReadAt)
+		states = append(states, state)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("%s: error occured while iterating over reading states: %s", errMsg, err)
+	}
+
+	return states, nil
+}
+
+// PostReadingState inserts the reading state, the last read time is set to now.
+func PostReadingState(db querier.Querier, state *ReadingState) (int64, error) {
+	const errMsg = "can't post reading state"
+
+	stmt, err := db.Prepare(`
+    INSERT INTO reading_states
+    (user_id, book_id, status, page, percent, last_read_at)
+    VALUES
+    (?, ?, ?, ?, ?, ?);
+  `)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+
+	res, err := stmt.Exec(state.UserId, state.BookId, state.Status, state.Page, state.Percent, now.Unix())
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	state.LastReadAt = &now
+
+	return n, nil
+}
+
+// PutReadingState updates the reading state, the last read time is set to now.
+func PutReadingState(db querier.Querier, state *ReadingState) (int64, error) {
+	const errMsg = "can't put reading state"
+
+	stmt, err := db.Prepare(`
+    UPDATE reading_states
+    SET status = ?, page = ?, percent = ?, last_read_at = ?
+    WHERE user_id = ?
+    AND book_id = ?;
+  `)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+
+	res, err := stmt.Exec(state.Status, state.Page, state.Percent, now.Unix(), state.UserId, state.BookId)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	state.LastReadAt = &now
+
+	return n, nil
+}
+
+func DeleteReadingState(db *sql.DB, userId, bookId int) (int64, error) {
+	const errMsg = "can't delete reading state"
+
+	stmt, err := db.Prepare(`
+    DELETE FROM reading_states
+    WHERE user_id = ?
+    AND book_id = ?
+  `)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	res, err := stmt.Exec(userId, bookId)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return n, nil
+}
+
+func timeOf(t sql.NullInt64) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	tm := time.Unix(t.Int64, 0).UTC()
+	return &tm
+}
@@ -0,0 +1,284 @@
+package recommendation
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/qo/digital-library/internal/storage/book"
+)
+
+// Recommendation is the book recommended to the user.
+type Recommendation struct {
+	UserId int     `json:"user_id"`
+	BookId int     `json:"book_id"`
+	Score  float64 `json:"score"`
+	// Reason is the signal which contributed the most to the score
+	Reason string `json:"reason"`
+}
+
+// RecommendedBook is the recommended book along with the score and the reason of the recommendation.
+type RecommendedBook struct {
+	book.Book
+	Score  float64 `json:"score"`
+	Reason string  `json:"reason"`
+}
+
+// Signals are the interactions of the users with the not deleted books
+// the recommendations are computed from.
+type Signals struct {
+	// Ratings are the ratings of the not deleted reviews by user id and book id
+	Ratings map[int]map[int]int
+	// Favorites are the favorite books by user id
+	Favorites map[int][]int
+	// FavoriteAuthors are the favorite not deleted authors by user id
+	FavoriteAuthors map[int][]int
+	// Authors are the not deleted authors by book id
+	Authors map[int][]int
+	// Publishers are the publishers by book id, every not deleted book is there
+	Publishers map[int]string
+}
+
+func InitTable(db *sql.DB) error {
+	const errMsg = "can't init recommendations table"
+
+	stmt, err := db.Prepare(`
+    CREATE TABLE IF NOT EXISTS recommendations(
+      user_id INTEGER,
+      book_id INTEGER,
+      score REAL,
+      reason TEXT,
+      computed_at INTEGER,
+      FOREIGN KEY (user_id) REFERENCES users (id),
+      FOREIGN KEY (book_id) REFERENCES books (id),
+      PRIMARY KEY (user_id, book_id)
+    );
+  `)
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	_, err = stmt.Exec()
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return nil
+}
+
+// GetRecommendations returns the recommended books of the user, the best first.
+// The books deleted, favorited or reviewed by the user since the recommendations
+// were computed are skipped.
+func GetRecommendations(db *sql.DB, userId, limit, offset int) ([]RecommendedBook, error) {
+	const errMsg = "can't get recommendations"
+
+	stmt, err := db.Prepare(`
+    SELECT b.id, b.isbn, b.title, b.year, b.publisher, b.rating_sum, b.rating_count, b.copies, r.score, r.reason
+    FROM recommendations AS r
+    JOIN books AS b
+    ON r.book_id = b.id
+    WHERE r.user_id = ?
+    AND b.deleted_at IS NULL
+    AND b.id NOT IN (
+      SELECT book_id FROM favorite_books
+      WHERE user_id = ?
+    )
+    AND b.id NOT IN (
+      SELECT book_id FROM book_reviews
+      WHERE user_id = ?
+      AND deleted_at IS NULL
+    )
+    ORDER BY r.score DESC, b.id
+    LIMIT ? OFFSET ?;
+  `)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	rows, err := stmt.Query(userId, userId, userId, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+	defer rows.Close()
+
+	books := make([]RecommendedBook, 0)
+
+	for rows.Next() {
+		var (
+			b                      RecommendedBook
+			ratingSum, ratingCount int
+		)
+		err := rows.Scan(&b.Id, &b.Isbn, &b.Title, &b.Year, &b.Publisher, &ratingSum, &ratingCount, &b.Copies, &b.Score, &b.Reason)
+		if err != nil {
+			return nil, fmt.Errorf("%s: can't scan recommended book: %s", errMsg, err)
+		}
+		b.SetRating(ratingSum, ratingCount)
+		books = append(books, b)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("%s: error occured while iterating over recommended books: %s", errMsg, err)
+	}
+
+	return books, nil
+}
+
+// PutRecommendations replaces the recommendations of all users.
+func PutRecommendations(tx *sql.Tx, recommendations []Recommendation, computedAt time.Time) error {
+	const errMsg = "can't put recommendations"
+
+	_, err := tx.Exec(`
+    DELETE FROM recommendations;
+  `)
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	stmt, err := tx.Prepare(`
+    INSERT INTO recommendations
+    (user_id, book_id, score, reason, computed_at)
+    VALUES
+    (?, ?, ?, ?, ?);
+  `)
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+	defer stmt.Close()
+
+	for _, r := range recommendations {
+		_, err = stmt.Exec(r.UserId, r.BookId, r.Score, r.Reason, computedAt.Unix())
+		if err != nil {
+			return fmt.Errorf("%s: %w", errMsg, err)
+		}
+	}
+
+	return nil
+}
+
+// GetSignals reads the interactions of all users with the not deleted books.
+func GetSignals(db *sql.DB) (*Signals, error) {
+	const errMsg = "can't get recommendation signals"
+
+	s := Signals{
+		Ratings:         make(map[int]map[int]int),
+		Favorites:       make(map[int][]int),
+		FavoriteAuthors: make(map[int][]int),
+		Authors:         make(map[int][]int),
+		Publishers:      make(map[int]string),
+	}
+
+	rows, err := db.Query(`
+    SELECT id, COALESCE(publisher, '') FROM books
+    WHERE deleted_at IS NULL;
+  `)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+	err = scanRows(rows, func() error {
+		var (
+			id        int
+			publisher string
+		)
+		err := rows.Scan(&id, &publisher)
+		if err != nil {
+			return err
+		}
+		s.Publishers[id] = publisher
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: can't get books: %w", errMsg, err)
+	}
+
+	rows, err = db.Query(`
+    SELECT r.user_id, r.book_id, r.rating FROM book_reviews AS r
+    JOIN books AS b
+    ON r.book_id = b.id
+    WHERE r.deleted_at IS NULL
+    AND b.deleted_at IS NULL;
+  `)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+	err = scanRows(rows, func() error {
+		var userId, bookId, rating int
+		err := rows.Scan(&userId, &bookId, &rating)
+		if err != nil {
+			return err
+		}
+		if s.Ratings[userId] == nil {
+			s.Ratings[userId] = make(map[int]int)
+		}
+		s.Ratings[userId][bookId] = rating
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: can't get ratings: %w", errMsg, err)
+	}
+
+	for _, pairs := range []struct {
+		query string
+		to    map[int][]int
+	}{
+		{`
+    SELECT fb.user_id, fb.book_id FROM favorite_books AS fb
+    JOIN books AS b
+    ON fb.book_id = b.id
+    WHERE b.deleted_at IS NULL;
+  `, s.Favorites},
+		{`
+    SELECT fa.user_id, fa.author_id FROM favorite_authors AS fa
+    JOIN authors AS a
+    ON fa.author_id = a.id
+    WHERE a.deleted_at IS NULL;
+  `, s.FavoriteAuthors},
+		{`
+    SELECT ash.book_id, ash.author_id FROM authorships AS ash
+    JOIN books AS b
+    ON ash.book_id = b.id
+    JOIN authors AS a
+    ON ash.author_id = a.id
+    WHERE b.deleted_at IS NULL
+    AND a.deleted_at IS NULL;
+  `, s.Authors},
+	} {
+		rows, err := db.Query(pairs.query)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", errMsg, err)
+		}
+		err = scanRows(rows, func() error {
+			var from, to int
+			err := rows.Scan(&from, &to)
+			if err != nil {
+				return err
+			}
+			pairs.to[from] = append(pairs.to[from], to)
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", errMsg, err)
+		}
+	}
+
+	return &s, nil
+}
+
+// scanRows calls scan for every row and closes the rows.
+func scanRows(rows *sql.Rows, scan func() error) error {
+	defer rows.Close()
+
+	for rows.Next() {
+		err := scan()
+		if err != nil {
+			return fmt.Errorf("can't scan row: %s", err)
+		}
+	}
+
+	err := rows.Err()
+	if err != nil {
+		return fmt.Errorf("error occured while iterating over rows: %s", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,251 @@
+package review_report
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/qo/digital-library/internal/storage/book_review"
+)
+
+// ReviewReport is a complaint about a book review
+// waiting for a moderator to resolve it.
+type ReviewReport struct {
+	Id         int        `json:"id"`
+	ReporterId int        `json:"reporter_id"`
+	UserId     int        `json:"user_id"`
+	BookId     int        `json:"book_id"`
+	Reason     string     `json:"reason"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+}
+
+// QueueEntry is an open report with the reported review.
+type QueueEntry struct {
+	ReviewReport
+	Review    book_review.BookReview
+	BookTitle string
+}
+
+func InitTable(db *sql.DB) error {
+	const errMsg = "can't init review reports table"
+
+	stmt, err := db.Prepare(`
+    CREATE TABLE IF NOT EXISTS review_reports(
+      id INTEGER PRIMARY KEY,
+      reporter_id INTEGER,
+      user_id INTEGER,
+      book_id INTEGER,
+      reason TEXT,
+      created_at INTEGER,
+      resolved_at INTEGER,
+      FOREIGN KEY (reporter_id) REFERENCES users (id)
+    );
+  `)
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	_, err = stmt.Exec()
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return nil
+}
+
+func PostReviewReport(db *sql.DB, report *ReviewReport) (int64, error) {
+	const errMsg = "can't post review report"
+
+	stmt, err := db.Prepare(`
+    INSERT INTO review_reports
+    (reporter_id, user_id, book_id, reason, created_at)
+    VALUES
+    (?, ?, ?, ?, ?);
+  `)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	res, err := stmt.Exec(report.ReporterId, report.UserId, report.BookId, report.Reason, report.CreatedAt.Unix())
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+	report.Id = int(id)
+
+	return n, nil
+}
+
+func GetReviewReport(db *sql.DB, id int) (*ReviewReport, error) {
+	const errMsg = "can't get review report"
+
+	stmt, err := db.Prepare(`
+    SELECT id, reporter_id, user_id, book_id, reason, created_at, resolved_at FROM review_reports
+    WHERE id = ?;
+  `)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	var (
+		report     ReviewReport
+		createdAt  int64
+		resolvedAt sql.NullInt64
+	)
+
+	err = stmt.QueryRow(id).Scan(&report.Id, &report.ReporterId, &report.UserId, &report.BookId,
+		&report.Reason, &createdAt, &resolvedAt)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	report.CreatedAt = time.Unix(createdAt, 0).UTC()
+	if resolvedAt.Valid {
+		t := time.Unix(resolvedAt.Int64, 0).UTC()
+		report.ResolvedAt = &t
+	}
+
+	return &report, nil
+}
+
+// GetQueue returns the open reports of the not deleted reviews, oldest first.
+func GetQueue(db *sql.DB) ([]QueueEntry, error) {
+	const errMsg = "can't get review reports queue"
+
+	stmt, err := db.Prepare(`
+    SELECT rr.id, rr.reporter_id, rr.user_id, rr.book_id, rr.reason, rr.created_at,
+      r.rating, COALESCE(r.body, ''), b.title
+    FROM review_reports AS rr
+    JOIN book_reviews AS r
+    ON r.user_id = rr.user_id AND r.book_id = rr.book_id
+    JOIN books AS b
+    ON b.id = rr.book_id
+    WHERE rr.resolved_at IS NULL
+    AND r.deleted_at IS NULL
+    ORDER BY rr.created_at, rr.id;
+  `)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	rows, err := stmt.Query()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+	defer rows.Close()
+
+	queue := make([]QueueEntry, 0)
+
+	for rows.Next() {
+		var (
+			entry     QueueEntry
+			createdAt int64
+		)
+		err := rows.Scan(&entry.Id, &entry.ReporterId, &entry.UserId, &entry.BookId, &entry.Reason, &createdAt,
+			&entry.Review.Rating, &entry.Review.Body, &entry.BookTitle)
+		if err != nil {
+			return nil, fmt.Errorf("%s: can't scan review report: %s", errMsg, err)
+		}
+		entry.CreatedAt = time.Unix(createdAt, 0).UTC()
+		entry.Review.UserId = entry.UserId
+		entry.Review.BookId = entry.BookId
+		queue = append(queue, entry)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("%s: error occured while iterating over review reports: %s", errMsg, err)
+	}
+
+	return queue, nil
+}
+
+// ResolveReviewReport resolves the report.
+func ResolveReviewReport(db *sql.DB, id int) (int64, error) {
+	const errMsg = "can't resolve review report"
+
+	stmt, err := db.Prepare(`
+    UPDATE review_reports
+    SET resolved_at = ?
+    WHERE id = ?
+    AND resolved_at IS NULL;
+  `)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	res, err := stmt.Exec(time.Now().Unix(), id)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return n, nil
+}
+
+// ResolveReviewReports resolves all the open reports of the review.
+func ResolveReviewReports(db *sql.DB, userId, bookId int) (int64, error) {
+	const errMsg = "can't resolve review reports"
+
+	stmt, err := db.Prepare(`
+    UPDATE review_reports
+    SET resolved_at = ?
+    WHERE user_id = ?
+    AND book_id = ?
+    AND resolved_at IS NULL;
+  `)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	res, err := stmt.Exec(time.Now().Unix(), userId, bookId)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return n, nil
+}
+
+// PurgeReviewReports removes the reports of the reviews which don't exist anymore.
+// It should run after the reviews are purged.
+func PurgeReviewReports(tx *sql.Tx, _ time.Time) (int64, error) {
+	const errMsg = "can't purge review reports"
+
+	res, err := tx.Exec(`
+    DELETE FROM review_reports
+    WHERE NOT EXISTS (
+      SELECT 1 FROM book_reviews AS r
+      WHERE r.user_id = review_reports.user_id
+      AND r.book_id = review_reports.book_id
+    );
+  `)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return n, nil
+}
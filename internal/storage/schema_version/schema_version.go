@@ -0,0 +1,83 @@
+package schema_version
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+func InitTable(db *sql.DB) error {
+	const errMsg = "can't init schema_version table"
+
+	stmt, err := db.Prepare(`
+    CREATE TABLE IF NOT EXISTS schema_version(
+      version INTEGER NOT NULL
+    );
+  `)
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	_, err = stmt.Exec()
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return nil
+}
+
+// GetVersion returns the version of the schema the db was migrated to,
+// or 0 if it was never recorded.
+func GetVersion(db *sql.DB) (int, error) {
+	const errMsg = "can't get schema version"
+
+	stmt, err := db.Prepare(`
+    SELECT COALESCE(MAX(version), 0) FROM schema_version;
+  `)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	var version int
+
+	err = stmt.QueryRow().Scan(&version)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return version, nil
+}
+
+// PutVersion records the version of the schema the db was migrated to.
+func PutVersion(db *sql.DB, version int) error {
+	const errMsg = "can't put schema version"
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+    DELETE FROM schema_version;
+  `)
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	_, err = tx.Exec(`
+    INSERT INTO schema_version
+    (version)
+    VALUES
+    (?);
+  `, version)
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,165 @@
+package session
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/qo/digital-library/internal/storage/querier"
+)
+
+// Session is a logged in user.
+// Only the hash of the session token is stored.
+type Session struct {
+	TokenHash string
+	UserId    int
+	ExpiresAt time.Time
+}
+
+func InitTable(db *sql.DB) error {
+	const errMsg = "can't init sessions table"
+
+	stmt, err := db.Prepare(`
+    CREATE TABLE IF NOT EXISTS sessions(
+      token_hash VARCHAR(64) PRIMARY KEY,
+      user_id INTEGER,
+      expires_at INTEGER,
+      FOREIGN KEY (user_id) REFERENCES users (id)
+    );
+  `)
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	_, err = stmt.Exec()
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return nil
+}
+
+func PostSession(db *sql.DB, s *Session) (int64, error) {
+	const errMsg = "can't post session"
+
+	stmt, err := db.Prepare(`
+    INSERT INTO sessions
+    (token_hash, user_id, expires_at)
+    VALUES
+    (?, ?, ?);
+  `)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	res, err := stmt.Exec(s.TokenHash, s.UserId, s.ExpiresAt.Unix())
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return n, nil
+}
+
+// GetSession returns the not expired session.
+func GetSession(db *sql.DB, tokenHash string) (*Session, error) {
+	const errMsg = "can't get session"
+
+	stmt, err := db.Prepare(`
+    SELECT token_hash, user_id, expires_at FROM sessions
+    WHERE token_hash = ?
+    AND expires_at > ?;
+  `)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	var (
+		s         Session
+		expiresAt int64
+	)
+
+	err = stmt.QueryRow(tokenHash, time.Now().Unix()).Scan(&s.TokenHash, &s.UserId, &expiresAt)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	s.ExpiresAt = time.Unix(expiresAt, 0).UTC()
+
+	return &s, nil
+}
+
+func DeleteSession(db *sql.DB, tokenHash string) (int64, error) {
+	const errMsg = "can't delete session"
+
+	stmt, err := db.Prepare(`
+    DELETE FROM sessions
+    WHERE token_hash = ?;
+  `)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	res, err := stmt.Exec(tokenHash)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return n, nil
+}
+
+// DeleteUserSessions removes the sessions of the user except the one of the token hash,
+// which may be empty to remove all of them.
+func DeleteUserSessions(db querier.Querier, userId int, keepTokenHash string) (int64, error) {
+	const errMsg = "can't delete user sessions"
+
+	stmt, err := db.Prepare(`
+    DELETE FROM sessions
+    WHERE user_id = ?
+    AND token_hash <> ?;
+  `)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	res, err := stmt.Exec(userId, keepTokenHash)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return n, nil
+}
+
+// PurgeSessions removes the sessions expired before the specified time.
+func PurgeSessions(tx *sql.Tx, before time.Time) (int64, error) {
+	const errMsg = "can't purge sessions"
+
+	res, err := tx.Exec(`
+    DELETE FROM sessions
+    WHERE expires_at < ?;
+  `, before.Unix())
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return n, nil
+}
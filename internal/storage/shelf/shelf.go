@@ -0,0 +1,215 @@
+package shelf
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/qo/digital-library/internal/storage/querier"
+)
+
+// Shelf is a named list of books owned by the user.
+// Private shelves are seen only by the owner and admins.
+type Shelf struct {
+	Id          int        `json:"id"`
+	UserId      int        `json:"user_id"`
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Public      bool       `json:"public"`
+	CreatedAt   *time.Time `json:"created_at,omitempty"`
+}
+
+func InitTable(db *sql.DB) error {
+	const errMsg = "can't init shelves table"
+
+	stmt, err := db.Prepare(`
+    CREATE TABLE IF NOT EXISTS shelves(
+      id INTEGER PRIMARY KEY,
+      user_id INTEGER,
+      name TEXT,
+      description TEXT,
+      public INTEGER,
+      created_at INTEGER,
+      FOREIGN KEY (user_id) REFERENCES users (id)
+    );
+  `)
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	_, err = stmt.Exec()
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return nil
+}
+
+func GetShelf(db *sql.DB, id int) (*Shelf, error) {
+	const errMsg = "can't get shelf"
+
+	stmt, err := db.Prepare(`
+    SELECT id, user_id, name, COALESCE(description, ''), public, created_at FROM shelves
+    WHERE id = ?;
+  `)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	shelf, err := scanShelf(stmt.QueryRow(id))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return shelf, nil
+}
+
+// GetUserShelves returns the shelves of the user ordered by name.
+// The private shelves are returned only with includePrivate.
+func GetUserShelves(db *sql.DB, userId int, includePrivate bool) ([]Shelf, error) {
+	const errMsg = "can't get user shelves"
+
+	stmt, err := db.Prepare(`
+    SELECT id, user_id, name, COALESCE(description, ''), public, created_at FROM shelves
+    WHERE user_id = ?
+    AND (? OR public)
+    ORDER BY name, id;
+  `)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	rows, err := stmt.Query(userId, includePrivate)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+	defer rows.Close()
+
+	shelves := make([]Shelf, 0)
+
+	for rows.Next() {
+		shelf, err := scanShelf(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: can't scan shelf: %s", errMsg, err)
+		}
+		shelves = append(shelves, *shelf)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("%s: error occured while iterating over shelves: %s", errMsg, err)
+	}
+
+	return shelves, nil
+}
+
+// PostShelf inserts the shelf, its id is assigned by the db and set on the shelf.
+func PostShelf(db *sql.DB, shelf *Shelf) (int64, error) {
+	const errMsg = "can't post shelf"
+
+	stmt, err := db.Prepare(`
+    INSERT INTO shelves
+    (user_id, name, description, public, created_at)
+    VALUES
+    (?, ?, ?, ?, ?);
+  `)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+
+	res, err := stmt.Exec(shelf.UserId, shelf.Name, shelf.Description, shelf.Public, now.Unix())
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	shelf.Id = int(id)
+	shelf.CreatedAt = &now
+
+	return n, nil
+}
+
+// PutShelf updates the name, the description and the visibility of the shelf.
+func PutShelf(db *sql.DB, shelf *Shelf) (int64, error) {
+	const errMsg = "can't put shelf"
+
+	stmt, err := db.Prepare(`
+    UPDATE shelves
+    SET name = ?, description = ?, public = ?
+    WHERE id = ?;
+  `)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	res, err := stmt.Exec(shelf.Name, shelf.Description, shelf.Public, shelf.Id)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return n, nil
+}
+
+// DeleteShelf removes the shelf, its books have to be removed first.
+func DeleteShelf(db querier.Querier, id int) (int64, error) {
+	const errMsg = "can't delete shelf"
+
+	stmt, err := db.Prepare(`
+    DELETE FROM shelves
+    WHERE id = ?;
+  `)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	res, err := stmt.Exec(id)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return n, nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanShelf(row scanner) (*Shelf, error) {
+	var (
+		shelf     Shelf
+		createdAt sql.NullInt64
+	)
+
+	err := row.Scan(&shelf.Id, &shelf.UserId, &shelf.Name, &shelf.Description, &shelf.Public, &createdAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if createdAt.Valid {
+		t := time.Unix(createdAt.Int64, 0).UTC()
+		shelf.CreatedAt = &t
+	}
+
+	return &shelf, nil
+}
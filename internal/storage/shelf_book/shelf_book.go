@@ -0,0 +1,260 @@
+package shelf_book
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/qo/digital-library/internal/storage/book"
+	"github.com/qo/digital-library/internal/storage/querier"
+)
+
+// ErrInvalidOrder is returned if the new order of the shelf
+// doesn't list every book of the shelf exactly once.
+var ErrInvalidOrder = errors.New("order should list every book of the shelf once")
+
+type ShelfBook struct {
+	ShelfId int `json:"shelf_id"`
+	BookId  int `json:"book_id"`
+	// Position orders the books of the shelf, the positions may have gaps
+	Position int `json:"position"`
+}
+
+func InitTable(db *sql.DB) error {
+	const errMsg = "can't init shelf books table"
+
+	stmt, err := db.Prepare(`
+    CREATE TABLE IF NOT EXISTS shelf_books(
+      shelf_id INTEGER,
+      book_id INTEGER,
+      position INTEGER,
+      FOREIGN KEY (shelf_id) REFERENCES shelves (id),
+      FOREIGN KEY (book_id) REFERENCES books (id),
+      PRIMARY KEY (shelf_id, book_id)
+    );
+  `)
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	_, err = stmt.Exec()
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return nil
+}
+
+func GetShelfBook(db querier.Querier, shelfId, bookId int) (*ShelfBook, error) {
+	const errMsg = "can't get shelf book"
+
+	stmt, err := db.Prepare(`
+    SELECT shelf_id, book_id, position FROM shelf_books
+    WHERE shelf_id = ?
+    AND book_id = ?
+  `)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	var shelfBook ShelfBook
+
+	err = stmt.QueryRow(shelfId, bookId).Scan(&shelfBook.ShelfId, &shelfBook.BookId, &shelfBook.Position)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return &shelfBook, nil
+}
+
+// GetShelfBooks returns the not deleted books of the shelf in the order of the shelf.
+func GetShelfBooks(db *sql.DB, shelfId int) ([]book.Book, error) {
+	const errMsg = "can't get shelf books"
+
+	stmt, err := db.Prepare(`
+    SELECT b.id, b.isbn, b.title, b.year, b.publisher, b.rating_sum, b.rating_count, b.copies FROM shelf_books AS sb
+    JOIN books AS b
+    ON sb.book_id = b.id
+    WHERE sb.shelf_id = ?
+    AND b.deleted_at IS NULL
+    ORDER BY sb.position, b.id;
+  `)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	rows, err := stmt.Query(shelfId)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+	defer rows.Close()
+
+	books := make([]book.Book, 0)
+
+	for rows.Next() {
+		var (
+			book                   book.Book
+			ratingSum, ratingCount int
+		)
+		err := rows.Scan(&book.Id, &book.Isbn, &book.Title, &book.Year, &book.Publisher, &ratingSum, &ratingCount, &book.Copies)
+		if err != nil {
+			return nil, fmt.Errorf("%s: can't scan book: %s", errMsg, err)
+		}
+		book.SetRating(ratingSum, ratingCount)
+		books = append(books, book)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("%s: error occured while iterating over books: %s", errMsg, err)
+	}
+
+	return books, nil
+}
+
+// getBookIds returns the ids of all books of the shelf, the deleted ones too.
+func getBookIds(db querier.Querier, shelfId int) (map[int]bool, error) {
+	rows, err := db.Query(`
+    SELECT book_id FROM shelf_books
+    WHERE shelf_id = ?;
+  `, shelfId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make(map[int]bool)
+
+	for rows.Next() {
+		var id int
+		err := rows.Scan(&id)
+		if err != nil {
+			return nil, err
+		}
+		ids[id] = true
+	}
+
+	return ids, rows.Err()
+}
+
+// PostShelfBook appends the book to the end of the shelf.
+func PostShelfBook(db querier.Querier, shelfId, bookId int) (int64, error) {
+	const errMsg = "can't post shelf book"
+
+	stmt, err := db.Prepare(`
+    INSERT INTO shelf_books
+    (shelf_id, book_id, position)
+    SELECT ?, ?, COALESCE(MAX(position), 0) + 1 FROM shelf_books
+    WHERE shelf_id = ?;
+  `)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	res, err := stmt.Exec(shelfId, bookId, shelfId)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return n, nil
+}
+
+func DeleteShelfBook(db *sql.DB, shelfId, bookId int) (int64, error) {
+	const errMsg = "can't delete shelf book"
+
+	stmt, err := db.Prepare(`
+    DELETE FROM shelf_books
+    WHERE shelf_id = ?
+    AND book_id = ?
+  `)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	res, err := stmt.Exec(shelfId, bookId)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return n, nil
+}
+
+// DeleteShelfBooks removes all books from the shelf.
+func DeleteShelfBooks(db querier.Querier, shelfId int) (int64, error) {
+	const errMsg = "can't delete shelf books"
+
+	res, err := db.Exec(`
+    DELETE FROM shelf_books
+    WHERE shelf_id = ?;
+  `, shelfId)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return n, nil
+}
+
+// PutOrder orders the books of the shelf as listed.
+// It returns ErrInvalidOrder if the ids aren't the books of the shelf.
+func PutOrder(db querier.Querier, shelfId int, bookIds []int) (int64, error) {
+	const errMsg = "can't put shelf order"
+
+	ids, err := getBookIds(db, shelfId)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	if len(ids) != len(bookIds) {
+		return 0, fmt.Errorf("%s: %w", errMsg, ErrInvalidOrder)
+	}
+	for _, id := range bookIds {
+		if !ids[id] {
+			return 0, fmt.Errorf("%s: %w", errMsg, ErrInvalidOrder)
+		}
+		// the duplicates are caught by deleting the listed ids
+		delete(ids, id)
+	}
+
+	stmt, err := db.Prepare(`
+    UPDATE shelf_books
+    SET position = ?
+    WHERE shelf_id = ?
+    AND book_id = ?;
+  `)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+	defer stmt.Close()
+
+	var total int64
+
+	for i, id := range bookIds {
+		res, err := stmt.Exec(i+1, shelfId, id)
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", errMsg, err)
+		}
+
+		n, err := res.RowsAffected()
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", errMsg, err)
+		}
+		total += n
+	}
+
+	return total, nil
+}
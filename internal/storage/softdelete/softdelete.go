@@ -0,0 +1,34 @@
+package softdelete
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/qo/digital-library/internal/storage/column"
+)
+
+// InitColumn adds the deleted_at column to the table
+// if the table was created before soft delete was introduced.
+func InitColumn(db *sql.DB, table string) error {
+	return column.Init(db, table, "deleted_at", "INTEGER")
+}
+
+// Time converts the scanned deleted_at column to a time.
+// It returns nil if the row is not deleted.
+func Time(deletedAt sql.NullInt64) *time.Time {
+	if !deletedAt.Valid {
+		return nil
+	}
+	t := time.Unix(deletedAt.Int64, 0).UTC()
+	return &t
+}
+
+// Filter returns the condition excluding deleted rows
+// unless includeDeleted is set.
+func Filter(column string, includeDeleted bool) string {
+	if includeDeleted {
+		return ""
+	}
+	return fmt.Sprintf("AND %s IS NULL", column)
+}
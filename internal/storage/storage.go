@@ -1,25 +1,58 @@
 package storage
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/qo/digital-library/internal/config"
 	"github.com/qo/digital-library/internal/storage/author"
 	"github.com/qo/digital-library/internal/storage/authorship"
 	"github.com/qo/digital-library/internal/storage/book"
 	"github.com/qo/digital-library/internal/storage/book_review"
+	"github.com/qo/digital-library/internal/storage/bookmark"
+	"github.com/qo/digital-library/internal/storage/column"
+	"github.com/qo/digital-library/internal/storage/email"
 	"github.com/qo/digital-library/internal/storage/favorite_author"
 	"github.com/qo/digital-library/internal/storage/favorite_book"
+	"github.com/qo/digital-library/internal/storage/hold"
+	"github.com/qo/digital-library/internal/storage/loan"
 	"github.com/qo/digital-library/internal/storage/mysql"
+	"github.com/qo/digital-library/internal/storage/notification"
+	"github.com/qo/digital-library/internal/storage/notification_preference"
+	"github.com/qo/digital-library/internal/storage/querier"
+	"github.com/qo/digital-library/internal/storage/reading_state"
+	"github.com/qo/digital-library/internal/storage/recommendation"
+	"github.com/qo/digital-library/internal/storage/review_report"
+	"github.com/qo/digital-library/internal/storage/schema_version"
+	"github.com/qo/digital-library/internal/storage/session"
+	"github.com/qo/digital-library/internal/storage/shelf"
+	"github.com/qo/digital-library/internal/storage/shelf_book"
 	"github.com/qo/digital-library/internal/storage/sqlite"
 	"github.com/qo/digital-library/internal/storage/user"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type Storage struct {
 	db *sql.DB
+	// dialect is the db option, mysql or sqlite
+	dialect  string
+	observer QueryObserver
 }
 
+// QueryObserver observes how long the queries of the storage take.
+// The query is the name of the storage method.
+type QueryObserver interface {
+	ObserveQuery(query string, d time.Duration)
+}
+
+// SchemaVersion is the version of the schema created by initTables.
+// It has to be bumped whenever a table or a column is added.
+const SchemaVersion = 7
+
 const (
 	mysqlDb  = "mysql"
 	sqliteDb = "sqlite"
@@ -46,7 +79,7 @@ func Init(options config.StorageOptions) (*Storage, error) {
 		return nil, fmt.Errorf("%s: %w", errMsg, err)
 	}
 
-	st := Storage{db}
+	st := Storage{db: db, dialect: options.Db}
 
 	err = st.initTables()
 	if err != nil {
@@ -71,6 +104,10 @@ func (st *Storage) initTables() error {
 		return fmt.Errorf("can't init authorship: %w", err)
 	}
 
+	// the rating aggregates of the books are computed once when they are introduced,
+	// then they are updated along with the reviews
+	recountRatings := !column.Exists(db, "books", "rating_count")
+
 	err = book.InitTable(db)
 	if err != nil {
 		return fmt.Errorf("can't init book: %w", err)
@@ -81,6 +118,13 @@ func (st *Storage) initTables() error {
 		return fmt.Errorf("can't init book_review: %w", err)
 	}
 
+	if recountRatings {
+		err = book.RecountRatings(db)
+		if err != nil {
+			return fmt.Errorf("can't init book: %w", err)
+		}
+	}
+
 	err = favorite_author.InitTable(db)
 	if err != nil {
 		return fmt.Errorf("can't init favorite_author: %w", err)
@@ -96,65 +140,1452 @@ func (st *Storage) initTables() error {
 		return fmt.Errorf("can't init user: %w", err)
 	}
 
+	err = session.InitTable(db)
+	if err != nil {
+		return fmt.Errorf("can't init session: %w", err)
+	}
+
+	err = review_report.InitTable(db)
+	if err != nil {
+		return fmt.Errorf("can't init review_report: %w", err)
+	}
+
+	err = recommendation.InitTable(db)
+	if err != nil {
+		return fmt.Errorf("can't init recommendation: %w", err)
+	}
+
+	err = reading_state.InitTable(db)
+	if err != nil {
+		return fmt.Errorf("can't init reading_state: %w", err)
+	}
+
+	err = bookmark.InitTable(db)
+	if err != nil {
+		return fmt.Errorf("can't init bookmark: %w", err)
+	}
+
+	err = shelf.InitTable(db)
+	if err != nil {
+		return fmt.Errorf("can't init shelf: %w", err)
+	}
+
+	err = shelf_book.InitTable(db)
+	if err != nil {
+		return fmt.Errorf("can't init shelf_book: %w", err)
+	}
+
+	err = loan.InitTable(db)
+	if err != nil {
+		return fmt.Errorf("can't init loan: %w", err)
+	}
+
+	err = hold.InitTable(db)
+	if err != nil {
+		return fmt.Errorf("can't init hold: %w", err)
+	}
+
+	err = notification.InitTable(db)
+	if err != nil {
+		return fmt.Errorf("can't init notification: %w", err)
+	}
+
+	err = notification_preference.InitTable(db)
+	if err != nil {
+		return fmt.Errorf("can't init notification_preference: %w", err)
+	}
+
+	err = email.InitTable(db)
+	if err != nil {
+		return fmt.Errorf("can't init email: %w", err)
+	}
+
+	err = schema_version.InitTable(db)
+	if err != nil {
+		return fmt.Errorf("can't init schema_version: %w", err)
+	}
+
+	// the version isn't lowered, so an older server can tell the db was migrated by a newer one
+	version, err := schema_version.GetVersion(db)
+	if err != nil {
+		return fmt.Errorf("can't init schema_version: %w", err)
+	}
+
+	if version < SchemaVersion {
+		err = schema_version.PutVersion(db, SchemaVersion)
+		if err != nil {
+			return fmt.Errorf("can't init schema_version: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// SetQueryObserver sets the observer of the queries made after the call.
+func (s *Storage) SetQueryObserver(o QueryObserver) {
+	s.observer = o
+}
+
+// observe starts the span and the timer of the query, end ends them.
+func (s Storage) observe(ctx context.Context, query string) *observation {
+	_, span := tracer.Start(ctx, "storage."+query,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", s.dialect),
+			attribute.String("db.operation.name", query),
+		),
+	)
+	return &observation{s.observer, query, span, time.Now()}
+}
+
+// Ping checks the connection to the db.
+func (s Storage) Ping(ctx context.Context) error {
+	defer s.observe(ctx, "Ping").end()
+
+	err := s.db.PingContext(ctx)
+	if err != nil {
+		return fmt.Errorf("can't ping db: %w", err)
+	}
+
+	return nil
+}
+
+// CheckSchema checks that the db was migrated to the schema of this server.
+// It fails if the db was migrated by a newer server since the server was started.
+func (s Storage) CheckSchema(ctx context.Context) error {
+	defer s.observe(ctx, "CheckSchema").end()
+
+	version, err := schema_version.GetVersion(s.db)
+	if err != nil {
+		return err
+	}
+
+	if version != SchemaVersion {
+		return fmt.Errorf("db schema version is %d, the server expects %d", version, SchemaVersion)
+	}
+
+	return nil
+}
+
+// Stats returns the stats of the db connection pool.
+func (s Storage) Stats() sql.DBStats {
+	return s.db.Stats()
+}
+
+// Dialect returns the db option, mysql or sqlite.
+func (s Storage) Dialect() string {
+	return s.dialect
+}
+
+func (s Storage) CountBooks(ctx context.Context) (int, error) {
+	defer s.observe(ctx, "CountBooks").end()
+	return book.CountBooks(s.db)
+}
+
+func (s Storage) CountAuthors(ctx context.Context) (int, error) {
+	defer s.observe(ctx, "CountAuthors").end()
+	return author.CountAuthors(s.db)
+}
+
+func (s Storage) CountUsers(ctx context.Context) (int, error) {
+	defer s.observe(ctx, "CountUsers").end()
+	return user.CountUsers(s.db)
+}
+
+func (s Storage) CountBookReviews(ctx context.Context) (int, error) {
+	defer s.observe(ctx, "CountBookReviews").end()
+	return book_review.CountBookReviews(s.db)
+}
+
+func (s Storage) GetAuthor(ctx context.Context, id int, includeDeleted bool) (*author.Author, error) {
+	defer s.observe(ctx, "GetAuthor").end()
+	return author.GetAuthor(s.db, id, includeDeleted)
+}
+
+func (s Storage) PostAuthor(ctx context.Context, a *author.Author) error {
+	o := s.observe(ctx, "PostAuthor")
+	defer o.end()
+	return o.written(author.PostAuthor(s.db, a))
+}
+
+func (s Storage) PutAuthor(ctx context.Context, a *author.Author) error {
+	o := s.observe(ctx, "PutAuthor")
+	defer o.end()
+	return o.written(author.PutAuthor(s.db, a))
+}
+
+func (s Storage) DeleteAuthor(ctx context.Context, id int) error {
+	o := s.observe(ctx, "DeleteAuthor")
+	defer o.end()
+	return o.written(author.DeleteAuthor(s.db, id))
+}
+
+func (s Storage) RestoreAuthor(ctx context.Context, id int) error {
+	o := s.observe(ctx, "RestoreAuthor")
+	defer o.end()
+	return o.written(author.RestoreAuthor(s.db, id))
+}
+
+func (s Storage) GetAuthors(ctx context.Context) ([]author.Author, error) {
+	defer s.observe(ctx, "GetAuthors").end()
+	return author.GetAuthors(s.db)
+}
+
+func (s Storage) GetAuthorBooks(ctx context.Context, authorId int) ([]book.Book, error) {
+	defer s.observe(ctx, "GetAuthorBooks").end()
+	return authorship.GetAuthorBooks(s.db, authorId)
+}
+
+func (s Storage) GetBooks(ctx context.Context, limit, offset int) ([]book.Book, error) {
+	defer s.observe(ctx, "GetBooks").end()
+	return book.GetBooks(s.db, limit, offset)
+}
+
+func (s Storage) GetNewBooks(ctx context.Context, limit, offset int) ([]book.Book, error) {
+	defer s.observe(ctx, "GetNewBooks").end()
+	return book.GetNewBooks(s.db, limit, offset)
+}
+
+func (s Storage) GetPublisherBooks(ctx context.Context, publisher string, limit, offset int) ([]book.Book, error) {
+	defer s.observe(ctx, "GetPublisherBooks").end()
+	return book.GetPublisherBooks(s.db, publisher, limit, offset)
+}
+
+func (s Storage) SearchBooks(ctx context.Context, query string, limit, offset int) ([]book.Book, error) {
+	defer s.observe(ctx, "SearchBooks").end()
+	return book.SearchBooks(s.db, query, limit, offset)
+}
+
+func (s Storage) GetPublishers(ctx context.Context) ([]string, error) {
+	defer s.observe(ctx, "GetPublishers").end()
+	return book.GetPublishers(s.db)
+}
+
+func (s Storage) GetBook(ctx context.Context, id int, includeDeleted bool) (*book.Book, error) {
+	defer s.observe(ctx, "GetBook").end()
+	return book.GetBook(s.db, id, includeDeleted)
+}
+
+func (s Storage) PostBook(ctx context.Context, a *book.Book) error {
+	o := s.observe(ctx, "PostBook")
+	defer o.end()
+	return o.written(book.PostBook(s.db, a))
+}
+
+func (s Storage) PutBook(ctx context.Context, a *book.Book) error {
+	o := s.observe(ctx, "PutBook")
+	defer o.end()
+	return o.written(book.PutBook(s.db, a))
+}
+
+func (s Storage) DeleteBook(ctx context.Context, id int) error {
+	o := s.observe(ctx, "DeleteBook")
+	defer o.end()
+	return o.written(book.DeleteBook(s.db, id))
+}
+
+func (s Storage) RestoreBook(ctx context.Context, id int) error {
+	o := s.observe(ctx, "RestoreBook")
+	defer o.end()
+	return o.written(book.RestoreBook(s.db, id))
+}
+
+func (s Storage) GetBookReviews(ctx context.Context, bookId int) ([]book_review.BookReview, error) {
+	defer s.observe(ctx, "GetBookReviews").end()
+	return book_review.GetBookReviews(s.db, bookId)
+}
+
+func (s Storage) GetBookReview(ctx context.Context, userId, bookId int, includeDeleted bool) (*book_review.BookReview, error) {
+	defer s.observe(ctx, "GetBookReview").end()
+	return book_review.GetBookReview(s.db, userId, bookId, includeDeleted)
+}
+
+// PostBookReview inserts the review and adds its rating to the aggregates of the book.
+func (s Storage) PostBookReview(ctx context.Context, r *book_review.BookReview) error {
+	o := s.observe(ctx, "PostBookReview")
+	defer o.end()
+
+	var n int64
+	err := s.withRating(r.BookId, func(tx *sql.Tx) (int, int, error) {
+		var err error
+		n, err = book_review.PostBookReview(tx, r)
+		return r.Rating, 1, err
+	})
+	return o.written(n, err)
+}
+
+// PutBookReview updates the review and replaces its rating in the aggregates of the book.
+func (s Storage) PutBookReview(ctx context.Context, r *book_review.BookReview) error {
+	o := s.observe(ctx, "PutBookReview")
+	defer o.end()
+
+	var n int64
+	err := s.withRating(r.BookId, func(tx *sql.Tx) (int, int, error) {
+		old, err := book_review.GetBookReview(tx, r.UserId, r.BookId, false)
+		if err != nil {
+			return 0, 0, err
+		}
+		n, err = book_review.PutBookReview(tx, r)
+		return r.Rating - old.Rating, 0, err
+	})
+	return o.written(n, err)
+}
+
+// DeleteBookReview marks the review as deleted and removes its rating from the aggregates of the book.
+// Deleting a review which doesn't exist or is already deleted does nothing.
+func (s Storage) DeleteBookReview(ctx context.Context, userId, bookId int) error {
+	o := s.observe(ctx, "DeleteBookReview")
+	defer o.end()
+
+	var n int64
+	err := s.withRating(bookId, func(tx *sql.Tx) (int, int, error) {
+		old, err := book_review.GetBookReview(tx, userId, bookId, false)
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, 0, nil
+		}
+		if err != nil {
+			return 0, 0, err
+		}
+		n, err = book_review.DeleteBookReview(tx, userId, bookId)
+		return -old.Rating, -1, err
+	})
+	return o.written(n, err)
+}
+
+// RestoreBookReview restores the deleted review and adds its rating back to the aggregates of the book.
+func (s Storage) RestoreBookReview(ctx context.Context, userId, bookId int) error {
+	o := s.observe(ctx, "RestoreBookReview")
+	defer o.end()
+
+	var n int64
+	err := s.withRating(bookId, func(tx *sql.Tx) (int, int, error) {
+		var err error
+		n, err = book_review.RestoreBookReview(tx, userId, bookId)
+		if err != nil {
+			return 0, 0, err
+		}
+		r, err := book_review.GetBookReview(tx, userId, bookId, false)
+		if err != nil {
+			return 0, 0, err
+		}
+		return r.Rating, 1, nil
+	})
+	return o.written(n, err)
+}
+
+// withRating runs the change of the reviews of the book in a transaction
+// along with the update of the rating aggregates of the book,
+// so the aggregates are never out of sync with the reviews.
+// fn returns the deltas of the rating sum and the rating count.
+func (s Storage) withRating(bookId int, fn func(*sql.Tx) (int, int, error)) error {
+	const errMsg = "can't update book rating"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+	defer tx.Rollback()
+
+	ratingSum, ratingCount, err := fn(tx)
+	if err != nil {
+		return err
+	}
+
+	if ratingSum != 0 || ratingCount != 0 {
+		err = book.AddRating(tx, bookId, ratingSum, ratingCount)
+		if err != nil {
+			return fmt.Errorf("%s: %w", errMsg, err)
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
 	return nil
 }
 
-func (s Storage) GetAuthor(id int) (*author.Author, error) {
-	return author.GetAuthor(s.db, id)
+// GetTopBooks returns the books ranked by the ratings of the reviews updated since the specified time,
+// or of all reviews if since is nil.
+func (s Storage) GetTopBooks(ctx context.Context, since *time.Time, limit, offset int) ([]book.TopBook, error) {
+	defer s.observe(ctx, "GetTopBooks").end()
+	return book.GetTopBooks(s.db, since, limit, offset)
 }
 
-func (s Storage) PostAuthor(a *author.Author) error {
-	return author.PostAuthor(s.db, a)
+func (s Storage) PostReviewReport(ctx context.Context, r *review_report.ReviewReport) error {
+	o := s.observe(ctx, "PostReviewReport")
+	defer o.end()
+	return o.written(review_report.PostReviewReport(s.db, r))
 }
 
-func (s Storage) PutAuthor(a *author.Author) error {
-	return author.PutAuthor(s.db, a)
+func (s Storage) GetReviewReport(ctx context.Context, id int) (*review_report.ReviewReport, error) {
+	defer s.observe(ctx, "GetReviewReport").end()
+	return review_report.GetReviewReport(s.db, id)
 }
 
-func (s Storage) DeleteAuthor(id int) error {
-	return author.DeleteAuthor(s.db, id)
+func (s Storage) GetReviewReportQueue(ctx context.Context) ([]review_report.QueueEntry, error) {
+	defer s.observe(ctx, "GetReviewReportQueue").end()
+	return review_report.GetQueue(s.db)
 }
 
-func (s Storage) GetBook(id int) (*book.Book, error) {
-	return book.GetBook(s.db, id)
+func (s Storage) ResolveReviewReport(ctx context.Context, id int) error {
+	o := s.observe(ctx, "ResolveReviewReport")
+	defer o.end()
+	return o.written(review_report.ResolveReviewReport(s.db, id))
 }
 
-func (s Storage) PostBook(a *book.Book) error {
-	return book.PostBook(s.db, a)
+func (s Storage) ResolveReviewReports(ctx context.Context, userId, bookId int) error {
+	o := s.observe(ctx, "ResolveReviewReports")
+	defer o.end()
+	return o.written(review_report.ResolveReviewReports(s.db, userId, bookId))
 }
 
-func (s Storage) PutBook(a *book.Book) error {
-	return book.PutBook(s.db, a)
+func (s Storage) GetBookAuthors(ctx context.Context, bookId int) ([]author.Author, error) {
+	defer s.observe(ctx, "GetBookAuthors").end()
+	return authorship.GetBookAuthors(s.db, bookId)
 }
 
-func (s Storage) DeleteBook(id int) error {
-	return book.DeleteBook(s.db, id)
+func (s Storage) EachBookWithAuthors(ctx context.Context, fn func(book.Book, []author.Author) error) error {
+	defer s.observe(ctx, "EachBookWithAuthors").end()
+	return authorship.EachBookWithAuthors(s.db, fn)
 }
 
-func (s Storage) GetUser(id int) (*user.User, error) {
+func (s Storage) GetUser(ctx context.Context, id int) (*user.User, error) {
+	defer s.observe(ctx, "GetUser").end()
 	return user.GetUser(s.db, id)
 }
 
-func (s Storage) PostUser(u *user.User) error {
-	return user.PostUser(s.db, u)
+func (s Storage) GetUsers(ctx context.Context, limit, offset int) ([]user.User, error) {
+	defer s.observe(ctx, "GetUsers").end()
+	return user.GetUsers(s.db, limit, offset)
+}
+
+func (s Storage) PutRole(ctx context.Context, id, from, to int) error {
+	o := s.observe(ctx, "PutRole")
+	defer o.end()
+	return o.written(user.PutRole(s.db, id, from, to))
+}
+
+func (s Storage) PostUser(ctx context.Context, u *user.User) error {
+	o := s.observe(ctx, "PostUser")
+	defer o.end()
+	return o.written(user.PostUser(s.db, u))
+}
+
+func (s Storage) PutUser(ctx context.Context, u *user.User) error {
+	o := s.observe(ctx, "PutUser")
+	defer o.end()
+	return o.written(user.PutUser(s.db, u))
+}
+
+func (s Storage) DeleteUser(ctx context.Context, id int) error {
+	o := s.observe(ctx, "DeleteUser")
+	defer o.end()
+	return o.written(user.DeleteUser(s.db, id))
+}
+
+func (s Storage) GetPasswordHash(ctx context.Context, id int) (string, error) {
+	defer s.observe(ctx, "GetPasswordHash").end()
+	return user.GetPasswordHash(s.db, id)
+}
+
+// PutPasswordHash sets the password hash of the user and ends the other sessions of the user,
+// the session of keepTokenHash is kept so the user who changes their own password stays logged in.
+func (s Storage) PutPasswordHash(ctx context.Context, id int, hash, keepTokenHash string) (err error) {
+	o := s.observe(ctx, "PutPasswordHash")
+	defer o.end()
+
+	var n int64
+	defer func() { o.written(n, err) }()
+
+	const errMsg = "can't put password hash"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+	defer tx.Rollback()
+
+	n, err = user.PutPasswordHash(tx, id, hash)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := session.DeleteUserSessions(tx, id, keepTokenHash)
+	if err != nil {
+		return err
+	}
+	n += deleted
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return nil
+}
+
+func (s Storage) PostSession(ctx context.Context, ss *session.Session) error {
+	o := s.observe(ctx, "PostSession")
+	defer o.end()
+	return o.written(session.PostSession(s.db, ss))
+}
+
+func (s Storage) GetSession(ctx context.Context, tokenHash string) (*session.Session, error) {
+	defer s.observe(ctx, "GetSession").end()
+	return session.GetSession(s.db, tokenHash)
+}
+
+func (s Storage) DeleteSession(ctx context.Context, tokenHash string) error {
+	o := s.observe(ctx, "DeleteSession")
+	defer o.end()
+	return o.written(session.DeleteSession(s.db, tokenHash))
+}
+
+func (s Storage) GetFavoriteBook(ctx context.Context, userId, bookId int) (*favorite_book.FavoriteBook, error) {
+	defer s.observe(ctx, "GetFavoriteBook").end()
+	return favorite_book.GetFavoriteBook(s.db, userId, bookId)
+}
+
+func (s Storage) PostFavoriteBook(ctx context.Context, userId, bookId int) error {
+	o := s.observe(ctx, "PostFavoriteBook")
+	defer o.end()
+	return o.written(favorite_book.PutFavoriteBook(s.db, &favorite_book.FavoriteBook{UserId: userId, BookId: bookId}))
+}
+
+func (s Storage) DeleteFavoriteBook(ctx context.Context, userId, bookId int) error {
+	o := s.observe(ctx, "DeleteFavoriteBook")
+	defer o.end()
+	return o.written(favorite_book.DeleteFavoriteBook(s.db, userId, bookId))
+}
+
+func (s Storage) GetFavoriteAuthor(ctx context.Context, userId, authorId int) (*favorite_author.FavoriteAuthor, error) {
+	defer s.observe(ctx, "GetFavoriteAuthor").end()
+	return favorite_author.GetFavoriteAuthor(s.db, userId, authorId)
+}
+
+func (s Storage) PostFavoriteAuthor(ctx context.Context, userId, authorId int) error {
+	o := s.observe(ctx, "PostFavoriteAuthor")
+	defer o.end()
+	return o.written(favorite_author.PutFavoriteAuthor(s.db, &favorite_author.FavoriteAuthor{UserId: userId, AuthorId: authorId}))
 }
 
-func (s Storage) PutUser(u *user.User) error {
-	return user.PutUser(s.db, u)
+func (s Storage) DeleteFavoriteAuthor(ctx context.Context, userId, authorId int) error {
+	o := s.observe(ctx, "DeleteFavoriteAuthor")
+	defer o.end()
+	return o.written(favorite_author.DeleteFavoriteAuthor(s.db, userId, authorId))
 }
 
-func (s Storage) DeleteUser(id int) error {
-	return user.DeleteUser(s.db, id)
+func (s Storage) GetUserBookReviews(ctx context.Context, id int, includeDeleted bool) ([]book_review.BookReview, error) {
+	defer s.observe(ctx, "GetUserBookReviews").end()
+	return user.GetBookReviews(s.db, id, includeDeleted)
 }
 
-func (s Storage) GetUserBookReviews(id int) ([]book_review.BookReview, error) {
-	return user.GetBookReviews(s.db, id)
+func (s Storage) GetUserFavoriteAuthors(ctx context.Context, id int, includeDeleted bool) ([]author.Author, error) {
+	defer s.observe(ctx, "GetUserFavoriteAuthors").end()
+	return user.GetFavoriteAuthors(s.db, id, includeDeleted)
 }
 
-func (s Storage) GetUserFavoriteAuthors(id int) ([]author.Author, error) {
-	return user.GetFavoriteAuthors(s.db, id)
+func (s Storage) GetUserFavoriteBooks(ctx context.Context, id int, includeDeleted bool) ([]book.Book, error) {
+	defer s.observe(ctx, "GetUserFavoriteBooks").end()
+	return user.GetFavoriteBooks(s.db, id, includeDeleted)
 }
 
-func (s Storage) GetUserFavoriteBooks(id int) ([]book.Book, error) {
-	return user.GetFavoriteBooks(s.db, id)
+func (s Storage) GetRecommendations(ctx context.Context, userId, limit, offset int) ([]recommendation.RecommendedBook, error) {
+	defer s.observe(ctx, "GetRecommendations").end()
+	return recommendation.GetRecommendations(s.db, userId, limit, offset)
+}
+
+func (s Storage) GetRecommendationSignals(ctx context.Context) (*recommendation.Signals, error) {
+	defer s.observe(ctx, "GetRecommendationSignals").end()
+	return recommendation.GetSignals(s.db)
+}
+
+// PutRecommendations replaces the recommendations of all users in a transaction,
+// so the users never see a partially written list.
+func (s Storage) PutRecommendations(ctx context.Context, recommendations []recommendation.Recommendation, computedAt time.Time) (err error) {
+	o := s.observe(ctx, "PutRecommendations")
+	defer o.end()
+	defer func() { o.written(int64(len(recommendations)), err) }()
+
+	const errMsg = "can't put recommendations"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+	defer tx.Rollback()
+
+	err = recommendation.PutRecommendations(tx, recommendations, computedAt)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return nil
+}
+
+func (s Storage) GetReadingState(ctx context.Context, userId, bookId int) (*reading_state.ReadingState, error) {
+	defer s.observe(ctx, "GetReadingState").end()
+	return reading_state.GetReadingState(s.db, userId, bookId)
+}
+
+func (s Storage) GetReadingStates(ctx context.Context, userId int, status string) ([]reading_state.ReadingState, error) {
+	defer s.observe(ctx, "GetReadingStates").end()
+	return reading_state.GetReadingStates(s.db, userId, status)
+}
+
+// PutReadingState creates or updates the reading state of the book.
+func (s Storage) PutReadingState(ctx context.Context, state *reading_state.ReadingState) (err error) {
+	o := s.observe(ctx, "PutReadingState")
+	defer o.end()
+
+	var n int64
+	defer func() { o.written(n, err) }()
+
+	const errMsg = "can't put reading state"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+	defer tx.Rollback()
+
+	_, err = reading_state.GetReadingState(tx, state.UserId, state.BookId)
+	if errors.Is(err, sql.ErrNoRows) {
+		n, err = reading_state.PostReadingState(tx, state)
+	} else if err == nil {
+		n, err = reading_state.PutReadingState(tx, state)
+	}
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return nil
+}
+
+func (s Storage) DeleteReadingState(ctx context.Context, userId, bookId int) error {
+	o := s.observe(ctx, "DeleteReadingState")
+	defer o.end()
+	return o.written(reading_state.DeleteReadingState(s.db, userId, bookId))
+}
+
+func (s Storage) GetBookmarks(ctx context.Context, userId, bookId int) ([]bookmark.Bookmark, error) {
+	defer s.observe(ctx, "GetBookmarks").end()
+	return bookmark.GetBookmarks(s.db, userId, bookId)
+}
+
+func (s Storage) PostBookmark(ctx context.Context, b *bookmark.Bookmark) error {
+	o := s.observe(ctx, "PostBookmark")
+	defer o.end()
+	return o.written(bookmark.PostBookmark(s.db, b))
+}
+
+func (s Storage) PutBookmark(ctx context.Context, b *bookmark.Bookmark) error {
+	o := s.observe(ctx, "PutBookmark")
+	defer o.end()
+	return o.written(bookmark.PutBookmark(s.db, b))
+}
+
+func (s Storage) DeleteBookmark(ctx context.Context, userId, bookId, id int) error {
+	o := s.observe(ctx, "DeleteBookmark")
+	defer o.end()
+	return o.written(bookmark.DeleteBookmark(s.db, userId, bookId, id))
+}
+
+func (s Storage) GetShelf(ctx context.Context, id int) (*shelf.Shelf, error) {
+	defer s.observe(ctx, "GetShelf").end()
+	return shelf.GetShelf(s.db, id)
+}
+
+func (s Storage) GetUserShelves(ctx context.Context, userId int, includePrivate bool) ([]shelf.Shelf, error) {
+	defer s.observe(ctx, "GetUserShelves").end()
+	return shelf.GetUserShelves(s.db, userId, includePrivate)
+}
+
+func (s Storage) PostShelf(ctx context.Context, sh *shelf.Shelf) error {
+	o := s.observe(ctx, "PostShelf")
+	defer o.end()
+	return o.written(shelf.PostShelf(s.db, sh))
+}
+
+func (s Storage) PutShelf(ctx context.Context, sh *shelf.Shelf) error {
+	o := s.observe(ctx, "PutShelf")
+	defer o.end()
+	return o.written(shelf.PutShelf(s.db, sh))
+}
+
+// DeleteShelf deletes the shelf along with its books.
+func (s Storage) DeleteShelf(ctx context.Context, id int) (err error) {
+	o := s.observe(ctx, "DeleteShelf")
+	defer o.end()
+
+	var n int64
+	defer func() { o.written(n, err) }()
+
+	const errMsg = "can't delete shelf"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+	defer tx.Rollback()
+
+	n, err = shelf_book.DeleteShelfBooks(tx, id)
+	if err != nil {
+		return err
+	}
+
+	m, err := shelf.DeleteShelf(tx, id)
+	if err != nil {
+		return err
+	}
+	n += m
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return nil
+}
+
+func (s Storage) GetShelfBooks(ctx context.Context, shelfId int) ([]book.Book, error) {
+	defer s.observe(ctx, "GetShelfBooks").end()
+	return shelf_book.GetShelfBooks(s.db, shelfId)
+}
+
+// PostShelfBook adds the book to the end of the shelf.
+// Adding the book already on the shelf keeps its position.
+func (s Storage) PostShelfBook(ctx context.Context, shelfId, bookId int) (err error) {
+	o := s.observe(ctx, "PostShelfBook")
+	defer o.end()
+
+	var n int64
+	defer func() { o.written(n, err) }()
+
+	const errMsg = "can't post shelf book"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+	defer tx.Rollback()
+
+	_, err = shelf_book.GetShelfBook(tx, shelfId, bookId)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	n, err = shelf_book.PostShelfBook(tx, shelfId, bookId)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return nil
+}
+
+func (s Storage) DeleteShelfBook(ctx context.Context, shelfId, bookId int) error {
+	o := s.observe(ctx, "DeleteShelfBook")
+	defer o.end()
+	return o.written(shelf_book.DeleteShelfBook(s.db, shelfId, bookId))
+}
+
+// PutShelfOrder orders the books of the shelf as listed,
+// it returns shelf_book.ErrInvalidOrder if the ids aren't the books of the shelf.
+func (s Storage) PutShelfOrder(ctx context.Context, shelfId int, bookIds []int) (err error) {
+	o := s.observe(ctx, "PutShelfOrder")
+	defer o.end()
+
+	var n int64
+	defer func() { o.written(n, err) }()
+
+	const errMsg = "can't put shelf order"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+	defer tx.Rollback()
+
+	n, err = shelf_book.PutOrder(tx, shelfId, bookIds)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return nil
+}
+
+func (s Storage) GetLoan(ctx context.Context, id int) (*loan.Loan, error) {
+	defer s.observe(ctx, "GetLoan").end()
+	return loan.GetLoan(s.db, id)
+}
+
+// GetLoans returns the loans of the user with the status, the loans of all users if the user id is 0.
+func (s Storage) GetLoans(ctx context.Context, userId int, status string, limit, offset int) ([]loan.Loan, error) {
+	defer s.observe(ctx, "GetLoans").end()
+	return loan.GetLoans(s.db, userId, status, time.Now(), limit, offset)
+}
+
+func (s Storage) GetUserHolds(ctx context.Context, userId int) ([]hold.Hold, error) {
+	defer s.observe(ctx, "GetUserHolds").end()
+	return hold.GetUserHolds(s.db, userId)
+}
+
+// Availability tells how many copies of the book can be checked out.
+type Availability struct {
+	Copies int `json:"copies"`
+	// Lent is the number of the copies which are not returned yet
+	Lent int `json:"lent"`
+	// Holds is the length of the queue for the copies
+	Holds int `json:"holds"`
+	// Available is the number of the copies which can be checked out right away,
+	// the copies are assigned to the queue first
+	Available int `json:"available"`
+}
+
+// GetAvailability returns the availability of the not deleted book.
+func (s Storage) GetAvailability(ctx context.Context, bookId int) (*Availability, error) {
+	defer s.observe(ctx, "GetAvailability").end()
+	return availability(s.db, bookId)
+}
+
+func availability(db querier.Querier, bookId int) (*Availability, error) {
+	const errMsg = "can't get availability"
+
+	copies, err := book.GetCopies(db, bookId)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	lent, err := loan.CountBookLoans(db, bookId)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	holds, err := hold.CountHolds(db, bookId)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return &Availability{
+		Copies:    copies,
+		Lent:      lent,
+		Holds:     holds,
+		Available: max(0, copies-lent-holds),
+	}, nil
+}
+
+// CheckoutBook lends a copy of the not deleted book to the user for the loan period.
+// The copy is lent only if nobody ahead of the user is in the queue for it,
+// the hold of the user is removed then.
+func (s Storage) CheckoutBook(ctx context.Context, userId, bookId int, options config.LoansOptions) (_ *loan.Loan, err error) {
+	o := s.observe(ctx, "CheckoutBook")
+	defer o.end()
+
+	var n int64
+	defer func() { o.written(n, err) }()
+
+	const errMsg = "can't checkout book"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+	defer tx.Rollback()
+
+	err = lockLending(tx, bookId, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	a, err := availability(tx, bookId)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = loan.GetActiveLoan(tx, userId, bookId)
+	if err == nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, loan.ErrBorrowed)
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	borrowed, err := loan.CountUserLoans(tx, userId)
+	if err != nil {
+		return nil, err
+	}
+	if borrowed >= options.Limit {
+		return nil, fmt.Errorf("%s: %w", errMsg, loan.ErrLimit)
+	}
+
+	ahead := a.Holds
+	h, err := hold.GetHold(tx, userId, bookId)
+	if err == nil {
+		ahead = h.Position - 1
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if a.Copies-a.Lent-ahead <= 0 {
+		return nil, fmt.Errorf("%s: %w", errMsg, loan.ErrNoCopies)
+	}
+
+	n, err = hold.DeleteHold(tx, userId, bookId)
+	if err != nil {
+		return nil, err
+	}
+
+	l, m, err := lend(tx, userId, bookId, options.Period)
+	if err != nil {
+		return nil, err
+	}
+	n += m
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return l, nil
+}
+
+// ReturnLoan marks the loan as returned and lends the freed copy
+// to the first user in the queue for the book.
+// It returns the loans made to the queue.
+func (s Storage) ReturnLoan(ctx context.Context, id int, options config.LoansOptions) (_ []loan.Loan, err error) {
+	o := s.observe(ctx, "ReturnLoan")
+	defer o.end()
+
+	var n int64
+	defer func() { o.written(n, err) }()
+
+	const errMsg = "can't return loan"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+	defer tx.Rollback()
+
+	l, err := loan.GetLoan(tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	n, err = loan.ReturnLoan(tx, id, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	loans, m, err := assignHolds(tx, l.BookId, options)
+	if err != nil {
+		return nil, err
+	}
+	n += m
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return loans, nil
+}
+
+// PlaceHold puts the user in the queue for a copy of the not deleted book.
+// It returns hold.ErrAvailable if the user can check the book out right away
+// and loan.ErrLimit if the user borrowed as many books as allowed.
+func (s Storage) PlaceHold(ctx context.Context, userId, bookId int, options config.LoansOptions) (_ *hold.Hold, err error) {
+	o := s.observe(ctx, "PlaceHold")
+	defer o.end()
+
+	var n int64
+	defer func() { o.written(n, err) }()
+
+	const errMsg = "can't place hold"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+	defer tx.Rollback()
+
+	err = lockLending(tx, bookId, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	a, err := availability(tx, bookId)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = loan.GetActiveLoan(tx, userId, bookId)
+	if err == nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, loan.ErrBorrowed)
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	_, err = hold.GetHold(tx, userId, bookId)
+	if err == nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, hold.ErrHeld)
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	borrowed, err := loan.CountUserLoans(tx, userId)
+	if err != nil {
+		return nil, err
+	}
+	if borrowed >= options.Limit {
+		return nil, fmt.Errorf("%s: %w", errMsg, loan.ErrLimit)
+	}
+
+	if a.Available > 0 {
+		return nil, fmt.Errorf("%s: %w", errMsg, hold.ErrAvailable)
+	}
+
+	n, err = hold.PostHold(tx, &hold.Hold{UserId: userId, BookId: bookId})
+	if err != nil {
+		return nil, err
+	}
+
+	h, err := hold.GetHold(tx, userId, bookId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return h, nil
+}
+
+func (s Storage) CancelHold(ctx context.Context, userId, bookId int) error {
+	o := s.observe(ctx, "CancelHold")
+	defer o.end()
+	return o.written(hold.DeleteHold(s.db, userId, bookId))
+}
+
+// SetBookCopies sets the number of the copies of the not deleted book,
+// the added copies are lent to the queue for the book.
+// It returns the loans made to the queue.
+func (s Storage) SetBookCopies(ctx context.Context, bookId, copies int, options config.LoansOptions) (_ []loan.Loan, err error) {
+	o := s.observe(ctx, "SetBookCopies")
+	defer o.end()
+
+	var n int64
+	defer func() { o.written(n, err) }()
+
+	const errMsg = "can't set book copies"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+	defer tx.Rollback()
+
+	_, err = book.GetCopies(tx, bookId)
+	if err != nil {
+		return nil, err
+	}
+
+	n, err = book.SetCopies(tx, bookId, copies)
+	if err != nil {
+		return nil, err
+	}
+
+	loans, m, err := assignHolds(tx, bookId, options)
+	if err != nil {
+		return nil, err
+	}
+	n += m
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return loans, nil
+}
+
+// AssignHolds lends the free copies of all books to their queues,
+// e.g. the copies freed by the deleted users or kept for the queued users at the loan limit.
+// It returns the loans made to the queues.
+func (s Storage) AssignHolds(ctx context.Context, options config.LoansOptions) (_ []loan.Loan, err error) {
+	o := s.observe(ctx, "AssignHolds")
+	defer o.end()
+
+	var n int64
+	defer func() { o.written(n, err) }()
+
+	const errMsg = "can't assign holds"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+	defer tx.Rollback()
+
+	ids, err := hold.GetHeldBooks(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	var loans []loan.Loan
+
+	for _, id := range ids {
+		l, m, err := assignHolds(tx, id, options)
+		if err != nil {
+			return nil, err
+		}
+		loans = append(loans, l...)
+		n += m
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return loans, nil
+}
+
+// MarkOverdueLoans marks the loans which became overdue since the last call and returns them.
+func (s Storage) MarkOverdueLoans(ctx context.Context, now time.Time) (loans []loan.Loan, err error) {
+	o := s.observe(ctx, "MarkOverdueLoans")
+	defer o.end()
+	defer func() { o.written(int64(len(loans)), err) }()
+
+	const errMsg = "can't mark overdue loans"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+	defer tx.Rollback()
+
+	loans, err = loan.MarkOverdue(tx, now)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return loans, nil
+}
+
+func (s Storage) GetNotifications(ctx context.Context, userId int, unreadOnly bool, limit, offset int) ([]notification.Notification, error) {
+	defer s.observe(ctx, "GetNotifications").end()
+	return notification.GetNotifications(s.db, userId, unreadOnly, limit, offset)
+}
+
+func (s Storage) CountUnreadNotifications(ctx context.Context, userId int) (int, error) {
+	defer s.observe(ctx, "CountUnreadNotifications").end()
+	return notification.CountUnread(s.db, userId)
+}
+
+func (s Storage) MarkNotificationRead(ctx context.Context, userId, id int) error {
+	o := s.observe(ctx, "MarkNotificationRead")
+	defer o.end()
+	return o.written(notification.MarkRead(s.db, userId, id))
+}
+
+func (s Storage) MarkAllNotificationsRead(ctx context.Context, userId int) error {
+	o := s.observe(ctx, "MarkAllNotificationsRead")
+	defer o.end()
+	return o.written(notification.MarkAllRead(s.db, userId))
+}
+
+func (s Storage) DeleteNotification(ctx context.Context, userId, id int) error {
+	o := s.observe(ctx, "DeleteNotification")
+	defer o.end()
+	return o.written(notification.DeleteNotification(s.db, userId, id))
+}
+
+// PostNotification puts the notification in the inbox and the email in the outbox
+// in one transaction, either of them may be nil.
+func (s Storage) PostNotification(ctx context.Context, n *notification.Notification, e *email.Email) (err error) {
+	o := s.observe(ctx, "PostNotification")
+	defer o.end()
+
+	var rows int64
+	defer func() { o.written(rows, err) }()
+
+	const errMsg = "can't post notification"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+	defer tx.Rollback()
+
+	if n != nil {
+		m, err := notification.PostNotification(tx, n)
+		if err != nil {
+			return err
+		}
+		rows += m
+	}
+
+	if e != nil {
+		m, err := email.PostEmail(tx, e)
+		if err != nil {
+			return err
+		}
+		rows += m
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return nil
+}
+
+func (s Storage) GetEmail(ctx context.Context, userId int) (string, error) {
+	defer s.observe(ctx, "GetEmail").end()
+	return user.GetEmail(s.db, userId)
+}
+
+func (s Storage) GetNotificationPreference(ctx context.Context, userId int, typ string) (*notification_preference.NotificationPreference, error) {
+	defer s.observe(ctx, "GetNotificationPreference").end()
+	return notification_preference.GetNotificationPreference(s.db, userId, typ)
+}
+
+func (s Storage) GetNotificationPreferences(ctx context.Context, userId int, types []string) ([]notification_preference.NotificationPreference, error) {
+	defer s.observe(ctx, "GetNotificationPreferences").end()
+	return notification_preference.GetNotificationPreferences(s.db, userId, types)
+}
+
+// PutNotificationPreferences sets the email address of the user unless it's nil
+// and the preferences of the listed types, the preferences of the other types are kept.
+// It returns sql.ErrNoRows if the user doesn't exist.
+func (s Storage) PutNotificationPreferences(ctx context.Context, userId int, address *string, preferences []notification_preference.NotificationPreference) (err error) {
+	o := s.observe(ctx, "PutNotificationPreferences")
+	defer o.end()
+
+	var n int64
+	defer func() { o.written(n, err) }()
+
+	const errMsg = "can't put notification preferences"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+	defer tx.Rollback()
+
+	_, err = user.GetEmail(tx, userId)
+	if err != nil {
+		return err
+	}
+
+	if address != nil {
+		n, err = user.PutEmail(tx, userId, *address)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, p := range preferences {
+		p.UserId = userId
+		m, err := notification_preference.PutNotificationPreference(tx, p)
+		if err != nil {
+			return err
+		}
+		n += m
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return nil
+}
+
+func (s Storage) GetDueEmails(ctx context.Context, now time.Time, limit int) ([]email.Email, error) {
+	defer s.observe(ctx, "GetDueEmails").end()
+	return email.GetDueEmails(s.db, now, limit)
+}
+
+func (s Storage) PutEmailSent(ctx context.Context, id int, sentAt time.Time) error {
+	o := s.observe(ctx, "PutEmailSent")
+	defer o.end()
+	return o.written(email.PutSent(s.db, id, sentAt))
+}
+
+func (s Storage) PutFailedEmailAttempt(ctx context.Context, id int, lastError string, now time.Time, nextAttemptAt *time.Time) error {
+	o := s.observe(ctx, "PutFailedEmailAttempt")
+	defer o.end()
+	return o.written(email.PutFailedAttempt(s.db, id, lastError, now, nextAttemptAt))
+}
+
+// assignHolds lends the free copies of the book to the first users in its queue.
+// The users who borrowed as many books as allowed are skipped and kept in the queue,
+// they get a copy once they return a book and a copy is free again.
+// The deleted books aren't lent. It returns the loans and the number of the written rows.
+func assignHolds(tx *sql.Tx, bookId int, options config.LoansOptions) ([]loan.Loan, int64, error) {
+	err := book.Lock(tx, bookId)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	a, err := availability(tx, bookId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	free := a.Copies - a.Lent
+	if free <= 0 || a.Holds == 0 {
+		return nil, 0, nil
+	}
+
+	queue, err := hold.GetQueue(tx, bookId, a.Holds)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	loans := make([]loan.Loan, 0, free)
+
+	var rows int64
+
+	for _, h := range queue {
+		if len(loans) == free {
+			break
+		}
+
+		err = user.Lock(tx, h.UserId)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		n, err := loan.CountUserLoans(tx, h.UserId)
+		if err != nil {
+			return nil, 0, err
+		}
+		if n >= options.Limit {
+			continue
+		}
+
+		deleted, err := hold.DeleteHold(tx, h.UserId, h.BookId)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		l, lent, err := lend(tx, h.UserId, h.BookId, options.Period)
+		if err != nil {
+			return nil, 0, err
+		}
+		loans = append(loans, *l)
+		rows += deleted + lent
+	}
+
+	return loans, rows, nil
+}
+
+// lockLending locks the book and then the user,
+// the lending transactions lock them in this order so that they don't deadlock.
+func lockLending(tx *sql.Tx, bookId, userId int) error {
+	err := book.Lock(tx, bookId)
+	if err != nil {
+		return err
+	}
+
+	return user.Lock(tx, userId)
+}
+
+// lend lends a copy of the book to the user,
+// it returns the loan and the number of the written rows.
+func lend(tx *sql.Tx, userId, bookId int, period time.Duration) (*loan.Loan, int64, error) {
+	now := time.Now().UTC().Truncate(time.Second)
+
+	l := loan.Loan{
+		UserId:       userId,
+		BookId:       bookId,
+		CheckedOutAt: now,
+		DueAt:        now.Add(period),
+	}
+
+	n, err := loan.PostLoan(tx, &l)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return &l, n, nil
+}
+
+// Purge permanently removes the books, authors and book reviews
+// deleted before the specified time, the sessions expired before it
+// and the reports of the removed reviews.
+// It returns the number of removed rows.
+func (s Storage) Purge(ctx context.Context, before time.Time) (total int64, err error) {
+	o := s.observe(ctx, "Purge")
+	defer o.end()
+	defer func() { o.written(total, err) }()
+
+	const errMsg = "can't purge deleted rows"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+	defer tx.Rollback()
+
+	for _, purge := range []func(*sql.Tx, time.Time) (int64, error){
+		book_review.PurgeBookReviews,
+		book.PurgeBooks,
+		author.PurgeAuthors,
+		session.PurgeSessions,
+		review_report.PurgeReviewReports,
+	} {
+		n, err := purge(tx, before)
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", errMsg, err)
+		}
+		total += n
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return total, nil
 }
@@ -0,0 +1,54 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/qo/digital-library/internal/storage/author"
+	"github.com/qo/digital-library/internal/storage/authorship"
+	"github.com/qo/digital-library/internal/storage/book"
+)
+
+// Tx is a storage transaction.
+// It exposes the operations which have to be done atomically.
+type Tx struct {
+	tx *sql.Tx
+}
+
+func (s Storage) Begin() (*Tx, error) {
+	const errMsg = "can't begin transaction"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return &Tx{tx}, nil
+}
+
+func (t Tx) Commit() error {
+	return t.tx.Commit()
+}
+
+func (t Tx) Rollback() error {
+	return t.tx.Rollback()
+}
+
+func (t Tx) PostBook(b *book.Book) error {
+	_, err := book.PostBook(t.tx, b)
+	return err
+}
+
+func (t Tx) GetAuthorByName(fullName string) (*author.Author, error) {
+	return author.GetAuthorByName(t.tx, fullName)
+}
+
+func (t Tx) PostAuthor(a *author.Author) error {
+	_, err := author.PostAuthor(t.tx, a)
+	return err
+}
+
+func (t Tx) PostAuthorship(a *authorship.Authorship) error {
+	_, _, err := authorship.PostAuthorship(t.tx, a)
+	return err
+}
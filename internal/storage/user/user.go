@@ -2,11 +2,15 @@ package user
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 
 	"github.com/qo/digital-library/internal/storage/author"
 	"github.com/qo/digital-library/internal/storage/book"
 	"github.com/qo/digital-library/internal/storage/book_review"
+	"github.com/qo/digital-library/internal/storage/column"
+	"github.com/qo/digital-library/internal/storage/querier"
+	"github.com/qo/digital-library/internal/storage/softdelete"
 )
 
 type User struct {
@@ -16,6 +20,29 @@ type User struct {
 	Role       int    `json:"role"` // 1 - user, 2 - mod, 3 - admin
 }
 
+// ErrAdmin is returned when an admin is deleted
+var ErrAdmin = errors.New("admins can't be deleted")
+
+const (
+	RoleUser  = 1
+	RoleMod   = 2
+	RoleAdmin = 3
+)
+
+// CanChangeRole reports whether the role can be changed from one to another:
+// users can be promoted to mods and mods can be demoted to users.
+// Admins are neither promoted nor demoted.
+func CanChangeRole(from, to int) bool {
+	return from == RoleUser && to == RoleMod ||
+		from == RoleMod && to == RoleUser
+}
+
+// CanCreateRole reports whether a user can be created with the role:
+// new users are users or mods, admins are never created.
+func CanCreateRole(role int) bool {
+	return role == RoleUser || role == RoleMod
+}
+
 func InitTable(db *sql.DB) error {
 	const errMsg = "can't init users table"
 
@@ -24,7 +51,8 @@ func InitTable(db *sql.DB) error {
       id INTEGER PRIMARY KEY,
       first_name TEXT,
       second_name TEXT,
-      role INTEGER
+      role INTEGER,
+      password_hash TEXT
     );
   `)
 	if err != nil {
@@ -36,10 +64,22 @@ func InitTable(db *sql.DB) error {
 		return fmt.Errorf("%s: %w", errMsg, err)
 	}
 
+	err = column.Init(db, "users", "password_hash", "TEXT")
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	err = column.Init(db, "users", "email", "TEXT")
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
 	return nil
 }
 
-func PostUser(db *sql.DB, user *User) error {
+// PostUser creates the user.
+// The id is assigned by the db if it is 0.
+func PostUser(db *sql.DB, user *User) (int64, error) {
 	const errMsg = "can't post user"
 
 	stmt, err := db.Prepare(`
@@ -49,22 +89,109 @@ func PostUser(db *sql.DB, user *User) error {
     (?, ?, ?, ?);
   `)
 	if err != nil {
-		return fmt.Errorf("%s: %w", errMsg, err)
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	var id any
+	if user.Id != 0 {
+		id = user.Id
 	}
 
-	_, err = stmt.Exec(user.Id, user.FirstName, user.SecondName, user.Role)
+	res, err := stmt.Exec(id, user.FirstName, user.SecondName, user.Role)
 	if err != nil {
-		return fmt.Errorf("%s: %w", errMsg, err)
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
 	}
 
-	return nil
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	if user.Id == 0 {
+		lastId, err := res.LastInsertId()
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", errMsg, err)
+		}
+		user.Id = int(lastId)
+	}
+
+	return n, nil
+}
+
+// GetUsers returns a page of the users ordered by id.
+func GetUsers(db *sql.DB, limit, offset int) ([]User, error) {
+	const errMsg = "can't get users"
+
+	stmt, err := db.Prepare(`
+    SELECT id, first_name, second_name, role FROM users
+    ORDER BY id
+    LIMIT ? OFFSET ?;
+  `)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	rows, err := stmt.Query(limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+	defer rows.Close()
+
+	users := make([]User, 0)
+
+	for rows.Next() {
+		var user User
+		err := rows.Scan(&user.Id, &user.FirstName, &user.SecondName, &user.Role)
+		if err != nil {
+			return nil, fmt.Errorf("%s: can't scan user: %s", errMsg, err)
+		}
+		users = append(users, user)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("%s: error occured while iterating over users: %s", errMsg, err)
+	}
+
+	return users, nil
+}
+
+// PutRole changes the role of the user if the user still has the expected role.
+// It returns sql.ErrNoRows otherwise.
+func PutRole(db *sql.DB, id, from, to int) (int64, error) {
+	const errMsg = "can't put role"
+
+	stmt, err := db.Prepare(`
+    UPDATE users
+    SET role = ?
+    WHERE id = ?
+    AND role = ?;
+  `)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	res, err := stmt.Exec(to, id, from)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+	if n == 0 {
+		return 0, fmt.Errorf("%s: %w", errMsg, sql.ErrNoRows)
+	}
+
+	return n, nil
 }
 
 func GetUser(db *sql.DB, id int) (*User, error) {
 	const errMsg = "can't get user"
 
 	stmt, err := db.Prepare(`
-    SELECT * FROM users
+    SELECT id, first_name, second_name, role FROM users
     WHERE id = ?;
   `)
 	if err != nil {
@@ -83,54 +210,330 @@ func GetUser(db *sql.DB, id int) (*User, error) {
 	return &user, nil
 }
 
-func PutUser(db *sql.DB, user *User) error {
+// PutUser updates the names of the user, the role is changed with PutRole only.
+func PutUser(db *sql.DB, user *User) (int64, error) {
 	const errMsg = "can't put user"
 
 	stmt, err := db.Prepare(`
     UPDATE users
-    SET first_name = ?, second_name = ?, role = ?
+    SET first_name = ?, second_name = ?
     WHERE id = ?;
   `)
 	if err != nil {
-		return fmt.Errorf("%s: %w", errMsg, err)
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
 	}
 
-	_, err = stmt.Exec(user.FirstName, user.SecondName, user.Role, user.Id)
+	res, err := stmt.Exec(user.FirstName, user.SecondName, user.Id)
 	if err != nil {
-		return fmt.Errorf("%s: %w", errMsg, err)
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
 	}
 
-	return nil
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return n, nil
 }
 
-func DeleteUser(db *sql.DB, id int) error {
+// DeleteUser deletes the user along with everything of the user.
+// It returns sql.ErrNoRows if there is no such user
+// and ErrAdmin if the user is an admin: admins are never deleted.
+func DeleteUser(db *sql.DB, id int) (int64, error) {
 	const errMsg = "can't delete user"
 
-	stmt, err := db.Prepare(`
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+	defer tx.Rollback()
+
+	var role int
+
+	err = tx.QueryRow(`
+    SELECT role FROM users
+    WHERE id = ?;
+  `, id).Scan(&role)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	if role == RoleAdmin {
+		return 0, fmt.Errorf("%s: %w", errMsg, ErrAdmin)
+	}
+
+	// the reviews are deleted along with the user,
+	// so their ratings are removed from the aggregates of the books
+	err = removeRatings(tx, id)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	var total int64
+
+	for _, query := range []string{
+		`
+    DELETE FROM sessions
+    WHERE user_id = ?;
+  `,
+		`
+    DELETE FROM recommendations
+    WHERE user_id = ?;
+  `,
+		`
+    DELETE FROM reading_states
+    WHERE user_id = ?;
+  `,
+		`
+    DELETE FROM bookmarks
+    WHERE user_id = ?;
+  `,
+		`
+    DELETE FROM shelf_books
+    WHERE shelf_id IN (
+      SELECT id FROM shelves
+      WHERE user_id = ?
+    );
+  `,
+		`
+    DELETE FROM shelves
+    WHERE user_id = ?;
+  `,
+		`
+    DELETE FROM loans
+    WHERE user_id = ?;
+  `,
+		`
+    DELETE FROM holds
+    WHERE user_id = ?;
+  `,
+		`
+    DELETE FROM notifications
+    WHERE user_id = ?;
+  `,
+		`
+    DELETE FROM notification_preferences
+    WHERE user_id = ?;
+  `,
+		`
+    DELETE FROM emails
+    WHERE user_id = ?;
+  `,
+		`
+    DELETE FROM favorite_books
+    WHERE user_id = ?;
+  `,
+		`
+    DELETE FROM favorite_authors
+    WHERE user_id = ?;
+  `,
+		`
+    DELETE FROM review_reports
+    WHERE reporter_id = ?;
+  `,
+		`
+    DELETE FROM review_reports
+    WHERE user_id = ?;
+  `,
+		`
+    DELETE FROM book_reviews
+    WHERE user_id = ?;
+  `,
+		`
     DELETE FROM users
     WHERE id = ?;
-  `)
+  `,
+	} {
+		res, err := tx.Exec(query, id)
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", errMsg, err)
+		}
+
+		n, err := res.RowsAffected()
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", errMsg, err)
+		}
+		total += n
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return total, nil
+}
+
+// removeRatings removes the ratings of the not deleted reviews of the user
+// from the aggregates of the books.
+func removeRatings(tx *sql.Tx, id int) error {
+	const errMsg = "can't remove ratings of user"
+
+	rows, err := tx.Query(`
+    SELECT book_id, rating FROM book_reviews
+    WHERE user_id = ?
+    AND deleted_at IS NULL;
+  `, id)
 	if err != nil {
 		return fmt.Errorf("%s: %w", errMsg, err)
 	}
+	defer rows.Close()
+
+	// bookId -> rating
+	ratings := make(map[int]int)
+
+	for rows.Next() {
+		var bookId, rating int
+		err = rows.Scan(&bookId, &rating)
+		if err != nil {
+			return fmt.Errorf("%s: can't scan review: %w", errMsg, err)
+		}
+		ratings[bookId] = rating
+	}
 
-	_, err = stmt.Exec(id)
+	err = rows.Err()
 	if err != nil {
 		return fmt.Errorf("%s: %w", errMsg, err)
 	}
 
+	for bookId, rating := range ratings {
+		err = book.AddRating(tx, bookId, -rating, -1)
+		if err != nil {
+			return fmt.Errorf("%s: %w", errMsg, err)
+		}
+	}
+
 	return nil
 }
 
-func GetFavoriteBooks(db *sql.DB, id int) ([]book.Book, error) {
-	const errMsg = "can't get favorite books"
+// Lock locks the row of the user until the end of the transaction,
+// so that the loans of the user are counted and made by one transaction at a time.
+// It is locked after the books, see book.Lock.
+func Lock(tx querier.Querier, id int) error {
+	const errMsg = "can't lock user"
+
+	_, err := tx.Exec(`
+    UPDATE users
+    SET role = role
+    WHERE id = ?;
+  `, id)
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return nil
+}
+
+// GetPasswordHash returns the password hash of the user.
+// It returns an empty hash if the user has no password.
+func GetPasswordHash(db *sql.DB, id int) (string, error) {
+	const errMsg = "can't get password hash"
 
 	stmt, err := db.Prepare(`
-    SELECT b.* FROM favorite_books AS fb
+    SELECT COALESCE(password_hash, '') FROM users
+    WHERE id = ?;
+  `)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	var hash string
+
+	err = stmt.QueryRow(id).Scan(&hash)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return hash, nil
+}
+
+func PutPasswordHash(db querier.Querier, id int, hash string) (int64, error) {
+	const errMsg = "can't put password hash"
+
+	stmt, err := db.Prepare(`
+    UPDATE users
+    SET password_hash = ?
+    WHERE id = ?;
+  `)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	res, err := stmt.Exec(hash, id)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+	if n == 0 {
+		return 0, fmt.Errorf("%s: %w", errMsg, sql.ErrNoRows)
+	}
+
+	return n, nil
+}
+
+// GetEmail returns the email address the notifications are sent to.
+// It returns an empty address if the user has none.
+// The address isn't a field of User so that it's never shown to other users.
+func GetEmail(db querier.Querier, id int) (string, error) {
+	const errMsg = "can't get email"
+
+	stmt, err := db.Prepare(`
+    SELECT COALESCE(email, '') FROM users
+    WHERE id = ?;
+  `)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	var email string
+
+	err = stmt.QueryRow(id).Scan(&email)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return email, nil
+}
+
+func PutEmail(db querier.Querier, id int, email string) (int64, error) {
+	const errMsg = "can't put email"
+
+	stmt, err := db.Prepare(`
+    UPDATE users
+    SET email = ?
+    WHERE id = ?;
+  `)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	res, err := stmt.Exec(email, id)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return n, nil
+}
+
+func GetFavoriteBooks(db *sql.DB, id int, includeDeleted bool) ([]book.Book, error) {
+	const errMsg = "can't get favorite books"
+
+	stmt, err := db.Prepare(fmt.Sprintf(`
+    SELECT b.id, b.isbn, b.title, b.year, b.publisher, b.deleted_at, b.rating_sum, b.rating_count, b.copies FROM favorite_books AS fb
     JOIN books AS b
     ON fb.book_id = b.id
-    WHERE fb.user_id = ?;
-  `)
+    WHERE fb.user_id = ?
+    %s;
+  `, softdelete.Filter("b.deleted_at", includeDeleted)))
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", errMsg, err)
 	}
@@ -143,11 +546,17 @@ func GetFavoriteBooks(db *sql.DB, id int) ([]book.Book, error) {
 	books := make([]book.Book, 0)
 
 	for rows.Next() {
-		var book book.Book
-		err := rows.Scan(&book.Id, &book.Isbn, &book.Title, &book.Year, &book.Publisher)
+		var (
+			book                   book.Book
+			deletedAt              sql.NullInt64
+			ratingSum, ratingCount int
+		)
+		err := rows.Scan(&book.Id, &book.Isbn, &book.Title, &book.Year, &book.Publisher, &deletedAt, &ratingSum, &ratingCount, &book.Copies)
 		if err != nil {
 			return nil, fmt.Errorf("%s: can't scan book: %s", errMsg, err)
 		}
+		book.DeletedAt = softdelete.Time(deletedAt)
+		book.SetRating(ratingSum, ratingCount)
 		books = append(books, book)
 	}
 
@@ -159,15 +568,16 @@ func GetFavoriteBooks(db *sql.DB, id int) ([]book.Book, error) {
 	return books, nil
 }
 
-func GetFavoriteAuthors(db *sql.DB, id int) ([]author.Author, error) {
+func GetFavoriteAuthors(db *sql.DB, id int, includeDeleted bool) ([]author.Author, error) {
 	const errMsg = "can't get favorite authors"
 
-	stmt, err := db.Prepare(`
-    SELECT * FROM favorite_authors
-    JOIN authors
-    ON favorite_authors.author_id = authors.id
-    WHERE favorite_books.user_id = ?;
-  `)
+	stmt, err := db.Prepare(fmt.Sprintf(`
+    SELECT a.id, a.full_name, a.deleted_at FROM favorite_authors AS fa
+    JOIN authors AS a
+    ON fa.author_id = a.id
+    WHERE fa.user_id = ?
+    %s;
+  `, softdelete.Filter("a.deleted_at", includeDeleted)))
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", errMsg, err)
 	}
@@ -180,11 +590,15 @@ func GetFavoriteAuthors(db *sql.DB, id int) ([]author.Author, error) {
 	authors := make([]author.Author, 0)
 
 	for rows.Next() {
-		var author author.Author
-		err := rows.Scan(&author.Id, &author.FullName)
+		var (
+			author    author.Author
+			deletedAt sql.NullInt64
+		)
+		err := rows.Scan(&author.Id, &author.FullName, &deletedAt)
 		if err != nil {
 			return nil, fmt.Errorf("%s: can't scan author: %s", errMsg, err)
 		}
+		author.DeletedAt = softdelete.Time(deletedAt)
 		authors = append(authors, author)
 	}
 
@@ -196,13 +610,14 @@ func GetFavoriteAuthors(db *sql.DB, id int) ([]author.Author, error) {
 	return authors, nil
 }
 
-func GetBookReviews(db *sql.DB, id int) ([]book_review.BookReview, error) {
+func GetBookReviews(db *sql.DB, id int, includeDeleted bool) ([]book_review.BookReview, error) {
 	const errMsg = "can't get book reviews"
 
-	stmt, err := db.Prepare(`
-    SELECT * FROM book_reviews
-    WHERE user_id = ?;
-  `)
+	stmt, err := db.Prepare(fmt.Sprintf(`
+    SELECT user_id, book_id, rating, COALESCE(body, ''), deleted_at FROM book_reviews
+    WHERE user_id = ?
+    %s;
+  `, softdelete.Filter("deleted_at", includeDeleted)))
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", errMsg, err)
 	}
@@ -215,11 +630,15 @@ func GetBookReviews(db *sql.DB, id int) ([]book_review.BookReview, error) {
 	reviews := make([]book_review.BookReview, 0)
 
 	for rows.Next() {
-		var review book_review.BookReview
-		err := rows.Scan(&review.UserId, &review.BookId, &review.Rating)
+		var (
+			review    book_review.BookReview
+			deletedAt sql.NullInt64
+		)
+		err := rows.Scan(&review.UserId, &review.BookId, &review.Rating, &review.Body, &deletedAt)
 		if err != nil {
 			return nil, fmt.Errorf("%s: can't scan book review: %s", errMsg, err)
 		}
+		review.DeletedAt = softdelete.Time(deletedAt)
 		reviews = append(reviews, review)
 	}
 
@@ -230,3 +649,24 @@ func GetBookReviews(db *sql.DB, id int) ([]book_review.BookReview, error) {
 
 	return reviews, nil
 }
+
+// CountUsers returns the number of the users.
+func CountUsers(db *sql.DB) (int, error) {
+	const errMsg = "can't count users"
+
+	stmt, err := db.Prepare(`
+    SELECT COUNT(*) FROM users;
+  `)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	var n int
+
+	err = stmt.QueryRow().Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	return n, nil
+}
@@ -0,0 +1,130 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/qo/digital-library/internal/storage/author"
+	"github.com/qo/digital-library/internal/storage/book"
+	"github.com/qo/digital-library/internal/storage/book_review"
+	"github.com/qo/digital-library/internal/storage/review_report"
+)
+
+func TestDeleteUser(t *testing.T) {
+	st := newTestStorage(t, 3)
+	ctx := context.Background()
+
+	// the user 2 is deleted, the user 3 stays
+	reviews := []book_review.BookReview{
+		{UserId: 2, BookId: 1, Rating: 5},
+		{UserId: 2, BookId: 2, Rating: 1},
+		{UserId: 2, BookId: 3, Rating: 4},
+		{UserId: 3, BookId: 1, Rating: 3},
+	}
+	for _, r := range reviews {
+		err := st.PostBookReview(ctx, &r)
+		if err != nil {
+			t.Fatalf("PostBookReview() error: %s", err)
+		}
+	}
+	// the deleted review isn't in the aggregates, so it must not be removed from them again
+	err := st.DeleteBookReview(ctx, 2, 3)
+	if err != nil {
+		t.Fatalf("DeleteBookReview() error: %s", err)
+	}
+
+	err = st.PostAuthor(ctx, &author.Author{Id: 1, FullName: "Author"})
+	if err != nil {
+		t.Fatalf("PostAuthor() error: %s", err)
+	}
+	err = st.PostFavoriteBook(ctx, 2, 1)
+	if err != nil {
+		t.Fatalf("PostFavoriteBook() error: %s", err)
+	}
+	err = st.PostFavoriteAuthor(ctx, 2, 1)
+	if err != nil {
+		t.Fatalf("PostFavoriteAuthor() error: %s", err)
+	}
+
+	reports := []review_report.ReviewReport{
+		{ReporterId: 2, UserId: 3, BookId: 1, Reason: "spam"},
+		{ReporterId: 3, UserId: 2, BookId: 1, Reason: "spam"},
+	}
+	for _, r := range reports {
+		r.CreatedAt = time.Now()
+		err = st.PostReviewReport(ctx, &r)
+		if err != nil {
+			t.Fatalf("PostReviewReport() error: %s", err)
+		}
+	}
+
+	err = st.DeleteUser(ctx, 2)
+	if err != nil {
+		t.Fatalf("DeleteUser() error: %s", err)
+	}
+
+	_, err = st.GetUser(ctx, 2)
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetUser() of the deleted user error = %v, want %v", err, sql.ErrNoRows)
+	}
+
+	for _, table := range []string{"book_reviews", "favorite_books", "favorite_authors"} {
+		var n int
+		err = st.db.QueryRow("SELECT COUNT(*) FROM " + table + " WHERE user_id = 2").Scan(&n)
+		if err != nil {
+			t.Fatalf("can't count %s: %s", table, err)
+		}
+		if n != 0 {
+			t.Errorf("%d rows of the deleted user left in %s", n, table)
+		}
+	}
+
+	var n int
+	err = st.db.QueryRow("SELECT COUNT(*) FROM review_reports").Scan(&n)
+	if err != nil {
+		t.Fatalf("can't count review_reports: %s", err)
+	}
+	if n != 0 {
+		t.Errorf("%d reports by or of the deleted user left", n)
+	}
+
+	tests := []struct {
+		bookId      int
+		wantAverage float64
+		wantCount   int
+	}{
+		{1, 3, 1},
+		{2, 0, 0},
+		{3, 0, 0},
+	}
+
+	for _, tt := range tests {
+		b, err := st.GetBook(ctx, tt.bookId, false)
+		if err != nil {
+			t.Fatalf("GetBook() error: %s", err)
+		}
+		if b.AverageRating != tt.wantAverage || b.ReviewCount != tt.wantCount {
+			t.Errorf("rating of book %d = %v of %d reviews, want %v of %d",
+				tt.bookId, b.AverageRating, b.ReviewCount, tt.wantAverage, tt.wantCount)
+		}
+	}
+
+	// the aggregates match the ones computed from scratch
+	err = book.RecountRatings(st.db)
+	if err != nil {
+		t.Fatalf("RecountRatings() error: %s", err)
+	}
+	for _, tt := range tests {
+		b, err := st.GetBook(ctx, tt.bookId, false)
+		if err != nil {
+			t.Fatalf("GetBook() error: %s", err)
+		}
+		if b.AverageRating != tt.wantAverage || b.ReviewCount != tt.wantCount {
+			t.Errorf("recounted rating of book %d = %v of %d reviews, want %v of %d",
+				tt.bookId, b.AverageRating, b.ReviewCount, tt.wantAverage, tt.wantCount)
+		}
+	}
+}
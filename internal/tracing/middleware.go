@@ -0,0 +1,60 @@
+package tracing
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/qo/digital-library/internal/logger"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/qo/digital-library/internal/tracing")
+
+// Middleware starts the span of the request, continuing the trace of the client
+// if the request carries the trace context headers.
+// The span is named after the route pattern, e.g. GET /api/book/{id},
+// so it has to be used by the root router, as the pattern is only complete once the request is routed.
+// The trace id and the span id are added to everything logged with the request context.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+		ctx, span := tracer.Start(ctx, r.Method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", r.Method),
+				attribute.String("url.path", r.URL.Path),
+			),
+		)
+		defer span.End()
+
+		if sc := span.SpanContext(); sc.IsValid() {
+			ctx = logger.WithAttrs(ctx, "trace id", sc.TraceID().String(), "span id", sc.SpanID().String())
+		}
+
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+		next.ServeHTTP(ww, r.WithContext(ctx))
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			span.SetName(fmt.Sprintf("%s %s", r.Method, rctx.RoutePattern()))
+			span.SetAttributes(attribute.String("http.route", rctx.RoutePattern()))
+		}
+
+		span.SetAttributes(attribute.Int("http.response.status_code", status))
+		if status >= 500 {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	})
+}
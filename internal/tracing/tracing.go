@@ -0,0 +1,79 @@
+// Package tracing sets up the OpenTelemetry tracing of the server.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/qo/digital-library/internal/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+const (
+	exporterOff    = "off"
+	exporterStdout = "stdout"
+	exporterOTLP   = "otlp"
+)
+
+// Init sets the global tracer provider exporting the spans as configured
+// and the propagator of the W3C trace context and baggage headers.
+// The propagator is set even if the exporter is off,
+// so the trace ids of the incoming requests still get into the logs.
+// The returned func flushes the spans and stops the exporter.
+func Init(ctx context.Context, options config.TracingOptions) (func(context.Context) error, error) {
+	const errMsg = "can't init tracing"
+
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	var (
+		exp sdktrace.SpanExporter
+		err error
+	)
+
+	switch options.Exporter {
+	case exporterOff:
+		return func(context.Context) error { return nil }, nil
+	case exporterStdout:
+		exp, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case exporterOTLP:
+		opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(options.Endpoint)}
+		if options.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		exp, err = otlptracehttp.New(ctx, opts...)
+	default:
+		err = fmt.Errorf("exporter %s is unknown", options.Exporter)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	res, err := resource.Merge(
+		resource.Default(),
+		resource.NewSchemaless(attribute.String("service.name", options.ServiceName)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exp),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(options.SampleRatio))),
+	)
+
+	otel.SetTracerProvider(tp)
+
+	return tp.Shutdown, nil
+}
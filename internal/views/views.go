@@ -0,0 +1,7 @@
+// Package views holds the html templates embedded into the binary.
+package views
+
+import "embed"
+
+//go:embed */*.tmpl
+var FS embed.FS
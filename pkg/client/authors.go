@@ -0,0 +1,50 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// GetAuthor returns the author. Deleted authors are returned only to admins with includeDeleted.
+func (c *Client) GetAuthor(ctx context.Context, id int, includeDeleted bool) (*Author, error) {
+	var a Author
+
+	err := c.do(ctx, request{
+		method: http.MethodGet,
+		path:   fmt.Sprintf("/author/%d", id),
+		query:  includeDeletedQuery(includeDeleted),
+	}, &a)
+	if err != nil {
+		return nil, err
+	}
+
+	return &a, nil
+}
+
+// PostAuthor creates the author and returns its id, the id is assigned if it's 0.
+func (c *Client) PostAuthor(ctx context.Context, a Author) (int, error) {
+	var resp idResponse
+
+	err := c.do(ctx, request{method: http.MethodPost, path: "/author", json: a}, &resp)
+	if err != nil {
+		return 0, err
+	}
+
+	return resp.Id, nil
+}
+
+// PutAuthor updates the author.
+func (c *Client) PutAuthor(ctx context.Context, a Author) error {
+	return c.do(ctx, request{method: http.MethodPut, path: "/author", json: a}, nil)
+}
+
+// DeleteAuthor deletes the author, it can be restored until it's purged.
+func (c *Client) DeleteAuthor(ctx context.Context, id int) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: fmt.Sprintf("/author/%d", id)}, nil)
+}
+
+// RestoreAuthor restores the deleted author.
+func (c *Client) RestoreAuthor(ctx context.Context, id int) error {
+	return c.do(ctx, request{method: http.MethodPost, path: fmt.Sprintf("/author/%d/restore", id)}, nil)
+}
@@ -0,0 +1,170 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// GetBook returns the book. Deleted books are returned only to admins with includeDeleted.
+func (c *Client) GetBook(ctx context.Context, id int, includeDeleted bool) (*Book, error) {
+	var b Book
+
+	err := c.do(ctx, request{
+		method: http.MethodGet,
+		path:   fmt.Sprintf("/book/%d", id),
+		query:  includeDeletedQuery(includeDeleted),
+	}, &b)
+	if err != nil {
+		return nil, err
+	}
+
+	return &b, nil
+}
+
+// ListBooks returns a page of the books ordered by title.
+// If the query is not empty only the books whose title, isbn, publisher or author contains it are returned.
+// Zero limit means the default page size of the server.
+func (c *Client) ListBooks(ctx context.Context, query string, limit, offset int) ([]Book, error) {
+	var resp struct {
+		Books []Book `json:"books"`
+	}
+
+	q := pageQuery(limit, offset)
+	if query != "" {
+		q["q"] = query
+	}
+
+	err := c.do(ctx, request{method: http.MethodGet, path: "/books", query: q}, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Books, nil
+}
+
+// TopBooks returns a page of the books ranked by the bayesian average of their ratings.
+// The period is week, month, year or all, empty means all.
+// Zero limit means the default page size of the server.
+func (c *Client) TopBooks(ctx context.Context, period string, limit, offset int) ([]TopBook, error) {
+	var resp struct {
+		Books []TopBook `json:"books"`
+	}
+
+	q := pageQuery(limit, offset)
+	if period != "" {
+		q["period"] = period
+	}
+
+	err := c.do(ctx, request{method: http.MethodGet, path: "/books/top", query: q}, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Books, nil
+}
+
+// PostBook creates the book with the authors and returns its id, the id is assigned if it's 0.
+// The authors are looked up by full name and created if they don't exist.
+func (c *Client) PostBook(ctx context.Context, b Book, authors ...string) (int, error) {
+	var resp idResponse
+
+	err := c.do(ctx, request{
+		method: http.MethodPost,
+		path:   "/book",
+		json: struct {
+			Book
+			Authors []string `json:"authors,omitempty"`
+		}{b, authors},
+	}, &resp)
+	if err != nil {
+		return 0, err
+	}
+
+	return resp.Id, nil
+}
+
+// PutBook updates the book.
+func (c *Client) PutBook(ctx context.Context, b Book) error {
+	return c.do(ctx, request{method: http.MethodPut, path: "/book", json: b}, nil)
+}
+
+// DeleteBook deletes the book, it can be restored until it's purged.
+func (c *Client) DeleteBook(ctx context.Context, id int) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: fmt.Sprintf("/book/%d", id)}, nil)
+}
+
+// RestoreBook restores the deleted book.
+func (c *Client) RestoreBook(ctx context.Context, id int) error {
+	return c.do(ctx, request{method: http.MethodPost, path: fmt.Sprintf("/book/%d/restore", id)}, nil)
+}
+
+// PutBookFile uploads the PDF of the book and returns its size.
+// The upload is not retried since the file can't be read twice.
+func (c *Client) PutBookFile(ctx context.Context, id int, pdf io.Reader) (int64, error) {
+	var resp struct {
+		Size int64 `json:"size"`
+	}
+
+	err := c.do(ctx, request{
+		method:      http.MethodPut,
+		path:        fmt.Sprintf("/book/%d/file", id),
+		body:        pdf,
+		contentType: "application/pdf",
+	}, &resp)
+	if err != nil {
+		return 0, err
+	}
+
+	return resp.Size, nil
+}
+
+// GetBookFile downloads the PDF of the book. The returned reader must be closed.
+func (c *Client) GetBookFile(ctx context.Context, id int) (io.ReadCloser, error) {
+	resp, err := c.send(ctx, request{method: http.MethodGet, path: fmt.Sprintf("/book/%d/file", id)})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Body, nil
+}
+
+// CiteBook returns the citation of the book in the format, e.g. bibtex, ris, csl-json, apa or mla.
+func (c *Client) CiteBook(ctx context.Context, id int, format string) ([]byte, error) {
+	resp, err := c.send(ctx, request{
+		method: http.MethodGet,
+		path:   fmt.Sprintf("/book/%d/cite", id),
+		query:  map[string]string{"format": format},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	citation, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("can't read citation of book %d: %w", id, err)
+	}
+
+	return citation, nil
+}
+
+func includeDeletedQuery(includeDeleted bool) map[string]string {
+	if !includeDeleted {
+		return nil
+	}
+	return map[string]string{"include_deleted": strconv.FormatBool(includeDeleted)}
+}
+
+func pageQuery(limit, offset int) map[string]string {
+	q := make(map[string]string)
+	if limit > 0 {
+		q["limit"] = strconv.Itoa(limit)
+	}
+	if offset > 0 {
+		q["offset"] = strconv.Itoa(offset)
+	}
+	return q
+}
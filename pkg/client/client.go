@@ -0,0 +1,238 @@
+// Package client is a typed client of the digital-library REST API.
+//
+//	c := client.New("http://localhost:5454", client.Options{Retries: 2})
+//	_, err := c.Login(ctx, 1, "password")
+//	...
+//	book, err := c.GetBook(ctx, 1)
+//	if errors.Is(err, client.ErrNotFound) {
+//		...
+//	}
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultBackoff = 200 * time.Millisecond
+
+// Options configure the client.
+type Options struct {
+	// Token is sent as the bearer token, it's either the admin token or a session token
+	Token string
+	// HTTPClient sends the requests, http.DefaultClient is used if it's nil
+	HTTPClient *http.Client
+	// Retries is how many times the idempotent requests are retried
+	// after a network error or a 429, 502, 503 or 504 response
+	Retries int
+	// Backoff is the delay before the first retry, it doubles with every retry.
+	// Retry-After of the response takes precedence.
+	Backoff time.Duration
+}
+
+// Client calls the api of the server at the base url.
+// It is safe for concurrent use.
+type Client struct {
+	baseUrl string
+	http    *http.Client
+	retries int
+	backoff time.Duration
+
+	mu    sync.RWMutex
+	token string
+}
+
+// New creates a client of the server at the base url, e.g. http://localhost:5454.
+func New(baseUrl string, options Options) *Client {
+	c := Client{
+		baseUrl: strings.TrimSuffix(baseUrl, "/") + "/api",
+		http:    options.HTTPClient,
+		retries: options.Retries,
+		backoff: options.Backoff,
+		token:   options.Token,
+	}
+
+	if c.http == nil {
+		c.http = http.DefaultClient
+	}
+
+	if c.backoff == 0 {
+		c.backoff = defaultBackoff
+	}
+
+	return &c
+}
+
+// SetToken sets the bearer token sent with the requests, an empty token makes the requests anonymous.
+func (c *Client) SetToken(token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.token = token
+}
+
+// Token returns the bearer token sent with the requests.
+func (c *Client) Token() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.token
+}
+
+// request is a request to the api.
+type request struct {
+	method string
+	path   string
+	query  map[string]string
+	// json is encoded as the body
+	json any
+	// body is sent as is with the content type, the request is not retried
+	body        io.Reader
+	contentType string
+}
+
+// do sends the request and decodes the json response into out if it's not nil.
+// The responses with the error statuses are returned as *Error.
+func (c *Client) do(ctx context.Context, req request, out any) error {
+	resp, err := c.send(ctx, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if out == nil {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+
+	err = json.NewDecoder(resp.Body).Decode(out)
+	if err != nil {
+		return fmt.Errorf("can't decode response of %s %s: %w", req.method, req.path, err)
+	}
+
+	return nil
+}
+
+// send sends the request retrying it if it's idempotent.
+// The body of the returned response must be closed.
+func (c *Client) send(ctx context.Context, req request) (*http.Response, error) {
+	var payload []byte
+
+	if req.json != nil {
+		var err error
+		payload, err = json.Marshal(req.json)
+		if err != nil {
+			return nil, fmt.Errorf("can't encode request of %s %s: %w", req.method, req.path, err)
+		}
+	}
+
+	retries := c.retries
+	if req.method == http.MethodPost || req.body != nil {
+		retries = 0
+	}
+
+	for attempt := 0; ; attempt++ {
+		resp, err := c.sendOnce(ctx, req, payload)
+
+		if attempt >= retries || !retryable(resp, err) || ctx.Err() != nil {
+			if err != nil {
+				return nil, err
+			}
+			if resp.StatusCode >= 400 {
+				defer resp.Body.Close()
+				return nil, newError(resp)
+			}
+			return resp, nil
+		}
+
+		delay := c.backoff << attempt
+		if resp != nil {
+			if after, ok := retryAfter(resp); ok {
+				delay = after
+			}
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (c *Client) sendOnce(ctx context.Context, req request, payload []byte) (*http.Response, error) {
+	var body io.Reader
+	switch {
+	case req.body != nil:
+		body = req.body
+	case payload != nil:
+		body = bytes.NewReader(payload)
+	}
+
+	r, err := http.NewRequestWithContext(ctx, req.method, c.baseUrl+req.path, body)
+	if err != nil {
+		return nil, fmt.Errorf("can't create request %s %s: %w", req.method, req.path, err)
+	}
+
+	if len(req.query) > 0 {
+		q := r.URL.Query()
+		for k, v := range req.query {
+			q.Set(k, v)
+		}
+		r.URL.RawQuery = q.Encode()
+	}
+
+	switch {
+	case req.contentType != "":
+		r.Header.Set("Content-Type", req.contentType)
+	case payload != nil:
+		r.Header.Set("Content-Type", "application/json")
+	}
+
+	if token := c.Token(); token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := c.http.Do(r)
+	if err != nil {
+		return nil, fmt.Errorf("can't send request %s %s: %w", req.method, req.path, err)
+	}
+
+	return resp, nil
+}
+
+func retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// retryAfter returns the delay of the Retry-After header in seconds.
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	seconds, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || seconds < 0 {
+		return 0, false
+	}
+	return time.Duration(seconds) * time.Second, true
+}
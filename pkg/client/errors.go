@@ -0,0 +1,60 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Error is an error response of the api.
+// It matches the sentinel errors with the same status code:
+//
+//	errors.Is(err, client.ErrNotFound)
+type Error struct {
+	StatusCode int
+	// Message is the error field of the response
+	Message string
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("digital-library api: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("digital-library api: %d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.StatusCode == e.StatusCode && (t.Message == "" || t.Message == e.Message)
+}
+
+// The errors with the status codes the api responds with.
+var (
+	ErrBadRequest           = &Error{StatusCode: http.StatusBadRequest}
+	ErrUnauthorized         = &Error{StatusCode: http.StatusUnauthorized}
+	ErrForbidden            = &Error{StatusCode: http.StatusForbidden}
+	ErrNotFound             = &Error{StatusCode: http.StatusNotFound}
+	ErrConflict             = &Error{StatusCode: http.StatusConflict}
+	ErrUnsupportedMediaType = &Error{StatusCode: http.StatusUnsupportedMediaType}
+	ErrUnprocessableEntity  = &Error{StatusCode: http.StatusUnprocessableEntity}
+	ErrTooManyRequests      = &Error{StatusCode: http.StatusTooManyRequests}
+	ErrInternal             = &Error{StatusCode: http.StatusInternalServerError}
+)
+
+// maxErrorSize limits the error responses read.
+const maxErrorSize = 1 << 16
+
+func newError(resp *http.Response) *Error {
+	e := Error{StatusCode: resp.StatusCode}
+
+	var body struct {
+		Error string `json:"error"`
+	}
+
+	if json.NewDecoder(io.LimitReader(resp.Body, maxErrorSize)).Decode(&body) == nil {
+		e.Message = body.Error
+	}
+
+	return &e
+}
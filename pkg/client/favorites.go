@@ -0,0 +1,55 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// GetFavoriteBooks returns the favorite books of the user.
+func (c *Client) GetFavoriteBooks(ctx context.Context, userId int) ([]Book, error) {
+	var resp struct {
+		Books []Book
+	}
+
+	err := c.do(ctx, request{method: http.MethodGet, path: fmt.Sprintf("/user/%d/books", userId)}, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Books, nil
+}
+
+// AddFavoriteBook adds the book to the favorites of the user, adding it twice is not an error.
+func (c *Client) AddFavoriteBook(ctx context.Context, userId, bookId int) error {
+	return c.do(ctx, request{method: http.MethodPut, path: fmt.Sprintf("/user/%d/books/%d", userId, bookId)}, nil)
+}
+
+// RemoveFavoriteBook removes the book from the favorites of the user.
+func (c *Client) RemoveFavoriteBook(ctx context.Context, userId, bookId int) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: fmt.Sprintf("/user/%d/books/%d", userId, bookId)}, nil)
+}
+
+// GetFavoriteAuthors returns the favorite authors of the user.
+func (c *Client) GetFavoriteAuthors(ctx context.Context, userId int) ([]Author, error) {
+	var resp struct {
+		Authors []Author
+	}
+
+	err := c.do(ctx, request{method: http.MethodGet, path: fmt.Sprintf("/user/%d/authors", userId)}, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Authors, nil
+}
+
+// AddFavoriteAuthor adds the author to the favorites of the user, adding it twice is not an error.
+func (c *Client) AddFavoriteAuthor(ctx context.Context, userId, authorId int) error {
+	return c.do(ctx, request{method: http.MethodPut, path: fmt.Sprintf("/user/%d/authors/%d", userId, authorId)}, nil)
+}
+
+// RemoveFavoriteAuthor removes the author from the favorites of the user.
+func (c *Client) RemoveFavoriteAuthor(ctx context.Context, userId, authorId int) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: fmt.Sprintf("/user/%d/authors/%d", userId, authorId)}, nil)
+}
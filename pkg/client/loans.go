@@ -0,0 +1,119 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// GetLoans returns the loans of the user, the most recently checked out first.
+// If the status is not empty only the loans with it are returned.
+func (c *Client) GetLoans(ctx context.Context, userId int, status string, limit, offset int) ([]Loan, error) {
+	return c.getLoans(ctx, fmt.Sprintf("/user/%d/loans", userId), status, limit, offset)
+}
+
+// GetAllLoans returns the loans of all users, only admins can list them.
+func (c *Client) GetAllLoans(ctx context.Context, status string, limit, offset int) ([]Loan, error) {
+	return c.getLoans(ctx, "/loans", status, limit, offset)
+}
+
+func (c *Client) getLoans(ctx context.Context, path, status string, limit, offset int) ([]Loan, error) {
+	var resp struct {
+		Loans []Loan `json:"loans"`
+	}
+
+	q := pageQuery(limit, offset)
+	if status != "" {
+		q["status"] = status
+	}
+
+	err := c.do(ctx, request{method: http.MethodGet, path: path, query: q}, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Loans, nil
+}
+
+// CheckoutBook lends a copy of the book to the user and returns the loan.
+func (c *Client) CheckoutBook(ctx context.Context, userId, bookId int) (*Loan, error) {
+	var l Loan
+
+	err := c.do(ctx, request{
+		method: http.MethodPost,
+		path:   fmt.Sprintf("/user/%d/loans", userId),
+		json:   bookIdRequest{bookId},
+	}, &l)
+	if err != nil {
+		return nil, err
+	}
+
+	return &l, nil
+}
+
+// ReturnLoan returns the copy lent to the user.
+func (c *Client) ReturnLoan(ctx context.Context, userId, loanId int) error {
+	return c.do(ctx, request{method: http.MethodPost, path: fmt.Sprintf("/user/%d/loans/%d/return", userId, loanId)}, nil)
+}
+
+// GetHolds returns the holds of the user, the oldest first.
+func (c *Client) GetHolds(ctx context.Context, userId int) ([]Hold, error) {
+	var resp struct {
+		Holds []Hold `json:"holds"`
+	}
+
+	err := c.do(ctx, request{method: http.MethodGet, path: fmt.Sprintf("/user/%d/holds", userId)}, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Holds, nil
+}
+
+// PlaceHold puts the user in the queue for a copy of the book and returns the hold.
+func (c *Client) PlaceHold(ctx context.Context, userId, bookId int) (*Hold, error) {
+	var h Hold
+
+	err := c.do(ctx, request{
+		method: http.MethodPost,
+		path:   fmt.Sprintf("/user/%d/holds", userId),
+		json:   bookIdRequest{bookId},
+	}, &h)
+	if err != nil {
+		return nil, err
+	}
+
+	return &h, nil
+}
+
+// CancelHold removes the user from the queue for the book.
+func (c *Client) CancelHold(ctx context.Context, userId, bookId int) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: fmt.Sprintf("/user/%d/holds/%d", userId, bookId)}, nil)
+}
+
+// GetAvailability returns how many copies of the book can be checked out.
+func (c *Client) GetAvailability(ctx context.Context, bookId int) (*Availability, error) {
+	var a Availability
+
+	err := c.do(ctx, request{method: http.MethodGet, path: fmt.Sprintf("/book/%d/availability", bookId)}, &a)
+	if err != nil {
+		return nil, err
+	}
+
+	return &a, nil
+}
+
+// PutBookCopies sets the number of the copies of the book, only admins can set it.
+func (c *Client) PutBookCopies(ctx context.Context, bookId, copies int) error {
+	return c.do(ctx, request{
+		method: http.MethodPut,
+		path:   fmt.Sprintf("/book/%d/copies", bookId),
+		json: struct {
+			Copies int `json:"copies"`
+		}{copies},
+	}, nil)
+}
+
+type bookIdRequest struct {
+	BookId int `json:"book_id"`
+}
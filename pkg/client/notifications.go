@@ -0,0 +1,87 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// NotificationList is a page of the notifications of the user
+// along with the number of all unread ones.
+type NotificationList struct {
+	Notifications []Notification `json:"notifications"`
+	Unread        int            `json:"unread"`
+}
+
+// NotificationPreference tells how the user is notified of the type.
+type NotificationPreference struct {
+	Type  string `json:"type"`
+	InApp bool   `json:"in_app"`
+	Email bool   `json:"email"`
+}
+
+// NotificationPreferences are the email address of the user, empty if there is none,
+// and the preferences of the notification types.
+type NotificationPreferences struct {
+	Email       string                   `json:"email"`
+	Preferences []NotificationPreference `json:"preferences"`
+}
+
+// GetNotifications returns the notifications of the user, the newest first.
+// With unreadOnly the read ones are skipped.
+func (c *Client) GetNotifications(ctx context.Context, userId int, unreadOnly bool, limit, offset int) (*NotificationList, error) {
+	var resp NotificationList
+
+	q := pageQuery(limit, offset)
+	if unreadOnly {
+		q["unread"] = strconv.FormatBool(unreadOnly)
+	}
+
+	err := c.do(ctx, request{method: http.MethodGet, path: fmt.Sprintf("/user/%d/notifications", userId), query: q}, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
+
+// ReadNotification marks the notification as read.
+func (c *Client) ReadNotification(ctx context.Context, userId, id int) error {
+	return c.do(ctx, request{method: http.MethodPost, path: fmt.Sprintf("/user/%d/notifications/%d/read", userId, id)}, nil)
+}
+
+// ReadAllNotifications marks all notifications of the user as read.
+func (c *Client) ReadAllNotifications(ctx context.Context, userId int) error {
+	return c.do(ctx, request{method: http.MethodPost, path: fmt.Sprintf("/user/%d/notifications/read", userId)}, nil)
+}
+
+func (c *Client) DeleteNotification(ctx context.Context, userId, id int) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: fmt.Sprintf("/user/%d/notifications/%d", userId, id)}, nil)
+}
+
+// GetNotificationPreferences returns the email address of the user
+// and the preferences of all notification types.
+func (c *Client) GetNotificationPreferences(ctx context.Context, userId int) (*NotificationPreferences, error) {
+	var p NotificationPreferences
+
+	err := c.do(ctx, request{method: http.MethodGet, path: fmt.Sprintf("/user/%d/notifications/preferences", userId)}, &p)
+	if err != nil {
+		return nil, err
+	}
+
+	return &p, nil
+}
+
+// PutNotificationPreferences sets the preferences of the listed types, the other types are kept.
+// The email address of the user is replaced only if email isn't nil, empty removes it.
+func (c *Client) PutNotificationPreferences(ctx context.Context, userId int, email *string, preferences []NotificationPreference) error {
+	return c.do(ctx, request{
+		method: http.MethodPut,
+		path:   fmt.Sprintf("/user/%d/notifications/preferences", userId),
+		json: struct {
+			Email       *string                  `json:"email,omitempty"`
+			Preferences []NotificationPreference `json:"preferences"`
+		}{email, preferences},
+	}, nil)
+}
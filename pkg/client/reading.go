@@ -0,0 +1,123 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// GetReadingStates returns the reading states of the user, the most recently read first.
+// If the status is not empty only the states with it are returned.
+func (c *Client) GetReadingStates(ctx context.Context, userId int, status string) ([]ReadingState, error) {
+	var resp struct {
+		States []ReadingState `json:"states"`
+	}
+
+	q := map[string]string{}
+	if status != "" {
+		q["status"] = status
+	}
+
+	err := c.do(ctx, request{method: http.MethodGet, path: fmt.Sprintf("/user/%d/reading", userId), query: q}, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.States, nil
+}
+
+// GetReadingState returns the reading state of the book.
+func (c *Client) GetReadingState(ctx context.Context, userId, bookId int) (*ReadingState, error) {
+	var s ReadingState
+
+	err := c.do(ctx, request{method: http.MethodGet, path: readingPath(userId, bookId)}, &s)
+	if err != nil {
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+// PutReadingState creates or updates the reading state of the book and returns it
+// with the last read time set by the server. An empty status means reading.
+func (c *Client) PutReadingState(ctx context.Context, s ReadingState) (*ReadingState, error) {
+	var resp ReadingState
+
+	err := c.do(ctx, request{
+		method: http.MethodPut,
+		path:   readingPath(s.UserId, s.BookId),
+		json: struct {
+			Status  string  `json:"status"`
+			Page    int     `json:"page"`
+			Percent float64 `json:"percent"`
+		}{s.Status, s.Page, s.Percent},
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
+
+// DeleteReadingState removes the reading state of the book, the bookmarks are kept.
+func (c *Client) DeleteReadingState(ctx context.Context, userId, bookId int) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: readingPath(userId, bookId)}, nil)
+}
+
+// GetBookmarks returns the bookmarks of the book ordered by page.
+func (c *Client) GetBookmarks(ctx context.Context, userId, bookId int) ([]Bookmark, error) {
+	var resp struct {
+		Bookmarks []Bookmark `json:"bookmarks"`
+	}
+
+	err := c.do(ctx, request{method: http.MethodGet, path: readingPath(userId, bookId) + "/bookmarks"}, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Bookmarks, nil
+}
+
+// PostBookmark creates the bookmark of the book and returns its id.
+func (c *Client) PostBookmark(ctx context.Context, b Bookmark) (int, error) {
+	var resp idResponse
+
+	err := c.do(ctx, request{
+		method: http.MethodPost,
+		path:   readingPath(b.UserId, b.BookId) + "/bookmarks",
+		json:   bookmarkRequest(b),
+	}, &resp)
+	if err != nil {
+		return 0, err
+	}
+
+	return resp.Id, nil
+}
+
+// PutBookmark updates the page and the note of the bookmark.
+func (c *Client) PutBookmark(ctx context.Context, b Bookmark) error {
+	return c.do(ctx, request{
+		method: http.MethodPut,
+		path:   fmt.Sprintf("%s/bookmarks/%d", readingPath(b.UserId, b.BookId), b.Id),
+		json:   bookmarkRequest(b),
+	}, nil)
+}
+
+// DeleteBookmark removes the bookmark.
+func (c *Client) DeleteBookmark(ctx context.Context, userId, bookId, id int) error {
+	return c.do(ctx, request{
+		method: http.MethodDelete,
+		path:   fmt.Sprintf("%s/bookmarks/%d", readingPath(userId, bookId), id),
+	}, nil)
+}
+
+func readingPath(userId, bookId int) string {
+	return fmt.Sprintf("/user/%d/reading/%d", userId, bookId)
+}
+
+func bookmarkRequest(b Bookmark) any {
+	return struct {
+		Page int    `json:"page"`
+		Note string `json:"note"`
+	}{b.Page, b.Note}
+}
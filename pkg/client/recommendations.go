@@ -0,0 +1,26 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// GetRecommendations returns a page of the books recommended to the user, the best first.
+// Only the user and admins can get them. Zero limit means the default page size of the server.
+func (c *Client) GetRecommendations(ctx context.Context, userId, limit, offset int) ([]RecommendedBook, error) {
+	var resp struct {
+		Books []RecommendedBook `json:"books"`
+	}
+
+	err := c.do(ctx, request{
+		method: http.MethodGet,
+		path:   fmt.Sprintf("/user/%d/recommendations", userId),
+		query:  pageQuery(limit, offset),
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Books, nil
+}
@@ -0,0 +1,74 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+type reviewsResponse struct {
+	Reviews []Review `json:"reviews"`
+}
+
+// GetBookReviews returns the reviews of the book.
+func (c *Client) GetBookReviews(ctx context.Context, bookId int) ([]Review, error) {
+	var resp reviewsResponse
+
+	err := c.do(ctx, request{method: http.MethodGet, path: fmt.Sprintf("/book/%d/reviews", bookId)}, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Reviews, nil
+}
+
+// GetUserReviews returns the reviews written by the user.
+// Deleted reviews are returned only to admins with includeDeleted.
+func (c *Client) GetUserReviews(ctx context.Context, userId int, includeDeleted bool) ([]Review, error) {
+	var resp reviewsResponse
+
+	err := c.do(ctx, request{
+		method: http.MethodGet,
+		path:   fmt.Sprintf("/user/%d/reviews", userId),
+		query:  includeDeletedQuery(includeDeleted),
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Reviews, nil
+}
+
+// GetReview returns the review of the book written by the user.
+func (c *Client) GetReview(ctx context.Context, bookId, userId int) (*Review, error) {
+	var r Review
+
+	err := c.do(ctx, request{method: http.MethodGet, path: reviewPath(bookId, userId)}, &r)
+	if err != nil {
+		return nil, err
+	}
+
+	return &r, nil
+}
+
+// PutReview creates or updates the review of the book written by the user of the review.
+// The rating is from 1 to 5.
+func (c *Client) PutReview(ctx context.Context, r Review) error {
+	return c.do(ctx, request{
+		method: http.MethodPut,
+		path:   reviewPath(r.BookId, r.UserId),
+		json: struct {
+			Rating int    `json:"rating"`
+			Body   string `json:"body"`
+		}{r.Rating, r.Body},
+	}, nil)
+}
+
+// DeleteReview deletes the review of the book written by the user.
+func (c *Client) DeleteReview(ctx context.Context, bookId, userId int) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: reviewPath(bookId, userId)}, nil)
+}
+
+func reviewPath(bookId, userId int) string {
+	return fmt.Sprintf("/book/%d/review/%d", bookId, userId)
+}
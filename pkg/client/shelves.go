@@ -0,0 +1,98 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// GetUserShelves returns the shelves of the user ordered by name,
+// the private ones are returned only to the user and admins.
+func (c *Client) GetUserShelves(ctx context.Context, userId int) ([]Shelf, error) {
+	var resp struct {
+		Shelves []Shelf `json:"shelves"`
+	}
+
+	err := c.do(ctx, request{method: http.MethodGet, path: fmt.Sprintf("/user/%d/shelves", userId)}, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Shelves, nil
+}
+
+// GetShelf returns the shelf along with its books in the order of the shelf.
+func (c *Client) GetShelf(ctx context.Context, id int) (*Shelf, []Book, error) {
+	var resp struct {
+		Shelf
+		Books []Book `json:"books"`
+	}
+
+	err := c.do(ctx, request{method: http.MethodGet, path: fmt.Sprintf("/shelf/%d", id)}, &resp)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &resp.Shelf, resp.Books, nil
+}
+
+// PostShelf creates the shelf of the user and returns its id.
+func (c *Client) PostShelf(ctx context.Context, s Shelf) (int, error) {
+	var resp idResponse
+
+	err := c.do(ctx, request{
+		method: http.MethodPost,
+		path:   fmt.Sprintf("/user/%d/shelves", s.UserId),
+		json:   shelfRequest(s),
+	}, &resp)
+	if err != nil {
+		return 0, err
+	}
+
+	return resp.Id, nil
+}
+
+// PutShelf updates the name, the description and the visibility of the shelf.
+func (c *Client) PutShelf(ctx context.Context, s Shelf) error {
+	return c.do(ctx, request{
+		method: http.MethodPut,
+		path:   fmt.Sprintf("/shelf/%d", s.Id),
+		json:   shelfRequest(s),
+	}, nil)
+}
+
+// DeleteShelf removes the shelf, the books on it aren't affected.
+func (c *Client) DeleteShelf(ctx context.Context, id int) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: fmt.Sprintf("/shelf/%d", id)}, nil)
+}
+
+// AddShelfBook adds the book to the end of the shelf,
+// the book already on the shelf keeps its position.
+func (c *Client) AddShelfBook(ctx context.Context, shelfId, bookId int) error {
+	return c.do(ctx, request{method: http.MethodPut, path: fmt.Sprintf("/shelf/%d/books/%d", shelfId, bookId)}, nil)
+}
+
+// RemoveShelfBook removes the book from the shelf.
+func (c *Client) RemoveShelfBook(ctx context.Context, shelfId, bookId int) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: fmt.Sprintf("/shelf/%d/books/%d", shelfId, bookId)}, nil)
+}
+
+// ReorderShelf orders the books of the shelf as listed,
+// every book of the shelf should be listed once.
+func (c *Client) ReorderShelf(ctx context.Context, shelfId int, bookIds []int) error {
+	return c.do(ctx, request{
+		method: http.MethodPut,
+		path:   fmt.Sprintf("/shelf/%d/books", shelfId),
+		json: struct {
+			BookIds []int `json:"book_ids"`
+		}{bookIds},
+	}, nil)
+}
+
+func shelfRequest(s Shelf) any {
+	return struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+		Public      bool   `json:"public"`
+	}{s.Name, s.Description, s.Public}
+}
@@ -0,0 +1,63 @@
+package client
+
+import (
+	"time"
+
+	"github.com/qo/digital-library/internal/notify"
+	"github.com/qo/digital-library/internal/storage"
+	"github.com/qo/digital-library/internal/storage/author"
+	"github.com/qo/digital-library/internal/storage/book"
+	"github.com/qo/digital-library/internal/storage/book_review"
+	"github.com/qo/digital-library/internal/storage/bookmark"
+	"github.com/qo/digital-library/internal/storage/hold"
+	"github.com/qo/digital-library/internal/storage/loan"
+	"github.com/qo/digital-library/internal/storage/notification"
+	"github.com/qo/digital-library/internal/storage/reading_state"
+	"github.com/qo/digital-library/internal/storage/recommendation"
+	"github.com/qo/digital-library/internal/storage/shelf"
+	"github.com/qo/digital-library/internal/storage/user"
+)
+
+// The types of the api are the types of the server.
+type (
+	User    = user.User
+	Book    = book.Book
+	TopBook = book.TopBook
+	Author  = author.Author
+	Review  = book_review.BookReview
+
+	RecommendedBook = recommendation.RecommendedBook
+	ReadingState    = reading_state.ReadingState
+	Bookmark        = bookmark.Bookmark
+	Shelf           = shelf.Shelf
+	Loan            = loan.Loan
+	Hold            = hold.Hold
+	Availability    = storage.Availability
+	Notification    = notification.Notification
+)
+
+// The statuses of the loans.
+const (
+	LoanActive   = loan.StatusActive
+	LoanOverdue  = loan.StatusOverdue
+	LoanReturned = loan.StatusReturned
+)
+
+// The types of the notifications.
+const (
+	NotificationHoldAvailable = notify.TypeHoldAvailable
+	NotificationLoanOverdue   = notify.TypeLoanOverdue
+)
+
+// The roles of the users.
+const (
+	RoleUser  = user.RoleUser
+	RoleMod   = user.RoleMod
+	RoleAdmin = user.RoleAdmin
+)
+
+// Session is a started session, its token is sent as the bearer token.
+type Session struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
@@ -0,0 +1,121 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// Login starts a session of the user and makes the client act as the user.
+func (c *Client) Login(ctx context.Context, userId int, password string) (*Session, error) {
+	var s Session
+
+	err := c.do(ctx, request{
+		method: http.MethodPost,
+		path:   "/login",
+		json: struct {
+			UserId   int    `json:"user_id"`
+			Password string `json:"password"`
+		}{userId, password},
+	}, &s)
+	if err != nil {
+		return nil, err
+	}
+
+	c.SetToken(s.Token)
+
+	return &s, nil
+}
+
+// Logout ends the session of the client.
+func (c *Client) Logout(ctx context.Context) error {
+	err := c.do(ctx, request{method: http.MethodPost, path: "/logout"}, nil)
+	if err != nil {
+		return err
+	}
+
+	c.SetToken("")
+
+	return nil
+}
+
+// GetUser returns the user.
+func (c *Client) GetUser(ctx context.Context, id int) (*User, error) {
+	var u User
+
+	err := c.do(ctx, request{method: http.MethodGet, path: fmt.Sprintf("/user/%d", id)}, &u)
+	if err != nil {
+		return nil, err
+	}
+
+	return &u, nil
+}
+
+type userRequest struct {
+	User
+	Password string `json:"password,omitempty"`
+}
+
+// PostUser creates the user and returns its id, the id is assigned if it's 0.
+// Users without a password can't log in.
+func (c *Client) PostUser(ctx context.Context, u User, password string) (int, error) {
+	var resp idResponse
+
+	err := c.do(ctx, request{
+		method: http.MethodPost,
+		path:   "/user",
+		json:   userRequest{u, password},
+	}, &resp)
+	if err != nil {
+		return 0, err
+	}
+
+	return resp.Id, nil
+}
+
+// PutUser updates the user, the password is changed only if it's not empty.
+// The role is changed only if it differs, and only by admins.
+func (c *Client) PutUser(ctx context.Context, u User, password string) error {
+	return c.do(ctx, request{
+		method: http.MethodPut,
+		path:   "/user",
+		json:   userRequest{u, password},
+	}, nil)
+}
+
+// ListUsers returns a page of the users ordered by id.
+// Zero limit means the default page size of the server.
+func (c *Client) ListUsers(ctx context.Context, limit, offset int) ([]User, error) {
+	var resp struct {
+		Users []User `json:"users"`
+	}
+
+	err := c.do(ctx, request{method: http.MethodGet, path: "/users", query: pageQuery(limit, offset)}, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Users, nil
+}
+
+// PutRole changes the role of the user, users can become mods and mods can become users.
+// It returns ErrConflict if the role was changed by someone else at the same time.
+func (c *Client) PutRole(ctx context.Context, id, role int) error {
+	return c.do(ctx, request{
+		method: http.MethodPut,
+		path:   fmt.Sprintf("/user/%d/role", id),
+		json: struct {
+			Role int `json:"role"`
+		}{role},
+	}, nil)
+}
+
+// DeleteUser deletes the user, only admins can delete users and admins are never deleted.
+func (c *Client) DeleteUser(ctx context.Context, id int) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: fmt.Sprintf("/user/%d", id)}, nil)
+}
+
+// idResponse is the response of the requests creating an entity.
+type idResponse struct {
+	Id int `json:"id"`
+}